@@ -0,0 +1,368 @@
+package abc
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Lecture de la notation ABC (https://abcnotation.com/wiki/abc:standard:v2.1), utilisée pour les airs traditionnels.
+// Seul le premier air (X:) du texte est lu. Sont reconnus :
+//	- l'en-tête : X:, T:, C:, K:, M:, L:, Q:, R:, O: (les autres champs sont ignorés)
+//	- le corps : notes et octaves, altérations, durées, rythmes pointés (> <), silences, accords [CEG],
+//	  mesures, barres de reprise et fins ([1 [2), triolets (3, symboles d'accord "Am" et champs en ligne [K:...].
+// Les ornements, petites notes, liaisons et paroles sont acceptés mais ne sont pas dessinés.
+
+// ParseError indique la ligne du texte ABC en erreur
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("abc line %d: %s", e.Line, e.Msg)
+}
+
+// Tune : air ABC
+type Tune struct {
+	Number     int
+	Titles     []string
+	Composer   string
+	Origin     string
+	Rhythm     string
+	Key        Key
+	Meter      string  // ex: 6/8, C, none
+	UnitLength float64 // L: en fraction de ronde, ex: 0.125
+	Tempo      int     // Q: en noires par minute, 0 = inconnu
+	Lines      [][]Element
+}
+
+// Title retourne le titre principal
+func (t *Tune) Title() string {
+	if len(t.Titles) == 0 {
+		return ""
+	}
+	return t.Titles[0]
+}
+
+// Key : tonalité du champ K:
+type Key struct {
+	Tonic  string // ex: Bb, F#
+	Mode   string // major, minor, dorian, mixolydian ...
+	Fifths int    // armure : > 0 dièses, < 0 bémols
+	Bass   bool   // clef=bass
+}
+
+// Name retourne le nom de la tonalité, ex: "D dorian", vide sans tonalité
+func (k Key) Name() string {
+	if k.Tonic == "" {
+		return ""
+	}
+	return k.Tonic + " " + k.Mode
+}
+
+// Parse lit le premier air du texte ABC
+func Parse(text string) (*Tune, error) {
+	tune := &Tune{}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	inTune, inBody := false, false
+	var body []sourceLine
+	for i, raw := range lines {
+		line := strings.TrimRight(stripComment(raw), " \t")
+		number := i + 1
+
+		if !inTune {
+			if strings.HasPrefix(line, "X:") {
+				inTune = true
+				tune.Number, _ = strconv.Atoi(strings.TrimSpace(line[2:]))
+			} else if isField(line) && strings.HasPrefix(line, "T:") {
+				// X: est facultatif pour un air seul
+				inTune = true
+			} else {
+				continue
+			}
+		}
+		if inBody && strings.TrimSpace(raw) == "" {
+			break // une ligne vide termine l'air
+		}
+		if inBody && strings.HasPrefix(line, "X:") {
+			break
+		}
+		if line == "" {
+			continue
+		}
+
+		if isField(line) {
+			field, value := line[0], strings.TrimSpace(line[2:])
+			if !inBody {
+				if err := tune.setHeader(field, value, number); err != nil {
+					return nil, err
+				}
+				if field == 'K' {
+					inBody = true
+				}
+				continue
+			}
+			// Champs dans le corps : K:, L: et M: changent la suite, les autres (w:, V:, P: ...) sont ignorés
+			if field == 'K' || field == 'L' || field == 'M' {
+				body = append(body, sourceLine{number, "[" + line + "]", false})
+			}
+			continue
+		}
+		if !inBody {
+			return nil, &ParseError{number, "music before the K: field"}
+		}
+		body = append(body, sourceLine{number, line, false})
+	}
+
+	if !inTune {
+		return nil, &ParseError{1, "no tune found (missing X: or T: field)"}
+	}
+	if !inBody {
+		return nil, &ParseError{len(lines), "missing K: field"}
+	}
+	if tune.UnitLength == 0 {
+		tune.UnitLength = defaultUnitLength(tune.Meter)
+	}
+
+	// Une ligne terminée par \ se poursuit sur la suivante
+	var merged []sourceLine
+	for _, l := range body {
+		if n := len(merged); n > 0 && merged[n-1].continued {
+			merged[n-1].text += " " + l.text
+			merged[n-1].continued = strings.HasSuffix(l.text, "\\")
+			merged[n-1].text = strings.TrimSuffix(merged[n-1].text, "\\")
+			continue
+		}
+		l.continued = strings.HasSuffix(l.text, "\\")
+		l.text = strings.TrimSuffix(l.text, "\\")
+		merged = append(merged, l)
+	}
+
+	p := &musicParser{unit: tune.UnitLength, key: tune.Key}
+	for _, l := range merged {
+		elements, err := p.parseLine(l.text, l.number)
+		if err != nil {
+			return nil, err
+		}
+		if len(elements) > 0 {
+			tune.Lines = append(tune.Lines, elements)
+		}
+	}
+	if len(tune.Lines) == 0 {
+		return nil, &ParseError{len(lines), "tune has no music"}
+	}
+	return tune, nil
+}
+
+type sourceLine struct {
+	number    int
+	text      string
+	continued bool
+}
+
+var fieldPattern = regexp.MustCompile(`^[A-Za-z]:`)
+
+func isField(line string) bool {
+	return fieldPattern.MatchString(line)
+}
+
+func stripComment(line string) string {
+	if strings.HasPrefix(line, "%%") {
+		return "" // directives de mise en page
+	}
+	if i := strings.IndexByte(line, '%'); i >= 0 && (i == 0 || line[i-1] != '\\') {
+		return line[:i]
+	}
+	return line
+}
+
+func (t *Tune) setHeader(field byte, value string, line int) error {
+	switch field {
+	case 'T':
+		if value != "" {
+			t.Titles = append(t.Titles, value)
+		}
+	case 'C':
+		if t.Composer == "" {
+			t.Composer = value
+		}
+	case 'O':
+		t.Origin = value
+	case 'R':
+		t.Rhythm = value
+	case 'M':
+		if _, ok := meterLength(value); !ok {
+			return &ParseError{line, fmt.Sprintf("invalid meter %q", value)}
+		}
+		t.Meter = value
+	case 'L':
+		length, ok := parseFraction(value)
+		if !ok {
+			return &ParseError{line, fmt.Sprintf("invalid unit note length %q", value)}
+		}
+		t.UnitLength = length
+	case 'Q':
+		t.Tempo = parseTempo(value)
+	case 'K':
+		key, err := ParseKey(value)
+		if err != nil {
+			return &ParseError{line, err.Error()}
+		}
+		t.Key = key
+	}
+	return nil
+}
+
+// defaultUnitLength : 1/16 pour une mesure de moins de 3/4, 1/8 sinon
+func defaultUnitLength(meter string) float64 {
+	if length, ok := meterLength(meter); ok && length > 0 && length < 0.75 {
+		return 1.0 / 16
+	}
+	return 1.0 / 8
+}
+
+// meterLength retourne la durée d'une mesure en rondes (0 pour "none")
+func meterLength(meter string) (float64, bool) {
+	switch meter = strings.TrimSpace(meter); meter {
+	case "", "none":
+		return 0, true
+	case "C":
+		return 1, true
+	case "C|":
+		return 1, true
+	}
+	// Chiffrages composés : 2+3/8
+	if i := strings.IndexByte(meter, '/'); i > 0 {
+		sum := 0
+		for _, part := range strings.Split(meter[:i], "+") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n <= 0 {
+				return 0, false
+			}
+			sum += n
+		}
+		den, err := strconv.Atoi(strings.TrimSpace(meter[i+1:]))
+		if err != nil || den <= 0 {
+			return 0, false
+		}
+		return float64(sum) / float64(den), true
+	}
+	return 0, false
+}
+
+// parseFraction lit "1/8"
+func parseFraction(s string) (float64, bool) {
+	num, den, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return 0, false
+	}
+	n, err1 := strconv.Atoi(num)
+	d, err2 := strconv.Atoi(den)
+	if err1 != nil || err2 != nil || n <= 0 || d <= 0 {
+		return 0, false
+	}
+	return float64(n) / float64(d), true
+}
+
+var (
+	tempoPattern = regexp.MustCompile(`(?:([0-9]+/[0-9]+)(?:\s+[0-9]+/[0-9]+)*\s*=\s*)?([0-9]+)`)
+	quotePattern = regexp.MustCompile(`"[^"]*"`)
+)
+
+// parseTempo convertit Q: en noires par minute ("1/4=120", "3/8=60", "120", "\"Allegro\" 1/2=80")
+func parseTempo(value string) int {
+	value = quotePattern.ReplaceAllString(value, "")
+	m := tempoPattern.FindStringSubmatch(value)
+	if m == nil {
+		return 0
+	}
+	bpm, _ := strconv.Atoi(m[2])
+	beat := 0.25
+	if m[1] != "" {
+		beat, _ = parseFraction(m[1])
+	}
+	return int(math.Round(float64(bpm) * beat / 0.25))
+}
+
+// Décalage des modes sur le cycle des quintes par rapport au majeur
+var modes = []struct {
+	prefix, name string
+	offset       int
+}{
+	{"maj", "major", 0}, {"ion", "major", 0}, {"min", "minor", -3}, {"aeo", "minor", -3},
+	{"m", "minor", -3}, {"mix", "mixolydian", -1}, {"dor", "dorian", -2}, {"phr", "phrygian", -4},
+	{"lyd", "lydian", 1}, {"loc", "locrian", -5},
+}
+
+var keyPattern = regexp.MustCompile(`^([A-G])([#b]?)\s*([A-Za-z]*)`)
+
+// ParseKey lit le champ K: ("G", "Am", "D dor", "Bb mix", "F#m clef=bass", "none", "HP")
+func ParseKey(value string) (Key, error) {
+	var key Key
+	fields := strings.Fields(value)
+	var rest []string
+	for _, f := range fields {
+		switch strings.ToLower(f) {
+		case "clef=bass", "bass":
+			key.Bass = true
+		case "clef=treble", "treble":
+		default:
+			rest = append(rest, f)
+		}
+	}
+	value = strings.Join(rest, " ")
+
+	switch value {
+	case "", "none":
+		return key, nil
+	case "HP", "Hp":
+		key.Tonic, key.Mode = "A", "mixolydian" // cornemuse
+		key.Fifths = 0
+		if value == "Hp" {
+			key.Fifths = 2
+		}
+		return key, nil
+	}
+
+	m := keyPattern.FindStringSubmatch(value)
+	if m == nil {
+		return key, fmt.Errorf("invalid key %q", value)
+	}
+	key.Tonic = m[1] + m[2]
+	key.Fifths = letterFifths[strings.IndexByte(letters, m[1][0])]
+	switch m[2] {
+	case "#":
+		key.Fifths += 7
+	case "b":
+		key.Fifths -= 7
+	}
+
+	key.Mode = "major"
+	if mode := strings.ToLower(m[3]); mode != "" {
+		found := false
+		for _, md := range modes {
+			if strings.HasPrefix(mode, md.prefix) && (md.prefix != "m" || mode == "m") {
+				key.Mode = md.name
+				key.Fifths += md.offset
+				found = true
+				break
+			}
+		}
+		if !found {
+			return key, fmt.Errorf("invalid mode %q", m[3])
+		}
+	}
+	if key.Fifths < -7 || key.Fifths > 7 {
+		return key, fmt.Errorf("invalid key %q", value)
+	}
+	return key, nil
+}
+
+var (
+	letters      = "CDEFGAB"
+	letterFifths = []int{0, 2, 4, -1, 1, 3, 5}
+)
@@ -0,0 +1,193 @@
+package abc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTune = `%abc-2.1
+X:1
+T:The Kesh
+T:The Kesh Jig
+C:Trad.
+O:Ireland
+R:jig
+M:6/8
+L:1/8
+Q:3/8=120
+K:G
+|:"G"GAG GAB|"D"ABA ABd|"G"edd gdd|"D"edB dBA|
+GAG GAB|ABA ABd|edd gdB|[1 AGF G3:|[2 AGF G2||
+% second part
+K:D dor
+|:(3ABc d>e ^f2 z2|[DFA]2 _B,/C/ c'2 x|]
+`
+
+func TestParseHeaders(t *testing.T) {
+	tune, err := Parse(testTune)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, tune.Number)
+	assert.Equal(t, "The Kesh", tune.Title())
+	assert.Equal(t, []string{"The Kesh", "The Kesh Jig"}, tune.Titles)
+	assert.Equal(t, "Trad.", tune.Composer)
+	assert.Equal(t, "Ireland", tune.Origin)
+	assert.Equal(t, "jig", tune.Rhythm)
+	assert.Equal(t, "6/8", tune.Meter)
+	assert.Equal(t, 0.125, tune.UnitLength)
+	assert.Equal(t, 180, tune.Tempo) // 3/8 = 120 : 180 noires par minute
+	assert.Equal(t, "G major", tune.Key.Name())
+	assert.Equal(t, 1, tune.Key.Fifths)
+	assert.Len(t, tune.Lines, 4)
+}
+
+func TestParseMusic(t *testing.T) {
+	tune, err := Parse(testTune)
+	require.NoError(t, err)
+
+	first := tune.Lines[0]
+	assert.Equal(t, BarElement, first[0].Kind)
+	assert.Equal(t, "|:", first[0].Bar)
+	assert.Equal(t, "G", first[1].Annotation)
+	assert.Equal(t, []Pitch{{Step: 4, Octave: 4}}, first[1].Pitches)
+	assert.Equal(t, 0.125, first[1].Length)
+
+	second := tune.Lines[1]
+	var endings []string
+	for _, el := range second {
+		if el.Ending != "" {
+			endings = append(endings, el.Ending)
+		}
+	}
+	assert.Equal(t, []string{"1", "2"}, endings)
+
+	// Changement de tonalité dans le corps
+	assert.Equal(t, KeyElement, tune.Lines[2][0].Kind)
+	assert.Equal(t, "D dorian", tune.Lines[2][0].Key.Name())
+	assert.Equal(t, 0, tune.Lines[2][0].Key.Fifths)
+
+	last := tune.Lines[3]
+	notes := []Element{}
+	for _, el := range last {
+		if el.Kind == NoteElement || el.Kind == RestElement {
+			notes = append(notes, el)
+		}
+	}
+	require.Len(t, notes, 12)
+	assert.Equal(t, 3, notes[0].Tuplet)
+	assert.Equal(t, 0.1875, notes[3].Length) // d>e : croche pointée
+	assert.Equal(t, 0.0625, notes[4].Length) // puis double croche
+	assert.Equal(t, "^", notes[5].Pitches[0].Accidental)
+	assert.Equal(t, RestElement, notes[6].Kind)
+	assert.Len(t, notes[7].Pitches, 3) // accord [DFA]2
+	assert.Equal(t, 0.25, notes[7].Length)
+	assert.Equal(t, Pitch{Step: 6, Octave: 3, Accidental: "_"}, notes[8].Pitches[0])
+	assert.Equal(t, 0.0625, notes[8].Length)
+	assert.Equal(t, 6, notes[10].Pitches[0].Octave) // c'
+	assert.True(t, notes[11].Invisible)
+	assert.Equal(t, "|]", last[len(last)-1].Bar)
+}
+
+func TestParseKey(t *testing.T) {
+	cases := []struct {
+		value  string
+		name   string
+		fifths int
+		bass   bool
+	}{
+		{"C", "C major", 0, false},
+		{"Am", "A minor", 0, false},
+		{"Bb", "Bb major", -2, false},
+		{"F#m", "F# minor", 3, false},
+		{"E min", "E minor", 1, false},
+		{"A mix", "A mixolydian", 2, false},
+		{"G Dorian clef=bass", "G dorian", -1, true},
+		{"HP", "A mixolydian", 0, false},
+		{"none", "", 0, false},
+	}
+	for _, c := range cases {
+		key, err := ParseKey(c.value)
+		require.NoError(t, err, c.value)
+		assert.Equal(t, c.name, key.Name(), c.value)
+		assert.Equal(t, c.fifths, key.Fifths, c.value)
+		assert.Equal(t, c.bass, key.Bass, c.value)
+	}
+
+	for _, value := range []string{"H", "C foo", "Fb loc"} {
+		_, err := ParseKey(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseDefaultUnitLength(t *testing.T) {
+	tune, err := Parse("X:1\nT:Reel\nM:2/4\nK:D\nde|")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0/16, tune.UnitLength)
+	assert.Equal(t, 1.0/16, tune.Lines[0][0].Length)
+
+	// X: facultatif, seul le premier air est lu
+	tune, err = Parse("T:First\nK:C\nCDEF|\n\nX:2\nT:Second\nK:G\nG|")
+	require.NoError(t, err)
+	assert.Equal(t, "First", tune.Title())
+	assert.Len(t, tune.Lines, 1)
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		text string
+		line int
+	}{
+		{"hello world", 1},
+		{"X:1\nT:No key\nM:3/4", 3},
+		{"X:1\nT:Bad meter\nM:three\nK:C\nC|", 3},
+		{"X:1\nT:Bad key\nK:Q\nC|", 3},
+		{"X:1\nT:Music first\nCDE\nK:C", 3},
+		{"X:1\nT:Bad note\nK:C\nCDE|\nCD$E|", 5},
+		{"X:1\nT:Chord\nK:C\n[CEG|", 4},
+		{"X:1\nT:Empty\nK:C\n", 4},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.text)
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), c.text)
+		assert.Equal(t, c.line, parseErr.Line, c.text)
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	tune, err := Parse(testTune)
+	require.NoError(t, err)
+
+	svg := string(RenderSVG(tune))
+	assert.True(t, strings.HasPrefix(svg, "<svg "))
+	assert.Contains(t, svg, ">The Kesh</text>")
+	assert.Contains(t, svg, ">Trad. (Ireland)</text>")
+	assert.Contains(t, svg, "<ellipse ")
+	assert.Contains(t, svg, "<line ")
+	assert.True(t, strings.HasSuffix(svg, "</svg>\n"))
+}
+
+func TestRenderPDF(t *testing.T) {
+	tune, err := Parse(testTune)
+	require.NoError(t, err)
+
+	conf := model.NewDefaultConfiguration()
+	ctx, err := api.ReadAndValidate(bytes.NewReader(RenderPDF(tune)), conf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, ctx.PageCount)
+
+	// Un air long est réparti sur plusieurs pages A4
+	long := "X:1\nT:Long\nM:4/4\nL:1/4\nK:C\n" + strings.Repeat("C D E F|G A B c|\n", 40)
+	tune, err = Parse(long)
+	require.NoError(t, err)
+	ctx, err = api.ReadAndValidate(bytes.NewReader(RenderPDF(tune)), conf)
+	require.NoError(t, err)
+	assert.Greater(t, ctx.PageCount, 1)
+}
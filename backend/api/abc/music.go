@@ -0,0 +1,367 @@
+package abc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ElementKind : type d'un élément du corps de l'air
+type ElementKind int
+
+const (
+	NoteElement  ElementKind = iota // note ou accord
+	RestElement                     // silence (z), x = silence invisible, Z = mesures de silence
+	BarElement                      // barre de mesure
+	KeyElement                      // changement de tonalité [K:...]
+	MeterElement                    // changement de mesure [M:...]
+)
+
+// Pitch : hauteur écrite. C = Do central (octave 4), c = octave 5.
+type Pitch struct {
+	Step       int    // 0 = C ... 6 = B
+	Octave     int    // 4 pour C, 5 pour c
+	Accidental string // "^", "^^", "_", "__", "=" ou vide
+}
+
+// Diatonic retourne le numéro de degré absolu (octave * 7 + degré)
+func (p Pitch) Diatonic() int {
+	return p.Octave*7 + p.Step
+}
+
+// Element : note, silence, barre ou changement de tonalité / mesure
+type Element struct {
+	Kind       ElementKind
+	Pitches    []Pitch // plusieurs hauteurs pour un accord
+	Length     float64 // durée écrite en rondes
+	Invisible  bool    // silence x
+	Tuplet     int     // > 0 sur la première note d'un n-olet
+	Bar        string  // |, ||, |], [|, |:, :|, ::
+	Ending     string  // numéro de fin de reprise après la barre
+	Annotation string  // symbole d'accord, ex: Am
+	Key        Key
+	Meter      string
+}
+
+type musicParser struct {
+	unit float64
+	key  Key
+
+	line       int
+	text       string
+	pos        int
+	elements   []Element
+	annotation string
+	tuplet     int
+	broken     float64 // facteur de durée de la note suivante après > ou <
+}
+
+func (p *musicParser) errorf(format string, args ...interface{}) error {
+	return &ParseError{p.line, fmt.Sprintf("column %d: ", p.pos+1) + fmt.Sprintf(format, args...)}
+}
+
+func (p *musicParser) peek(offset int) byte {
+	if p.pos+offset < len(p.text) {
+		return p.text[p.pos+offset]
+	}
+	return 0
+}
+
+// skipTo avance après le prochain caractère end
+func (p *musicParser) skipTo(end byte, what string) (string, error) {
+	i := strings.IndexByte(p.text[p.pos+1:], end)
+	if i < 0 {
+		return "", p.errorf("unterminated %s", what)
+	}
+	content := p.text[p.pos+1 : p.pos+1+i]
+	p.pos += i + 2
+	return content, nil
+}
+
+func (p *musicParser) parseLine(text string, line int) ([]Element, error) {
+	p.text, p.line, p.pos, p.elements = text, line, 0, nil
+
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '`' || c == ')' || c == '-' || c == '&' || c == 'y':
+			p.pos++
+		case strings.IndexByte(".~HLMOPSTuv", c) >= 0:
+			p.pos++ // ornements abrégés
+		case c == '"':
+			content, err := p.skipTo('"', "annotation")
+			if err != nil {
+				return nil, err
+			}
+			// "^texte", "_texte" ... sont des annotations libres, les autres des symboles d'accord
+			if content != "" && strings.IndexByte("^_<>@", content[0]) < 0 {
+				p.annotation = content
+			}
+		case c == '!' || c == '+':
+			if _, err := p.skipTo(c, "decoration"); err != nil {
+				return nil, err
+			}
+		case c == '{':
+			if _, err := p.skipTo('}', "grace notes"); err != nil {
+				return nil, err
+			}
+		case c == '(':
+			p.pos++
+			if n := p.readNumber(); n > 0 {
+				p.tuplet = n
+				// (p:q:r : seul p est dessiné
+				for p.peek(0) == ':' {
+					p.pos++
+					p.readNumber()
+				}
+			}
+		case c == '>' || c == '<':
+			if err := p.brokenRhythm(); err != nil {
+				return nil, err
+			}
+		case c == '[':
+			if err := p.bracket(); err != nil {
+				return nil, err
+			}
+		case c == '|' || c == ':':
+			p.barLine()
+		case c == 'z' || c == 'x' || c == 'Z' || c == 'X':
+			p.pos++
+			el := Element{Kind: RestElement, Invisible: c == 'x' || c == 'X'}
+			if c == 'Z' || c == 'X' {
+				// Mesure(s) entière(s) de silence, dessinées comme une pause
+				p.readNumber()
+				el.Length = 1
+			} else {
+				el.Length = p.unit * p.readLength()
+			}
+			p.add(el)
+		case strings.IndexByte("^_=ABCDEFGabcdefg", c) >= 0:
+			pitch, err := p.readPitch()
+			if err != nil {
+				return nil, err
+			}
+			p.add(Element{Kind: NoteElement, Pitches: []Pitch{pitch}, Length: p.unit * p.readLength()})
+		default:
+			return nil, p.errorf("unexpected character %q", c)
+		}
+	}
+	return p.elements, nil
+}
+
+// add ajoute une note ou un silence, avec le symbole d'accord, le n-olet et le rythme pointé en attente
+func (p *musicParser) add(el Element) {
+	el.Annotation, p.annotation = p.annotation, ""
+	el.Tuplet, p.tuplet = p.tuplet, 0
+	if p.broken != 0 {
+		el.Length *= p.broken
+		p.broken = 0
+	}
+	p.elements = append(p.elements, el)
+}
+
+func (p *musicParser) lastNote() *Element {
+	for i := len(p.elements) - 1; i >= 0; i-- {
+		switch p.elements[i].Kind {
+		case NoteElement, RestElement:
+			return &p.elements[i]
+		case BarElement:
+			return nil
+		}
+	}
+	return nil
+}
+
+// brokenRhythm : A>B = A pointée + B double croche, A>>B = A doublement pointée
+func (p *musicParser) brokenRhythm() error {
+	c := p.text[p.pos]
+	n := 0
+	for p.peek(0) == c {
+		p.pos++
+		n++
+	}
+	prev := p.lastNote()
+	if prev == nil || n > 3 {
+		return p.errorf("misplaced broken rhythm %q", strings.Repeat(string(c), n))
+	}
+	short := 1.0 / float64(int(1)<<n)
+	long := 2 - short
+	if c == '<' {
+		long, short = short, long
+	}
+	prev.Length *= long
+	p.broken = short
+	return nil
+}
+
+// bracket : accord [CEG], champ en ligne [K:G], barre [| ou fin de reprise [1
+func (p *musicParser) bracket() error {
+	next := p.peek(1)
+	switch {
+	case next == '|':
+		p.pos++
+		p.barLine()
+		p.elements[len(p.elements)-1].Bar = "[" + p.elements[len(p.elements)-1].Bar
+		return nil
+	case next >= '0' && next <= '9':
+		p.pos++
+		p.ending()
+		return nil
+	case p.peek(2) == ':' && (next >= 'A' && next <= 'Z' || next >= 'a' && next <= 'z'):
+		content, err := p.skipTo(']', "inline field")
+		if err != nil {
+			return err
+		}
+		return p.inlineField(content[0], strings.TrimSpace(content[2:]))
+	}
+
+	// Accord : la durée après ] s'applique à toutes les notes
+	p.pos++
+	var pitches []Pitch
+	length := 0.0
+	for p.peek(0) != ']' {
+		if p.pos >= len(p.text) {
+			return p.errorf("unterminated chord")
+		}
+		if c := p.peek(0); c == ' ' || c == '-' || c == '.' || c == '~' {
+			p.pos++
+			continue
+		}
+		pitch, err := p.readPitch()
+		if err != nil {
+			return err
+		}
+		noteLength := p.readLength()
+		if len(pitches) == 0 {
+			length = noteLength
+		}
+		pitches = append(pitches, pitch)
+	}
+	p.pos++
+	if len(pitches) == 0 {
+		return p.errorf("empty chord")
+	}
+	p.add(Element{Kind: NoteElement, Pitches: pitches, Length: p.unit * length * p.readLength()})
+	return nil
+}
+
+func (p *musicParser) inlineField(field byte, value string) error {
+	switch field {
+	case 'K':
+		key, err := ParseKey(value)
+		if err != nil {
+			return p.errorf("%v", err)
+		}
+		p.key = key
+		p.elements = append(p.elements, Element{Kind: KeyElement, Key: key})
+	case 'L':
+		length, ok := parseFraction(value)
+		if !ok {
+			return p.errorf("invalid unit note length %q", value)
+		}
+		p.unit = length
+	case 'M':
+		if _, ok := meterLength(value); !ok {
+			return p.errorf("invalid meter %q", value)
+		}
+		p.elements = append(p.elements, Element{Kind: MeterElement, Meter: value})
+	}
+	return nil
+}
+
+// barLine lit une barre : |, ||, |], |:, :|, ::, :|:, puis une fin de reprise éventuelle (|1, :|2)
+func (p *musicParser) barLine() {
+	start := p.pos
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		if c == '|' || c == ':' || (c == ']' && p.pos > start && p.text[p.pos-1] == '|') {
+			p.pos++
+			continue
+		}
+		break
+	}
+	bar := p.text[start:p.pos]
+	if bar == ":" {
+		bar = "|" // ":" isolé toléré
+	}
+	p.elements = append(p.elements, Element{Kind: BarElement, Bar: bar})
+	if c := p.peek(0); c >= '0' && c <= '9' {
+		p.ending()
+	}
+}
+
+// ending lit un numéro de fin de reprise (1, 2, 1,3, 1-2) et le rattache à la dernière barre
+func (p *musicParser) ending() {
+	start := p.pos
+	for p.pos < len(p.text) && strings.IndexByte("0123456789,-", p.text[p.pos]) >= 0 {
+		p.pos++
+	}
+	ending := p.text[start:p.pos]
+	if n := len(p.elements); n > 0 && p.elements[n-1].Kind == BarElement {
+		p.elements[n-1].Ending = ending
+		return
+	}
+	p.elements = append(p.elements, Element{Kind: BarElement, Ending: ending})
+}
+
+func (p *musicParser) readNumber() int {
+	start := p.pos
+	for p.pos < len(p.text) && p.text[p.pos] >= '0' && p.text[p.pos] <= '9' {
+		p.pos++
+	}
+	n, _ := strconv.Atoi(p.text[start:p.pos])
+	return n
+}
+
+// readLength lit le multiplicateur de durée : 2, /2, /, //, 3/2
+func (p *musicParser) readLength() float64 {
+	length := 1.0
+	if n := p.readNumber(); n > 0 {
+		length = float64(n)
+	}
+	for p.peek(0) == '/' {
+		p.pos++
+		if d := p.readNumber(); d > 0 {
+			length /= float64(d)
+		} else {
+			length /= 2
+		}
+	}
+	return length
+}
+
+func (p *musicParser) readPitch() (Pitch, error) {
+	var pitch Pitch
+	for p.pos < len(p.text) && strings.IndexByte("^_=", p.text[p.pos]) >= 0 {
+		pitch.Accidental += string(p.text[p.pos])
+		p.pos++
+	}
+	switch pitch.Accidental {
+	case "", "^", "^^", "_", "__", "=":
+	default:
+		return pitch, p.errorf("invalid accidental %q", pitch.Accidental)
+	}
+
+	c := p.peek(0)
+	switch {
+	case c >= 'A' && c <= 'G':
+		pitch.Step, pitch.Octave = strings.IndexByte(letters, c), 4
+	case c >= 'a' && c <= 'g':
+		pitch.Step, pitch.Octave = strings.IndexByte(letters, c-'a'+'A'), 5
+	default:
+		return pitch, p.errorf("expected a note after %q", pitch.Accidental)
+	}
+	p.pos++
+	for p.pos < len(p.text) {
+		switch p.text[p.pos] {
+		case '\'':
+			pitch.Octave++
+		case ',':
+			pitch.Octave--
+		default:
+			return pitch, nil
+		}
+		p.pos++
+	}
+	return pitch, nil
+}
@@ -0,0 +1,179 @@
+package abc
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// RenderSVG retourne la gravure de l'air en SVG, sur une seule page
+func RenderSVG(t *Tune) []byte {
+	pg := layout(t, false)[0]
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		num(pageWidth), num(pg.height), num(pageWidth), num(pg.height))
+	b.WriteString(`<rect width="100%" height="100%" fill="white"/>` + "\n")
+	b.WriteString(`<g stroke="black" fill="none" stroke-linecap="round" font-family="Helvetica, Arial, sans-serif">` + "\n")
+	for _, s := range pg.shapes {
+		switch s.kind {
+		case lineShape:
+			fmt.Fprintf(&b, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke-width="%s"/>`+"\n",
+				num(s.points[0].x), num(s.points[0].y), num(s.points[1].x), num(s.points[1].y), num(s.width))
+		case polylineShape:
+			var pts []string
+			for _, p := range s.points {
+				pts = append(pts, num(p.x)+","+num(p.y))
+			}
+			if s.filled {
+				fmt.Fprintf(&b, `<polygon points="%s" fill="black" stroke-width="%s"/>`+"\n", strings.Join(pts, " "), num(s.width))
+			} else {
+				fmt.Fprintf(&b, `<polyline points="%s" stroke-width="%s"/>`+"\n", strings.Join(pts, " "), num(s.width))
+			}
+		case ellipseShape:
+			fill := "none"
+			if s.filled {
+				fill = "black"
+			}
+			fmt.Fprintf(&b, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s" fill="%s" stroke-width="%s"/>`+"\n",
+				num(s.center.x), num(s.center.y), num(s.rx), num(s.ry), fill, num(s.width))
+		case textShape:
+			weight := ""
+			if s.bold {
+				weight = ` font-weight="bold"`
+			}
+			fmt.Fprintf(&b, `<text x="%s" y="%s" font-size="%s" text-anchor="%s" fill="black" stroke="none"%s>%s</text>`+"\n",
+				num(s.points[0].x), num(s.points[0].y), num(s.size), s.anchor, weight, html.EscapeString(s.text))
+		}
+	}
+	b.WriteString("</g>\n</svg>\n")
+	return b.Bytes()
+}
+
+// RenderPDF retourne la gravure de l'air en PDF A4 (polices Helvetica standard, non incorporées)
+func RenderPDF(t *Tune) []byte {
+	pages := layout(t, true)
+
+	var objects []string
+	add := func(content string) int {
+		objects = append(objects, content)
+		return len(objects)
+	}
+	catalog := add("") // complété plus bas
+	pagesObj := add("")
+	regular := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	bold := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	var kids []string
+	for _, pg := range pages {
+		content := pdfContent(pg)
+		stream := add(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+		pageObj := add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObj, num(pageWidth), num(pageHeight), regular, bold, stream))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObj))
+	}
+	objects[catalog-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj)
+	objects[pagesObj-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, catalog, xref)
+	return b.Bytes()
+}
+
+// Constante des courbes de Bézier approchant un quart d'ellipse
+const kappa = 0.5523
+
+// pdfContent écrit les formes de la page. L'axe y du PDF est inversé (origine en bas à gauche).
+func pdfContent(pg *page) string {
+	var b strings.Builder
+	y := func(v float64) string { return num(pageHeight - v) }
+	b.WriteString("1 J 1 j\n")
+	for _, s := range pg.shapes {
+		switch s.kind {
+		case lineShape:
+			fmt.Fprintf(&b, "%s w %s %s m %s %s l S\n", num(s.width), num(s.points[0].x), y(s.points[0].y), num(s.points[1].x), y(s.points[1].y))
+		case polylineShape:
+			fmt.Fprintf(&b, "%s w %s %s m", num(s.width), num(s.points[0].x), y(s.points[0].y))
+			for _, p := range s.points[1:] {
+				fmt.Fprintf(&b, " %s %s l", num(p.x), y(p.y))
+			}
+			if s.filled {
+				b.WriteString(" h B\n")
+			} else {
+				b.WriteString(" S\n")
+			}
+		case ellipseShape:
+			cx, cy, rx, ry := s.center.x, pageHeight-s.center.y, s.rx, s.ry
+			kx, ky := rx*kappa, ry*kappa
+			fmt.Fprintf(&b, "%s w %s %s m", num(s.width), num(cx+rx), num(cy))
+			fmt.Fprintf(&b, " %s %s %s %s %s %s c", num(cx+rx), num(cy+ky), num(cx+kx), num(cy+ry), num(cx), num(cy+ry))
+			fmt.Fprintf(&b, " %s %s %s %s %s %s c", num(cx-kx), num(cy+ry), num(cx-rx), num(cy+ky), num(cx-rx), num(cy))
+			fmt.Fprintf(&b, " %s %s %s %s %s %s c", num(cx-rx), num(cy-ky), num(cx-kx), num(cy-ry), num(cx), num(cy-ry))
+			fmt.Fprintf(&b, " %s %s %s %s %s %s c", num(cx+kx), num(cy-ry), num(cx+rx), num(cy-ky), num(cx+rx), num(cy))
+			if s.filled {
+				b.WriteString(" f\n")
+			} else {
+				b.WriteString(" S\n")
+			}
+		case textShape:
+			font := "F1"
+			if s.bold {
+				font = "F2"
+			}
+			text := pdfString(s.text)
+			x := s.points[0].x
+			// Largeur approchée : les polices standard ne sont pas mesurées
+			width := textWidth(s.text, s.size)
+			switch s.anchor {
+			case "middle":
+				x -= width / 2
+			case "end":
+				x -= width
+			}
+			fmt.Fprintf(&b, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, num(s.size), num(x), y(s.points[0].y), text)
+		}
+	}
+	return b.String()
+}
+
+func textWidth(s string, size float64) float64 {
+	return float64(len([]rune(s))) * size * 0.52
+}
+
+// pdfString encode le texte en WinAnsi et échappe les caractères spéciaux
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '♩':
+			b.WriteString("q") // noire : pas de glyphe musical dans Helvetica
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,508 @@
+package abc
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Gravure simplifiée d'un air ABC : portée, clé, armure, chiffrage, têtes de notes, hampes et crochets
+// (sans ligatures), altérations, points, silences, barres, fins de reprise et symboles d'accord.
+// La mise en page produit une liste de formes indépendante du format, écrite ensuite en SVG ou en PDF.
+// Les coordonnées sont en points, origine en haut à gauche.
+
+// Format A4 en points
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 50.0
+
+	space        = 7.0              // écart entre deux lignes de la portée
+	staffHeight  = 4 * space        // hauteur de la portée
+	systemHeight = staffHeight + 60 // portée et marges pour les symboles d'accord et les notes hors portée
+)
+
+type point struct{ x, y float64 }
+
+type shapeKind int
+
+const (
+	lineShape shapeKind = iota
+	polylineShape
+	ellipseShape
+	textShape
+)
+
+// shape : forme élémentaire de la gravure
+type shape struct {
+	kind   shapeKind
+	points []point // ligne (2 points) ou polyligne
+	width  float64 // épaisseur du trait
+	center point   // ellipse
+	rx, ry float64
+	filled bool
+	text   string
+	size   float64
+	anchor string // start, middle ou end
+	bold   bool
+}
+
+// page : formes d'une page
+type page struct {
+	height float64
+	shapes []shape
+}
+
+func (pg *page) line(x1, y1, x2, y2, width float64) {
+	pg.shapes = append(pg.shapes, shape{kind: lineShape, points: []point{{x1, y1}, {x2, y2}}, width: width})
+}
+
+func (pg *page) polyline(points []point, width float64, filled bool) {
+	pg.shapes = append(pg.shapes, shape{kind: polylineShape, points: points, width: width, filled: filled})
+}
+
+func (pg *page) ellipse(cx, cy, rx, ry float64, filled bool) {
+	pg.shapes = append(pg.shapes, shape{kind: ellipseShape, center: point{cx, cy}, rx: rx, ry: ry, filled: filled, width: 1})
+}
+
+func (pg *page) text(x, y, size float64, anchor string, bold bool, s string) {
+	pg.shapes = append(pg.shapes, shape{kind: textShape, points: []point{{x, y}}, size: size, anchor: anchor, bold: bold, text: s})
+}
+
+// layout met l'air en page. Avec paginate, les systèmes sont répartis sur des pages A4 ;
+// sinon une seule page aussi haute que nécessaire est produite (SVG).
+func layout(t *Tune, paginate bool) []*page {
+	pages := []*page{{}}
+	pg := pages[0]
+	y := margin
+
+	// En-tête
+	if title := t.Title(); title != "" {
+		y += 18
+		pg.text(pageWidth/2, y, 18, "middle", true, title)
+	}
+	for _, subtitle := range t.Titles[min(1, len(t.Titles)):] {
+		y += 16
+		pg.text(pageWidth/2, y, 12, "middle", false, subtitle)
+	}
+	if t.Composer != "" || t.Origin != "" {
+		y += 16
+		pg.text(pageWidth-margin, y, 10, "end", false, strings.TrimSpace(t.Composer+" "+parenthesize(t.Origin)))
+	}
+	if t.Rhythm != "" || t.Tempo > 0 {
+		label := t.Rhythm
+		if t.Tempo > 0 {
+			label = strings.TrimSpace(label + "  ♩ = " + strconv.Itoa(t.Tempo))
+		}
+		pg.text(margin, y, 10, "start", false, label)
+	}
+	y += 20
+
+	key, meter := t.Key, t.Meter
+	for i, system := range breakSystems(t, key) {
+		if paginate && y+systemHeight > pageHeight-margin {
+			pg = &page{}
+			pages = append(pages, pg)
+			y = margin
+		}
+		staffTop := y + 25
+		key, meter = drawSystem(pg, system, staffTop, key, meter, i == 0)
+		y += systemHeight
+	}
+
+	for _, p := range pages {
+		p.height = pageHeight
+	}
+	if !paginate {
+		pg.height = y + margin
+	}
+	return pages
+}
+
+func parenthesize(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "(" + s + ")"
+}
+
+// Largeur de l'en-tête d'un système : clé et armure
+func prefixWidth(key Key) float64 {
+	return 30 + float64(abs(key.Fifths))*7 + 8
+}
+
+// elementWidth : espace horizontal naturel d'un élément, croissant avec la durée
+func elementWidth(el Element) float64 {
+	switch el.Kind {
+	case NoteElement, RestElement:
+		w := 12 + 9*math.Log2(1+el.Length*16)
+		for _, p := range el.Pitches {
+			if p.Accidental != "" {
+				w += 8
+				break
+			}
+		}
+		return w
+	case BarElement:
+		return 10 + 4*float64(len(el.Bar))
+	case KeyElement:
+		return 10 + float64(abs(el.Key.Fifths))*7
+	case MeterElement:
+		return 22
+	}
+	return 0
+}
+
+// breakSystems découpe les lignes de l'air en systèmes tenant dans la largeur de la page,
+// en coupant après une barre de mesure
+func breakSystems(t *Tune, key Key) [][]Element {
+	available := pageWidth - 2*margin - prefixWidth(key) - 30 // place du chiffrage
+	var systems [][]Element
+	for _, line := range t.Lines {
+		start, width, lastBar := 0, 0.0, -1
+		for i, el := range line {
+			width += elementWidth(el)
+			if el.Kind == BarElement {
+				lastBar = i
+			}
+			if width > available && lastBar >= start && lastBar < i {
+				systems = append(systems, line[start:lastBar+1])
+				start = lastBar + 1
+				width = 0
+				for _, e := range line[start : i+1] {
+					width += elementWidth(e)
+				}
+			}
+		}
+		if start < len(line) {
+			systems = append(systems, line[start:])
+		}
+	}
+	return systems
+}
+
+// Position verticale d'une hauteur en demi-interlignes au-dessus de la ligne du bas
+func staffPosition(p Pitch, bass bool) int {
+	if bass {
+		return p.Diatonic() - (2*7 + 4) // Sol 2
+	}
+	return p.Diatonic() - (4*7 + 2) // Mi 4
+}
+
+func positionY(staffTop float64, pos int) float64 {
+	return staffTop + staffHeight - float64(pos)*space/2
+}
+
+// Positions des dièses et des bémols de l'armure en clé de sol
+var (
+	sharpPositions = []int{8, 5, 9, 6, 3, 7, 4}
+	flatPositions  = []int{4, 7, 3, 6, 2, 5, 1}
+)
+
+func drawSystem(pg *page, system []Element, staffTop float64, key Key, meter string, first bool) (Key, string) {
+	left, right := margin, pageWidth-margin
+	for i := 0; i < 5; i++ {
+		y := staffTop + float64(i)*space
+		pg.line(left, y, right, y, 0.6)
+	}
+
+	x := left + 6
+	drawClef(pg, x, staffTop, key.Bass)
+	x += 26
+	x = drawKeySignature(pg, x, staffTop, key)
+	if first && meter != "" && meter != "none" {
+		x = drawMeter(pg, x+4, staffTop, meter)
+	}
+
+	// Justification : l'espace restant est réparti entre les éléments, sauf pour un dernier système court
+	natural := 0.0
+	for _, el := range system {
+		natural += elementWidth(el)
+	}
+	stretch := 1.0
+	if natural > 0 && (natural > 0.6*(right-x) || natural > right-x) {
+		stretch = (right - x - 4) / natural
+	}
+
+	for _, el := range system {
+		w := elementWidth(el) * stretch
+		switch el.Kind {
+		case NoteElement:
+			drawNote(pg, x+w/2-4, staffTop, el, key.Bass)
+		case RestElement:
+			if !el.Invisible {
+				drawRest(pg, x+w/2-4, staffTop, el.Length)
+			}
+		case BarElement:
+			drawBar(pg, x+w/2, staffTop, el)
+		case KeyElement:
+			key = el.Key
+			drawKeySignature(pg, x+4, staffTop, key)
+		case MeterElement:
+			meter = el.Meter
+			drawMeter(pg, x+4, staffTop, meter)
+		}
+		x += w
+	}
+	return key, meter
+}
+
+// drawClef dessine une clé de sol ou de fa stylisée
+func drawClef(pg *page, x, staffTop float64, bass bool) {
+	if bass {
+		y := positionY(staffTop, 6) // ligne du fa
+		var arc []point
+		for a := math.Pi; a >= -0.6*math.Pi; a -= math.Pi / 12 {
+			arc = append(arc, point{x + 8 + 7*math.Cos(a), y - 1 - 7*math.Sin(a)*0.9})
+		}
+		arc = append(arc, point{x + 2, y + 2.6*space})
+		pg.polyline(arc, 1.6, false)
+		pg.ellipse(x+2, y, 2.2, 2.2, true)
+		pg.ellipse(x+19, y-space/2, 1.2, 1.2, true)
+		pg.ellipse(x+19, y+space/2, 1.2, 1.2, true)
+		return
+	}
+	// Spirale autour de la ligne du sol, puis hampe et crosse
+	cy := positionY(staffTop, 2)
+	var spiral []point
+	for a := 0.0; a <= 2.6*math.Pi; a += math.Pi / 10 {
+		r := 2 + a*1.2
+		spiral = append(spiral, point{x + 8 + r*math.Cos(a+math.Pi), cy - r*math.Sin(a+math.Pi)*1.1})
+	}
+	spiral = append(spiral,
+		point{x + 13, staffTop - 0.5*space},
+		point{x + 11, staffTop - 1.5*space},
+		point{x + 8, staffTop - 1.2*space},
+		point{x + 8, staffTop + staffHeight + 1.2*space},
+		point{x + 5, staffTop + staffHeight + 1.6*space},
+	)
+	pg.polyline(spiral, 1.4, false)
+}
+
+func drawKeySignature(pg *page, x, staffTop float64, key Key) float64 {
+	shift := 0
+	if key.Bass {
+		shift = -2
+	}
+	for i := 0; i < abs(key.Fifths); i++ {
+		if key.Fifths > 0 {
+			drawAccidental(pg, x, positionY(staffTop, sharpPositions[i]+shift), "^")
+		} else {
+			drawAccidental(pg, x, positionY(staffTop, flatPositions[i]+shift), "_")
+		}
+		x += 7
+	}
+	return x + 8
+}
+
+func drawMeter(pg *page, x, staffTop float64, meter string) float64 {
+	switch meter {
+	case "C", "C|":
+		pg.text(x+6, staffTop+staffHeight/2+6, 18, "middle", true, "C")
+		if meter == "C|" {
+			pg.line(x+6, staffTop-2, x+6, staffTop+staffHeight+2, 1)
+		}
+		return x + 20
+	}
+	num, den, _ := strings.Cut(meter, "/")
+	pg.text(x+6, staffTop+staffHeight/2-1.5, 15, "middle", true, num)
+	pg.text(x+6, staffTop+staffHeight-1.5, 15, "middle", true, den)
+	return x + 20
+}
+
+// drawAccidental dessine ^ (dièse), _ (bémol), = (bécarre) et les doubles altérations, centrés sur y
+func drawAccidental(pg *page, x, y float64, accidental string) {
+	switch accidental {
+	case "^":
+		pg.line(x-1.5, y-space*1.2, x-1.5, y+space*1.3, 0.8)
+		pg.line(x+1.5, y-space*1.3, x+1.5, y+space*1.2, 0.8)
+		pg.line(x-3, y-space*0.3, x+3, y-space*0.5, 1.6)
+		pg.line(x-3, y+space*0.5, x+3, y+space*0.3, 1.6)
+	case "^^":
+		pg.line(x-2.5, y-2.5, x+2.5, y+2.5, 1.2)
+		pg.line(x-2.5, y+2.5, x+2.5, y-2.5, 1.2)
+	case "_":
+		pg.line(x-2, y-space*1.6, x-2, y+space*0.5, 0.9)
+		pg.polyline([]point{{x - 2, y - space*0.1}, {x + 1, y - space*0.45}, {x + 3, y - space*0.2}, {x + 1, y + space*0.2}, {x - 2, y + space*0.5}}, 1.1, false)
+	case "__":
+		drawAccidental(pg, x-3, y, "_")
+		drawAccidental(pg, x+2, y, "_")
+	case "=":
+		pg.line(x-2, y-space*1.2, x-2, y+space*0.5, 0.8)
+		pg.line(x+2, y-space*0.5, x+2, y+space*1.2, 0.8)
+		pg.line(x-2, y+space*0.5, x+2, y+space*0.25, 1.4)
+		pg.line(x-2, y-space*0.25, x+2, y-space*0.5, 1.4)
+	}
+}
+
+// Valeurs de notes : ronde, blanche, noire, croche, double croche, triple croche
+var noteValues = []float64{1, 0.5, 0.25, 0.125, 0.0625, 0.03125}
+
+// noteValue retourne la valeur de base et le nombre de points d'une durée en rondes
+func noteValue(length float64) (float64, int) {
+	for _, base := range noteValues {
+		for dots, factor := range []float64{1, 1.5, 1.75} {
+			if math.Abs(length-base*factor) < 1e-6 {
+				return base, dots
+			}
+		}
+	}
+	// Durée irrégulière : la valeur inférieure la plus proche
+	for _, base := range noteValues {
+		if length >= base {
+			return base, 0
+		}
+	}
+	return noteValues[len(noteValues)-1], 0
+}
+
+func drawNote(pg *page, x, staffTop float64, el Element, bass bool) {
+	base, dots := noteValue(el.Length)
+	if el.Length > 1 {
+		base, dots = 1, 0 // brève et plus : dessinées comme une ronde
+	}
+	const rx, ry = 4.2, 3.0
+
+	low, high := 1000, -1000
+	for _, p := range el.Pitches {
+		pos := staffPosition(p, bass)
+		low, high = min(low, pos), max(high, pos)
+		y := positionY(staffTop, pos)
+		pg.ellipse(x, y, rx, ry, base < 0.5)
+		if base >= 0.5 {
+			pg.ellipse(x, y, rx-1.3, ry-1.3, false)
+		}
+		if p.Accidental != "" {
+			drawAccidental(pg, x-rx-6, y, p.Accidental)
+		}
+		for d := 0; d < dots; d++ {
+			dotY := y
+			if pos%2 == 0 {
+				dotY -= space / 2 // point dans l'interligne
+			}
+			pg.ellipse(x+rx+4+float64(d)*4, dotY, 1.2, 1.2, true)
+		}
+	}
+
+	// Lignes supplémentaires
+	for pos := -2; pos >= low; pos -= 2 {
+		y := positionY(staffTop, pos)
+		pg.line(x-rx-3, y, x+rx+3, y, 0.8)
+	}
+	for pos := 10; pos <= high; pos += 2 {
+		y := positionY(staffTop, pos)
+		pg.line(x-rx-3, y, x+rx+3, y, 0.8)
+	}
+
+	// Hampe vers le haut sous la ligne du milieu, vers le bas au-dessus
+	if base < 1 {
+		up := (low+high)/2 < 4
+		var stemX, fromY, toY float64
+		if up {
+			stemX, fromY = x+rx-0.6, positionY(staffTop, low)
+			toY = positionY(staffTop, high) - 3.5*space
+		} else {
+			stemX, fromY = x-rx+0.6, positionY(staffTop, high)
+			toY = positionY(staffTop, low) + 3.5*space
+		}
+		pg.line(stemX, fromY, stemX, toY, 1)
+		flags := 0
+		for v := base; v < 0.25; v *= 2 {
+			flags++
+		}
+		for f := 0; f < flags; f++ {
+			if up {
+				fy := toY + float64(f)*space*0.8
+				pg.polyline([]point{{stemX, fy}, {stemX + 5, fy + space}, {stemX + 6, fy + 2*space}}, 1.2, false)
+			} else {
+				fy := toY - float64(f)*space*0.8
+				pg.polyline([]point{{stemX, fy}, {stemX + 5, fy - space}, {stemX + 6, fy - 2*space}}, 1.2, false)
+			}
+		}
+	}
+
+	if el.Annotation != "" {
+		pg.text(x, staffTop-min(3*space, positionY(staffTop, high)-staffTop-8)-2*space, 9, "middle", false, el.Annotation)
+	}
+	if el.Tuplet > 0 {
+		pg.text(x, positionY(staffTop, max(high, 8))-4*space, 8, "middle", false, strconv.Itoa(el.Tuplet))
+	}
+}
+
+func drawRest(pg *page, x, staffTop float64, length float64) {
+	base, dots := noteValue(length)
+	middle := staffTop + staffHeight/2
+	switch {
+	case base >= 1:
+		pg.polyline([]point{{x - 4, staffTop + space}, {x + 4, staffTop + space}, {x + 4, staffTop + space*1.5}, {x - 4, staffTop + space*1.5}}, 0.5, true)
+	case base >= 0.5:
+		pg.polyline([]point{{x - 4, middle - space/2}, {x + 4, middle - space/2}, {x + 4, middle}, {x - 4, middle}}, 0.5, true)
+	case base >= 0.25:
+		pg.polyline([]point{{x - 1, middle - 1.5*space}, {x + 2.5, middle - 0.5*space}, {x - 1.5, middle + 0.3*space}, {x + 2, middle + 1.2*space}, {x - 1, middle + 1.1*space}}, 1.6, false)
+	default:
+		flags := 0
+		for v := base; v < 0.25; v *= 2 {
+			flags++
+		}
+		for f := 0; f < flags; f++ {
+			fy := middle - space/2 + float64(f)*space
+			pg.ellipse(x-2, fy, 1.6, 1.6, true)
+			pg.line(x-2, fy+1, x+3, fy-1.5, 1)
+		}
+		pg.line(x+3, middle-space-1.5, x, middle+float64(flags)*space, 1)
+	}
+	for d := 0; d < dots; d++ {
+		pg.ellipse(x+7+float64(d)*4, middle-space/2, 1.2, 1.2, true)
+	}
+}
+
+func drawBar(pg *page, x, staffTop float64, el Element) {
+	top, bottom := staffTop, staffTop+staffHeight
+	bar := el.Bar
+	thin := func(x float64) { pg.line(x, top, x, bottom, 0.8) }
+	thick := func(x float64) { pg.line(x, top, x, bottom, 3) }
+	dots := func(x float64) {
+		pg.ellipse(x, staffTop+1.5*space, 1.4, 1.4, true)
+		pg.ellipse(x, staffTop+2.5*space, 1.4, 1.4, true)
+	}
+
+	switch {
+	case bar == "":
+	case bar == "||":
+		thin(x - 1.5)
+		thin(x + 1.5)
+	case bar == "|]":
+		thin(x - 2.5)
+		thick(x + 1)
+	case bar == "[|":
+		thick(x - 1)
+		thin(x + 2.5)
+	case strings.HasPrefix(bar, ":") && strings.HasSuffix(bar, ":") && len(bar) > 1:
+		thin(x - 1.5)
+		thin(x + 1.5)
+		dots(x - 5)
+		dots(x + 5)
+	case strings.HasPrefix(bar, ":"):
+		thin(x + 1)
+		dots(x - 3)
+	case strings.HasSuffix(bar, ":"):
+		thin(x - 1)
+		dots(x + 3)
+	default:
+		thin(x)
+	}
+
+	if el.Ending != "" {
+		y := staffTop - 3*space
+		pg.line(x, y, x, y+space, 0.8)
+		pg.line(x, y, x+40, y, 0.8)
+		pg.text(x+3, y+8, 8, "start", false, el.Ending+".")
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,207 @@
+package catalogue
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Reconnaissance des numéros de catalogue dans les titres ("Prelude in C major, BWV 846", "Sonata K.545",
+// "Nocturne Op. 9 No. 2", "Symphony Hob. I:104") et clé de tri normalisée : BWV 846 est classé avant BWV 1001.
+
+// System : catalogue d'oeuvres, par sa forme usuelle et les écritures reconnues
+type System struct {
+	Name   string // forme usuelle, ex: Op., K.
+	prefix string // expression régulière du préfixe, sans tenir compte de la casse
+	roman  bool   // numéro de groupe en chiffres romains (Hob. XVI:52)
+}
+
+// Systems : catalogues reconnus. L'ordre est celui des listes par compositeur.
+var Systems = []System{
+	{Name: "Op.", prefix: `op(?:us)?\.?`},
+	{Name: "WoO", prefix: `woo\.?`},
+	{Name: "BWV", prefix: `bwv`},
+	{Name: "BuxWV", prefix: `buxwv`},
+	{Name: "HWV", prefix: `hwv`},
+	{Name: "RV", prefix: `rv\.?`},
+	{Name: "K.", prefix: `kv?\.?`},
+	{Name: "Hob.", prefix: `hob\.?`, roman: true},
+	{Name: "D.", prefix: `d\.?`},
+	{Name: "S.", prefix: `s\.?`},
+}
+
+var (
+	ErrUnknownSystem = errors.New("unknown catalogue type, expected Op., WoO, BWV, BuxWV, HWV, RV, K., Hob., D. or S.")
+	ErrInvalidNumber = errors.New("invalid catalogue number")
+)
+
+// Numéro : 846, 331a, 331/300i (Köchel 1 et 6), suivi éventuellement de "No. 2" (recueils d'opus)
+const (
+	numberPattern = `(\d+[a-z]?(?:/\d+[a-z]*)?)(?:\s*,?\s*no\.?\s*(\d+[a-z]?))?`
+	romanPattern  = `([ivxl]+[a-z]?|\d+)\s*[:/]\s*(\d+[a-z]?)`
+)
+
+var (
+	systemPatterns    = map[string]*regexp.Regexp{} // préfixe seul
+	titlePatterns     = map[string]*regexp.Regexp{} // préfixe et numéro dans un titre
+	referencePatterns = map[string]*regexp.Regexp{} // préfixe et numéro seuls
+	numberPatterns    = map[string]*regexp.Regexp{} // numéro seul
+)
+
+func init() {
+	for _, s := range Systems {
+		number := numberPattern
+		if s.roman {
+			number = romanPattern
+		}
+		systemPatterns[s.Name] = regexp.MustCompile(`(?i)^(?:` + s.prefix + `)$`)
+		titlePatterns[s.Name] = regexp.MustCompile(`(?i)\b(?:` + s.prefix + `)\s*` + number + `\b`)
+		referencePatterns[s.Name] = regexp.MustCompile(`(?i)^(?:` + s.prefix + `)\s*` + number + `$`)
+		numberPatterns[s.Name] = regexp.MustCompile(`(?i)^` + number + `$`)
+	}
+	numberPatterns[""] = regexp.MustCompile(`(?i)^(?:` + numberPattern + `|` + romanPattern + `)$`)
+}
+
+// Number : numéro de catalogue reconnu
+type Number struct {
+	System string // ex: BWV
+	Number string // forme normalisée, ex: 27 No. 2, XVI:52
+	Sort   string // clé de tri, ex: 000027.000002
+}
+
+func (n Number) String() string {
+	return strings.TrimSpace(n.System + " " + n.Number)
+}
+
+// NormalizeSystem retourne la forme usuelle d'un type de catalogue ("opus" → "Op.", "KV" → "K.")
+func NormalizeSystem(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, s := range Systems {
+		if systemPatterns[s.Name].MatchString(value) {
+			return s.Name, true
+		}
+	}
+	return "", false
+}
+
+// Parse cherche le premier numéro de catalogue d'un titre
+func Parse(title string) (Number, bool) {
+	var (
+		found Number
+		start = -1
+	)
+	for _, s := range Systems {
+		m := titlePatterns[s.Name].FindStringSubmatchIndex(title)
+		if m == nil || (start >= 0 && m[0] >= start) {
+			continue
+		}
+		start = m[0]
+		found = newNumber(s.Name, group(title, m, 1), group(title, m, 2), s.roman)
+	}
+	return found, start >= 0
+}
+
+// ParseReference lit un numéro complet, catalogue compris ("BWV 846", "op. 27 no 2")
+func ParseReference(value string) (Number, bool) {
+	value = strings.TrimSpace(value)
+	for _, s := range Systems {
+		if m := referencePatterns[s.Name].FindStringSubmatch(value); m != nil {
+			return newNumber(s.Name, m[1], m[2], s.roman), true
+		}
+	}
+	return Number{}, false
+}
+
+// ParseNumber normalise le numéro saisi pour le catalogue donné ("27 no 2" → "27 No. 2").
+// Avec un catalogue vide, tout numéro de forme reconnue est accepté.
+func ParseNumber(system string, number string) (Number, error) {
+	pattern, ok := numberPatterns[system]
+	if !ok {
+		return Number{}, ErrUnknownSystem
+	}
+	number = strings.TrimSpace(number)
+	m := pattern.FindStringSubmatch(number)
+	if m == nil {
+		return Number{}, fmt.Errorf("%w %q", ErrInvalidNumber, number)
+	}
+	if system == "" && m[1] == "" {
+		return newNumber(system, m[3], m[4], true), nil // numéro de groupe (Hob.)
+	}
+	return newNumber(system, m[1], m[2], system == "Hob."), nil
+}
+
+func group(s string, m []int, i int) string {
+	if m[2*i] < 0 {
+		return ""
+	}
+	return s[m[2*i]:m[2*i+1]]
+}
+
+// newNumber normalise le numéro : "27 No. 2", "XVI:52" pour un groupe (Hob.), "846"
+func newNumber(system string, main string, sub string, grouped bool) Number {
+	n := Number{System: system, Sort: SortKey(main, sub)}
+	switch {
+	case grouped:
+		n.Number = strings.ToUpper(main) + ":" + strings.ToLower(sub)
+	case sub != "":
+		n.Number = strings.ToLower(main) + " No. " + strings.ToLower(sub)
+	default:
+		n.Number = strings.ToLower(main)
+	}
+	return n
+}
+
+// SortKey construit la clé de tri des parties d'un numéro : chaque nombre (ou chiffre romain)
+// est complété à 6 chiffres, le suffixe en lettres est conservé ("331a" → "000331a")
+func SortKey(parts ...string) string {
+	keys := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.ToLower(strings.TrimSpace(part)); part == "" {
+			continue
+		}
+		// Köchel : seul le premier numéro compte pour le tri
+		part, _, _ = strings.Cut(part, "/")
+		i := 0
+		for i < len(part) && part[i] >= '0' && part[i] <= '9' {
+			i++
+		}
+		var value int
+		suffix := part[i:]
+		if i > 0 {
+			value, _ = strconv.Atoi(part[:i])
+		} else {
+			j := 0
+			for j < len(part) && strings.IndexByte("ivxl", part[j]) >= 0 {
+				j++
+			}
+			value, suffix = romanValue(part[:j]), part[j:]
+		}
+		keys = append(keys, fmt.Sprintf("%06d%s", value, suffix))
+	}
+	return strings.Join(keys, ".")
+}
+
+// SortKeyOf retourne la clé de tri d'un numéro déjà normalisé (27 No. 2, XVI:52, 846)
+func SortKeyOf(number string) string {
+	n, err := ParseNumber("", number)
+	if err != nil {
+		return ""
+	}
+	return n.Sort
+}
+
+func romanValue(s string) int {
+	values := map[byte]int{'i': 1, 'v': 5, 'x': 10, 'l': 50}
+	total := 0
+	for i := 0; i < len(s); i++ {
+		v := values[s[i]]
+		if i+1 < len(s) && values[s[i+1]] > v {
+			total -= v
+		} else {
+			total += v
+		}
+	}
+	return total
+}
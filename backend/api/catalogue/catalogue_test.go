@@ -0,0 +1,111 @@
+package catalogue
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTitles(t *testing.T) {
+	cases := []struct {
+		title  string
+		system string
+		number string
+	}{
+		{"Prelude in C major, BWV 846", "BWV", "846"},
+		{"Sonata K.545", "K.", "545"},
+		{"Fantasia in D minor, KV 397/385g", "K.", "397/385g"},
+		{"Nocturne Op. 9 No. 2", "Op.", "9 No. 2"},
+		{"Étude op.10, no.3 \"Tristesse\"", "Op.", "10 No. 3"},
+		{"Piano Sonata No. 14, Opus 27 no 2", "Op.", "27 No. 2"},
+		{"Symphony No. 94 in G major, Hob. I:94", "Hob.", "I:94"},
+		{"Piano Sonata Hob.XVI:52", "Hob.", "XVI:52"},
+		{"Sonata in B-flat major, D. 960", "D.", "960"},
+		{"Für Elise WoO 59", "WoO", "59"},
+		{"La Campanella S.141 No.3", "S.", "141 No. 3"},
+		{"Gloria RV 589", "RV", "589"},
+		{"Messiah HWV 56", "HWV", "56"},
+		{"Passacaglia BuxWV 161", "BuxWV", "161"},
+		{"Cantata BWV 140a", "BWV", "140a"},
+	}
+	for _, c := range cases {
+		n, ok := Parse(c.title)
+		require.True(t, ok, c.title)
+		assert.Equal(t, c.system, n.System, c.title)
+		assert.Equal(t, c.number, n.Number, c.title)
+	}
+
+	for _, title := range []string{"Sonata in D major", "Top 10 Hits", "Symphony No. 5", "Gymnopédie 1"} {
+		_, ok := Parse(title)
+		assert.False(t, ok, title)
+	}
+
+	// Le premier numéro du titre est retenu
+	n, ok := Parse("Variations Op. 35 on a theme from WoO 14")
+	require.True(t, ok)
+	assert.Equal(t, "Op. 35", n.String())
+}
+
+func TestNormalizeSystem(t *testing.T) {
+	for value, expected := range map[string]string{
+		"op": "Op.", "Opus": "Op.", "OP.": "Op.", "kv": "K.", "K.": "K.", "bwv": "BWV",
+		"hob": "Hob.", "d": "D.", "woo": "WoO", "S.": "S.", "BuxWV": "BuxWV",
+	} {
+		system, ok := NormalizeSystem(value)
+		assert.True(t, ok, value)
+		assert.Equal(t, expected, system, value)
+	}
+	for _, value := range []string{"", "LW", "opp", "BWVV"} {
+		_, ok := NormalizeSystem(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	n, err := ParseNumber("Op.", " 27 no 2 ")
+	require.NoError(t, err)
+	assert.Equal(t, "27 No. 2", n.Number)
+	assert.Equal(t, "000027.000002", n.Sort)
+
+	n, err = ParseNumber("Hob.", "xvi:52")
+	require.NoError(t, err)
+	assert.Equal(t, "XVI:52", n.Number)
+	assert.Equal(t, "000016.000052", n.Sort)
+
+	n, err = ParseNumber("", "XVI:52")
+	require.NoError(t, err)
+	assert.Equal(t, "XVI:52", n.Number)
+
+	_, err = ParseNumber("BWV", "posth.")
+	assert.ErrorIs(t, err, ErrInvalidNumber)
+	_, err = ParseNumber("Hob.", "52")
+	assert.ErrorIs(t, err, ErrInvalidNumber)
+	_, err = ParseNumber("LW", "1")
+	assert.ErrorIs(t, err, ErrUnknownSystem)
+}
+
+func TestParseReference(t *testing.T) {
+	n, ok := ParseReference(" bwv 846 ")
+	require.True(t, ok)
+	assert.Equal(t, "BWV 846", n.String())
+
+	n, ok = ParseReference("op. 27 no 2")
+	require.True(t, ok)
+	assert.Equal(t, "Op. 27 No. 2", n.String())
+
+	for _, value := range []string{"846", "Prelude BWV 846", "BWV"} {
+		_, ok := ParseReference(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestSortKeyOrdersNumerically(t *testing.T) {
+	numbers := []string{"1001", "846", "140a", "140", "27 No. 2", "27 No. 10", "9 No. 2"}
+	sort.Slice(numbers, func(i, j int) bool { return SortKeyOf(numbers[i]) < SortKeyOf(numbers[j]) })
+	assert.Equal(t, []string{"9 No. 2", "27 No. 2", "27 No. 10", "140", "140a", "846", "1001"}, numbers)
+
+	assert.Equal(t, "000331", SortKeyOf("331/300i"))
+	assert.Equal(t, "", SortKeyOf("posth."))
+}
@@ -41,6 +41,32 @@ type DatabaseConfig struct {
 	Port     int    `env:"DB_PORT"`
 }
 
+// Configuration de l'upload des partitions
+type UploadConfig struct {
+	// Si StrictDedup est vrai, un PDF dont le hash SHA-256 existe déjà est refusé.
+	// Sinon l'upload est accepté avec un avertissement indiquant la partition existante.
+	StrictDedup bool `env:"UPLOAD_STRICT_DEDUP"`
+	// Taille maximale d'un enregistrement, MIDI ou piste d'accompagnement, en Mo
+	MediaMaxMB int `env:"UPLOAD_MEDIA_MAX_MB"`
+}
+
+// MediaMaxBytes retourne la taille maximale d'un média en octets
+func (u UploadConfig) MediaMaxBytes() int64 {
+	return int64(u.MediaMaxMB) << 20
+}
+
+// Configuration du filigrane des PDF téléchargés (partitions sous licence)
+type WatermarkConfig struct {
+	// Texte de pied de page ajouté sous l'email et la date, sauf si la catégorie en définit un autre
+	Footer string `env:"WATERMARK_FOOTER"`
+}
+
+// Configuration du droit d'auteur : une oeuvre entre dans le domaine public term années après le décès du compositeur
+type CopyrightConfig struct {
+	// Durée de protection après le décès : 70 (Union européenne, Etats-Unis) ou 50 (Canada avant 2022, ...)
+	Term int `env:"COPYRIGHT_TERM"`
+}
+
 // ServerConfig est la struct qui contient tous les paramètres de configuration du serveur.
 type ServerConfig struct {
 	AdminEmail    string `env:"ADMIN_EMAIL"`
@@ -49,11 +75,22 @@ type ServerConfig struct {
 	ServerUrl     string `env:"SERVER_URL"`
 	ConfigPath    string `env:"CONFIG_PATH"`
 
+	// Adresse publique du frontend, encodée dans les QR codes des étiquettes : <PUBLIC_URL>/sheet/<safe_sheet_name>
+	// Exemple : PUBLIC_URL=https://app.sheetflow.com
+	PublicUrl string `env:"PUBLIC_URL"`
+
+	// Fournisseurs de métadonnées des compositeurs, séparés par des virgules : openopus, offline, none
+	// Exemple pour un serveur sans accès Internet : COMPOSER_PROVIDER=offline
+	ComposerProvider string `env:"COMPOSER_PROVIDER"`
+
 	Dev  bool `env:"DEV"`
 	Port int  `env:"PORT"`
 
 	Database   DatabaseConfig
 	Smtp       SmtpConfig
+	Upload     UploadConfig
+	Watermark  WatermarkConfig
+	Copyright  CopyrightConfig
 	CorsOrigin string `env:"CORS_ORIGIN"` //"https://app.sheetflow.com" ou "http://localhost:3000" pour dev, ou "*" pour autoriser toutes les origines
 }
 
@@ -81,7 +118,9 @@ func (c ServerConfig) LogSafe() {
 	// log.Printf("AdminPassword: %s\n", c.AdminPassword) // Affiche les secrets de l'administrateur, à éviter en production
 	// log.Printf("ApiSecret: %s\n", c.ApiSecret)         // Affiche la configuration de base du serveur, y compris les secrets (à éviter en production)
 	log.Printf("ServerUrl: %s\n", c.ServerUrl)
+	log.Printf("PublicUrl: %s\n", c.PublicUrl)
 	log.Printf("ConfigPath: %s\n", c.ConfigPath)
+	log.Printf("ComposerProvider: %s\n", c.ComposerProvider)
 	log.Printf("Dev mode: %v\n", c.Dev)
 	log.Printf("Port: %d\n", c.Port)
 
@@ -101,6 +140,16 @@ func (c ServerConfig) LogSafe() {
 	log.Printf("  Port: %d\n", c.Smtp.HostServerPort)
 	log.Printf("  Username: %s\n", c.Smtp.Username)
 	log.Printf("  Password: %s\n", c.Smtp.Password) // Affiche la configuration SMTP, y compris le mot de passe (à éviter en production)
+
+	log.Println("Upload:")
+	log.Printf("  StrictDedup: %v\n", c.Upload.StrictDedup)
+	log.Printf("  MediaMaxMB: %d\n", c.Upload.MediaMaxMB)
+
+	log.Println("Watermark:")
+	log.Printf("  Footer: %s\n", c.Watermark.Footer)
+
+	log.Println("Copyright:")
+	log.Printf("  Term: %d\n", c.Copyright.Term)
 	log.Println("--------------------------------------")
 }
 
@@ -177,12 +226,14 @@ func (b configBuilder) Build() ServerConfig {
 // la valeur par défaut "sqlite" sera utilisée pour la configuration de la base de données.
 func NewConfig() ServerConfig {
 	return ServerConfig{
-		AdminEmail:    "admin@admin.com",
-		AdminPassword: "sheetflow",
-		ApiSecret:     "sheetflow_secret_key",
-		ServerUrl:     "http://localhost:8080",
-		ConfigPath:    "./config/",
-		CorsOrigin:    "",
+		AdminEmail:       "admin@admin.com",
+		AdminPassword:    "sheetflow",
+		ApiSecret:        "sheetflow_secret_key",
+		ServerUrl:        "http://localhost:8080",
+		PublicUrl:        "http://localhost:3000",
+		ConfigPath:       "./config/",
+		ComposerProvider: "openopus,offline",
+		CorsOrigin:       "",
 		Database: DatabaseConfig{
 			Driver: "sqlite",
 		},
@@ -194,5 +245,14 @@ func NewConfig() ServerConfig {
 			Username:       "christian.klugesherz@gmail.com",
 			Password:       "", // récupéré depuis variable d'environnement : SMTP_PASSWORD
 		},
+		Upload: UploadConfig{
+			MediaMaxMB: 100,
+		},
+		Watermark: WatermarkConfig{
+			Footer: "Licensed copy, do not distribute",
+		},
+		Copyright: CopyrightConfig{
+			Term: 70,
+		},
 	}
 }
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"backend/api/auth"
+	"backend/api/config"
+	"backend/api/forms"
+	"backend/api/models"
+	"backend/api/pdf"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Register a piece of an anthology (Das Wohltemperierte Klavier, a fake book ...) as a page range of its PDF
+The piece is a sheet of its own: title, composer, tags, metadata, search, listings and works.
+Its PDF is not stored, the pages are extracted from the anthology when requested.
+Example request:
+
+	POST /api/sheet/wohltemperierte-klavier-1/excerpts
+		Body (FormValue or JSON):
+		- sheetName: Prelude and Fugue in C major, BWV 846
+		- composer: Bach (default: composer of the anthology)
+		- firstPage: 3
+		- lastPage: 6 (default: firstPage)
+		- categories, tags, informationText, releaseDate, work
+		- metadata fields as for the upload (key, difficulty, duration ...)
+*/
+func (server *Server) CreateExcerpt(c *gin.Context) {
+	token := utils.ExtractToken(c)
+	uid, err := auth.ExtractTokenID(token, config.Config().ApiSecret)
+	if err != nil || uid == 0 {
+		c.String(http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	parent := getSheet(server.DB, c)
+	if parent == nil {
+		return
+	}
+
+	var form forms.CreateExcerptRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad excerpt request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Plage de pages vérifiée avant de créer la pièce, sur le PDF si le nombre de pages n'est pas connu
+	if parent.PageCount == 0 && parent.HasPdf() {
+		if count, err := pdf.PageCount(sheetPdfPath(parent)); err == nil {
+			parent.PageCount = count
+		}
+	}
+	if err := (&models.Sheet{}).SetExcerpt(parent, form.FirstPage, form.LastPage); err != nil {
+		if errors.Is(err, models.ErrInvalidPageRange) {
+			utils.DoError(c, http.StatusBadRequest, err)
+			return
+		}
+		utils.DoError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	metadata := form.Metadata()
+	catalogueFromTitle(&metadata, form.SheetName)
+
+	var work *models.Work
+	if form.Work != "" {
+		if work, err = models.FindWork(server.DB, form.Work); err != nil {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+	}
+
+	var comp models.Composer
+	if strings.TrimSpace(form.Composer) == "" {
+		var composerModel models.Composer
+		found, err := composerModel.FindComposerBySafeName(server.DB, parent.SafeComposer)
+		if err != nil {
+			utils.DoError(c, http.StatusNotFound, models.ErrComposerNotFound)
+			return
+		}
+		comp = *found
+	} else {
+		// Compositeur de l'oeuvre vérifié avant que safeComposer ne crée un compositeur inconnu
+		if work != nil {
+			if err := editionComposer(server, work, form.Composer); err != nil {
+				utils.DoError(c, http.StatusConflict, err)
+				return
+			}
+		}
+		comp = safeComposer(server, form.Composer)
+	}
+	if work != nil && work.SafeComposer != comp.SafeName {
+		utils.DoError(c, http.StatusConflict, fmt.Errorf("%w: %s", models.ErrEditionComposer, comp.SafeName))
+		return
+	}
+
+	// Un même titre dans deux recueils (standards d'un fake book) est numéroté
+	uploadPath := checkComposer(path.Join(config.Config().ConfigPath, "sheets/uploaded-sheets"), comp)
+	safeSheetName, fullpath, err := editionSheetName(server, uploadPath, form.SheetName, true, metadata)
+	if err != nil {
+		utils.DoError(c, http.StatusConflict, err)
+		return
+	}
+
+	sheet, err := createFile(uid, server, fullpath, nil, "", nil, comp, form.SheetName, safeSheetName, form.ReleaseDate,
+		form.InformationText, form.Categories, form.Tags, metadata)
+	if err == nil {
+		err = sheet.SaveExcerpt(server.DB, parent, form.FirstPage, form.LastPage)
+	}
+	if err == nil {
+		err = linkUploadedEdition(server, work, sheet)
+	}
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Thumbnail : première page de la pièce
+	if file, err := sheetFile(server.DB, sheet); err != nil {
+		log.Printf("excerpt %s: %v\n", sheet.SafeSheetName, err)
+	} else {
+		utils.RequestToPdfToImage(file, sheet.SafeSheetName)
+	}
+	c.JSON(http.StatusCreated, sheet)
+}
+
+/*
+List the pieces of an anthology in page order
+Example request:
+
+	GET /api/sheet/wohltemperierte-klavier-1/excerpts
+*/
+func (server *Server) GetExcerpts(c *gin.Context) {
+	parent := getSheet(server.DB, c)
+	if parent == nil {
+		return
+	}
+	excerpts, err := parent.Excerpts(server.DB)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sheet":    parent,
+		"excerpts": excerpts,
+	})
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"backend/api/config"
 	"backend/api/models"
+	"backend/api/provider"
 	"fmt"
 	"log"
 	"net/http"
@@ -21,9 +22,10 @@ import (
 )
 
 type Server struct {
-	DB      *gorm.DB
-	Router  *gin.Engine
-	Version string
+	DB        *gorm.DB
+	Router    *gin.Engine
+	Version   string
+	Composers provider.ComposerProvider // enrichissement des compositeurs à l'upload
 }
 
 func (server *Server) Initialize(version string) {
@@ -108,6 +110,9 @@ func (server *Server) Initialize(version string) {
 		log.Fatalf("migration failed: %v", err)
 	}
 
+	server.Composers = provider.New(cfg.ComposerProvider, path.Join(cfg.ConfigPath, "cache/openopus"))
+	log.Printf("Composer provider: %s\n", server.Composers.Name())
+
 	server.SetupRouter()
 }
 
@@ -1,14 +1,15 @@
 package controllers
 
 import (
+	"backend/api/catalogue"
 	"backend/api/config"
 	"backend/api/forms"
 	"backend/api/models"
 	"backend/api/utils"
 	"errors"
 	"fmt"
+	"image"
 	"net/http"
-	"os"
 	"path"
 
 	"github.com/gin-gonic/gin"
@@ -53,8 +54,17 @@ Update a composer via PUT request
 body - formdata
 example:
   - name: Chopin
-  - portrait_url: url
+  - portrait_url: https://... (remote portrait, downloaded once and served locally)
   - epoch: romance
+  - birth: 1810-03-01
+  - death: 1849-10-17
+  - nationality: Polish
+  - biography: text
+  - wikidata_id: Q1268
+  - viaf_id: 7390735
+  - musicbrainz_id: 09ff1fe8-d61c-4b98-bb82-18487c74d7b7
+  - aliases: Chopin; Szopen (replaces all aliases, empty value removes them)
+  - portrait: file (JPEG, PNG, WebP or GIF, cropped to a square and resized to the standard sizes)
 */
 func (server *Server) UpdateComposer(c *gin.Context) {
 	composerName := c.Param("composerName")
@@ -69,27 +79,60 @@ func (server *Server) UpdateComposer(c *gin.Context) {
 		return
 	}
 
-	uploadComposerName := composerName
-	if form.Name != "" {
-		uploadComposerName = form.Name
+	// Le portrait (fichier uploadé ou URL distante) est validé avant toute modification
+	portrait, err := readPortrait(form)
+	if err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("invalid portrait: %v", err))
+		return
+	}
+	portraitUrl := form.PortraitUrl
+	if portrait != nil {
+		portraitUrl = ""
 	}
 
-	// Uploads a portrait to the server if given
-	uploadSuccess := false
-	uploadSuccess = uploadPortait(form, uploadComposerName, composerName)
+	details := models.ComposerDetails{
+		Birth:         form.Birth,
+		Death:         form.Death,
+		Nationality:   form.Nationality,
+		Biography:     form.Biography,
+		WikidataID:    form.WikidataID,
+		ViafID:        form.ViafID,
+		MusicBrainzID: form.MusicBrainzID,
+	}
+	if form.Aliases != nil {
+		details.Aliases = parseSemicolonList(*form.Aliases)
+		if details.Aliases == nil {
+			details.Aliases = []string{}
+		}
+	}
 
 	composer := &models.Composer{}
 	newComp, err := composer.UpdateComposer(server.DB,
 		composerName,
 		form.Name,
-		form.PortraitUrl,
+		portraitUrl,
 		form.Epoch,
-		uploadSuccess,
+		details,
+		portrait != nil,
 	)
 	if err != nil {
-		utils.DoError(c, http.StatusNotFound, fmt.Errorf("composer not found: %v", err))
+		switch {
+		case errors.Is(err, models.ErrComposerNotFound):
+			utils.DoError(c, http.StatusNotFound, err)
+		case errors.Is(err, models.ErrAliasTaken), errors.Is(err, models.ErrComposerExists):
+			utils.DoError(c, http.StatusConflict, err)
+		default:
+			utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("failed to update composer: %v", err))
+		}
 		return
 	}
+
+	if portrait != nil {
+		if err := utils.SavePortrait(portrait, path.Join(config.Config().ConfigPath, "composer"), newComp.SafeName); err != nil {
+			utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("failed to save portrait: %v", err))
+			return
+		}
+	}
 	c.JSON(http.StatusOK, newComp)
 }
 
@@ -103,7 +146,14 @@ func (server *Server) DeleteComposer(c *gin.Context) {
 	composer := &models.Composer{}
 	_, err := composer.DeleteComposer(server.DB, composerName)
 	if err != nil {
-		utils.DoError(c, http.StatusNotFound, fmt.Errorf("failed to delete composer, composer not found: %v", err))
+		switch {
+		case errors.Is(err, models.ErrComposerNotFound):
+			utils.DoError(c, http.StatusNotFound, fmt.Errorf("failed to delete composer: %v", err))
+		case errors.Is(err, models.ErrDeleteUnknownComposer):
+			utils.DoError(c, http.StatusConflict, fmt.Errorf("failed to delete composer: %v", err))
+		default:
+			utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("failed to delete composer: %v", err))
+		}
 		return
 	}
 
@@ -111,43 +161,126 @@ func (server *Server) DeleteComposer(c *gin.Context) {
 }
 
 /*
-Serve the Composer Portraits
+Merge a composer into another one: sheets, PDF files, portrait and aliases are moved to the target,
+then the merged composer is deleted and its name kept as an alias of the target.
 Example request:
 
-	GET /composer/portrait/Chopin
+	POST /api/composer/chopin/merge
+		Body (FormValue):
+		- target: frederic-chopin
 */
-func (server *Server) ServePortraits(c *gin.Context) {
-	name := c.Param("composerName")
-	filePath := path.Join(config.Config().ConfigPath, "composer", name+".png")
-	c.File(filePath)
+func (server *Server) MergeComposer(c *gin.Context) {
+	composerName := c.Param("composerName")
+	if composerName == "" {
+		utils.DoError(c, http.StatusBadRequest, errors.New("no composer given"))
+		return
+	}
+
+	var form forms.MergeComposerRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("unable to parse form: %v", err))
+		return
+	}
+	if form.Target == "" {
+		utils.DoError(c, http.StatusBadRequest, errors.New("no target composer given"))
+		return
+	}
+
+	merged, err := models.MergeComposer(server.DB, composerName, form.Target)
+	if err != nil {
+		if errors.Is(err, models.ErrMergeSameComposer) {
+			utils.DoError(c, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, models.ErrComposerNotFound) {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+		utils.DoError(c, http.StatusConflict, fmt.Errorf("failed to merge composer: %v", err))
+		return
+	}
+	c.JSON(http.StatusOK, merged)
 }
 
 /*
-Upload a portrait
-! Currently only PNG files supported
+List the sheets of a composer ordered by catalogue number (BWV 846 before BWV 1001), sheets without number last
+Query parameters:
+  - type: only one catalogue (BWV, Op., K. ...)
+
+Example request:
+
+	GET /api/composer/bach/catalogue?type=BWV
 */
-func uploadPortait(form forms.UpdateComposersRequest, compName string, originalName string) bool {
-	if form.File == nil {
-		return false
+func (server *Server) GetComposerCatalogue(c *gin.Context) {
+	composerName := c.Param("composerName")
+	var composerModel models.Composer
+	composer, err := composerModel.FindComposerBySafeName(server.DB, composerName)
+	if err != nil {
+		utils.DoError(c, http.StatusNotFound, models.ErrComposerNotFound)
+		return
+	}
+
+	system := ""
+	if value := c.Query("type"); value != "" {
+		var ok bool
+		if system, ok = catalogue.NormalizeSystem(value); !ok {
+			utils.DoError(c, http.StatusBadRequest, fmt.Errorf("%w: %q", catalogue.ErrUnknownSystem, value))
+			return
+		}
 	}
-	portrait, err := form.File.Open()
+
+	sheets, err := models.ComposerCatalogue(server.DB, composer.SafeName, system)
 	if err != nil {
-		return false
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"composer": composer,
+		"sheets":   sheets,
+	})
+}
+
+/*
+Serve the Composer Portraits, stored locally as square PNG
+Example request:
 
-	defer portrait.Close()
+	GET /composer/portrait/Chopin?size=small
 
-	// Create the composer Directory if it doesn't exist yet
-	dir := path.Join(config.Config().ConfigPath, "composer")
-	fullpath := path.Join(dir, utils.SanitizeName(compName)+".png")
-	if originalName != compName {
-		os.Remove(path.Join(dir, originalName+".png"))
+size: small (64px), medium (256px) or large (512px, default)
+*/
+func (server *Server) ServePortraits(c *gin.Context) {
+	name := c.Param("composerName")
+	size := c.DefaultQuery("size", utils.DefaultPortraitSize)
+	if _, ok := utils.PortraitSizes[size]; !ok {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("unknown portrait size %q", size))
+		return
 	}
-	utils.CreateDir(dir)
 
-	err = utils.OsCreateFile(fullpath, portrait)
+	filePath, err := utils.EnsurePortraitSize(path.Join(config.Config().ConfigPath, "composer"), utils.SanitizeName(name), size)
 	if err != nil {
-		return false
+		// Pas de portrait : silhouette par défaut
+		c.Data(http.StatusOK, "image/png", utils.DefaultPortraitPNG())
+		return
+	}
+	c.File(filePath)
+}
+
+/*
+Read the portrait given with a composer update: an uploaded file or a remote portrait_url,
+which is downloaded once so that it can be served from our own storage.
+Accepted formats: JPEG, PNG, WebP and GIF, detected from the content.
+*/
+func readPortrait(form forms.UpdateComposersRequest) (image.Image, error) {
+	if form.File != nil {
+		file, err := form.File.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return utils.DecodePortrait(file)
+	}
+	if utils.IsRemoteURL(form.PortraitUrl) {
+		return utils.FetchPortrait(form.PortraitUrl)
 	}
-	return true
+	return nil, nil
 }
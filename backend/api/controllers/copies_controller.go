@@ -0,0 +1,341 @@
+package controllers
+
+import (
+	"backend/api/auth"
+	"backend/api/config"
+	"backend/api/forms"
+	"backend/api/models"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+List the printed copies of a sheet, with their current loan
+Example request:
+
+	GET /api/sheet/fuer-elise/copies
+*/
+func (server *Server) GetCopies(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	copies, err := sheet.Copies(server.DB, time.Now())
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, copies)
+}
+
+/*
+Register a printed copy of a sheet (admin only)
+Example request:
+
+	POST /api/sheet/fuer-elise/copies
+		Body (FormValue or JSON):
+		- barcode: SF-000123
+		- shelf: B3-2
+		- condition: good (new, good, fair, poor, damaged)
+		- notes: pencil markings in bars 12-20
+*/
+func (server *Server) AddCopy(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	var form forms.CopyRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad copy request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	item := models.SheetCopy{Barcode: form.Barcode, Shelf: form.Shelf, Condition: form.Condition, Notes: form.Notes}
+	if err := sheet.AddCopy(server.DB, &item); err != nil {
+		copyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+/*
+Find a printed copy by its barcode, with its sheet and current loan
+Example request:
+
+	GET /api/copy/SF-000123
+*/
+func (server *Server) GetCopy(c *gin.Context) {
+	item, err := models.FindCopy(server.DB, c.Param("barcode"), time.Now())
+	if err != nil {
+		copyError(c, err)
+		return
+	}
+	sheet, err := (&models.Sheet{}).FindSheetBySafeName(server.DB, item.SheetSafeName)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to get sheet %s: %v", item.SheetSafeName, err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"copy":  item,
+		"sheet": sheet,
+	})
+}
+
+/*
+Update the barcode, shelf, condition or notes of a printed copy (admin only)
+Example request:
+
+	PUT /api/copy/SF-000123
+		Body (FormValue or JSON):
+		- shelf: C1-4
+		- condition: fair
+*/
+func (server *Server) UpdateCopy(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var form forms.UpdateCopyRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad copy request: %v", err))
+		return
+	}
+	item, err := models.UpdateCopy(server.DB, c.Param("barcode"), models.CopyUpdate{
+		Barcode:   form.Barcode,
+		Shelf:     form.Shelf,
+		Condition: form.Condition,
+		Notes:     form.Notes,
+	})
+	if err != nil {
+		copyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+/*
+Delete a printed copy and its loan history (admin only), refused while it is on loan
+Example request:
+
+	DELETE /api/copy/SF-000123
+*/
+func (server *Server) DeleteCopy(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	if err := models.DeleteCopy(server.DB, c.Param("barcode")); err != nil {
+		copyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, "Copy deleted")
+}
+
+/*
+Check out a printed copy until a due date (last day of the loan, default in 28 days)
+Users check out for themselves within 28 days, the admin for any user and any due date.
+Example request:
+
+	POST /api/copy/SF-000123/checkout
+		Body (FormValue or JSON):
+		- userId: 4
+		- dueDate: 2026-11-30
+*/
+func (server *Server) CheckoutCopy(c *gin.Context) {
+	uid, err := auth.ExtractTokenID(utils.ExtractToken(c), config.Config().ApiSecret)
+	if err != nil {
+		utils.DoError(c, http.StatusUnauthorized, err)
+		return
+	}
+	var form forms.CheckoutRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad checkout request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	borrower := form.UserID
+	if borrower == 0 {
+		borrower = uid
+	}
+	if borrower != uid && uid != config.ADMIN_UID {
+		utils.DoError(c, http.StatusUnauthorized, errors.New("only admins are able to check out a copy for another user"))
+		return
+	}
+	if _, err := (&models.User{}).FindByID(server.DB, borrower); err != nil {
+		utils.DoError(c, http.StatusNotFound, err)
+		return
+	}
+	due, _ := form.Due()
+	now := time.Now()
+	if uid != config.ADMIN_UID {
+		if err := models.CheckLoanLength(due, now); err != nil {
+			copyError(c, err)
+			return
+		}
+	}
+	loan, err := models.CheckoutCopy(server.DB, c.Param("barcode"), borrower, due, now)
+	if err != nil {
+		copyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, loan)
+}
+
+/*
+Return a printed copy, with the condition found on return (admin or borrower)
+Example request:
+
+	POST /api/copy/SF-000123/return
+		Body (FormValue or JSON):
+		- condition: fair (optional)
+*/
+func (server *Server) ReturnCopy(c *gin.Context) {
+	uid, err := auth.ExtractTokenID(utils.ExtractToken(c), config.Config().ApiSecret)
+	if err != nil {
+		utils.DoError(c, http.StatusUnauthorized, err)
+		return
+	}
+	var form forms.ReturnRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad return request: %v", err))
+		return
+	}
+	item, err := models.FindCopy(server.DB, c.Param("barcode"), time.Now())
+	if err != nil {
+		copyError(c, err)
+		return
+	}
+	if item.Loan != nil && item.Loan.UserID != uid && uid != config.ADMIN_UID {
+		utils.DoError(c, http.StatusUnauthorized, errors.New("only the borrower or an admin can return this copy"))
+		return
+	}
+	loan, err := models.ReturnCopy(server.DB, item.Barcode, form.Condition, time.Now())
+	if err != nil {
+		copyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, loan)
+}
+
+/*
+List the loans, oldest due dates first: current loans by default, users only see their own
+Example request:
+
+	GET /api/loans?overdue=true
+	GET /api/loans?user_id=4&history=true
+*/
+func (server *Server) GetLoans(c *gin.Context) {
+	uid, err := auth.ExtractTokenID(utils.ExtractToken(c), config.Config().ApiSecret)
+	if err != nil {
+		utils.DoError(c, http.StatusUnauthorized, err)
+		return
+	}
+	var form forms.GetLoansRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad loans request: %v", err))
+		return
+	}
+	filter := models.LoanFilter{UserID: form.UserID, Sheet: form.Sheet, Overdue: form.Overdue, History: form.History}
+	if uid != config.ADMIN_UID {
+		filter.UserID = uid
+	}
+	loans, err := models.ListLoans(server.DB, filter, time.Now())
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, loans)
+}
+
+/*
+Email a reminder to the borrowers of overdue copies (admin only)
+Loans already reminded during the last 24 hours are skipped.
+Example request:
+
+	POST /api/loans/reminders
+*/
+func (server *Server) SendLoanReminders(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	if config.Config().Smtp.Enabled != "true" {
+		c.JSON(http.StatusBadGateway, "SMTP backend not configured.")
+		return
+	}
+	now := time.Now()
+	loans, err := models.ListLoans(server.DB, models.LoanFilter{Overdue: true}, now)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	sent := []models.SheetLoan{}
+	failed := []gin.H{}
+	for i := range loans {
+		loan := &loans[i]
+		if loan.RemindedAt != nil && now.Sub(*loan.RemindedAt) < 24*time.Hour {
+			continue
+		}
+		if err := server.sendLoanReminder(loan); err != nil {
+			failed = append(failed, gin.H{"barcode": loan.Barcode, "user_id": loan.UserID, "error": err.Error()})
+			continue
+		}
+		if err := models.MarkReminded(server.DB, loan, now); err != nil {
+			utils.DoError(c, http.StatusInternalServerError, err)
+			return
+		}
+		sent = append(sent, *loan)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sent":   sent,
+		"failed": failed,
+	})
+}
+
+func (server *Server) sendLoanReminder(loan *models.SheetLoan) error {
+	user, err := (&models.User{}).FindByID(server.DB, loan.UserID)
+	if err != nil {
+		return err
+	}
+	title := loan.SheetSafeName
+	if sheet, err := (&models.Sheet{}).FindSheetBySafeName(server.DB, loan.SheetSafeName); err == nil {
+		title = fmt.Sprintf("%s (%s)", sheet.SheetName, sheet.Composer)
+	}
+	return utils.SendMail(user.Email, "Overdue score: "+title, buildLoanReminderBody(loan, title))
+}
+
+func buildLoanReminderBody(loan *models.SheetLoan, title string) string {
+	return fmt.Sprintf(
+		"The printed copy of %s (barcode %s) you borrowed on %s was due on %s.\n\nPlease return it to the library.",
+		title,
+		loan.Barcode,
+		loan.CheckedOutAt.Format("2006-01-02"),
+		loan.DueAt.Format("2006-01-02"),
+	)
+}
+
+func copyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrCopyNotFound):
+		utils.DoError(c, http.StatusNotFound, err)
+	case errors.Is(err, models.ErrBarcodeExists), errors.Is(err, models.ErrCopyOnLoan), errors.Is(err, models.ErrCopyNotOnLoan):
+		utils.DoError(c, http.StatusConflict, err)
+	case errors.Is(err, models.ErrInvalidBarcode), errors.Is(err, models.ErrInvalidCondition), errors.Is(err, models.ErrInvalidDueDate),
+		errors.Is(err, models.ErrDueDateTooLate):
+		utils.DoError(c, http.StatusBadRequest, err)
+	default:
+		utils.DoError(c, http.StatusInternalServerError, err)
+	}
+}
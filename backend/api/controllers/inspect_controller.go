@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"backend/api/forms"
+	"backend/api/pdf"
+	"backend/api/utils"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Read the metadata of a PDF before the final upload and suggest values for the upload form.
+Example request:
+
+	POST /api/upload/inspect
+		Body (multipart/form-data):
+		- uploadFile: the PDF
+
+Return:
+  - metadata: title, author, subject, keywords, creation_date, page_count
+  - suggestion: sheetName, composer, releaseDate, tags, informationText
+*/
+func (server *Server) InspectUpload(c *gin.Context) {
+	var form forms.InspectUploadRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad upload request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	meta, err := inspectUploadedPDF(form.File)
+	if err != nil {
+		utils.DoError(c, http.StatusUnprocessableEntity, fmt.Errorf("unable to read PDF: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"metadata":   meta,
+		"suggestion": suggestUpload(meta, form.File.Filename),
+	})
+}
+
+func inspectUploadedPDF(header *multipart.FileHeader) (*pdf.Metadata, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return pdf.Inspect(file)
+}
+
+// Déduit les valeurs du formulaire d'upload à partir des métadonnées du PDF.
+// Sans titre dans le PDF, le nom du fichier est utilisé ("Chopin - Nocturne Op. 9.pdf").
+// Sans auteur, la partie avant " - " du titre est prise comme compositeur.
+func suggestUpload(meta *pdf.Metadata, filename string) forms.UploadSuggestion {
+	suggestion := forms.UploadSuggestion{
+		SheetName:       meta.Title,
+		Composer:        meta.Author,
+		Tags:            strings.Join(meta.Keywords, "; "),
+		InformationText: meta.Subject,
+	}
+
+	if suggestion.SheetName == "" {
+		name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		suggestion.SheetName = strings.TrimSpace(strings.NewReplacer("_", " ").Replace(name))
+	}
+
+	if suggestion.Composer == "" {
+		if parts := strings.SplitN(suggestion.SheetName, " - ", 2); len(parts) == 2 {
+			suggestion.Composer = strings.TrimSpace(parts[0])
+			suggestion.SheetName = strings.TrimSpace(parts[1])
+		}
+	}
+
+	if !meta.CreationDate.IsZero() {
+		suggestion.ReleaseDate = meta.CreationDate.Format("2006-01-02")
+	}
+
+	return suggestion
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"backend/api/forms"
+	"backend/api/pdf"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestUpload(t *testing.T) {
+	tests := []struct {
+		name     string
+		meta     pdf.Metadata
+		filename string
+		want     forms.UploadSuggestion
+	}{
+		{
+			name:     "full metadata",
+			meta:     pdf.Metadata{Title: "Nocturne Op. 9 No. 2", Author: "Frédéric Chopin", Subject: "Piano", Keywords: []string{"nocturne", "romantic"}, CreationDate: time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)},
+			filename: "scan_001.pdf",
+			want:     forms.UploadSuggestion{SheetName: "Nocturne Op. 9 No. 2", Composer: "Frédéric Chopin", ReleaseDate: "2024-03-15", Tags: "nocturne; romantic", InformationText: "Piano"},
+		},
+		{
+			name:     "no metadata, composer from the file name",
+			filename: "Chopin - Nocturne_Op_9.pdf",
+			want:     forms.UploadSuggestion{SheetName: "Nocturne Op 9", Composer: "Chopin"},
+		},
+		{
+			name:     "no metadata, file name only",
+			filename: "uploads/fuer_elise.pdf",
+			want:     forms.UploadSuggestion{SheetName: "fuer elise"},
+		},
+		{
+			name:     "title with composer, author kept",
+			meta:     pdf.Metadata{Title: "Bach - Prelude", Author: "Johann Sebastian Bach"},
+			filename: "prelude.pdf",
+			want:     forms.UploadSuggestion{SheetName: "Bach - Prelude", Composer: "Johann Sebastian Bach"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, suggestUpload(&tt.meta, tt.filename))
+		})
+	}
+}
+
+func TestInspectUpload(t *testing.T) {
+	server := setupServer(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = uploadRequest(t, http.MethodPost, "/api/upload/inspect", "Chopin - Etude.pdf", testPDF(t, 2))
+	server.InspectUpload(c)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var body struct {
+		Metadata   pdf.Metadata           `json:"metadata"`
+		Suggestion forms.UploadSuggestion `json:"suggestion"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 2, body.Metadata.PageCount)
+	// Sans titre ni auteur dans le PDF : déduits du nom du fichier
+	assert.Equal(t, "Etude", body.Suggestion.SheetName)
+	assert.Equal(t, "Chopin", body.Suggestion.Composer)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = uploadRequest(t, http.MethodPost, "/api/upload/inspect", "etude.pdf", []byte("%PDF-1.4 broken"))
+	server.InspectUpload(c)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"backend/api/catalogue"
+	"backend/api/config"
+	"backend/api/forms"
+	"backend/api/models"
+	"backend/api/pdf"
+	"backend/api/utils"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/*
+Get the QR code of the page of a sheet on the frontend: <PUBLIC_URL>/sheet/<safe_sheet_name>.
+The API routes need a token, a scanned label opens the frontend, which logs the user in.
+Example request:
+
+	GET /api/sheet/fuer-elise/qr.png?size=512
+*/
+func (server *Server) GetSheetQR(c *gin.Context) {
+	var form forms.QRRequest
+	if err := c.ShouldBindQuery(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad qr request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	png, err := pdf.QRCode(sheetURL(sheet), form.Size)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+/*
+Print A4 sheets of labels (2 x 7, 99.1 x 38.1 mm) for binders: title, composer, catalogue number
+and the QR code of the sheet (<PUBLIC_URL>/sheet/<safe_sheet_name>, as GET /api/sheet/:sheetName/qr.png),
+one label per sheet in the given order
+Example request:
+
+	POST /api/labels.pdf
+		Body (FormValue or JSON):
+		- sheets: ["fuer-elise", "nocturne-op-9-no-2"]
+*/
+func (server *Server) GetLabels(c *gin.Context) {
+	var form forms.LabelsRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad labels request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	labels := make([]pdf.Label, 0, len(form.Sheets))
+	for _, name := range form.Sheets {
+		sheet, err := (&models.Sheet{}).FindSheetBySafeName(server.DB, name)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.DoError(c, http.StatusNotFound, fmt.Errorf("sheet %s not found", name))
+				return
+			}
+			utils.DoError(c, http.StatusInternalServerError, err)
+			return
+		}
+		labels = append(labels, pdf.Label{
+			Title:     sheet.SheetName,
+			Composer:  sheet.Composer,
+			Catalogue: catalogue.Number{System: sheet.CatalogueType, Number: sheet.CatalogueNumber}.String(),
+			URL:       sheetURL(sheet),
+		})
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Labels(labels, &out); err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to create the labels: %v", err))
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="labels.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", out.Bytes())
+}
+
+// sheetURL retourne l'adresse de la partition sur le frontend (PUBLIC_URL) : la route JSON /api/sheet/:sheetName
+// demande un token, qu'un téléphone qui scanne l'étiquette n'a pas
+func sheetURL(sheet *models.Sheet) string {
+	return strings.TrimRight(config.Config().PublicUrl, "/") + "/sheet/" + url.PathEscape(sheet.SafeSheetName)
+}
@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"backend/api/config"
+	"backend/api/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSheetURLOpensFrontend(t *testing.T) {
+	// Le QR code mène au frontend, pas à la route JSON authentifiée
+	url := sheetURL(&models.Sheet{SafeSheetName: "etude-op-10"})
+	assert.Equal(t, strings.TrimRight(config.Config().PublicUrl, "/")+"/sheet/etude-op-10", url)
+	assert.NotContains(t, url, "/api/")
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"backend/api/auth"
+	"backend/api/config"
+	"backend/api/forms"
+	"backend/api/library"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Check the consistency between the database and the library folders.
+Only admins are allowed to run it. GET only reports, fix is only accepted with POST.
+Example request:
+
+	GET  /api/admin/library/check
+	POST /api/admin/library/check
+		Body (FormValue):
+		- fix: true (repair or quarantine the issues found)
+*/
+func (server *Server) CheckLibrary(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	var form forms.LibraryCheckRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("unable to parse form: %v", err))
+		return
+	}
+	if form.Fix && c.Request.Method != http.MethodPost {
+		utils.DoError(c, http.StatusBadRequest, errors.New("fix is only accepted with POST"))
+		return
+	}
+
+	report, err := library.Check(server.DB, config.Config().ConfigPath, form.Fix)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+/*
+List the sheets sharing the same PDF content (SHA-256).
+Only admins are allowed to run it.
+Example request:
+
+	GET /api/admin/library/duplicates
+*/
+func (server *Server) GetDuplicateSheets(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	groups, err := library.Duplicates(server.DB)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+/*
+List the pairs of composers that are likely the same person (transliterated fuzzy name similarity).
+Only admins are allowed to run it.
+Example request:
+
+	GET /api/admin/composers/duplicates
+*/
+func (server *Server) GetDuplicateComposers(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	duplicates, err := library.ComposerDuplicates(server.DB)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, duplicates)
+}
+
+// Vérifie que la requête provient de l'administrateur, sinon répond 401
+func requireAdmin(c *gin.Context) bool {
+	token := utils.ExtractToken(c)
+	uid, err := auth.ExtractTokenID(token, config.Config().ApiSecret)
+	if err != nil {
+		c.String(http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+	if uid != config.ADMIN_UID {
+		c.String(http.StatusUnauthorized, "Only admins are able to persue this command")
+		return false
+	}
+	return true
+}
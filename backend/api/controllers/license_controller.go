@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"backend/api/auth"
+	"backend/api/config"
+	"backend/api/forms"
+	"backend/api/models"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/*
+Get the license of a sheet, its public-domain status and the use of its seats
+The public-domain status is computed from the composer's death date and COPYRIGHT_TERM (default life+70).
+Example request:
+
+	GET /api/sheet/fuer-elise/license
+*/
+func (server *Server) GetLicense(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	status, err := sheet.LicenseStatus(server.DB, time.Now())
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+/*
+Set the license of a sheet (admin only)
+  - license: public-domain, cc0, cc-by, cc-by-sa, cc-by-nd, cc-by-nc, cc-by-nc-sa, cc-by-nc-nd, purchased, rental (empty = unknown)
+  - seats: number of users allowed by a purchased license (0 = unlimited)
+  - expiry: last day of a rental, downloads are refused afterwards
+
+Example request:
+
+	PUT /api/sheet/fuer-elise/license
+		Body (FormValue or JSON):
+		- license: rental
+		- holder: Bärenreiter
+		- expiry: 2026-06-30
+*/
+func (server *Server) SetLicense(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var form forms.LicenseRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad license request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	expiry, _ := form.ExpiryDate()
+	sheet, err := models.SetLicense(server.DB, c.Param("sheetName"), models.SheetLicense{
+		License: form.License,
+		Holder:  form.Holder,
+		Seats:   form.Seats,
+		Expiry:  expiry,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrUnknownLicense), errors.Is(err, models.ErrInvalidSeats), errors.Is(err, models.ErrRentalWithoutExpiry):
+			utils.DoError(c, http.StatusBadRequest, err)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.DoError(c, http.StatusNotFound, errors.New("sheet not found"))
+		default:
+			utils.DoError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, sheet)
+}
+
+/*
+List the license issues of the library: expired rentals and purchased licenses
+distributed to more users than their number of seats
+Example request:
+
+	GET /api/licenses/issues
+*/
+func (server *Server) GetLicenseIssues(c *gin.Context) {
+	expired, overused, err := models.LicenseIssues(server.DB, time.Now())
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"expired_rentals": expired,
+		"seats_exceeded":  overused,
+	})
+}
+
+// checkDownload refuse le téléchargement d'une location expirée (403, sauf pour l'administrateur)
+// et enregistre l'utilisateur parmi les destinataires de la partition pour le décompte des places
+func (server *Server) checkDownload(c *gin.Context, sheet *models.Sheet) bool {
+	uid, err := auth.ExtractTokenID(utils.ExtractToken(c), config.Config().ApiSecret)
+	if err != nil {
+		utils.DoError(c, http.StatusUnauthorized, err)
+		return false
+	}
+	if uid == config.ADMIN_UID {
+		return true
+	}
+	if err := sheet.CheckAccess(server.DB, time.Now()); err != nil {
+		if errors.Is(err, models.ErrRentalExpired) {
+			utils.DoError(c, http.StatusForbidden, err)
+			return false
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return false
+	}
+	if err := models.RecordDistribution(server.DB, sheet.SafeSheetName, uid); err != nil {
+		log.Printf("Erreur lors de l'enregistrement du téléchargement de %s : %v\n", sheet.SafeSheetName, err)
+	}
+	return true
+}
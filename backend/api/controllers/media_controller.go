@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"backend/api/config"
+	"backend/api/forms"
+	"backend/api/media"
+	"backend/api/models"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Attach a reference recording, a MIDI file or a practice track to a sheet
+Supported formats: MP3, OGG (Vorbis, Opus), FLAC and MIDI, detected from the file content
+Files larger than UPLOAD_MEDIA_MAX_MB (default 100MB) are refused before being read
+Example request:
+
+	POST /api/sheet/nocturne-op-9-2/media
+		Body (multipart/form-data):
+		- uploadFile: rubinstein.mp3
+		- label: Rubinstein 1965
+		- kind: recording, midi or practice (optional, default midi for a MIDI file, recording otherwise)
+
+The response contains the detected format and duration (in seconds)
+*/
+func (server *Server) UploadMedia(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+
+	// Le corps de la requête est borné avant l'analyse du formulaire multipart (marge de 1 Mo pour les autres champs)
+	maxSize := config.Config().Upload.MediaMaxBytes()
+	tooLarge := fmt.Errorf("media file exceeds %d MB", config.Config().Upload.MediaMaxMB)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+	var form forms.UploadMediaRequest
+	if err := c.ShouldBind(&form); err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			utils.DoError(c, http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad upload request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	if form.File.Size > maxSize {
+		utils.DoError(c, http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+
+	file, err := form.File.Open()
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer file.Close()
+
+	info, err := media.Probe(file)
+	if err != nil {
+		if errors.Is(err, media.ErrUnsupportedMedia) || errors.Is(err, media.ErrCorruptMedia) {
+			utils.DoError(c, http.StatusUnsupportedMediaType, err)
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	item := &models.SheetMedia{Label: form.Label, Kind: form.Kind}
+	if err := sheet.AddMedia(server.DB, item, info, file); err != nil {
+		switch {
+		case errors.Is(err, models.ErrMediaExists), errors.Is(err, os.ErrExist):
+			utils.DoError(c, http.StatusConflict, models.ErrMediaExists)
+		case errors.Is(err, models.ErrEmptyLabel):
+			utils.DoError(c, http.StatusBadRequest, err)
+		default:
+			utils.DoError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+/*
+Stream a media file of a sheet
+HTTP range requests are supported, so that players can seek without downloading the whole file
+Example request:
+
+	GET /api/sheet/nocturne-op-9-2/media/rubinstein-1965
+		Header: Range: bytes=1048576-
+*/
+func (server *Server) GetMedia(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil || !server.checkDownload(c, sheet) {
+		return
+	}
+
+	item, err := sheet.FindMedia(server.DB, c.Param("media"))
+	if err != nil {
+		if errors.Is(err, models.ErrMediaNotFound) {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	f, err := os.Open(item.Path(sheet))
+	if err != nil {
+		utils.DoError(c, http.StatusNotFound, fmt.Errorf("missing media file %s", item.SafeLabel))
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// ServeContent gère les en-têtes Range, If-Range et If-Modified-Since
+	c.Header("Content-Type", item.MimeType)
+	c.Header("Accept-Ranges", "bytes")
+	http.ServeContent(c.Writer, c.Request, stat.Name(), stat.ModTime(), f)
+}
+
+/*
+Delete a media file of a sheet
+Example request:
+
+	DELETE /api/sheet/nocturne-op-9-2/media/rubinstein-1965
+*/
+func (server *Server) DeleteMedia(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+
+	if err := sheet.DeleteMedia(server.DB, c.Param("media")); err != nil {
+		if errors.Is(err, models.ErrMediaNotFound) {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, "Media deleted successfully")
+}
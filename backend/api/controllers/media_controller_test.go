@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadMediaLimitsSize(t *testing.T) {
+	server := setupServer(t)
+
+	tests := []struct {
+		name   string
+		size   int
+		status int
+	}{
+		{"within the limit", 1 << 10, http.StatusUnsupportedMediaType},
+		{"file over the limit", 1<<20 + 1, http.StatusRequestEntityTooLarge},
+		{"body over the limit", 3 << 20, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = uploadRequest(t, http.MethodPost, "/api/sheet/etude/media", "take.mp3", make([]byte, tt.size), "label", "Take 1")
+			c.Params = gin.Params{{Key: "sheetName", Value: "etude"}}
+			server.UploadMedia(c)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,507 @@
+package controllers
+
+import (
+	"backend/api/auth"
+	"backend/api/config"
+	"backend/api/forms"
+	"backend/api/models"
+	"backend/api/pdf"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Rotate pages of the PDF of a sheet (a scan saved sideways, an upside-down page)
+The PDF before the change is kept as a revision, the thumbnail and page previews are regenerated.
+Example request:
+
+	POST /api/sheet/fuer-elise/pages/rotate
+		Body (FormValue or JSON):
+		- pages: 1,3-4 (default: all pages)
+		- angle: 90 (clockwise, -90 = counterclockwise)
+*/
+func (server *Server) RotatePages(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	var form forms.RotatePagesRequest
+	if !bindPagesForm(c, &form) {
+		return
+	}
+	count, ok := editablePdf(server, c, sheet, true)
+	if !ok {
+		return
+	}
+	pages, err := pdf.ParsePages(form.Pages, count)
+	if err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	editPdf(server, c, sheet, "rotate", func(src string, out string) error {
+		return pdf.RotatePages(src, out, pages, form.Angle)
+	})
+}
+
+/*
+Delete pages of the PDF of a sheet (blank pages, advertisements of a scanned edition)
+Example request:
+
+	POST /api/sheet/fuer-elise/pages/delete
+		Body (FormValue or JSON):
+		- pages: 2,5-6
+*/
+func (server *Server) DeletePages(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	var form forms.PagesRequest
+	if !bindPagesForm(c, &form) {
+		return
+	}
+	count, ok := editablePdf(server, c, sheet, false)
+	if !ok {
+		return
+	}
+	pages, err := pdf.ParsePages(form.Pages, count)
+	if err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	editPdf(server, c, sheet, "delete", func(src string, out string) error {
+		return pdf.DeletePages(src, out, pages, count)
+	})
+}
+
+/*
+Reorder the pages of the PDF of a sheet, every page must be listed once
+Example request:
+
+	POST /api/sheet/fuer-elise/pages/reorder
+		Body (FormValue or JSON):
+		- order: 2,1,3-8
+*/
+func (server *Server) ReorderPages(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	var form forms.ReorderPagesRequest
+	if !bindPagesForm(c, &form) {
+		return
+	}
+	count, ok := editablePdf(server, c, sheet, false)
+	if !ok {
+		return
+	}
+	order, err := pdf.ParseOrder(form.Order, count)
+	if err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	editPdf(server, c, sheet, "reorder", func(src string, out string) error {
+		return pdf.SelectPages(src, out, order)
+	})
+}
+
+/*
+Crop the margins of pages of the PDF of a sheet (scanner borders, punched holes)
+Margins are in points or percent, as in CSS: all sides, vertical horizontal, or top right bottom left.
+Example request:
+
+	POST /api/sheet/fuer-elise/pages/crop
+		Body (FormValue or JSON):
+		- pages: 1-4 (default: all pages)
+		- margins: 20 10 or 5%
+*/
+func (server *Server) CropPages(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	var form forms.CropPagesRequest
+	if !bindPagesForm(c, &form) {
+		return
+	}
+	count, ok := editablePdf(server, c, sheet, true)
+	if !ok {
+		return
+	}
+	pages, err := pdf.ParsePages(form.Pages, count)
+	if err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	editPdf(server, c, sheet, "crop", func(src string, out string) error {
+		return pdf.CropPages(src, out, pages, form.Margins)
+	})
+}
+
+/*
+Copy pages of the PDF of a sheet to a new sheet, e.g. one movement of a sonata or one song of a collection.
+With remove=true the pages are also deleted from the original (split), which is kept as a revision.
+To keep the pages in the original without copying them, register an excerpt instead (POST /sheet/:sheetName/excerpts).
+Example request:
+
+	POST /api/sheet/sonatas-op-2/pages/extract
+		Body (FormValue or JSON):
+		- pages: 12-20
+		- sheetName: Sonata No. 3 in C major, Op. 2 No. 3
+		- composer: Beethoven (default: composer of the original)
+		- remove: true (default: false)
+		- categories, tags, informationText, releaseDate, work
+		- metadata fields as for the upload (key, difficulty, duration ...)
+*/
+func (server *Server) ExtractPages(c *gin.Context) {
+	token := utils.ExtractToken(c)
+	uid, err := auth.ExtractTokenID(token, config.Config().ApiSecret)
+	if err != nil || uid == 0 {
+		c.String(http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	var form forms.ExtractPagesRequest
+	if !bindPagesForm(c, &form) {
+		return
+	}
+	count, ok := editablePdf(server, c, sheet, !form.Remove)
+	if !ok {
+		return
+	}
+	pages, err := pdf.ParsePages(form.Pages, count)
+	if err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Pages extraites et, pour un découpage, PDF restant : préparés avant de créer la nouvelle partition
+	src := models.PdfPath(sheet)
+	extracted, err := tempPdf(src, ".extract-*.pdf")
+	if err == nil {
+		defer os.Remove(extracted)
+		err = pdf.SelectPages(src, extracted, pages)
+	}
+	remaining := ""
+	if err == nil && form.Remove {
+		if remaining, err = tempPdf(src, ".split-*.pdf"); err == nil {
+			defer os.Remove(remaining)
+			err = pdf.DeletePages(src, remaining, pages, count)
+		}
+	}
+	if err != nil {
+		pagesError(c, err)
+		return
+	}
+
+	metadata := form.Metadata()
+	catalogueFromTitle(&metadata, form.SheetName)
+
+	var work *models.Work
+	if form.Work != "" {
+		if work, err = models.FindWork(server.DB, form.Work); err != nil {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+	}
+
+	var comp models.Composer
+	if strings.TrimSpace(form.Composer) == "" {
+		var composerModel models.Composer
+		found, err := composerModel.FindComposerBySafeName(server.DB, sheet.SafeComposer)
+		if err != nil {
+			utils.DoError(c, http.StatusNotFound, models.ErrComposerNotFound)
+			return
+		}
+		comp = *found
+	} else {
+		// Compositeur de l'oeuvre vérifié avant que safeComposer ne crée un compositeur inconnu
+		if work != nil {
+			if err := editionComposer(server, work, form.Composer); err != nil {
+				utils.DoError(c, http.StatusConflict, err)
+				return
+			}
+		}
+		comp = safeComposer(server, form.Composer)
+	}
+	if work != nil && work.SafeComposer != comp.SafeName {
+		utils.DoError(c, http.StatusConflict, fmt.Errorf("%w: %s", models.ErrEditionComposer, comp.SafeName))
+		return
+	}
+
+	uploadPath := checkComposer(path.Join(config.Config().ConfigPath, "sheets/uploaded-sheets"), comp)
+	safeSheetName, fullpath, err := editionSheetName(server, uploadPath, form.SheetName, work != nil || isEdition(metadata), metadata)
+	if err != nil {
+		utils.DoError(c, http.StatusConflict, err)
+		return
+	}
+
+	file, err := os.Open(extracted)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer file.Close()
+	structure, err := pdf.Analyze(file)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to read extracted pages: %v", err))
+		return
+	}
+	fileHash, err := utils.HashReader(file)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	created, err := createFile(uid, server, fullpath, file, fileHash, structure, comp, form.SheetName, safeSheetName, form.ReleaseDate,
+		form.InformationText, form.Categories, form.Tags, metadata)
+	if err == nil {
+		err = linkUploadedEdition(server, work, created)
+	}
+	// Les pages d'une partition sous licence restent filigranées
+	if err == nil && sheet.Watermark != models.WatermarkInherit {
+		created, err = models.SetSheetWatermark(server.DB, created.SafeSheetName, sheet.Watermark)
+	}
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.RequestToPdfToImage(fullpath, created.SafeSheetName)
+
+	if form.Remove {
+		if err := sheet.ReplacePdf(server.DB, remaining, "split"); err != nil {
+			utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("sheet %s created, unable to remove the pages from %s: %v",
+				created.SafeSheetName, sheet.SafeSheetName, err))
+			return
+		}
+		refreshPreviews(server, sheet)
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"sheet":     sheet,
+		"extracted": created,
+	})
+}
+
+/*
+List the previous versions of the PDF of a sheet, the most recent first
+Example request:
+
+	GET /api/sheet/fuer-elise/revisions
+*/
+func (server *Server) GetRevisions(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	revisions, err := sheet.Revisions(server.DB)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sheet":     sheet,
+		"revisions": revisions,
+	})
+}
+
+/*
+Download a previous version of the PDF of a sheet
+Example request:
+
+	GET /api/sheet/fuer-elise/revisions/1
+*/
+func (server *Server) GetRevision(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil || !server.checkDownload(c, sheet) {
+		return
+	}
+	revision, ok := findRevision(server, c, sheet)
+	if !ok {
+		return
+	}
+	filePath, err := server.watermarkedPDF(c, sheet, models.RevisionPath(sheet, revision.Revision), "revision-"+strconv.Itoa(revision.Revision))
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to watermark the PDF: %v", err))
+		return
+	}
+	c.Header("Content-Type", "application/pdf")
+	c.File(filePath)
+}
+
+/*
+Restore a previous version of the PDF of a sheet
+The current PDF is kept as a new revision, so a restore can itself be undone.
+Example request:
+
+	POST /api/sheet/fuer-elise/revisions/1/restore
+*/
+func (server *Server) RestoreRevision(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+	revision, ok := findRevision(server, c, sheet)
+	if !ok {
+		return
+	}
+	// Les pièces d'un recueil désignent des pages : le nombre de pages ne doit pas changer
+	if _, ok := editablePdf(server, c, sheet, revision.PageCount == sheet.PageCount); !ok {
+		return
+	}
+	if err := sheet.RestoreRevision(server.DB, revision.Revision); err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	refreshPreviews(server, sheet)
+	c.JSON(http.StatusOK, sheet)
+}
+
+// bindPagesForm lit et valide le formulaire d'une modification des pages
+func bindPagesForm(c *gin.Context, form interface{ ValidateForm() error }) bool {
+	if err := c.ShouldBind(form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad pages request: %v", err))
+		return false
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+// editablePdf vérifie que les pages de la partition peuvent être modifiées et retourne leur nombre.
+// Une modification qui déplace ou retire des pages (keepsPages faux) est refusée pour un recueil avec des pièces.
+func editablePdf(server *Server, c *gin.Context, sheet *models.Sheet, keepsPages bool) (int, bool) {
+	if sheet.IsExcerpt() {
+		utils.DoError(c, http.StatusUnprocessableEntity, fmt.Errorf("%s is an excerpt of %s, edit the pages of the anthology", sheet.SafeSheetName, sheet.ParentSheet))
+		return 0, false
+	}
+	if !sheet.HasPdf() {
+		utils.DoError(c, http.StatusUnprocessableEntity, errors.New("the sheet has no PDF"))
+		return 0, false
+	}
+	if !keepsPages {
+		excerpts, err := sheet.Excerpts(server.DB)
+		if err != nil {
+			utils.DoError(c, http.StatusInternalServerError, err)
+			return 0, false
+		}
+		if len(excerpts) > 0 {
+			utils.DoError(c, http.StatusConflict, models.ErrSheetHasExcerpts)
+			return 0, false
+		}
+	}
+	count, err := pdf.PageCount(models.PdfPath(sheet))
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to read the PDF: %v", err))
+		return 0, false
+	}
+	return count, true
+}
+
+// editPdf écrit le PDF modifié par fn dans un fichier temporaire qui remplace ensuite le PDF de la partition
+func editPdf(server *Server, c *gin.Context, sheet *models.Sheet, operation string, fn func(src string, out string) error) {
+	src := models.PdfPath(sheet)
+	out, err := tempPdf(src, ".edit-*.pdf")
+	if err == nil {
+		err = fn(src, out)
+		if err == nil {
+			err = sheet.ReplacePdf(server.DB, out, operation)
+		}
+		if err != nil {
+			os.Remove(out)
+		}
+	}
+	if err != nil {
+		pagesError(c, err)
+		return
+	}
+	refreshPreviews(server, sheet)
+	c.JSON(http.StatusOK, sheet)
+}
+
+// tempPdf crée un fichier temporaire vide à côté du PDF : le remplacement est un simple renommage
+func tempPdf(src string, pattern string) (string, error) {
+	tmp, err := os.CreateTemp(path.Dir(src), pattern)
+	if err != nil {
+		return "", err
+	}
+	return tmp.Name(), tmp.Close()
+}
+
+// pagesError répond 400 pour une sélection de pages, des marges ou un angle invalides, 500 sinon
+func pagesError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pdf.ErrInvalidPages), errors.Is(err, pdf.ErrInvalidMargins), errors.Is(err, pdf.ErrInvalidAngle):
+		utils.DoError(c, http.StatusBadRequest, err)
+	default:
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to edit the pages: %v", err))
+	}
+}
+
+// refreshPreviews régénère le thumbnail et supprime les miniatures redimensionnées, les rendus des pages
+// et les PDF filigranés de la partition et de ses pièces
+func refreshPreviews(server *Server, sheet *models.Sheet) {
+	removePreviews(sheet)
+	utils.RequestToPdfToImage(models.PdfPath(sheet), sheet.SafeSheetName)
+
+	excerpts, err := sheet.Excerpts(server.DB)
+	if err != nil {
+		log.Printf("excerpts of %s: %v\n", sheet.SafeSheetName, err)
+		return
+	}
+	for i := range excerpts {
+		excerpt := &excerpts[i]
+		removePreviews(excerpt)
+		file, err := sheetFile(server.DB, excerpt)
+		if err != nil {
+			log.Printf("excerpt %s: %v\n", excerpt.SafeSheetName, err)
+			continue
+		}
+		utils.RequestToPdfToImage(file, excerpt.SafeSheetName)
+	}
+}
+
+// removePreviews supprime les miniatures redimensionnées (sheets/thumbnails/<taille>/<nom>.<format>), recalculées
+// à la demande : leur date ne suffit pas à les invalider, le thumbnail d'origine pouvant ne pas être encore régénéré
+func removePreviews(sheet *models.Sheet) {
+	sized, _ := filepath.Glob(path.Join(config.Config().ConfigPath, "sheets/thumbnails", "*", sheet.SafeSheetName+".*"))
+	for _, file := range sized {
+		os.Remove(file)
+	}
+	os.RemoveAll(path.Join(config.Config().ConfigPath, "sheets/pages", sheet.SafeSheetName))
+	os.RemoveAll(models.WatermarkDir(sheet))
+}
+
+// findRevision lit le numéro de révision de l'URL, 404 si la partition n'a pas cette version
+func findRevision(server *Server, c *gin.Context, sheet *models.Sheet) (*models.SheetRevision, bool) {
+	n, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || n < 1 {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("invalid revision %q", c.Param("revision")))
+		return nil, false
+	}
+	revision, err := sheet.FindRevision(server.DB, n)
+	if err != nil {
+		if errors.Is(err, models.ErrRevisionNotFound) {
+			utils.DoError(c, http.StatusNotFound, err)
+			return nil, false
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	return revision, true
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"archive/zip"
+	"backend/api/forms"
+	"backend/api/models"
+	"backend/api/pdf"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Add a part (instrument or voice) to an existing sheet, the main PDF being the full score
+The part must be a valid PDF of at most 10MB, as for /api/upload
+Example request:
+
+	POST /api/sheet/quartet-op-18-1/parts
+		Body (multipart/form-data):
+		- uploadFile: violin-1.pdf
+		- label: Violin I
+		- position: 1 (optional, default after the other parts)
+*/
+func (server *Server) UploadPart(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+
+	var form forms.UploadPartRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad upload request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	file, err := form.File.Open()
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer file.Close()
+
+	structure, err := pdf.Analyze(file)
+	if err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("invalid PDF: %v", err))
+		return
+	}
+	fileHash, err := utils.HashReader(file)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	part := &models.SheetPart{
+		Label:     form.Label,
+		Position:  form.Position,
+		FileHash:  fileHash,
+		FileSize:  structure.FileSize,
+		PageCount: structure.PageCount,
+	}
+	if err := sheet.AddPart(server.DB, part, file); err != nil {
+		switch {
+		case errors.Is(err, models.ErrPartExists), errors.Is(err, os.ErrExist):
+			utils.DoError(c, http.StatusConflict, models.ErrPartExists)
+		case errors.Is(err, models.ErrEmptyLabel):
+			utils.DoError(c, http.StatusBadRequest, err)
+		default:
+			utils.DoError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, part)
+}
+
+/*
+Delete a part of a sheet
+Example request:
+
+	DELETE /api/sheet/quartet-op-18-1/parts/violin-i
+*/
+func (server *Server) DeletePart(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+
+	if err := sheet.DeletePart(server.DB, c.Param("part")); err != nil {
+		if errors.Is(err, models.ErrPartNotFound) {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, "Part deleted successfully")
+}
+
+/*
+Download the full score and all parts of a sheet as a ZIP archive
+Example request:
+
+	GET /api/sheet/quartet-op-18-1/parts.zip
+
+Archive content:
+  - 00 - Score.pdf
+  - 01 - Violin I.pdf
+  - 02 - Violin II.pdf ...
+*/
+func (server *Server) DownloadParts(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil || !server.checkDownload(c, sheet) {
+		return
+	}
+
+	score, err := sheetFile(server.DB, sheet)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to extract pages: %v", err))
+		return
+	}
+	type entry struct{ name, path, label string }
+	entries := []entry{{"00 - Score.pdf", score, "sheet"}}
+	for i, part := range sheet.Parts {
+		entries = append(entries, entry{fmt.Sprintf("%02d - %s.pdf", i+1, utils.SafeFileName(part.Label)), models.PartPath(sheet, part.SafeLabel), "part-" + part.SafeLabel})
+	}
+	// Partition sous licence : la partition et chaque partie sont filigranées
+	for i := range entries {
+		if entries[i].path, err = server.watermarkedPDF(c, sheet, entries[i].path, entries[i].label); err != nil {
+			utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to watermark %s: %v", entries[i].name, err))
+			return
+		}
+	}
+	// Tous les fichiers doivent exister avant de commencer à écrire la réponse
+	for _, e := range entries {
+		if _, err := os.Stat(e.path); err != nil {
+			utils.DoError(c, http.StatusNotFound, fmt.Errorf("missing file %s", e.name))
+			return
+		}
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", sheet.SafeSheetName+".zip"))
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	for _, e := range entries {
+		if err := addZipFile(archive, e.name, e.path); err != nil {
+			c.Error(err)
+			break
+		}
+	}
+	archive.Close()
+}
+
+func addZipFile(archive *zip.Writer, name string, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Les PDF sont déjà compressés : simple stockage
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
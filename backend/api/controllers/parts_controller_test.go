@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadPartValidatesFile(t *testing.T) {
+	server := setupServer(t)
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		status   int
+	}{
+		{"not a pdf extension", "violin-i.txt", testPDF(t, 1), http.StatusBadRequest},
+		{"invalid pdf", "violin-i.pdf", []byte("%PDF-1.4 broken"), http.StatusBadRequest},
+		{"too large", "violin-i.pdf", make([]byte, 10<<20+1), http.StatusBadRequest},
+		{"valid part", "violin-i.pdf", testPDF(t, 1), http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = uploadRequest(t, http.MethodPost, "/api/sheet/etude/parts", tt.filename, tt.data, "label", "Violin I")
+			c.Params = gin.Params{{Key: "sheetName", Value: "etude"}}
+			server.UploadPart(c)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
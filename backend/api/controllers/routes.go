@@ -65,12 +65,70 @@ func (server *Server) SetupRouter() {
 	secure.PUT("/sheet/:sheetName", server.UpdateSheet)
 	secure.DELETE("/sheet/:sheetName", server.DeleteSheet)
 	secure.POST("/upload", server.UploadFile)
+	secure.POST("/upload/inspect", server.InspectUpload)
 	secure.PUT("/sheet/:sheetName/info", server.UpdateSheetInformationText)
 	secure.POST("/sheet/:sheetName/info", server.UpdateSheetInformationText)
+	secure.PUT("/sheet/:sheetName/metadata", server.UpdateSheetMetadata)
 
 	// Thumbnails & PDFs
 	api.GET("/sheet/thumbnail/:name", server.GetThumbnail)
 	secure.GET("/sheet/pdf/:composer/:sheetName", server.GetPDF)
+	secure.GET("/sheet/:sheetName/page/:n", server.GetPage)
+
+	// Parts
+	secure.POST("/sheet/:sheetName/parts", server.UploadPart)
+	secure.DELETE("/sheet/:sheetName/parts/:part", server.DeletePart)
+	secure.GET("/sheet/:sheetName/parts.zip", server.DownloadParts)
+
+	// Anthologies (pieces as page ranges of one PDF)
+	secure.POST("/sheet/:sheetName/excerpts", server.CreateExcerpt)
+	secure.GET("/sheet/:sheetName/excerpts", server.GetExcerpts)
+
+	// Page operations on the PDF, previous versions kept as revisions
+	secure.POST("/sheet/:sheetName/pages/rotate", server.RotatePages)
+	secure.POST("/sheet/:sheetName/pages/delete", server.DeletePages)
+	secure.POST("/sheet/:sheetName/pages/reorder", server.ReorderPages)
+	secure.POST("/sheet/:sheetName/pages/crop", server.CropPages)
+	secure.POST("/sheet/:sheetName/pages/extract", server.ExtractPages)
+	secure.GET("/sheet/:sheetName/revisions", server.GetRevisions)
+	secure.GET("/sheet/:sheetName/revisions/:revision", server.GetRevision)
+	secure.POST("/sheet/:sheetName/revisions/:revision/restore", server.RestoreRevision)
+
+	// Watermark of downloaded PDFs, per sheet or per category
+	secure.PUT("/sheet/:sheetName/watermark", server.SetSheetWatermark)
+	secure.GET("/watermarks", server.GetWatermarkCategories)
+	secure.PUT("/watermark/:category", server.SetWatermarkCategory)
+	secure.DELETE("/watermark/:category", server.DeleteWatermarkCategory)
+
+	// License, public-domain status and seats
+	secure.GET("/sheet/:sheetName/license", server.GetLicense)
+	secure.PUT("/sheet/:sheetName/license", server.SetLicense)
+	secure.GET("/licenses/issues", server.GetLicenseIssues)
+
+	// Printed copies and loans
+	secure.GET("/sheet/:sheetName/copies", server.GetCopies)
+	secure.POST("/sheet/:sheetName/copies", server.AddCopy)
+	secure.GET("/copy/:barcode", server.GetCopy)
+	secure.PUT("/copy/:barcode", server.UpdateCopy)
+	secure.DELETE("/copy/:barcode", server.DeleteCopy)
+	secure.POST("/copy/:barcode/checkout", server.CheckoutCopy)
+	secure.POST("/copy/:barcode/return", server.ReturnCopy)
+	secure.GET("/loans", server.GetLoans)
+	secure.POST("/loans/reminders", server.SendLoanReminders)
+
+	// QR codes and binder labels
+	secure.GET("/sheet/:sheetName/qr.png", server.GetSheetQR)
+	secure.POST("/labels.pdf", server.GetLabels)
+
+	// MusicXML / MuseScore source
+	secure.POST("/sheet/:sheetName/source", server.UploadSource)
+	secure.GET("/sheet/:sheetName/source", server.GetSource)
+	secure.GET("/sheet/:sheetName/transpose", server.TransposeSheet)
+
+	// Media (recordings, MIDI, practice tracks)
+	secure.POST("/sheet/:sheetName/media", server.UploadMedia)
+	secure.GET("/sheet/:sheetName/media/:media", server.GetMedia)
+	secure.DELETE("/sheet/:sheetName/media/:media", server.DeleteMedia)
 
 	// Search
 	secure.GET("/search/:searchValue", server.SearchSheets)
@@ -87,7 +145,24 @@ func (server *Server) SetupRouter() {
 	secure.POST("/composers", server.GetComposersPage)
 	secure.PUT("/composer/:composerName", server.UpdateComposer)
 	secure.DELETE("/composer/:composerName", server.DeleteComposer)
+	secure.POST("/composer/:composerName/merge", server.MergeComposer)
+	secure.GET("/composer/:composerName/catalogue", server.GetComposerCatalogue)
 	api.GET("/composer/portrait/:composerName", server.ServePortraits)
 
+	// Works (several editions of the same piece)
+	secure.GET("/works", server.GetWorksPage)
+	secure.POST("/works", server.GetWorksPage)
+	secure.POST("/work", server.CreateWork)
+	secure.GET("/work/:workName", server.GetWork)
+	secure.DELETE("/work/:workName", server.DeleteWork)
+	secure.POST("/work/:workName/editions", server.LinkEdition)
+	secure.DELETE("/work/:workName/editions/:sheetName", server.UnlinkEdition)
+
+	// Admin
+	secure.GET("/admin/library/check", server.CheckLibrary)
+	secure.POST("/admin/library/check", server.CheckLibrary)
+	secure.GET("/admin/library/duplicates", server.GetDuplicateSheets)
+	secure.GET("/admin/composers/duplicates", server.GetDuplicateComposers)
+
 	server.Router = r
 }
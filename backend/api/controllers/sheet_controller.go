@@ -1,15 +1,22 @@
 package controllers
 
 import (
+	"backend/api/abc"
 	"backend/api/auth"
+	"backend/api/catalogue"
 	"backend/api/config"
 	"backend/api/forms"
 	"backend/api/models"
+	"backend/api/pdf"
+	"backend/api/score"
 	"backend/api/utils"
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -22,6 +29,18 @@ Meaning POST request will have 3 attributes:
   - page: (what page)
   - limit: (limit number)
   - composer: (what composer)
+  - min_pages, max_pages: (page count range, e.g. max_pages=3 for short pieces)
+  - orientation: (portrait, landscape or mixed)
+  - encrypted, has_text: (true or false)
+  - catalogue_type, key, language: (exact match, e.g. catalogue_type=BWV, key=D minor, language=de)
+  - instrumentation, arranger, lyricist, publisher, editor: (partial match, case insensitive)
+  - work: (safe name of a work, all its editions)
+  - parent: (safe name of an anthology, all its pieces)
+  - min_difficulty, max_difficulty: (grade 1 to 8)
+  - min_duration, max_duration: (e.g. max_duration=5:00 or 300 seconds)
+
+sort_by also accepts the PDF structure and metadata columns, e.g. "page_count asc", "file_size desc" or "difficulty asc".
+Catalogue numbers are sorted numerically (BWV 846 before BWV 1001) with "catalogue_type asc, catalogue_sort asc"
 
 Return:
   - sheets: [...]
@@ -41,8 +60,46 @@ func (server *Server) GetSheetsPage(c *gin.Context) {
 		Page:  form.Page,
 	}
 
+	filter := models.SheetFilter{
+		Composer:        form.Composer,
+		MinPages:        form.MinPages,
+		MaxPages:        form.MaxPages,
+		Orientation:     form.Orientation,
+		Encrypted:       form.Encrypted,
+		HasText:         form.HasText,
+		Key:             form.Key,
+		Instrumentation: form.Instrumentation,
+		MinDifficulty:   form.MinDifficulty,
+		MaxDifficulty:   form.MaxDifficulty,
+		Arranger:        form.Arranger,
+		Lyricist:        form.Lyricist,
+		Publisher:       form.Publisher,
+		Language:        form.Language,
+		Editor:          form.Editor,
+		Work:            form.Work,
+		Parent:          form.Parent,
+		License:         form.License,
+	}
+	if form.CatalogueType != "" {
+		catalogueType, ok := catalogue.NormalizeSystem(form.CatalogueType)
+		if !ok {
+			utils.DoError(c, http.StatusBadRequest, fmt.Errorf("unknown catalogue type %q", form.CatalogueType))
+			return
+		}
+		filter.CatalogueType = catalogueType
+	}
+	var err error
+	if filter.MinDuration, err = forms.ParseDuration(form.MinDuration); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	if filter.MaxDuration, err = forms.ParseDuration(form.MaxDuration); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	var sheet models.Sheet
-	pageNew, err := sheet.List(server.DB, pagination, form.Composer)
+	pageNew, err := sheet.List(server.DB, pagination, filter)
 	if err != nil {
 		utils.DoError(c, http.StatusInternalServerError, err)
 		return
@@ -75,28 +132,269 @@ func (server *Server) GetSheet(c *gin.Context) {
 }
 
 /*
-Serve the PDF file
+Serve the PDF file, or one of its parts with ?part=<safe_label>
+A sheet uploaded in ABC notation can also be served as SVG with ?format=svg (whole sheet only, admin only when watermarked)
 Example request:
 
 	GET /sheet/pdf/Frédéric Chopin/Étude N. 1
+	GET /sheet/pdf/beethoven/quartet-op-18-1?part=violin-i
+	GET /sheet/pdf/unknown/the-kesh?format=svg
 
 sheetname and composer name have to be the safeName of them
+A sheet under a watermark policy is stamped with the user's email, the date and the footer (see watermark_controller.go)
 */
 func (server *Server) GetPDF(c *gin.Context) {
 	sheetName := c.Param("sheetName") + ".pdf"
 	composer := c.Param("composer")
 	filePath := path.Join(config.Config().ConfigPath, "sheets/uploaded-sheets", composer, sheetName)
+
+	switch format := c.DefaultQuery("format", "pdf"); format {
+	case "pdf":
+	case "svg":
+		// La gravure SVG est celle de la partition entière, pas d'une partie
+		if c.Query("part") != "" {
+			utils.DoError(c, http.StatusBadRequest, errors.New("part is only available as pdf"))
+			return
+		}
+		server.getABCSvg(c, composer, c.Param("sheetName"))
+		return
+	default:
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("unknown format %q, expected pdf or svg", format))
+		return
+	}
+
+	if part := c.Query("part"); part != "" {
+		sheet := &models.Sheet{SafeComposer: composer, SafeSheetName: c.Param("sheetName")}
+		if _, err := sheet.FindPart(server.DB, part); err != nil {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+		filePath = models.PartPath(sheet, part)
+	}
+
+	var sheetModel models.Sheet
+	sheet, err := sheetModel.FindSheetBySafeName(server.DB, c.Param("sheetName"))
+	if err != nil || sheet.SafeComposer != composer {
+		c.File(filePath)
+		return
+	}
+	// Location expirée : téléchargement refusé
+	if !server.checkDownload(c, sheet) {
+		return
+	}
+
+	// Pièce d'un recueil : pas de PDF propre, les pages sont extraites du recueil
+	if _, err := os.Stat(filePath); err != nil && c.Query("part") == "" && sheet.IsExcerpt() {
+		if filePath, err = sheetFile(server.DB, sheet); err != nil {
+			utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to extract pages: %v", err))
+			return
+		}
+	}
+	// Partition sous licence : chaque page porte l'email de l'utilisateur, la date et le pied de page
+	label := "sheet"
+	if part := c.Query("part"); part != "" {
+		label = "part-" + part
+	}
+	if filePath, err = server.watermarkedPDF(c, sheet, filePath, label); err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to watermark the PDF: %v", err))
+		return
+	}
 	c.File(filePath)
 }
 
+// Gravure SVG d'une partition uploadée en notation ABC, refaite à chaque requête depuis le fichier source
+func (server *Server) getABCSvg(c *gin.Context, composer string, sheetName string) {
+	var sheetModel models.Sheet
+	sheet, err := sheetModel.FindSheetBySafeName(server.DB, sheetName)
+	if err != nil || sheet.SafeComposer != composer {
+		utils.DoError(c, http.StatusNotFound, errors.New("sheet not found"))
+		return
+	}
+	if !server.checkDownload(c, sheet) || !server.allowUnstamped(c, sheet) {
+		return
+	}
+	if sheet.SourceFormat != score.FormatABC {
+		utils.DoError(c, http.StatusUnprocessableEntity, errors.New("svg is only available for sheets in ABC notation"))
+		return
+	}
+	data, err := os.ReadFile(models.SourcePath(sheet))
+	if err != nil {
+		utils.DoError(c, http.StatusNotFound, errors.New("missing source file"))
+		return
+	}
+	tune, err := abc.Parse(string(data))
+	if err != nil {
+		utils.DoError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+	c.Data(http.StatusOK, "image/svg+xml", abc.RenderSVG(tune))
+}
+
 /*
 Serve the thumbnail file
 name = safename of sheet
+Query parameters:
+  - size: list (120px), grid (300px) or detail (800px wide), omitted = original thumbnail
+  - format: png (default) or webp
+
+Example request:
+
+	GET /sheet/thumbnail/fuer-elise?size=grid&format=webp
+
+Sized thumbnails are generated on demand and cached in sheets/thumbnails/<size>/.
+A missing thumbnail is rendered from page 1 only for a logged-in user allowed to download the sheet
+(the admin only for a watermarked sheet), otherwise the route answers 404.
 */
 func (server *Server) GetThumbnail(c *gin.Context) {
-	name := c.Param("name") + ".png"
-	filePath := path.Join(config.Config().ConfigPath, "sheets/thumbnails", name)
-	c.File(filePath)
+	name := utils.SanitizeName(c.Param("name"))
+	size, format, ok := previewOptions(c, "")
+	if !ok {
+		return
+	}
+
+	thumbnailDir := path.Join(config.Config().ConfigPath, "sheets/thumbnails")
+	original := path.Join(thumbnailDir, name+".png")
+	if size == "" && format == "png" {
+		c.File(original)
+		return
+	}
+
+	// Thumbnail manquant : rendu de la première page, réservé comme le PDF aux utilisateurs connectés
+	// (location expirée refusée) et, pour une partition filigranée, à l'admin : la route est publique
+	if _, err := os.Stat(original); err != nil {
+		var sheetModel models.Sheet
+		sheet, err := sheetModel.FindSheetBySafeName(server.DB, name)
+		if err == nil {
+			_, err = auth.ExtractTokenID(utils.ExtractToken(c), config.Config().ApiSecret)
+		}
+		if err != nil {
+			utils.DoError(c, http.StatusNotFound, fmt.Errorf("thumbnail %s not found", name))
+			return
+		}
+		if !server.checkDownload(c, sheet) || !server.allowUnstamped(c, sheet) {
+			return
+		}
+		file, err := sheetFile(server.DB, sheet)
+		if err == nil {
+			err = pdf.RenderPage(file, 1, original)
+		}
+		if err != nil {
+			utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to render thumbnail: %v", err))
+			return
+		}
+	}
+
+	if size == "" {
+		size = "detail"
+	}
+	target := path.Join(thumbnailDir, size, name+"."+format)
+	if err := pdf.Resize(original, target, size, format); err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to resize thumbnail: %v", err))
+		return
+	}
+	c.Header("Content-Type", pdf.PreviewFormats[format])
+	c.File(target)
+}
+
+/*
+Serve a rendered image of one page of the sheet, without downloading the whole PDF
+Parameters:
+  - n: page number, starting at 1
+  - size: list, grid or detail (default)
+  - format: png (default) or webp
+
+Example request:
+
+	GET /sheet/fuer-elise/page/2?size=detail&format=webp
+
+Pages are rendered on demand and cached in sheets/pages/<safe_sheet_name>/,
+next to the user's watermarked copy for a watermarked sheet
+*/
+func (server *Server) GetPage(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil || !server.checkDownload(c, sheet) {
+		return
+	}
+
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil || n < 1 {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("invalid page number %q", c.Param("n")))
+		return
+	}
+	size, format, ok := previewOptions(c, "detail")
+	if !ok {
+		return
+	}
+
+	pageDir := path.Join(config.Config().ConfigPath, "sheets/pages", sheet.SafeSheetName)
+	file, err := sheetFile(server.DB, sheet)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to extract pages: %v", err))
+		return
+	}
+	// Partition sous licence : les pages sont rendues depuis la copie filigranée de l'utilisateur, à côté d'elle
+	stamped, err := server.watermarkedPDF(c, sheet, file, "sheet")
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to watermark the PDF: %v", err))
+		return
+	}
+	if stamped != file {
+		file, pageDir = stamped, strings.TrimSuffix(stamped, ".pdf")
+	}
+	rendered := path.Join(pageDir, strconv.Itoa(n)+".png")
+	if err := pdf.RenderPage(file, n, rendered); err != nil {
+		if errors.Is(err, pdf.ErrPageOutOfRange) {
+			utils.DoError(c, http.StatusNotFound, fmt.Errorf("sheet %s has no page %d", sheet.SafeSheetName, n))
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to render page: %v", err))
+		return
+	}
+
+	target := path.Join(pageDir, fmt.Sprintf("%d-%s.%s", n, size, format))
+	if err := pdf.Resize(rendered, target, size, format); err != nil {
+		utils.DoError(c, http.StatusInternalServerError, fmt.Errorf("unable to resize page: %v", err))
+		return
+	}
+	c.Header("Content-Type", pdf.PreviewFormats[format])
+	c.File(target)
+}
+
+// previewOptions lit et valide les paramètres size et format d'un aperçu
+func previewOptions(c *gin.Context, defaultSize string) (string, string, bool) {
+	size := c.DefaultQuery("size", defaultSize)
+	if _, ok := pdf.PreviewSizes[size]; size != "" && !ok {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("unknown size %q, expected list, grid or detail", size))
+		return "", "", false
+	}
+	format := c.DefaultQuery("format", "png")
+	if _, ok := pdf.PreviewFormats[format]; !ok {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("unknown format %q, expected png or webp", format))
+		return "", "", false
+	}
+	return size, format, true
+}
+
+func sheetPdfPath(sheet *models.Sheet) string {
+	return models.PdfPath(sheet)
+}
+
+// sheetFile retourne le PDF à servir : celui de la partition ou, pour une pièce de recueil,
+// ses pages extraites du recueil (mises en cache, régénérées si le recueil a changé)
+func sheetFile(db *gorm.DB, sheet *models.Sheet) (string, error) {
+	if !sheet.IsExcerpt() {
+		return sheetPdfPath(sheet), nil
+	}
+	var parentModel models.Sheet
+	parent, err := parentModel.FindSheetBySafeName(db, sheet.ParentSheet)
+	if err != nil {
+		return "", fmt.Errorf("anthology %s not found", sheet.ParentSheet)
+	}
+	out := models.ExcerptPath(sheet)
+	if err := pdf.ExtractPages(sheetPdfPath(parent), sheet.FirstPage, sheet.LastPage, out); err != nil {
+		return "", err
+	}
+	return out, nil
 }
 
 // Has to be safeName of the sheet
@@ -121,6 +419,10 @@ func (server *Server) DeleteSheet(c *gin.Context) {
 
 	_, err = sheet.DeleteSheet(server.DB, sheetName)
 	if err != nil {
+		if errors.Is(err, models.ErrSheetHasExcerpts) || errors.Is(err, models.ErrCopyOnLoan) {
+			c.String(http.StatusConflict, err.Error())
+			return
+		}
 		c.String(http.StatusBadRequest, err.Error())
 		return
 	}
@@ -272,6 +574,46 @@ func (server *Server) UpdateSheetInformationText(c *gin.Context) {
 	c.JSON(http.StatusOK, newSheet)
 }
 
+/*
+Update the musical metadata of a sheet without uploading the file again
+Fields not given are left unchanged, an empty value clears the field
+Example request:
+
+	PUT /api/sheet/wohltemperierte-klavier-prelude-1/metadata
+		Body (FormValue or JSON):
+		- catalogueType: BWV
+		- catalogueNumber: 846
+		- key: C major
+		- instrumentation: keyboard
+		- difficulty: 5 (grade 1 to 8)
+		- duration: 2:20 (or 140 seconds)
+		- arranger, lyricist, publisher: Henle Urtext
+		- language: de (ISO 639-1)
+		- editor: Ewald Zimmermann
+		- editionYear: 2007
+*/
+func (server *Server) UpdateSheetMetadata(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+
+	var form forms.SheetMetadataRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad metadata request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := sheet.UpdateMetadata(server.DB, form.Metadata()); err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, sheet)
+}
+
 func getSheet(db *gorm.DB, c *gin.Context) *models.Sheet {
 	// Find a sheet by its name
 	sheetName := c.Param("sheetName")
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"backend/api/auth"
+	"backend/api/config"
+	"backend/api/models"
+	"backend/api/pdf"
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPDFSvgRejectsPart(t *testing.T) {
+	server := setupServer(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/sheet/pdf/chopin/etude?format=svg&part=violin-i", nil)
+	c.Params = gin.Params{{Key: "composer", Value: "chopin"}, {Key: "sheetName", Value: "etude"}}
+	server.GetPDF(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+}
+
+func TestGetThumbnailRendersOnlyForDownloaders(t *testing.T) {
+	server := setupServer(t)
+	renderer := pdf.Renderer
+	t.Cleanup(func() { pdf.Renderer = renderer })
+	pdf.Renderer = func(pdfPath string, outPath string) error {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 600, 800))); err != nil {
+			return err
+		}
+		return os.WriteFile(outPath, buf.Bytes(), 0666)
+	}
+	thumbnails := path.Join(config.Config().ConfigPath, "sheets/thumbnails")
+	get := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/sheet/thumbnail/etude?size=grid", nil)
+		if token != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Params = gin.Params{{Key: "name", Value: "etude"}}
+		server.GetThumbnail(c)
+		return w
+	}
+
+	// Route publique : pas de rendu à la demande sans utilisateur connecté
+	w := get("")
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	assert.NoFileExists(t, path.Join(thumbnails, "etude.png"))
+
+	token, err := auth.CreateToken(config.ADMIN_UID, config.Config().ApiSecret)
+	require.NoError(t, err)
+	w = get(token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.FileExists(t, path.Join(thumbnails, "grid", "etude.png"))
+
+	// Le PDF change : les miniatures redimensionnées sont supprimées
+	sheet, err := (&models.Sheet{}).FindSheetBySafeName(server.DB, "etude")
+	require.NoError(t, err)
+	refreshPreviews(server, sheet)
+	assert.NoFileExists(t, path.Join(thumbnails, "grid", "etude.png"))
+}
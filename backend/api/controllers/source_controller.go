@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"backend/api/abc"
+	"backend/api/forms"
+	"backend/api/models"
+	"backend/api/pdf"
+	"backend/api/score"
+	"backend/api/utils"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scoreSource : fichier MusicXML, MuseScore ou ABC lu en mémoire et ses métadonnées
+type scoreSource struct {
+	data []byte
+	meta *score.Metadata
+}
+
+// Lit et analyse un fichier MusicXML, MuseScore ou ABC uploadé
+func readScoreSource(header *multipart.FileHeader) (*scoreSource, error) {
+	format, ok := score.FormatFromName(header.Filename)
+	if !ok {
+		return nil, score.ErrUnsupportedScore
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := score.Parse(data, format)
+	if err != nil {
+		return nil, err
+	}
+	return &scoreSource{data: data, meta: meta}, nil
+}
+
+// engraveABC grave l'air ABC en PDF
+func engraveABC(source *scoreSource) ([]byte, error) {
+	tune, err := abc.Parse(string(source.data))
+	if err != nil {
+		return nil, err
+	}
+	return abc.RenderPDF(tune), nil
+}
+
+// renderABC grave l'air ABC en PDF, qui devient le PDF principal de la partition avec son empreinte, puis demande son thumbnail
+func (server *Server) renderABC(sheet *models.Sheet, source *scoreSource) error {
+	data, err := engraveABC(source)
+	if err != nil {
+		return err
+	}
+	structure, err := pdf.Analyze(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	fileHash, err := utils.HashReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	fullpath := sheetPdfPath(sheet)
+	if err := os.WriteFile(fullpath, data, 0666); err != nil {
+		return err
+	}
+	sheet.SetStructure(structure)
+	sheet.PdfUrl = "sheet/pdf/" + sheet.SafeComposer + "/" + sheet.SafeSheetName
+	sheet.FileHash = fileHash
+	err = server.DB.Model(&models.Sheet{}).Where("safe_sheet_name = ?", sheet.SafeSheetName).Updates(map[string]interface{}{
+		"pdf_url":     sheet.PdfUrl,
+		"file_hash":   sheet.FileHash,
+		"page_count":  sheet.PageCount,
+		"page_width":  sheet.PageWidth,
+		"page_height": sheet.PageHeight,
+		"orientation": sheet.Orientation,
+		"file_size":   sheet.FileSize,
+		"encrypted":   sheet.Encrypted,
+		"has_text":    sheet.HasText,
+	}).Error
+	if err != nil {
+		return err
+	}
+	// Demande le thumbnail au serveur python (première page du PDF gravé).
+	// La partition est enregistrée : un échec n'est qu'un avertissement, le thumbnail sera rendu à la demande.
+	if !utils.RequestToPdfToImage(fullpath, sheet.SafeSheetName) {
+		log.Printf("warning: no thumbnail for %s\n", sheet.SafeSheetName)
+	}
+	return nil
+}
+
+/*
+Attach or replace the MusicXML, MuseScore or ABC source of a sheet
+Key, time signature, tempo and instruments of the sheet are updated from the file
+An ABC tune is rendered as the PDF of the sheet, unless the sheet already has a PDF from another source
+Example request:
+
+	POST /api/sheet/quartet-op-18-1/source
+		Body (multipart/form-data):
+		- uploadFile: quartet.mxl (.musicxml, .xml, .mxl, .mscz or .abc)
+*/
+func (server *Server) UploadSource(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
+
+	var form forms.UploadSourceRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad upload request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	source, err := readScoreSource(form.File)
+	if err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	render := source.meta.Format == score.FormatABC && (!sheet.HasPdf() || sheet.SourceFormat == score.FormatABC)
+	if err := sheet.WriteSource(server.DB, source.meta, bytes.NewReader(source.data)); err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if render {
+		if err := server.renderABC(sheet, source); err != nil {
+			utils.DoError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sheet":  sheet,
+		"source": source.meta,
+	})
+}
+
+/*
+Download the MusicXML, MuseScore or ABC source of a sheet
+The source of a watermarked sheet cannot be stamped: only the admin can download it.
+Example request:
+
+	GET /api/sheet/quartet-op-18-1/source
+*/
+func (server *Server) GetSource(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil || !server.checkDownload(c, sheet) || !server.allowUnstamped(c, sheet) {
+		return
+	}
+
+	sourcePath := models.SourcePath(sheet)
+	if sourcePath == "" {
+		utils.DoError(c, http.StatusNotFound, errors.New("sheet has no source file"))
+		return
+	}
+	if _, err := os.Stat(sourcePath); err != nil {
+		utils.DoError(c, http.StatusNotFound, errors.New("missing source file"))
+		return
+	}
+	c.Header("Content-Type", score.MimeTypes[sheet.SourceFormat])
+	c.FileAttachment(sourcePath, sheet.SafeSheetName+score.Extensions[sheet.SourceFormat])
+}
+
+/*
+Transpose the MusicXML source of a sheet
+Query parameters (interval or to, instrument can be combined with both or used alone):
+  - interval: M2, -m3, P5, A4 ... or a number of semitones (-3)
+  - to: target tonic (Bb, F#), the shortest way from the key of the score
+  - instrument: written part for a transposing instrument (clarinet-bb, clarinet-a, horn-f, alto-sax, tenor-sax ...)
+
+Example requests:
+
+	GET /api/sheet/ave-verum/transpose?interval=-M2
+	GET /api/sheet/ave-verum/transpose?to=Bb
+	GET /api/sheet/ave-verum/transpose?instrument=clarinet-bb
+
+Pitches, chord symbols and accidentals are respelled, key signatures are rewritten.
+For an instrument, the <transpose> element of each part is written or updated.
+The response is a MusicXML document (an .mxl source is returned uncompressed).
+Like the source, a watermarked sheet is only transposed for the admin.
+*/
+func (server *Server) TransposeSheet(c *gin.Context) {
+	sheet := getSheet(server.DB, c)
+	if sheet == nil || !server.checkDownload(c, sheet) || !server.allowUnstamped(c, sheet) {
+		return
+	}
+
+	intervalParam, toParam, instrumentParam := c.Query("interval"), c.Query("to"), c.Query("instrument")
+	if intervalParam != "" && toParam != "" {
+		utils.DoError(c, http.StatusBadRequest, errors.New("use either interval or to"))
+		return
+	}
+	if intervalParam == "" && toParam == "" && instrumentParam == "" {
+		utils.DoError(c, http.StatusBadRequest, errors.New("missing query parameter interval, to or instrument"))
+		return
+	}
+
+	sourcePath := models.SourcePath(sheet)
+	if sourcePath == "" {
+		utils.DoError(c, http.StatusNotFound, errors.New("sheet has no source file"))
+		return
+	}
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		utils.DoError(c, http.StatusNotFound, errors.New("missing source file"))
+		return
+	}
+	doc, err := score.Document(data, sheet.SourceFormat)
+	if err != nil {
+		utils.DoError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var interval score.Interval
+	switch {
+	case intervalParam != "":
+		interval, err = score.ParseInterval(intervalParam)
+	case toParam != "":
+		var key *score.KeySignature
+		if key, err = score.FirstKey(doc); err == nil {
+			interval, err = score.IntervalToKey(key.Fifths, key.Minor, toParam)
+		}
+	}
+	var written score.Interval
+	if err == nil && instrumentParam != "" {
+		if written, err = score.InstrumentInterval(instrumentParam); err == nil {
+			interval = interval.Add(written)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, score.ErrNoKeySignature) || errors.Is(err, score.ErrInvalidScore) {
+			utils.DoError(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	transposed, err := score.Transpose(doc, interval)
+	// Partie d'instrument transpositeur : l'intervalle de la note écrite au son réel est inscrit dans le document
+	if err == nil && instrumentParam != "" {
+		transposed, err = score.SetTransposition(transposed, written)
+	}
+	if err != nil {
+		utils.DoError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", sheet.SafeSheetName+"-transposed"+score.Extensions[score.FormatMusicXML]))
+	c.Data(http.StatusOK, score.MimeTypes[score.FormatMusicXML], transposed)
+}
@@ -2,20 +2,28 @@
 	This file is for handeling the basic upload of sheets.
 	It will upload given file in the uploaded sheets folder either under
 	the unknown subfolder or under the author's name subfolder, depending on whether an author is given or not.
+	Parts (instrument or voice) are not sent here: they are added to the uploaded sheet with
+	POST /api/sheet/:sheetName/parts (parts_controller.go), with the same size limit and PDF validation.
 */
 
 package controllers
 
 import (
 	"backend/api/auth"
+	"backend/api/catalogue"
 	"backend/api/config"
 	"backend/api/forms"
 	"backend/api/models"
+	"backend/api/pdf"
+	"backend/api/provider"
+	"backend/api/score"
 	"backend/api/utils"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -27,22 +35,6 @@ import (
 	"github.com/mozillazg/go-unidecode"
 )
 
-// Structs for handling the response on the Open Opus API
-
-type Response struct {
-	Composers *[]Comp `json: "composers"`
-}
-
-type Comp struct {
-	Name         string `json:"name"`
-	CompleteName string `json:"complete_name"`
-	SafeName     string `json:"safe_name"`
-	Birth        string `json:"birth"`
-	Death        string `json:"death"`
-	Epoch        string `json:"epoch"`
-	Portrait     string `json:"portrait"`
-}
-
 func (server *Server) UploadFile(c *gin.Context) {
 	// Check for authentication
 	token := utils.ExtractToken(c)
@@ -57,10 +49,91 @@ func (server *Server) UploadFile(c *gin.Context) {
 		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad upload request: %v", err))
 		return
 	}
+	// Les champs laissés vides sont complétés avec le fichier MusicXML, MuseScore ou ABC, puis avec les métadonnées du PDF
+	var source *scoreSource
+	if header := uploadForm.Source(); header != nil {
+		if source, err = readScoreSource(header); err != nil {
+			utils.DoError(c, http.StatusBadRequest, fmt.Errorf("invalid source file: %v", err))
+			return
+		}
+		uploadForm.FillEmpty(forms.UploadSuggestion{SheetName: source.meta.Title, Composer: source.meta.Composer})
+	}
+	if uploadForm.File != nil && strings.HasSuffix(strings.ToLower(uploadForm.File.Filename), ".pdf") {
+		if meta, err := inspectUploadedPDF(uploadForm.File); err == nil {
+			uploadForm.FillEmpty(suggestUpload(meta, uploadForm.File.Filename))
+		}
+	}
+	// Nouvelle édition d'une oeuvre : titre et compositeur de l'oeuvre par défaut
+	var work *models.Work
+	if uploadForm.Work != "" {
+		if work, err = models.FindWork(server.DB, uploadForm.Work); err != nil {
+			if errors.Is(err, models.ErrWorkNotFound) {
+				utils.DoError(c, http.StatusNotFound, err)
+				return
+			}
+			utils.DoError(c, http.StatusInternalServerError, err)
+			return
+		}
+		uploadForm.FillEmpty(forms.UploadSuggestion{SheetName: work.Title, Composer: work.Composer})
+	}
 	if err = uploadForm.ValidateForm(); err != nil {
 		utils.DoError(c, http.StatusBadRequest, err)
 		return
 	}
+	// Compositeur de l'oeuvre vérifié avant que safeComposer ne crée un compositeur inconnu
+	if work != nil {
+		if err = editionComposer(server, work, uploadForm.Composer); err != nil {
+			utils.DoError(c, http.StatusConflict, err)
+			return
+		}
+	}
+	// La tonalité saisie est prioritaire sur celle du fichier source
+	metadata := uploadForm.Metadata()
+	catalogueFromTitle(&metadata, uploadForm.SheetName)
+	if source != nil && metadata.Key != nil && strings.TrimSpace(*metadata.Key) != "" {
+		source.meta.Key = ""
+	}
+
+	// Le PDF uploadé, les photos assemblées en PDF ou la gravure d'un air ABC uploadé seul.
+	// Un fichier MusicXML ou MuseScore seul n'a pas de PDF, donc pas d'empreinte.
+	// Les doublons sont refusés avant toute création (compositeur, répertoires).
+	var theFile multipart.File
+	if !uploadForm.SourceOnly() {
+		if theFile, err = openUpload(uploadForm.File, uploadForm.Images()); err != nil {
+			if errors.Is(err, pdf.ErrUnsupportedImage) {
+				utils.DoError(c, http.StatusBadRequest, err)
+				return
+			}
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer theFile.Close()
+	} else if source.meta.Format == score.FormatABC {
+		data, err := engraveABC(source)
+		if err != nil {
+			utils.DoError(c, http.StatusBadRequest, fmt.Errorf("invalid source file: %v", err))
+			return
+		}
+		theFile = memoryFile{bytes.NewReader(data)}
+	}
+
+	// Détection des doublons par contenu (SHA-256 du PDF)
+	var fileHash string
+	var duplicate *models.Sheet
+	if theFile != nil {
+		if fileHash, err = utils.HashReader(theFile); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		duplicate, _ = models.FindSheetByHash(server.DB, fileHash)
+	}
+	if duplicate != nil && config.Config().Upload.StrictDedup {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":        "file already uploaded as " + duplicate.SafeSheetName,
+			"duplicate_of": duplicate,
+		})
+		return
+	}
 
 	prePath := path.Join(config.Config().ConfigPath, "sheets")
 	uploadPath := path.Join(config.Config().ConfigPath, "sheets/uploaded-sheets")
@@ -76,31 +149,78 @@ func (server *Server) UploadFile(c *gin.Context) {
 	// Handle case where no composer is given
 	uploadPath = checkComposer(uploadPath, comp)
 
-	// Check if the file already exists
+	// Check if the file already exists, another edition of the same title gets its own name
 	sheetName := uploadForm.SheetName
 	releaseDate := uploadForm.ReleaseDate
 
-	fullpath, err := checkFile(uploadPath, sheetName)
+	safeSheetName, fullpath, err := editionSheetName(server, uploadPath, sheetName, work != nil || isEdition(metadata), metadata)
 	if fullpath == "" || err != nil {
+		utils.DoError(c, http.StatusConflict, err)
 		return
 	}
 
-	// Create file
-	theFile, err := uploadForm.File.Open()
-	if err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+	// Partition uploadée uniquement en MusicXML ou MuseScore : pas de PDF ni de thumbnail.
+	// Un air ABC est gravé en PDF, son empreinte est enregistrée par renderABC.
+	if uploadForm.SourceOnly() {
+		sheet, err := createFile(uid, server, fullpath, nil, "", nil, comp, sheetName, safeSheetName, releaseDate,
+			uploadForm.InformationText, uploadForm.Categories, uploadForm.Tags, metadata)
+		if err == nil {
+			err = linkUploadedEdition(server, work, sheet)
+		}
+		if err == nil {
+			err = sheet.WriteSource(server.DB, source.meta, bytes.NewReader(source.data))
+		}
+		if err == nil && source.meta.Format == score.FormatABC {
+			err = server.renderABC(sheet, source)
+		}
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		uploadAccepted(c, duplicate)
 		return
 	}
-	defer theFile.Close()
-	err = createFile(uid, server, fullpath, theFile, comp, sheetName, releaseDate,
-		uploadForm.InformationText, uploadForm.Categories, uploadForm.Tags)
+
+	// Structure du PDF : pages, dimensions, taille, chiffrement, couche texte
+	structure, err := pdf.Analyze(theFile)
+	if err != nil {
+		log.Printf("analyze %s: %v\n", uploadForm.File.Filename, err)
+		structure = nil
+	}
+
+	sheet, err := createFile(uid, server, fullpath, theFile, fileHash, structure, comp, sheetName, safeSheetName, releaseDate,
+		uploadForm.InformationText, uploadForm.Categories, uploadForm.Tags, metadata)
+	if err == nil {
+		err = linkUploadedEdition(server, work, sheet)
+	}
 	if err != nil {
 		c.String(http.StatusInternalServerError, err.Error())
 		return
 	}
+	if source != nil {
+		if err := sheet.WriteSource(server.DB, source.meta, bytes.NewReader(source.data)); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	// Send POST request to python server for creating the thumbnail (first page of pdf as an image).
+	// The sheet is saved: a failure is only a warning, the thumbnail is rendered on demand.
+	if !utils.RequestToPdfToImage(fullpath, sheet.SafeSheetName) {
+		log.Printf("warning: no thumbnail for %s\n", sheet.SafeSheetName)
+	}
+
+	uploadAccepted(c, duplicate)
+}
 
-	// Send POST request to python server for creating the thumbnail (first page of pdf as an image)
-	if !utils.RequestToPdfToImage(fullpath, utils.SafeFileName(sheetName)) {
+// uploadAccepted répond à un upload réussi, en prévenant le client si le même PDF existe déjà
+func uploadAccepted(c *gin.Context, duplicate *models.Sheet) {
+	if duplicate != nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":      "File uploaded successfully",
+			"warning":      "the same file was already uploaded as " + duplicate.SafeSheetName,
+			"duplicate_of": duplicate,
+		})
 		return
 	}
 
@@ -108,6 +228,24 @@ func (server *Server) UploadFile(c *gin.Context) {
 	c.JSON(http.StatusAccepted, "File uploaded successfully")
 }
 
+/*
+Update a sheet: title, composer, release date, categories, tags and information text, and optionally its PDF.
+Fields left out are unchanged. A new title or composer renames the sheet and moves its files (PDF, parts, media,
+source, revisions, thumbnails); parts, media, licence, copies, loans and work follow the sheet.
+A new PDF replaces the current one in place, or gives a PDF to a sheet uploaded as MusicXML or MuseScore:
+the current PDF is kept as a revision.
+Example request:
+
+	PUT /api/sheet/fuer-elise
+		Body (multipart/form-data):
+		- sheetName: Für Elise
+		- composer: Beethoven
+		- releaseDate: 1810-04-27
+		- categories: Classical;Piano
+		- tags: bagatelle
+		- informationText: WoO 59
+		- uploadFile: PDF, or JPEG/PNG/WebP photos (repeated, one page each)
+*/
 func (server *Server) UpdateSheet(c *gin.Context) {
 	// Check for authentication
 	token := utils.ExtractToken(c)
@@ -117,83 +255,269 @@ func (server *Server) UpdateSheet(c *gin.Context) {
 		return
 	}
 
-	sheetName := c.Param("sheetName")
+	sheet := getSheet(server.DB, c)
+	if sheet == nil {
+		return
+	}
 
-	// Delete Sheet
-	var sheet models.Sheet
-	_, err = sheet.DeleteSheet(server.DB, sheetName)
-	if err != nil {
-		c.String(http.StatusBadRequest, err.Error())
+	var form forms.UpdateSheetRequest
+	if err = c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad update request: %v", err))
+		return
+	}
+	if err = form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	if form.File != nil && sheet.IsExcerpt() {
+		utils.DoError(c, http.StatusUnprocessableEntity, fmt.Errorf("%s is an excerpt of %s, replace the PDF of the anthology", sheet.SafeSheetName, sheet.ParentSheet))
 		return
 	}
 
-	server.UploadFile(c)
-}
+	// Le nouveau PDF est vérifié avant toute modification
+	var theFile multipart.File
+	var duplicate *models.Sheet
+	if form.File != nil {
+		if theFile, err = openUpload(form.File, form.Images()); err != nil {
+			if errors.Is(err, pdf.ErrUnsupportedImage) {
+				utils.DoError(c, http.StatusBadRequest, err)
+				return
+			}
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer theFile.Close()
+		if duplicate, err = checkReplacement(server, c, sheet, theFile); err != nil {
+			return
+		}
+	}
 
-func getPortraitURL(composerName string) Comp {
-	resp, err := http.Get("https://api.openopus.org/composer/list/search/" + composerName + ".json")
+	// Titre, compositeur et informations
+	edit, err := sheetEdit(server, sheet, &form)
 	if err != nil {
-		fmt.Println(err)
-
-		return Comp{
-			CompleteName: composerName,
-			SafeName:     utils.SanitizeName(unidecode.Unidecode(composerName)),
-			Portrait:     "https://icon-library.com/images/unknown-person-icon/unknown-person-icon-4.jpg",
-			Epoch:        "Unknown",
+		if errors.Is(err, models.ErrSheetExists) || errors.Is(err, models.ErrEditionComposer) {
+			utils.DoError(c, http.StatusConflict, err)
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if edit != nil {
+		if _, err = sheet.EditSheet(server.DB, *edit); err != nil {
+			if errors.Is(err, models.ErrSheetExists) {
+				utils.DoError(c, http.StatusConflict, err)
+				return
+			}
+			utils.DoError(c, http.StatusInternalServerError, err)
+			return
 		}
 	}
+	if theFile == nil {
+		c.JSON(http.StatusOK, sheet)
+		return
+	}
 
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	// Le nouveau PDF est écrit à côté de l'actuel, puis le remplace : l'actuel devient une version antérieure
+	fullpath := models.PdfPath(sheet)
+	utils.CreateDir(path.Dir(fullpath))
+	tmp, err := tempPdf(fullpath, ".replace-*.pdf")
+	if err == nil {
+		if _, err = theFile.Seek(0, io.SeekStart); err == nil {
+			err = utils.OsCreateFile(tmp, theFile)
+		}
+	}
+	if err == nil {
+		if sheet.HasPdf() {
+			err = sheet.ReplacePdf(server.DB, tmp, "replace")
+		} else {
+			err = sheet.AttachPdf(server.DB, tmp)
+		}
+	}
 	if err != nil {
-		fmt.Println(err)
+		if tmp != "" {
+			os.Remove(tmp)
+		}
+		c.String(http.StatusInternalServerError, err.Error())
+		return
 	}
-	response := &Response{
-		Composers: &[]Comp{},
+	refreshPreviews(server, sheet)
+
+	if duplicate != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"sheet":        sheet,
+			"warning":      "the same file was already uploaded as " + duplicate.SafeSheetName,
+			"duplicate_of": duplicate,
+		})
+		return
 	}
+	c.JSON(http.StatusOK, sheet)
+}
 
-	err_new := json.Unmarshal([]byte(string(body)), response)
-	fmt.Println(err_new)
-	composers := *response.Composers
+// checkReplacement vérifie le nouveau PDF d'une partition : doublon (la partition elle-même exceptée) et,
+// si le nombre de pages change, absence de pièces. La réponse d'erreur est déjà écrite si err != nil.
+func checkReplacement(server *Server, c *gin.Context, sheet *models.Sheet, theFile multipart.File) (*models.Sheet, error) {
+	fileHash, err := utils.HashReader(theFile)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return nil, err
+	}
+	duplicate, _ := models.FindSheetByHash(server.DB, fileHash)
+	if duplicate != nil && duplicate.SafeSheetName == sheet.SafeSheetName {
+		duplicate = nil
+	}
+	if duplicate != nil && config.Config().Upload.StrictDedup {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":        "file already uploaded as " + duplicate.SafeSheetName,
+			"duplicate_of": duplicate,
+		})
+		return nil, errors.New("duplicate")
+	}
 
-	// Check if the given name and the name from the API are alike
-	if len(composers) == 0 || (!strings.EqualFold(composerName, composers[0].Name) && !strings.EqualFold(composerName, composers[0].CompleteName)) {
-		return Comp{
-			CompleteName: composerName,
-			SafeName:     utils.SanitizeName(unidecode.Unidecode(composerName)),
-			Portrait:     "https://icon-library.com/images/unknown-person-icon/unknown-person-icon-4.jpg",
-			Epoch:        "Unknown",
+	structure, err := pdf.Analyze(theFile)
+	if err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("invalid PDF: %v", err))
+		return nil, err
+	}
+	// Les pièces d'un recueil sont des plages de pages : elles doivent rester valides
+	if structure.PageCount != sheet.PageCount {
+		excerpts, err := sheet.Excerpts(server.DB)
+		if err != nil {
+			utils.DoError(c, http.StatusInternalServerError, err)
+			return nil, err
+		}
+		if len(excerpts) > 0 {
+			utils.DoError(c, http.StatusConflict, models.ErrSheetHasExcerpts)
+			return nil, models.ErrSheetHasExcerpts
 		}
 	}
+	return duplicate, nil
+}
 
-	return composers[0]
+// sheetEdit traduit le formulaire de PUT /sheet/:sheetName en modification, nil si seul le PDF change.
+// Le nom est vérifié, et le compositeur d'une édition comparé à celui de l'oeuvre, avant que safeComposer
+// n'enregistre un compositeur inconnu.
+func sheetEdit(server *Server, sheet *models.Sheet, form *forms.UpdateSheetRequest) (*models.SheetEdit, error) {
+	edit := models.SheetEdit{InformationText: form.InformationText}
+	changed := form.InformationText != nil
+
+	if title := strings.TrimSpace(form.SheetName); title != "" {
+		edit.SheetName = title
+		changed = true
+		if safeName := models.SafeSheetName(title); safeName != sheet.SafeSheetName {
+			var count int64
+			if err := server.DB.Model(&models.Sheet{}).Where("safe_sheet_name = ?", safeName).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				return nil, fmt.Errorf("%w: %s", models.ErrSheetExists, safeName)
+			}
+		}
+	}
+	if composer := strings.TrimSpace(form.Composer); composer != "" {
+		if sheet.WorkSafeName != "" {
+			work, err := models.FindWork(server.DB, sheet.WorkSafeName)
+			if err != nil {
+				return nil, err
+			}
+			if err = editionComposer(server, work, composer); err != nil {
+				return nil, err
+			}
+		}
+		comp := safeComposer(server, composer)
+		edit.Composer = &comp
+		changed = true
+	}
+	if form.ReleaseDate != nil {
+		releaseDate := createDate(*form.ReleaseDate)
+		edit.ReleaseDate = &releaseDate
+		changed = true
+	}
+	if form.Categories != nil {
+		categories := parseSemicolonList(*form.Categories)
+		edit.Categories = &categories
+		changed = true
+	}
+	if form.Tags != nil {
+		tags := parseSemicolonList(*form.Tags)
+		edit.Tags = &tags
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+	return &edit, nil
 }
 
-func safeComposer(server *Server, composer string) Comp {
-	compo := getPortraitURL(composer)
+// Retourne le composer correspondant au nom saisi, en le créant si besoin.
+// Un composer déjà connu en base, par son nom ou l'un de ses alias, est réutilisé sans interroger le fournisseur de métadonnées.
+// Sinon le fournisseur (OpenOpus, jeu de données hors ligne ...) complète nom, dates, époque, nationalité et portrait.
+// Le nom saisi, s'il diffère du nom retenu, est enregistré comme alias pour les uploads suivants.
+func safeComposer(server *Server, composer string) models.Composer {
+	composer = strings.TrimSpace(composer)
 
-	if compo.SafeName == "" {
-		// Used for chinese/japanese chars etc
-		unideCodeName := unidecode.Unidecode(compo.CompleteName)
-		compo.SafeName = utils.SanitizeName(unideCodeName)
+	if existing, err := models.ResolveComposer(server.DB, composer); err == nil {
+		return *existing
 	}
 
 	comp := models.Composer{
-		Name:        compo.CompleteName,
-		SafeName:    compo.SafeName,
-		PortraitURL: compo.Portrait,
-		Epoch:       compo.Epoch,
+		Name:     composer,
+		SafeName: utils.SanitizeName(composer),
+		Epoch:    "Unknown",
+	}
+	var aliases []string
+
+	info, err := server.Composers.Lookup(composer)
+	if err == nil {
+		if info.CompleteName != "" {
+			comp.Name = info.CompleteName
+		}
+		comp.SafeName = utils.SanitizeName(comp.Name)
+		comp.Birth = info.Birth
+		comp.Death = info.Death
+		comp.Nationality = info.Nationality
+		comp.PortraitURL = info.Portrait
+		if info.Epoch != "" {
+			comp.Epoch = info.Epoch
+		}
+		aliases = append([]string{composer, info.Name}, info.Aliases...)
+
+		// Le nom complet est peut-être déjà connu ("Chopin" → "Frédéric Chopin")
+		if existing, err := models.ResolveComposer(server.DB, comp.Name); err == nil {
+			addComposerAliases(server, existing.SafeName, []string{composer})
+			return *existing
+		}
+	} else if !errors.Is(err, provider.ErrNotFound) {
+		log.Printf("composer lookup %q: %v\n", composer, err)
 	}
 
+	// Le portrait distant est téléchargé une seule fois puis servi localement,
+	// la silhouette par défaut est servie si le téléchargement échoue
+	if utils.IsRemoteURL(comp.PortraitURL) {
+		dir := path.Join(config.Config().ConfigPath, "composer")
+		if err := utils.DownloadPortrait(comp.PortraitURL, dir, comp.SafeName); err != nil {
+			log.Printf("composer portrait %q: %v\n", comp.Name, err)
+		}
+	}
+	comp.PortraitURL = "/api/composer/portrait/" + comp.SafeName
+
 	comp.Prepare()
 	comp.SaveComposer(server.DB)
-	return compo
+	addComposerAliases(server, comp.SafeName, aliases)
+	return comp
+}
+
+// Les alias déjà utilisés par un autre composer sont ignorés
+func addComposerAliases(server *Server, composerSafeName string, aliases []string) {
+	for _, alias := range aliases {
+		if err := models.AddAliases(server.DB, composerSafeName, []string{alias}); err != nil {
+			log.Printf("composer alias %q: %v\n", alias, err)
+		}
+	}
 }
 
-func checkComposer(path string, comp Comp) string {
+func checkComposer(path string, comp models.Composer) string {
 	// Handle case where no composer is given
 	composer := comp.SafeName
-	fmt.Println(composer)
 	if composer != "" {
 		path += "/" + composer
 	} else {
@@ -208,15 +532,18 @@ func createFile(
 	server *Server,
 	fullpath string,
 	file multipart.File,
-	comp Comp,
+	fileHash string,
+	structure *pdf.Structure,
+	comp models.Composer,
 	sheetName string,
+	safeSheetName string,
 	releaseDate string,
 	informationText string,
 	categories string,
 	tags string,
-) error {
-	safeComposer := utils.SanitizeName(unidecode.Unidecode(strings.TrimSpace(comp.CompleteName)))
-	safeSheetName := utils.SanitizeName(unidecode.Unidecode(strings.TrimSpace(sheetName)))
+	metadata models.SheetMetadata,
+) (*models.Sheet, error) {
+	safeComposer := comp.SafeName
 
 	// parser tags et categories en slice
 	tagSlice := parseSemicolonList(tags)
@@ -230,22 +557,82 @@ func createFile(
 		SafeSheetName:   safeSheetName,
 		SheetName:       strings.TrimSpace(sheetName),
 		SafeComposer:    safeComposer,
-		Composer:        strings.TrimSpace(comp.CompleteName),
+		Composer:        comp.Name,
 		UploaderID:      uid,
 		ReleaseDate:     createDate(releaseDate),
 		InformationText: informationText,
 		Tags:            string(tagJSON),
 		Categories:      string(categoryJSON),
-		PdfUrl:          "sheet/pdf/" + safeComposer + "/" + safeSheetName,
+		FileHash:        fileHash,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
+	if structure != nil {
+		sheet.SetStructure(structure)
+	}
+	sheet.SetMetadata(metadata)
+	// Sans PDF (partition uploadée en MusicXML, MuseScore ou ABC), PdfUrl reste vide
+	if file != nil {
+		sheet.PdfUrl = "sheet/pdf/" + safeComposer + "/" + safeSheetName
+	}
 
 	if err := server.DB.Create(&sheet).Error; err != nil {
-		return err
+		return nil, err
+	}
+	if file == nil {
+		return &sheet, nil
 	}
+	return &sheet, utils.OsCreateFile(fullpath, file)
+}
 
-	return utils.OsCreateFile(fullpath, file)
+// imagesToPDF assemble les photos uploadées en un PDF, une page par image dans l'ordre d'envoi
+func imagesToPDF(headers []*multipart.FileHeader) (multipart.File, error) {
+	images := make([][]byte, 0, len(headers))
+	for _, header := range headers {
+		f, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, data)
+	}
+	var out bytes.Buffer
+	if err := pdf.ImagesToPDF(images, &out); err != nil {
+		return nil, err
+	}
+	return memoryFile{bytes.NewReader(out.Bytes())}, nil
+}
+
+// openUpload ouvre le PDF uploadé, ou assemble les photos en PDF
+func openUpload(file *multipart.FileHeader, images []*multipart.FileHeader) (multipart.File, error) {
+	if images != nil {
+		return imagesToPDF(images)
+	}
+	return file.Open()
+}
+
+// memoryFile : PDF construit en mémoire, utilisable comme un fichier uploadé
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
+
+// catalogueFromTitle déduit le numéro de catalogue du titre ("Prelude in C major, BWV 846") s'il n'est pas saisi
+func catalogueFromTitle(metadata *models.SheetMetadata, title string) {
+	if metadata.CatalogueNumber != nil && *metadata.CatalogueNumber != "" {
+		return
+	}
+	number, ok := catalogue.Parse(title)
+	if ok && (metadata.CatalogueType == nil || *metadata.CatalogueType == "" || *metadata.CatalogueType == number.System) {
+		metadata.CatalogueType, metadata.CatalogueNumber = &number.System, &number.Number
+	}
 }
 
 // Parser proprement catégories et tags
@@ -275,11 +662,83 @@ func createDate(date string) time.Time {
 	return t
 }
 
-func checkFile(pathName string, sheetName string) (string, error) {
+func checkFile(pathName string, safeSheetName string) (string, error) {
 	// Check if the file already exists
-	fullpath := fmt.Sprintf("%s/%s.pdf", pathName, utils.SanitizeName(unidecode.Unidecode(sheetName)))
+	fullpath := fmt.Sprintf("%s/%s.pdf", pathName, safeSheetName)
 	if _, err := os.Stat(fullpath); err == nil {
 		return "", errors.New("file already exists")
 	}
 	return fullpath, nil
 }
+
+// Nombre maximal d'éditions numérotées d'un même titre ("prelude-2" ... "prelude-20")
+const maxNumberedEditions = 20
+
+// isEdition indique si l'upload décrit une édition : éditeur, responsable de l'édition ou année renseignés
+func isEdition(metadata models.SheetMetadata) bool {
+	return (metadata.Publisher != nil && strings.TrimSpace(*metadata.Publisher) != "") ||
+		(metadata.Editor != nil && strings.TrimSpace(*metadata.Editor) != "") ||
+		(metadata.EditionYear != nil && *metadata.EditionYear != 0)
+}
+
+// editionSheetName retourne le nom (safe) et le chemin du PDF d'une nouvelle partition.
+// Si le titre est déjà pris par une autre édition, le nom est complété par l'éditeur, le responsable
+// de l'édition et l'année ("prelude-bwv-846-peters"), puis numéroté ("prelude-bwv-846-2").
+// Hors édition, un titre déjà pris reste une erreur.
+func editionSheetName(server *Server, uploadPath string, sheetName string, edition bool, metadata models.SheetMetadata) (string, string, error) {
+	base := utils.SanitizeName(unidecode.Unidecode(strings.TrimSpace(sheetName)))
+	candidates := []string{base}
+	if edition {
+		name := base
+		for _, part := range []*string{metadata.Publisher, metadata.Editor} {
+			if part != nil && utils.SanitizeName(*part) != "" {
+				name += "-" + utils.SanitizeName(*part)
+				candidates = append(candidates, name)
+			}
+		}
+		if metadata.EditionYear != nil && *metadata.EditionYear != 0 {
+			candidates = append(candidates, fmt.Sprintf("%s-%d", name, *metadata.EditionYear))
+		}
+		for i := 2; i <= maxNumberedEditions; i++ {
+			candidates = append(candidates, fmt.Sprintf("%s-%d", base, i))
+		}
+	}
+
+	for _, candidate := range candidates {
+		fullpath, err := checkFile(uploadPath, candidate)
+		if err != nil {
+			continue
+		}
+		// Le nom est unique dans toute la bibliothèque, pas seulement pour le compositeur
+		var count int64
+		if err := server.DB.Model(&models.Sheet{}).Where("safe_sheet_name = ?", candidate).Count(&count).Error; err != nil {
+			return "", "", err
+		}
+		if count == 0 {
+			return candidate, fullpath, nil
+		}
+	}
+	return "", "", errors.New("file already exists")
+}
+
+// editionComposer vérifie que le compositeur saisi pour une nouvelle édition est celui de l'oeuvre.
+// Il doit être déjà connu de la bibliothèque, par son nom ou l'un de ses alias.
+func editionComposer(server *Server, work *models.Work, composer string) error {
+	existing, err := models.ResolveComposer(server.DB, strings.TrimSpace(composer))
+	if err != nil {
+		return fmt.Errorf("%w: %s", models.ErrEditionComposer, utils.SanitizeName(composer))
+	}
+	if existing.SafeName != work.SafeComposer {
+		return fmt.Errorf("%w: %s", models.ErrEditionComposer, existing.SafeName)
+	}
+	return nil
+}
+
+// linkUploadedEdition rattache la partition uploadée à l'oeuvre donnée dans le formulaire
+func linkUploadedEdition(server *Server, work *models.Work, sheet *models.Sheet) error {
+	if work == nil {
+		return nil
+	}
+	_, err := models.LinkEdition(server.DB, work.SafeName, sheet.SafeSheetName)
+	return err
+}
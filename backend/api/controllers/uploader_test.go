@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"backend/api/auth"
+	"backend/api/config"
+	"backend/api/media"
+	"backend/api/models"
+	"backend/api/score"
+	"backend/api/utils"
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Tests d'intégration sur SQLite : base et dossiers de la bibliothèque dans un répertoire temporaire.
+// La configuration étant un singleton, CONFIG_PATH est fixé une seule fois pour tout le package.
+func TestMain(m *testing.M) {
+	root, err := os.MkdirTemp("", "sheetflow-controllers")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("CONFIG_PATH", root)
+	os.Setenv("UPLOAD_MEDIA_MAX_MB", "1") // médias limités à 1 Mo pour tester le refus
+	config.Config()
+	gin.SetMode(gin.TestMode)
+
+	code := m.Run()
+	os.RemoveAll(root)
+	os.Exit(code)
+}
+
+// setupServer repart d'une bibliothèque vide avec Chopin et sa partition etude (PDF de 2 pages)
+func setupServer(t *testing.T) *Server {
+	require.NoError(t, os.RemoveAll(path.Join(config.Config().ConfigPath, "sheets")))
+	db, err := gorm.Open(sqlite.Open(path.Join(t.TempDir(), "database.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Sheet{}, &models.Composer{}, &models.ComposerAlias{}, &models.SheetPart{}, &models.SheetMedia{},
+		&models.Work{}, &models.SheetRevision{}, &models.WatermarkCategory{}, &models.SheetDistribution{}, &models.SheetCopy{}, &models.SheetLoan{}))
+
+	require.NoError(t, db.Create(&models.Composer{SafeName: "chopin", Name: "Chopin", Epoch: "Romantic"}).Error)
+	sheet := models.Sheet{SafeSheetName: "etude", SheetName: "Etude", SafeComposer: "chopin", Composer: "Chopin", PdfUrl: "sheet/pdf/chopin/etude", PageCount: 2}
+	require.NoError(t, db.Create(&sheet).Error)
+	require.NoError(t, os.MkdirAll(path.Dir(models.PdfPath(&sheet)), os.ModePerm))
+	require.NoError(t, os.WriteFile(models.PdfPath(&sheet), testPDF(t, 2), 0666))
+	return &Server{DB: db}
+}
+
+// testPDF retourne un PDF valide de pages pages
+func testPDF(t *testing.T, pages int) []byte {
+	var imgs []io.Reader
+	for i := 0; i < pages; i++ {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 60, 80))))
+		imgs = append(imgs, &buf)
+	}
+	var out bytes.Buffer
+	require.NoError(t, api.ImportImages(nil, &out, imgs, nil, nil))
+	return out.Bytes()
+}
+
+// uploadRequest prépare une requête multipart authentifiée avec le fichier uploadFile (aucun si data est nil) et les champs fields
+func uploadRequest(t *testing.T, method string, url string, filename string, data []byte, fields ...string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i := 0; i+1 < len(fields); i += 2 {
+		require.NoError(t, writer.WriteField(fields[i], fields[i+1]))
+	}
+	if data != nil {
+		part, err := writer.CreateFormFile("uploadFile", filename)
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(method, url, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	token, err := auth.CreateToken(config.ADMIN_UID, config.Config().ApiSecret)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestUpdateSheetKeepsAttachments(t *testing.T) {
+	server := setupServer(t)
+	db := server.DB
+	sheet, err := (&models.Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+
+	// Partie, enregistrement, fichier source, oeuvre, licence, exemplaire et une première révision
+	require.NoError(t, sheet.AddPart(db, &models.SheetPart{Label: "Violin I"}, strings.NewReader("%PDF-1.4")))
+	mp3 := &media.Info{Format: media.FormatMP3, MimeType: "audio/mpeg", Duration: 182.5, Size: 4}
+	require.NoError(t, sheet.AddMedia(db, &models.SheetMedia{Label: "Pollini 1972"}, mp3, strings.NewReader("fake")))
+	require.NoError(t, sheet.WriteSource(db, &score.Metadata{Format: score.FormatMusicXML, Key: "C minor"}, strings.NewReader("<score-partwise/>")))
+	require.NoError(t, models.CreateWork(db, &models.Work{Title: "Etude", SafeComposer: "chopin"}))
+	var work models.Work
+	require.NoError(t, db.Take(&work).Error)
+	_, err = models.LinkEdition(db, work.SafeName, "etude")
+	require.NoError(t, err)
+	_, err = models.SetLicense(db, "etude", models.SheetLicense{License: models.LicensePurchased, Holder: "Henle", Seats: 3})
+	require.NoError(t, err)
+	require.NoError(t, models.RecordDistribution(db, "etude", 2))
+	require.NoError(t, sheet.AddCopy(db, &models.SheetCopy{Barcode: "SF-0001"}))
+
+	sheet, err = (&models.Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+	edited := path.Join(path.Dir(models.PdfPath(sheet)), ".edit.pdf")
+	require.NoError(t, os.WriteFile(edited, testPDF(t, 2), 0666))
+	require.NoError(t, sheet.ReplacePdf(db, edited, "rotate"))
+
+	// Nouveau PDF de 3 pages
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = uploadRequest(t, http.MethodPut, "/api/sheet/etude", "etude.pdf", testPDF(t, 3))
+	c.Params = gin.Params{{Key: "sheetName", Value: "etude"}}
+	server.UpdateSheet(c)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	sheet, err = (&models.Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+	assert.Equal(t, 3, sheet.PageCount)
+	assert.Equal(t, 2, sheet.Revision)
+	revisions, err := sheet.Revisions(db)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2, "the replaced PDF is kept as a revision")
+	assert.Equal(t, "replace", revisions[0].Operation)
+	assert.FileExists(t, models.RevisionPath(sheet, 1))
+	assert.FileExists(t, models.RevisionPath(sheet, 2))
+
+	require.Len(t, sheet.Parts, 1)
+	assert.FileExists(t, models.PartPath(sheet, sheet.Parts[0].SafeLabel))
+	require.Len(t, sheet.Media, 1)
+	assert.FileExists(t, sheet.Media[0].Path(sheet))
+	assert.Equal(t, score.FormatMusicXML, sheet.SourceFormat)
+	assert.FileExists(t, models.SourcePath(sheet))
+	assert.Equal(t, work.SafeName, sheet.WorkSafeName)
+	assert.Equal(t, models.LicensePurchased, sheet.License)
+	assert.Equal(t, "Henle", sheet.LicenseHolder)
+	assert.Equal(t, 3, sheet.LicenseSeats)
+
+	var distributions int64
+	require.NoError(t, db.Model(&models.SheetDistribution{}).Where("sheet_safe_name = ?", "etude").Count(&distributions).Error)
+	assert.EqualValues(t, 1, distributions)
+	_, err = models.FindCopy(db, "SF-0001", sheet.UpdatedAt)
+	assert.NoError(t, err)
+}
+
+func TestUpdateSheetRenames(t *testing.T) {
+	server := setupServer(t)
+	db := server.DB
+	require.NoError(t, db.Create(&models.Composer{SafeName: "liszt", Name: "Liszt", Epoch: "Romantic"}).Error)
+	sheet, err := (&models.Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+	require.NoError(t, sheet.AddPart(db, &models.SheetPart{Label: "Violin I"}, strings.NewReader("%PDF-1.4")))
+	require.NoError(t, sheet.WriteSource(db, &score.Metadata{Format: score.FormatMusicXML}, strings.NewReader("<score-partwise/>")))
+	require.NoError(t, sheet.AddCopy(db, &models.SheetCopy{Barcode: "SF-0001"}))
+	old, err := (&models.Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+
+	// Titre et compositeur changent : nouvelle clé, fichiers déplacés
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = uploadRequest(t, http.MethodPut, "/api/sheet/etude", "", nil,
+		"sheetName", "Étude transcendante", "composer", "Liszt", "releaseDate", "1852-01-01",
+		"tags", "virtuoso;study", "informationText", "S.139")
+	c.Params = gin.Params{{Key: "sheetName", Value: "etude"}}
+	server.UpdateSheet(c)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	sheet, err = (&models.Sheet{}).FindSheetBySafeName(db, "etude-transcendante")
+	require.NoError(t, err)
+	assert.Equal(t, "Étude transcendante", sheet.SheetName)
+	assert.Equal(t, "liszt", sheet.SafeComposer)
+	assert.Equal(t, "Liszt", sheet.Composer)
+	assert.Equal(t, "sheet/pdf/liszt/etude-transcendante", sheet.PdfUrl)
+	assert.Equal(t, 1852, sheet.ReleaseDate.Year())
+	assert.Equal(t, `["virtuoso","study"]`, sheet.Tags)
+	assert.Equal(t, "S.139", sheet.InformationText)
+	_, err = (&models.Sheet{}).FindSheetBySafeName(db, "etude")
+	assert.Error(t, err, "the old row is deleted")
+
+	assert.FileExists(t, models.PdfPath(sheet))
+	assert.NoFileExists(t, models.PdfPath(old))
+	require.Len(t, sheet.Parts, 1)
+	assert.FileExists(t, models.PartPath(sheet, sheet.Parts[0].SafeLabel))
+	assert.FileExists(t, models.SourcePath(sheet))
+	assert.NoDirExists(t, models.PartsDir(old))
+	_, err = models.FindCopy(db, "SF-0001", sheet.UpdatedAt)
+	assert.NoError(t, err)
+	var copies int64
+	require.NoError(t, db.Model(&models.SheetCopy{}).Where("sheet_safe_name = ?", "etude-transcendante").Count(&copies).Error)
+	assert.EqualValues(t, 1, copies)
+
+	// Le nom d'une autre partition est refusé
+	require.NoError(t, db.Create(&models.Sheet{SafeSheetName: "nocturne", SheetName: "Nocturne", SafeComposer: "chopin", Composer: "Chopin"}).Error)
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = uploadRequest(t, http.MethodPut, "/api/sheet/etude-transcendante", "", nil, "sheetName", "Nocturne")
+	c.Params = gin.Params{{Key: "sheetName", Value: "etude-transcendante"}}
+	server.UpdateSheet(c)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.FileExists(t, models.PdfPath(sheet))
+}
+
+func TestUploadEditionChecksComposerFirst(t *testing.T) {
+	server := setupServer(t)
+	db := server.DB
+	require.NoError(t, models.CreateWork(db, &models.Work{Title: "Etude", SafeComposer: "chopin"}))
+	var work models.Work
+	require.NoError(t, db.Take(&work).Error)
+
+	// Compositeur inconnu : refusé sans créer le compositeur (server.Composers n'est pas interrogé)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = uploadRequest(t, http.MethodPost, "/api/upload", "etude-2.pdf", testPDF(t, 1),
+		"composer", "Liszt", "sheetName", "Etude", "work", work.SafeName)
+	server.UploadFile(c)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	var count int64
+	require.NoError(t, db.Model(&models.Composer{}).Where("safe_name = ?", "liszt").Count(&count).Error)
+	assert.Zero(t, count)
+	assert.NoDirExists(t, path.Join(config.Config().ConfigPath, "sheets/uploaded-sheets/liszt"))
+}
+
+func TestUploadABCWithoutThumbnailService(t *testing.T) {
+	server := setupServer(t)
+
+	// Le service pdf2png n'écoute pas : l'air est enregistré et gravé, le thumbnail manque seulement
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = uploadRequest(t, http.MethodPost, "/api/upload", "reel.abc", []byte("X:1\nT:Reel\nC:Chopin\nM:2/4\nK:D\nde|fd|"),
+		"composer", "Chopin", "sheetName", "Reel")
+	server.UploadFile(c)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	sheet, err := (&models.Sheet{}).FindSheetBySafeName(server.DB, "reel")
+	require.NoError(t, err)
+	assert.Equal(t, score.FormatABC, sheet.SourceFormat)
+	assert.Equal(t, "sheet/pdf/chopin/reel", sheet.PdfUrl)
+	assert.FileExists(t, models.PdfPath(sheet))
+}
+
+func TestUploadSourceOnlyHash(t *testing.T) {
+	server := setupServer(t)
+	upload := func(filename string, data string, sheetName string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = uploadRequest(t, http.MethodPost, "/api/upload", filename, []byte(data), "composer", "Chopin", "sheetName", sheetName)
+		server.UploadFile(c)
+		return w
+	}
+
+	// MusicXML seul : pas de PDF, pas d'empreinte
+	w := upload("quartet.musicxml", `<?xml version="1.0"?><score-partwise version="4.0"><part-list/></score-partwise>`, "Quartet")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	sheet, err := (&models.Sheet{}).FindSheetBySafeName(server.DB, "quartet")
+	require.NoError(t, err)
+	assert.Empty(t, sheet.FileHash)
+
+	// Air ABC : empreinte du PDF gravé
+	tune := "X:1\nT:Reel\nM:2/4\nK:D\nde|fd|"
+	w = upload("reel.abc", tune, "Reel")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	sheet, err = (&models.Sheet{}).FindSheetBySafeName(server.DB, "reel")
+	require.NoError(t, err)
+	file, err := os.Open(models.PdfPath(sheet))
+	require.NoError(t, err)
+	defer file.Close()
+	hash, err := utils.HashReader(file)
+	require.NoError(t, err)
+	assert.Equal(t, hash, sheet.FileHash)
+
+	// Le même air sous un autre titre : accepté avec un avertissement
+	w = upload("reel-2.abc", tune, "Another reel")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"duplicate_of"`)
+	assert.Contains(t, w.Body.String(), "already uploaded as reel")
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"backend/api/auth"
+	"backend/api/config"
+	"backend/api/forms"
+	"backend/api/models"
+	"backend/api/pdf"
+	"backend/api/utils"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/*
+Set the watermark policy of a sheet (admin only)
+  - on: every download is stamped with the user's email, the date and the footer
+  - off: never stamped, even in a watermarked category
+  - inherit: stamped if one of its categories is watermarked
+
+Example request:
+
+	PUT /api/sheet/fuer-elise/watermark
+		Body (FormValue or JSON):
+		- watermark: on
+*/
+func (server *Server) SetSheetWatermark(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var form forms.WatermarkRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad watermark request: %v", err))
+		return
+	}
+	sheet, err := models.SetSheetWatermark(server.DB, c.Param("sheetName"), form.Watermark)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidWatermark):
+			utils.DoError(c, http.StatusBadRequest, err)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.DoError(c, http.StatusNotFound, errors.New("sheet not found"))
+		default:
+			utils.DoError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	os.RemoveAll(models.WatermarkDir(sheet))
+	c.JSON(http.StatusOK, sheet)
+}
+
+/*
+List the watermarked categories
+Example request:
+
+	GET /api/watermarks
+*/
+func (server *Server) GetWatermarkCategories(c *gin.Context) {
+	categories, err := models.ListWatermarkCategories(server.DB)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"footer":     config.Config().Watermark.Footer,
+		"categories": categories,
+	})
+}
+
+/*
+Watermark the downloads of every sheet of a category (admin only)
+Example request:
+
+	PUT /api/watermark/Licensed
+		Body (FormValue or JSON):
+		- footer: Rental material of Bärenreiter, do not copy (default: WATERMARK_FOOTER)
+*/
+func (server *Server) SetWatermarkCategory(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var form forms.WatermarkCategoryRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad watermark request: %v", err))
+		return
+	}
+	policy, err := models.SetWatermarkCategory(server.DB, c.Param("category"), form.Footer)
+	if err != nil {
+		if errors.Is(err, models.ErrEmptyWatermarkCategory) {
+			utils.DoError(c, http.StatusBadRequest, err)
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	clearWatermarks()
+	c.JSON(http.StatusOK, policy)
+}
+
+/*
+Stop watermarking a category (admin only)
+Example request:
+
+	DELETE /api/watermark/Licensed
+*/
+func (server *Server) DeleteWatermarkCategory(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	if err := models.DeleteWatermarkCategory(server.DB, c.Param("category")); err != nil {
+		if errors.Is(err, models.ErrWatermarkCategoryNotFound) {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	clearWatermarks()
+	c.JSON(http.StatusOK, "Category no longer watermarked")
+}
+
+// clearWatermarks supprime tous les PDF filigranés en cache après un changement de politique
+func clearWatermarks() {
+	os.RemoveAll(path.Join(config.Config().ConfigPath, "sheets/watermarked"))
+}
+
+// watermarkedPDF retourne le PDF à servir à l'utilisateur : filePath, ou sa copie filigranée si la partition est sous licence.
+// label distingue les fichiers d'une même partition (sheet, part-violin-i, revision-2).
+// La copie est gardée en cache par utilisateur, fichier, révision et jour : la date imprimée est celle du téléchargement.
+// Les copies des jours précédents sont supprimées.
+func (server *Server) watermarkedPDF(c *gin.Context, sheet *models.Sheet, filePath string, label string) (string, error) {
+	enabled, footer, err := sheet.WatermarkFooter(server.DB)
+	if err != nil || !enabled {
+		return filePath, err
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		return filePath, nil
+	}
+
+	uid, err := auth.ExtractTokenID(utils.ExtractToken(c), config.Config().ApiSecret)
+	if err != nil {
+		return "", err
+	}
+	user, err := (&models.User{}).FindByID(server.DB, uid)
+	if err != nil {
+		return "", err
+	}
+
+	// "_" sépare les champs : les labels (noms safe) n'en contiennent pas
+	today := time.Now().Format("2006-01-02")
+	sum := sha256.Sum256([]byte(user.Email + "\n" + footer))
+	prefix := fmt.Sprintf("%d_%s_", uid, label)
+	name := fmt.Sprintf("%s%d_%s_%s", prefix, sheet.Revision, today, hex.EncodeToString(sum[:6]))
+	out := path.Join(models.WatermarkDir(sheet), name+".pdf")
+	stale, _ := filepath.Glob(path.Join(models.WatermarkDir(sheet), prefix+"*"))
+	for _, old := range stale {
+		if !strings.HasPrefix(path.Base(old), name) {
+			os.RemoveAll(old)
+		}
+	}
+
+	lines := []string{fmt.Sprintf("Licensed to %s on %s", user.Email, today), footer}
+	if err := pdf.Watermark(filePath, out, lines); err != nil {
+		return "", err
+	}
+	c.Header("Cache-Control", "private")
+	return out, nil
+}
+
+// allowUnstamped refuse (403) les contenus qui ne peuvent pas porter de filigrane (fichier source,
+// MusicXML transposé, gravure SVG) d'une partition sous licence. L'admin y garde accès.
+func (server *Server) allowUnstamped(c *gin.Context, sheet *models.Sheet) bool {
+	enabled, _, err := sheet.WatermarkFooter(server.DB)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return false
+	}
+	if !enabled {
+		return true
+	}
+	uid, err := auth.ExtractTokenID(utils.ExtractToken(c), config.Config().ApiSecret)
+	if err == nil && uid == config.ADMIN_UID {
+		return true
+	}
+	utils.DoError(c, http.StatusForbidden, errors.New("this sheet is watermarked, only its PDF can be downloaded"))
+	return false
+}
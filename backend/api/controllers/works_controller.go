@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"backend/api/forms"
+	"backend/api/models"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+This endpoint will return all works in Page like style, each work with its editions.
+Meaning POST request will have 4 attributes:
+  - sort_by: (how is it sorted, e.g. "title asc" or "catalogue_type asc, catalogue_sort asc")
+  - page: (what page)
+  - limit: (limit number)
+  - composer: (safe name of the composer)
+
+Return:
+  - rows: [{ safe_name, title, composer, catalogue_type, catalogue_number, editions: [...] }]
+  - total_pages, total_rows
+*/
+func (server *Server) GetWorksPage(c *gin.Context) {
+	var form forms.GetWorksPageRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	pagination := models.Pagination{
+		Sort:  form.SortBy,
+		Limit: form.Limit,
+		Page:  form.Page,
+	}
+
+	pageNew, err := models.ListWorks(server.DB, pagination, form.Composer)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, pageNew)
+}
+
+/*
+Get a work and all its editions, ordered by edition year and publisher
+Example request:
+
+	GET /api/work/bach-prelude-in-c-major-bwv-846
+*/
+func (server *Server) GetWork(c *gin.Context) {
+	work, err := models.FindWork(server.DB, c.Param("workName"))
+	if err != nil {
+		if errors.Is(err, models.ErrWorkNotFound) {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, work)
+}
+
+/*
+Create a work (composer, canonical title and catalogue number) for the editions of a same piece
+Example requests:
+
+	POST /api/work
+		Body (FormValue or JSON):
+		- title: Prelude in C major
+		- composer: Bach
+		- catalogueType: BWV
+		- catalogueNumber: 846
+
+	POST /api/work
+		Body (FormValue or JSON):
+		- sheet: prelude-in-c-major-bwv-846 (title, composer and catalogue number taken from the sheet, which becomes the first edition)
+*/
+func (server *Server) CreateWork(c *gin.Context) {
+	var form forms.CreateWorkRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad work request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+	number, _ := form.Catalogue()
+	work := models.Work{
+		Title:           form.Title,
+		CatalogueType:   number.System,
+		CatalogueNumber: number.Number,
+	}
+
+	var sheet *models.Sheet
+	if form.Sheet != "" {
+		var sheetModel models.Sheet
+		found, err := sheetModel.FindSheetBySafeName(server.DB, form.Sheet)
+		if err != nil {
+			utils.DoError(c, http.StatusNotFound, models.ErrEditionNotFound)
+			return
+		}
+		if found.WorkSafeName != "" {
+			utils.DoError(c, http.StatusConflict, fmt.Errorf("%w: %s", models.ErrEditionLinked, found.WorkSafeName))
+			return
+		}
+		sheet = found
+		if strings.TrimSpace(work.Title) == "" {
+			work.Title = sheet.SheetName
+		}
+		if work.CatalogueNumber == "" {
+			work.CatalogueType, work.CatalogueNumber = sheet.CatalogueType, sheet.CatalogueNumber
+		}
+		work.SafeComposer = sheet.SafeComposer
+	}
+	if strings.TrimSpace(form.Composer) != "" {
+		composer, err := models.ResolveComposer(server.DB, form.Composer)
+		if err != nil {
+			utils.DoError(c, http.StatusNotFound, models.ErrComposerNotFound)
+			return
+		}
+		if sheet != nil && composer.SafeName != sheet.SafeComposer {
+			utils.DoError(c, http.StatusConflict, fmt.Errorf("%w: %s", models.ErrEditionComposer, sheet.SafeComposer))
+			return
+		}
+		work.SafeComposer = composer.SafeName
+	}
+
+	if err := models.CreateWork(server.DB, &work); err != nil {
+		switch {
+		case errors.Is(err, models.ErrEmptyWorkTitle), errors.Is(err, models.ErrEmptyWorkComposer):
+			utils.DoError(c, http.StatusBadRequest, err)
+		case errors.Is(err, models.ErrComposerNotFound):
+			utils.DoError(c, http.StatusNotFound, err)
+		case errors.Is(err, models.ErrWorkExists):
+			utils.DoError(c, http.StatusConflict, err)
+		default:
+			utils.DoError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	if sheet != nil {
+		if _, err := models.LinkEdition(server.DB, work.SafeName, sheet.SafeSheetName); err != nil {
+			utils.DoError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	created, err := models.FindWork(server.DB, work.SafeName)
+	if err != nil {
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+/*
+Delete a work, its editions stay in the library without work
+Example request:
+
+	DELETE /api/work/bach-prelude-in-c-major-bwv-846
+*/
+func (server *Server) DeleteWork(c *gin.Context) {
+	if err := models.DeleteWork(server.DB, c.Param("workName")); err != nil {
+		if errors.Is(err, models.ErrWorkNotFound) {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, "Work deleted successfully")
+}
+
+/*
+Link an existing sheet of the same composer to a work as one of its editions
+Example request:
+
+	POST /api/work/bach-prelude-in-c-major-bwv-846/editions
+		Body (FormValue or JSON):
+		- sheet: prelude-in-c-major-bwv-846-peters
+*/
+func (server *Server) LinkEdition(c *gin.Context) {
+	var form forms.LinkEditionRequest
+	if err := c.ShouldBind(&form); err != nil {
+		utils.DoError(c, http.StatusBadRequest, fmt.Errorf("bad edition request: %v", err))
+		return
+	}
+	if err := form.ValidateForm(); err != nil {
+		utils.DoError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	sheet, err := models.LinkEdition(server.DB, c.Param("workName"), form.Sheet)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrWorkNotFound), errors.Is(err, models.ErrEditionNotFound):
+			utils.DoError(c, http.StatusNotFound, err)
+		case errors.Is(err, models.ErrEditionComposer), errors.Is(err, models.ErrEditionLinked):
+			utils.DoError(c, http.StatusConflict, err)
+		default:
+			utils.DoError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, sheet)
+}
+
+/*
+Unlink an edition from its work, the sheet itself is kept
+Example request:
+
+	DELETE /api/work/bach-prelude-in-c-major-bwv-846/editions/prelude-in-c-major-bwv-846-peters
+*/
+func (server *Server) UnlinkEdition(c *gin.Context) {
+	if err := models.UnlinkEdition(server.DB, c.Param("workName"), c.Param("sheetName")); err != nil {
+		if errors.Is(err, models.ErrEditionNotLinked) {
+			utils.DoError(c, http.StatusNotFound, err)
+			return
+		}
+		utils.DoError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, "Edition unlinked successfully")
+}
@@ -1,7 +1,26 @@
 package forms
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type PaginatedRequest struct {
 	SortBy string `form:"sort_by,default=updated_at desc"`
 	Limit  int    `form:"limit,default=10"`
 	Page   int    `form:"page,default=1"`
 }
+
+// parseDate lit une date YYYY-MM-DD, nil si elle n'est pas saisie
+func parseDate(field string, value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date YYYY-MM-DD: %q", field, value)
+	}
+	return &date, nil
+}
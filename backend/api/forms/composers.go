@@ -7,8 +7,20 @@ type GetComposersPageRequest struct {
 }
 
 type UpdateComposersRequest struct {
-	Name        string                `form:"name"`
-	PortraitUrl string                `form:"portrait_url"`
-	Epoch       string                `form:"epoch"`
-	File        *multipart.FileHeader `form:"portrait"`
+	Name          string                `form:"name"`
+	PortraitUrl   string                `form:"portrait_url"`
+	Epoch         string                `form:"epoch"`
+	Birth         string                `form:"birth"`
+	Death         string                `form:"death"`
+	Nationality   string                `form:"nationality"`
+	Biography     string                `form:"biography"`
+	WikidataID    string                `form:"wikidata_id"`
+	ViafID        string                `form:"viaf_id"`
+	MusicBrainzID string                `form:"musicbrainz_id"`
+	Aliases       *string               `form:"aliases"` // liste séparée par des points-virgules, absente = inchangée
+	File          *multipart.FileHeader `form:"portrait"`
+}
+
+type MergeComposerRequest struct {
+	Target string `form:"target"` // safe_name du composer qui est conservé
 }
@@ -0,0 +1,62 @@
+package forms
+
+import (
+	"errors"
+	"time"
+)
+
+// CopyRequest : nouvel exemplaire imprimé (POST /api/sheet/:sheetName/copies)
+type CopyRequest struct {
+	Barcode   string `form:"barcode" json:"barcode"`     // code-barres collé sur la couverture
+	Shelf     string `form:"shelf" json:"shelf"`         // emplacement, ex: B3-2
+	Condition string `form:"condition" json:"condition"` // new, good, fair, poor ou damaged, défaut good
+	Notes     string `form:"notes" json:"notes"`
+}
+
+func (req *CopyRequest) ValidateForm() error {
+	if req.Barcode == "" {
+		return errors.New("barcode is required")
+	}
+	return nil
+}
+
+// UpdateCopyRequest : modification d'un exemplaire, un champ absent n'est pas modifié
+type UpdateCopyRequest struct {
+	Barcode   *string `form:"barcode" json:"barcode"`
+	Shelf     *string `form:"shelf" json:"shelf"`
+	Condition *string `form:"condition" json:"condition"`
+	Notes     *string `form:"notes" json:"notes"`
+}
+
+// CheckoutRequest : prêt d'un exemplaire (POST /api/copy/:barcode/checkout)
+type CheckoutRequest struct {
+	UserID  uint32 `form:"userId" json:"userId"`   // emprunteur, 0 = l'utilisateur connecté
+	DueDate string `form:"dueDate" json:"dueDate"` // dernier jour du prêt YYYY-MM-DD, défaut et maximum (hors admin) dans 28 jours
+}
+
+func (req *CheckoutRequest) ValidateForm() error {
+	_, err := req.Due()
+	return err
+}
+
+// Due retourne la date de retour, zéro si elle n'est pas saisie
+func (req *CheckoutRequest) Due() (time.Time, error) {
+	due, err := parseDate("dueDate", req.DueDate)
+	if err != nil || due == nil {
+		return time.Time{}, err
+	}
+	return *due, nil
+}
+
+// ReturnRequest : retour d'un exemplaire, avec son état constaté
+type ReturnRequest struct {
+	Condition string `form:"condition" json:"condition"` // vide = inchangé
+}
+
+// GetLoansRequest : filtres de la liste des prêts (GET /api/loans)
+type GetLoansRequest struct {
+	UserID  uint32 `form:"user_id"`
+	Sheet   string `form:"sheet"`   // safe_sheet_name
+	Overdue bool   `form:"overdue"` // uniquement les prêts en retard
+	History bool   `form:"history"` // inclut les prêts rendus
+}
@@ -0,0 +1,43 @@
+package forms
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Taille en pixels du QR code d'une partition
+const (
+	MinQRSize = 64
+	MaxQRSize = 1024
+)
+
+// MaxLabels : nombre maximal d'étiquettes par requête (soit 20 planches de 14 étiquettes)
+const MaxLabels = 280
+
+// QRRequest : QR code du lien vers une partition (GET /api/sheet/:sheetName/qr.png)
+type QRRequest struct {
+	Size int `form:"size,default=256"` // largeur et hauteur en pixels
+}
+
+func (req *QRRequest) ValidateForm() error {
+	if req.Size < MinQRSize || req.Size > MaxQRSize {
+		return fmt.Errorf("size must be between %d and %d", MinQRSize, MaxQRSize)
+	}
+	return nil
+}
+
+// LabelsRequest : planche d'étiquettes (POST /api/labels.pdf), une étiquette par partition dans l'ordre donné.
+// Une partition répétée donne plusieurs étiquettes.
+type LabelsRequest struct {
+	Sheets []string `form:"sheets" json:"sheets"` // safe_sheet_name des partitions
+}
+
+func (req *LabelsRequest) ValidateForm() error {
+	if len(req.Sheets) == 0 {
+		return errors.New("sheets is required")
+	}
+	if len(req.Sheets) > MaxLabels {
+		return fmt.Errorf("at most %d labels per request", MaxLabels)
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+package forms
+
+type LibraryCheckRequest struct {
+	Fix bool `form:"fix"`
+}
@@ -0,0 +1,27 @@
+package forms
+
+import (
+	"fmt"
+	"time"
+)
+
+// LicenseRequest : licence d'une partition (PUT /api/sheet/:sheetName/license)
+type LicenseRequest struct {
+	License string `form:"license" json:"license"` // public-domain, cc0, cc-by ..., purchased, rental, vide = inconnue
+	Holder  string `form:"holder" json:"holder"`   // éditeur ou loueur
+	Seats   int    `form:"seats" json:"seats"`     // licence achetée : nombre de places, 0 = illimité
+	Expiry  string `form:"expiry" json:"expiry"`   // location : dernier jour, YYYY-MM-DD
+}
+
+func (req *LicenseRequest) ValidateForm() error {
+	if req.Seats < 0 {
+		return fmt.Errorf("seats must not be negative (0 = unlimited)")
+	}
+	_, err := req.ExpiryDate()
+	return err
+}
+
+// ExpiryDate retourne la date d'expiration de la location, nil si elle n'est pas saisie
+func (req *LicenseRequest) ExpiryDate() (*time.Time, error) {
+	return parseDate("expiry", req.Expiry)
+}
@@ -0,0 +1,85 @@
+package forms
+
+import (
+	"errors"
+	"strings"
+)
+
+// Modification des pages du PDF d'une partition.
+// Les pages sont une sélection "1,3-5,8", vide = toutes les pages (sauf pour la suppression et l'extraction).
+
+// PagesRequest : pages à supprimer
+type PagesRequest struct {
+	Pages string `form:"pages" json:"pages"`
+}
+
+func (req *PagesRequest) ValidateForm() error {
+	if strings.TrimSpace(req.Pages) == "" {
+		return errors.New("pages are required")
+	}
+	return nil
+}
+
+// RotatePagesRequest : rotation en degrés dans le sens horaire, négatif = sens anti-horaire
+type RotatePagesRequest struct {
+	Pages string `form:"pages" json:"pages"`
+	Angle int    `form:"angle" json:"angle"`
+}
+
+func (req *RotatePagesRequest) ValidateForm() error {
+	if req.Angle == 0 || req.Angle%90 != 0 {
+		return errors.New("angle must be a non-zero multiple of 90")
+	}
+	return nil
+}
+
+// ReorderPagesRequest : nouvel ordre de toutes les pages, ex: 2,1,3-8
+type ReorderPagesRequest struct {
+	Order string `form:"order" json:"order"`
+}
+
+func (req *ReorderPagesRequest) ValidateForm() error {
+	if strings.TrimSpace(req.Order) == "" {
+		return errors.New("order is required")
+	}
+	return nil
+}
+
+// CropPagesRequest : marges à retirer en points ou en pourcentage, ex: 20 ou 10 5 ou 5%
+type CropPagesRequest struct {
+	Pages   string `form:"pages" json:"pages"`
+	Margins string `form:"margins" json:"margins"`
+}
+
+func (req *CropPagesRequest) ValidateForm() error {
+	if strings.TrimSpace(req.Margins) == "" {
+		return errors.New("margins are required")
+	}
+	return nil
+}
+
+// ExtractPagesRequest : pages copiées dans une nouvelle partition, retirées de l'originale si remove est vrai.
+// Le compositeur vide est celui de la partition d'origine.
+type ExtractPagesRequest struct {
+	Pages           string `form:"pages" json:"pages"`
+	SheetName       string `form:"sheetName" json:"sheetName"`
+	Composer        string `form:"composer" json:"composer"`
+	Remove          bool   `form:"remove" json:"remove"`
+	ReleaseDate     string `form:"releaseDate" json:"releaseDate"`
+	Categories      string `form:"categories" json:"categories"`
+	Tags            string `form:"tags" json:"tags"`
+	InformationText string `form:"informationText" json:"informationText"`
+	Work            string `form:"work" json:"work"`
+
+	SheetMetadataRequest
+}
+
+func (req *ExtractPagesRequest) ValidateForm() error {
+	if strings.TrimSpace(req.SheetName) == "" {
+		return errors.New("sheet name is required")
+	}
+	if strings.TrimSpace(req.Pages) == "" {
+		return errors.New("pages are required")
+	}
+	return req.SheetMetadataRequest.ValidateForm()
+}
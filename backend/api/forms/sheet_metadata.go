@@ -0,0 +1,145 @@
+package forms
+
+import (
+	"backend/api/catalogue"
+	"backend/api/models"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SheetMetadataRequest : informations musicales d'une partition, saisies à l'upload (POST /api/upload)
+// ou modifiées seules (PUT /api/sheet/:sheetName/metadata).
+// Un champ absent n'est pas modifié, un champ vide efface la valeur.
+type SheetMetadataRequest struct {
+	CatalogueType   *string `form:"catalogueType" json:"catalogueType"`     // Op., BWV, K., D., Hob. ...
+	CatalogueNumber *string `form:"catalogueNumber" json:"catalogueNumber"` // ex: 27 No. 2
+	Key             *string `form:"key" json:"key"`                         // ex: Eb major
+	Instrumentation *string `form:"instrumentation" json:"instrumentation"` // ex: string quartet
+	Difficulty      *int    `form:"difficulty" json:"difficulty"`           // grade de 1 à 8, 0 = inconnu
+	Duration        *string `form:"duration" json:"duration"`               // "7:30", "1:02:00" ou un nombre de secondes
+	Arranger        *string `form:"arranger" json:"arranger"`
+	Lyricist        *string `form:"lyricist" json:"lyricist"`
+	Publisher       *string `form:"publisher" json:"publisher"`
+	Language        *string `form:"language" json:"language"` // code ISO 639-1, ex: de
+	Editor          *string `form:"editor" json:"editor"`
+	EditionYear     *int    `form:"editionYear" json:"editionYear"` // 0 = inconnue
+}
+
+// Difficulté : grades de 1 (débutant) à 8 (concert)
+const (
+	MinDifficulty = 1
+	MaxDifficulty = 8
+)
+
+// Année d'édition : des premières partitions imprimées à l'année en cours
+const MinEditionYear = 1450
+
+var languagePattern = regexp.MustCompile(`^[a-z]{2}$`)
+
+func (req *SheetMetadataRequest) ValidateForm() error {
+	if _, err := req.catalogueNumber(); err != nil {
+		return err
+	}
+	if req.Difficulty != nil && *req.Difficulty != 0 && (*req.Difficulty < MinDifficulty || *req.Difficulty > MaxDifficulty) {
+		return fmt.Errorf("difficulty must be between %d and %d", MinDifficulty, MaxDifficulty)
+	}
+	if req.Duration != nil {
+		if _, err := ParseDuration(*req.Duration); err != nil {
+			return err
+		}
+	}
+	if req.EditionYear != nil && *req.EditionYear != 0 && (*req.EditionYear < MinEditionYear || *req.EditionYear > time.Now().Year()) {
+		return fmt.Errorf("edition year must be between %d and %d", MinEditionYear, time.Now().Year())
+	}
+	if req.Language != nil && strings.TrimSpace(*req.Language) != "" {
+		if !languagePattern.MatchString(strings.ToLower(strings.TrimSpace(*req.Language))) {
+			return errors.New("language must be a two-letter ISO 639-1 code, e.g. de")
+		}
+	}
+	return nil
+}
+
+// Metadata retourne les informations normalisées de la requête, à appeler après ValidateForm
+func (req *SheetMetadataRequest) Metadata() models.SheetMetadata {
+	m := models.SheetMetadata{
+		CatalogueType:   req.CatalogueType,
+		CatalogueNumber: req.CatalogueNumber,
+		Key:             req.Key,
+		Instrumentation: req.Instrumentation,
+		Difficulty:      req.Difficulty,
+		Arranger:        req.Arranger,
+		Lyricist:        req.Lyricist,
+		Publisher:       req.Publisher,
+		Language:        req.Language,
+		Editor:          req.Editor,
+		EditionYear:     req.EditionYear,
+	}
+	if number, _ := req.catalogueNumber(); number != nil {
+		m.CatalogueNumber = &number.Number
+		if req.CatalogueType != nil || number.System != "" {
+			m.CatalogueType = &number.System
+		}
+	} else if req.CatalogueType != nil {
+		catalogueType, _ := catalogue.NormalizeSystem(*req.CatalogueType)
+		m.CatalogueType = &catalogueType
+	}
+	if req.Duration != nil {
+		duration, _ := ParseDuration(*req.Duration)
+		m.Duration = &duration
+	}
+	if req.Language != nil {
+		language := strings.ToLower(strings.TrimSpace(*req.Language))
+		m.Language = &language
+	}
+	return m
+}
+
+// catalogueNumber normalise le numéro de catalogue saisi, nil s'il est absent ou vide.
+// Le numéro peut contenir le catalogue ("BWV 846") si catalogueType n'est pas donné.
+func (req *SheetMetadataRequest) catalogueNumber() (*catalogue.Number, error) {
+	system := ""
+	if req.CatalogueType != nil && strings.TrimSpace(*req.CatalogueType) != "" {
+		var ok bool
+		if system, ok = catalogue.NormalizeSystem(*req.CatalogueType); !ok {
+			return nil, fmt.Errorf("%w: %q", catalogue.ErrUnknownSystem, *req.CatalogueType)
+		}
+	}
+	if req.CatalogueNumber == nil || strings.TrimSpace(*req.CatalogueNumber) == "" {
+		return nil, nil
+	}
+	if system == "" {
+		if number, ok := catalogue.ParseReference(*req.CatalogueNumber); ok {
+			return &number, nil
+		}
+	}
+	number, err := catalogue.ParseNumber(system, *req.CatalogueNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &number, nil
+}
+
+// ParseDuration lit une durée en secondes : "450", "7:30" ou "1:02:00". Une chaîne vide vaut 0.
+func ParseDuration(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid duration %q, expected seconds, m:ss or h:mm:ss", value)
+	}
+	seconds := 0
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (i > 0 && (n > 59 || len(part) != 2)) {
+			return 0, fmt.Errorf("invalid duration %q, expected seconds, m:ss or h:mm:ss", value)
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, nil
+}
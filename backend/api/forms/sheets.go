@@ -2,5 +2,26 @@ package forms
 
 type GetSheetsPageRequest struct {
 	PaginatedRequest
-	Composer string `form:"composer"`
+	Composer    string `form:"composer"`
+	MinPages    int    `form:"min_pages"`
+	MaxPages    int    `form:"max_pages"`
+	Orientation string `form:"orientation"` // portrait, landscape ou mixed
+	Encrypted   *bool  `form:"encrypted"`
+	HasText     *bool  `form:"has_text"`
+
+	CatalogueType   string `form:"catalogue_type"` // Op., BWV, K. ... (opus, kv acceptés)
+	Key             string `form:"key"`            // ex: D minor
+	Instrumentation string `form:"instrumentation"`
+	MinDifficulty   int    `form:"min_difficulty"`
+	MaxDifficulty   int    `form:"max_difficulty"`
+	MinDuration     string `form:"min_duration"` // "5:00" ou un nombre de secondes
+	MaxDuration     string `form:"max_duration"`
+	Arranger        string `form:"arranger"`
+	Lyricist        string `form:"lyricist"`
+	Publisher       string `form:"publisher"`
+	Language        string `form:"language"`
+	Editor          string `form:"editor"`
+	Work            string `form:"work"`    // safe_name de l'oeuvre : toutes ses éditions
+	Parent          string `form:"parent"`  // safe_sheet_name d'un recueil : toutes ses pièces
+	License         string `form:"license"` // public-domain, cc-by, purchased, rental ... ou unknown
 }
@@ -1,8 +1,12 @@
 package forms
 
 import (
+	"backend/api/pdf"
+	"backend/api/score"
 	"errors"
+	"fmt"
 	"mime/multipart"
+	"path"
 	"strings"
 )
 
@@ -47,6 +51,19 @@ type UploadRequest struct {
 	Categories      string                `form:"categories"`
 	Tags            string                `form:"tags"`
 	InformationText string                `form:"informationText"`
+
+	// Fichier MusicXML, MuseScore ou ABC accompagnant le PDF.
+	// uploadFile peut aussi être directement un fichier source : la partition n'a alors pas de PDF.
+	SourceFile *multipart.FileHeader `form:"sourceFile"`
+
+	// Oeuvre (safe_name) dont la partition est une édition, titre et compositeur par défaut
+	Work string `form:"work"`
+
+	// Photos d'une partition papier (JPEG, PNG ou WebP) : uploadFile répété, une page par image dans l'ordre d'envoi
+	Files []*multipart.FileHeader `form:"uploadFile"`
+
+	// Catalogue, tonalité, effectif, difficulté, durée ...
+	SheetMetadataRequest
 }
 
 // Currently a no-op but enables us to add any custom form validation in without having to change any calling code.
@@ -73,13 +90,279 @@ func (req *UploadRequest) ValidateForm() error {
 		return errors.New("sheet name is required")
 	}
 
+	if err := req.SheetMetadataRequest.ValidateForm(); err != nil {
+		return err
+	}
+
 	if req.File.Size > 10<<20 { // 10MB
 		return errors.New("file too large")
 	}
 
+	if req.SourceOnly() {
+		if req.SourceFile != nil {
+			return errors.New("sourceFile is only allowed with a PDF")
+		}
+		return nil
+	}
+	if images := req.Images(); images != nil {
+		if err := validateImages(images); err != nil {
+			return err
+		}
+	} else if len(req.Files) > 1 {
+		return errors.New("several files are only allowed for JPEG, PNG or WebP images")
+	} else if !strings.HasSuffix(strings.ToLower(req.File.Filename), ".pdf") {
+		return errors.New("only PDF, JPEG, PNG, WebP, MusicXML (.musicxml, .xml, .mxl), MuseScore (.mscz) and ABC (.abc) files are allowed")
+	}
+
+	if req.SourceFile != nil {
+		if req.SourceFile.Size > 10<<20 {
+			return errors.New("source file too large")
+		}
+		if _, ok := score.FormatFromName(req.SourceFile.Filename); !ok {
+			return errors.New("sourceFile must be a MusicXML (.musicxml, .xml, .mxl), MuseScore (.mscz) or ABC (.abc) file")
+		}
+	}
+
+	return nil
+}
+
+// SourceOnly indique si uploadFile est un fichier MusicXML, MuseScore ou ABC, sans PDF
+func (req *UploadRequest) SourceOnly() bool {
+	if req.File == nil {
+		return false
+	}
+	_, ok := score.FormatFromName(req.File.Filename)
+	return ok
+}
+
+// Nombre maximal de photos assemblées en un PDF
+const MaxUploadImages = 50
+
+// Images retourne les photos à assembler en PDF, nil si uploadFile n'est pas une ou plusieurs images
+func (req *UploadRequest) Images() []*multipart.FileHeader {
+	return imageFiles(req.File, req.Files)
+}
+
+func imageFiles(file *multipart.FileHeader, files []*multipart.FileHeader) []*multipart.FileHeader {
+	if len(files) == 0 && file != nil {
+		files = []*multipart.FileHeader{file}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	for _, file := range files {
+		if !pdf.ImageExtensions[strings.ToLower(path.Ext(file.Filename))] {
+			return nil
+		}
+	}
+	return files
+}
+
+func validateImages(images []*multipart.FileHeader) error {
+	if len(images) > MaxUploadImages {
+		return fmt.Errorf("at most %d images are allowed", MaxUploadImages)
+	}
+	for _, image := range images {
+		if image.Size > 10<<20 {
+			return fmt.Errorf("image %s too large", image.Filename)
+		}
+	}
+	return nil
+}
+
+// Source retourne le fichier MusicXML, MuseScore ou ABC de la requête : sourceFile, ou uploadFile lui-même
+func (req *UploadRequest) Source() *multipart.FileHeader {
+	if req.SourceOnly() {
+		return req.File
+	}
+	return req.SourceFile
+}
+
+// UpdateSheetRequest : modification d'une partition existante (PUT /api/sheet/:sheetName).
+// Titre, compositeur, date, catégories, tags et texte d'information : un champ absent reste inchangé.
+// uploadFile, facultatif, remplace le PDF (ou des photos assemblées en PDF).
+type UpdateSheetRequest struct {
+	File            *multipart.FileHeader   `form:"uploadFile"`
+	Files           []*multipart.FileHeader `form:"uploadFile"`
+	Composer        string                  `form:"composer"`
+	SheetName       string                  `form:"sheetName"`
+	ReleaseDate     *string                 `form:"releaseDate"`
+	Categories      *string                 `form:"categories"`
+	Tags            *string                 `form:"tags"`
+	InformationText *string                 `form:"informationText"`
+}
+
+func (req *UpdateSheetRequest) ValidateForm() error {
+	if req.File == nil && strings.TrimSpace(req.SheetName) == "" && strings.TrimSpace(req.Composer) == "" &&
+		req.ReleaseDate == nil && req.Categories == nil && req.Tags == nil && req.InformationText == nil {
+		return errors.New("nothing to update")
+	}
+	if req.ReleaseDate != nil {
+		if _, err := parseDate("releaseDate", *req.ReleaseDate); err != nil {
+			return err
+		}
+	}
+	if req.File == nil {
+		return nil
+	}
+	if images := req.Images(); images != nil {
+		return validateImages(images)
+	}
+	if len(req.Files) > 1 {
+		return errors.New("several files are only allowed for JPEG, PNG or WebP images")
+	}
+	if req.File.Size > 10<<20 {
+		return errors.New("file too large")
+	}
+	if !strings.HasSuffix(strings.ToLower(req.File.Filename), ".pdf") {
+		return errors.New("only PDF, JPEG, PNG and WebP files are allowed")
+	}
+	return nil
+}
+
+// Images retourne les photos à assembler en PDF, nil si uploadFile n'est pas une ou plusieurs images
+func (req *UpdateSheetRequest) Images() []*multipart.FileHeader {
+	return imageFiles(req.File, req.Files)
+}
+
+// Requête de POST /api/upload/inspect : seul le fichier est attendu
+type InspectUploadRequest struct {
+	File *multipart.FileHeader `form:"uploadFile"`
+}
+
+func (req *InspectUploadRequest) ValidateForm() error {
+	if req.File == nil {
+		return errors.New("file is required")
+	}
 	if !strings.HasSuffix(strings.ToLower(req.File.Filename), ".pdf") {
 		return errors.New("only PDF files are allowed")
 	}
+	return nil
+}
+
+// UploadSuggestion contient les valeurs proposées pour le formulaire d'upload,
+// déduites des métadonnées du PDF. Les noms JSON reprennent ceux des champs du formulaire.
+type UploadSuggestion struct {
+	SheetName       string `json:"sheetName"`
+	Composer        string `json:"composer"`
+	ReleaseDate     string `json:"releaseDate"`
+	Tags            string `json:"tags"`
+	InformationText string `json:"informationText"`
+}
+
+// FillEmpty complète uniquement les champs laissés vides par l'utilisateur
+func (req *UploadRequest) FillEmpty(s UploadSuggestion) {
+	if strings.TrimSpace(req.SheetName) == "" {
+		req.SheetName = s.SheetName
+	}
+	if strings.TrimSpace(req.Composer) == "" {
+		req.Composer = s.Composer
+	}
+	if strings.TrimSpace(req.ReleaseDate) == "" {
+		req.ReleaseDate = s.ReleaseDate
+	}
+	if strings.TrimSpace(req.Tags) == "" {
+		req.Tags = s.Tags
+	}
+	if strings.TrimSpace(req.InformationText) == "" {
+		req.InformationText = s.InformationText
+	}
+}
+
+// UploadPartRequest : ajout d'une partie séparée (Violin I, Cello ...) à une partition existante
+type UploadPartRequest struct {
+	File     *multipart.FileHeader `form:"uploadFile"`
+	Label    string                `form:"label"`
+	Position int                   `form:"position"` // 0 = après les autres parties
+}
+
+func (req *UploadPartRequest) ValidateForm() error {
+	if req.File == nil {
+		return errors.New("no file given")
+	}
+	if strings.TrimSpace(req.Label) == "" {
+		return errors.New("label is required")
+	}
+	if req.Position < 0 {
+		return errors.New("position must be positive")
+	}
+	// Mêmes limites que le PDF principal
+	if req.File.Size > 10<<20 {
+		return errors.New("file too large")
+	}
+	if !strings.HasSuffix(strings.ToLower(req.File.Filename), ".pdf") {
+		return errors.New("only PDF files are allowed for a part")
+	}
+	return nil
+}
+
+// UploadMediaRequest : ajout d'un enregistrement, d'un fichier MIDI ou d'une piste d'accompagnement à une partition
+type UploadMediaRequest struct {
+	File  *multipart.FileHeader `form:"uploadFile"`
+	Label string                `form:"label"`
+	Kind  string                `form:"kind"` // recording, midi ou practice, vide = déduit du format
+}
+
+func (req *UploadMediaRequest) ValidateForm() error {
+	if req.File == nil {
+		return errors.New("no file given")
+	}
+	if strings.TrimSpace(req.Label) == "" {
+		return errors.New("label is required")
+	}
+	switch req.Kind {
+	case "", "recording", "midi", "practice":
+		return nil
+	}
+	return errors.New("kind must be recording, midi or practice")
+}
+
+// UploadSourceRequest : ajout ou remplacement du fichier MusicXML, MuseScore ou ABC d'une partition existante
+type UploadSourceRequest struct {
+	File *multipart.FileHeader `form:"uploadFile"`
+}
 
+func (req *UploadSourceRequest) ValidateForm() error {
+	if req.File == nil {
+		return errors.New("no file given")
+	}
+	if req.File.Size > 10<<20 {
+		return errors.New("file too large")
+	}
+	if _, ok := score.FormatFromName(req.File.Filename); !ok {
+		return errors.New("only MusicXML (.musicxml, .xml, .mxl), MuseScore (.mscz) and ABC (.abc) files are allowed")
+	}
 	return nil
 }
+
+// CreateExcerptRequest : pièce d'un recueil, pages firstPage à lastPage du PDF du recueil.
+// Le compositeur vide est celui du recueil.
+type CreateExcerptRequest struct {
+	SheetName       string `form:"sheetName" json:"sheetName"`
+	Composer        string `form:"composer" json:"composer"`
+	FirstPage       int    `form:"firstPage" json:"firstPage"`
+	LastPage        int    `form:"lastPage" json:"lastPage"` // 0 = une seule page
+	ReleaseDate     string `form:"releaseDate" json:"releaseDate"`
+	Categories      string `form:"categories" json:"categories"`
+	Tags            string `form:"tags" json:"tags"`
+	InformationText string `form:"informationText" json:"informationText"`
+	Work            string `form:"work" json:"work"`
+
+	SheetMetadataRequest
+}
+
+func (req *CreateExcerptRequest) ValidateForm() error {
+	if strings.TrimSpace(req.SheetName) == "" {
+		return errors.New("sheet name is required")
+	}
+	if req.FirstPage < 1 {
+		return errors.New("firstPage must be at least 1")
+	}
+	if req.LastPage == 0 {
+		req.LastPage = req.FirstPage
+	}
+	if req.LastPage < req.FirstPage {
+		return errors.New("lastPage must not be before firstPage")
+	}
+	return req.SheetMetadataRequest.ValidateForm()
+}
@@ -0,0 +1,37 @@
+package forms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFillEmpty(t *testing.T) {
+	suggestion := UploadSuggestion{SheetName: "Nocturne", Composer: "Chopin", ReleaseDate: "2024-03-15", Tags: "romantic", InformationText: "Piano"}
+	tests := []struct {
+		name string
+		req  UploadRequest
+		want UploadRequest
+	}{
+		{
+			name: "empty form",
+			want: UploadRequest{SheetName: "Nocturne", Composer: "Chopin", ReleaseDate: "2024-03-15", Tags: "romantic", InformationText: "Piano"},
+		},
+		{
+			name: "user values are kept",
+			req:  UploadRequest{SheetName: "Nocturne Op. 9", Composer: "Frédéric Chopin", Tags: "night"},
+			want: UploadRequest{SheetName: "Nocturne Op. 9", Composer: "Frédéric Chopin", ReleaseDate: "2024-03-15", Tags: "night", InformationText: "Piano"},
+		},
+		{
+			name: "blank values are filled",
+			req:  UploadRequest{SheetName: "  ", InformationText: "\n"},
+			want: UploadRequest{SheetName: "Nocturne", Composer: "Chopin", ReleaseDate: "2024-03-15", Tags: "romantic", InformationText: "Piano"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.FillEmpty(suggestion)
+			assert.Equal(t, tt.want, tt.req)
+		})
+	}
+}
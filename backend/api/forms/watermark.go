@@ -0,0 +1,11 @@
+package forms
+
+// WatermarkRequest : politique de filigrane d'une partition, on, off ou inherit (selon ses catégories)
+type WatermarkRequest struct {
+	Watermark string `form:"watermark" json:"watermark"`
+}
+
+// WatermarkCategoryRequest : pied de page des partitions de la catégorie, vide = celui de la configuration
+type WatermarkCategoryRequest struct {
+	Footer string `form:"footer" json:"footer"`
+}
@@ -0,0 +1,58 @@
+package forms
+
+import (
+	"backend/api/catalogue"
+	"errors"
+	"strings"
+)
+
+type GetWorksPageRequest struct {
+	PaginatedRequest
+	Composer string `form:"composer"` // safe_name du compositeur
+}
+
+// CreateWorkRequest : nouvelle oeuvre (POST /api/work).
+// Avec sheet, titre, compositeur et numéro de catalogue non saisis sont repris de la partition,
+// qui devient la première édition de l'oeuvre.
+type CreateWorkRequest struct {
+	Title           string `form:"title" json:"title"`       // titre de référence, ex: Prelude in C major
+	Composer        string `form:"composer" json:"composer"` // nom ou alias du compositeur
+	CatalogueType   string `form:"catalogueType" json:"catalogueType"`
+	CatalogueNumber string `form:"catalogueNumber" json:"catalogueNumber"`
+	Sheet           string `form:"sheet" json:"sheet"` // safe_sheet_name d'une partition existante
+}
+
+func (req *CreateWorkRequest) ValidateForm() error {
+	if strings.TrimSpace(req.Sheet) == "" {
+		if strings.TrimSpace(req.Title) == "" {
+			return errors.New("title is required")
+		}
+		if strings.TrimSpace(req.Composer) == "" {
+			return errors.New("composer is required")
+		}
+	}
+	_, err := req.Catalogue()
+	return err
+}
+
+// Catalogue retourne le numéro de catalogue normalisé, vide s'il n'est pas saisi
+func (req *CreateWorkRequest) Catalogue() (catalogue.Number, error) {
+	metadata := SheetMetadataRequest{CatalogueType: &req.CatalogueType, CatalogueNumber: &req.CatalogueNumber}
+	number, err := metadata.catalogueNumber()
+	if err != nil || number == nil {
+		return catalogue.Number{}, err
+	}
+	return *number, nil
+}
+
+// LinkEditionRequest : rattachement d'une partition existante à une oeuvre
+type LinkEditionRequest struct {
+	Sheet string `form:"sheet" json:"sheet"` // safe_sheet_name de l'édition
+}
+
+func (req *LinkEditionRequest) ValidateForm() error {
+	if strings.TrimSpace(req.Sheet) == "" {
+		return errors.New("sheet is required")
+	}
+	return nil
+}
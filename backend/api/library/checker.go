@@ -0,0 +1,520 @@
+package library
+
+import (
+	"backend/api/media"
+	"backend/api/models"
+	"backend/api/pdf"
+	"backend/api/score"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Vérificateur de cohérence de la bibliothèque
+// La base de données et les répertoires
+//	<root>/sheets/uploaded-sheets/<safe_composer>/<safe_sheet_name>.pdf
+//	<root>/sheets/thumbnails[/<size>]/<safe_sheet_name>.<png|webp>
+//	<root>/sheets/pages/<safe_sheet_name>/
+//	<root>/composer[/<size>]/<safe_name>.<png|jpg|webp>
+// peuvent diverger (renommage raté, suppression partielle, thumbnail non générée ...).
+// Check() liste toutes les incohérences et, en mode fix, répare ce qui peut l'être.
+// Ce qui ne peut pas être réparé est déplacé dans <root>/sheets/quarantine/ plutôt que supprimé.
+
+// Issue décrit une incohérence trouvée par le vérificateur
+type Issue struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Detail string `json:"detail"`
+	Fixed  bool   `json:"fixed"`
+	Action string `json:"action,omitempty"`
+}
+
+// Report est le résultat d'une vérification
+type Report struct {
+	CheckedAt        time.Time `json:"checked_at"`
+	Fix              bool      `json:"fix"`
+	SheetsChecked    int       `json:"sheets_checked"`
+	ComposersChecked int       `json:"composers_checked"`
+	Issues           []Issue   `json:"issues"`
+}
+
+// Types d'incohérences
+const (
+	IssueMissingFile      = "missing_file"      // ligne Sheet sans PDF
+	IssueOrphanFile       = "orphan_file"       // PDF sans ligne Sheet
+	IssueMissingThumbnail = "missing_thumbnail" // Sheet sans thumbnail
+	IssueOrphanThumbnail  = "orphan_thumbnail"  // thumbnail sans Sheet
+	IssueOrphanPages      = "orphan_pages"      // pages rendues d'une Sheet supprimée
+	IssueEmptyComposer    = "empty_composer"    // Composer sans aucune Sheet
+	IssueUnknownComposer  = "unknown_composer"  // Sheet dont le SafeComposer n'existe pas
+	IssueOrphanPortrait   = "orphan_portrait"   // portrait sans Composer
+	IssueBadPdfUrl        = "bad_pdf_url"       // PdfUrl ne correspond pas au chemin réel
+	IssueRemotePortrait   = "remote_portrait"   // PortraitURL pointe vers un site externe
+	IssueMissingStructure = "missing_structure" // Sheet sans nombre de pages (uploadée avant l'analyse du PDF)
+	IssueMissingPart      = "missing_part"      // SheetPart sans PDF
+	IssueMissingMedia     = "missing_media"     // SheetMedia sans fichier
+	IssueMissingSource    = "missing_source"    // Sheet avec SourceFormat sans fichier MusicXML / MuseScore
+	IssueMissingParent    = "missing_parent"    // pièce d'un recueil dont le recueil n'existe plus
+)
+
+// Counts retourne le nombre d'incohérences par type
+func (r *Report) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, issue := range r.Issues {
+		counts[issue.Kind]++
+	}
+	return counts
+}
+
+func (r *Report) add(kind string, target string, detail string) *Issue {
+	r.Issues = append(r.Issues, Issue{Kind: kind, Target: target, Detail: detail})
+	return &r.Issues[len(r.Issues)-1]
+}
+
+// Répertoires de la bibliothèque à partir de la racine (config.Config().ConfigPath)
+func UploadDir(root string) string     { return path.Join(root, "sheets/uploaded-sheets") }
+func ThumbnailDir(root string) string  { return path.Join(root, "sheets/thumbnails") }
+func PageDir(root string) string       { return path.Join(root, "sheets/pages") }
+func QuarantineDir(root string) string { return path.Join(root, "sheets/quarantine") }
+func PortraitDir(root string) string   { return path.Join(root, "composer") }
+
+// Check parcourt la base de données et le système de fichiers sous root.
+// Si fix est vrai, les incohérences sont réparées ou mises en quarantaine.
+func Check(db *gorm.DB, root string, fix bool) (*Report, error) {
+	report := &Report{CheckedAt: time.Now(), Fix: fix, Issues: []Issue{}}
+
+	var sheets []models.Sheet
+	if err := db.Find(&sheets).Error; err != nil {
+		return nil, err
+	}
+	var composers []models.Composer
+	if err := db.Find(&composers).Error; err != nil {
+		return nil, err
+	}
+	var parts []models.SheetPart
+	if err := db.Find(&parts).Error; err != nil {
+		return nil, err
+	}
+	var mediaFiles []models.SheetMedia
+	if err := db.Find(&mediaFiles).Error; err != nil {
+		return nil, err
+	}
+	var revisions []models.SheetRevision
+	if err := db.Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	var workComposers []string
+	if err := db.Model(&models.Work{}).Distinct().Pluck("safe_composer", &workComposers).Error; err != nil {
+		return nil, err
+	}
+	report.SheetsChecked = len(sheets)
+	report.ComposersChecked = len(composers)
+
+	// Index des lignes connues
+	knownComposers := map[string]bool{}
+	for _, comp := range composers {
+		knownComposers[comp.SafeName] = true
+	}
+	knownFiles := map[string]bool{}  // "<safe_composer>/<safe_sheet_name>"
+	knownSheets := map[string]bool{} // "<safe_sheet_name>"
+	usedComposers := map[string]bool{}
+	sheetsByName := map[string]*models.Sheet{}
+	for i, sheet := range sheets {
+		knownFiles[sheet.SafeComposer+"/"+sheet.SafeSheetName] = true
+		knownSheets[sheet.SafeSheetName] = true
+		usedComposers[sheet.SafeComposer] = true
+		sheetsByName[sheet.SafeSheetName] = &sheets[i]
+	}
+	// Un composer sans partition mais avec une oeuvre reste utilisé
+	for _, safeComposer := range workComposers {
+		usedComposers[safeComposer] = true
+	}
+	// Parties : "<safe_composer>/<safe_sheet_name>/<safe_label>"
+	for _, part := range parts {
+		if sheet := sheetsByName[part.SheetSafeName]; sheet != nil {
+			knownFiles[sheet.SafeComposer+"/"+sheet.SafeSheetName+"/"+part.SafeLabel] = true
+		}
+	}
+	// Versions antérieures : "<safe_composer>/<safe_sheet_name>/revisions/<revision>"
+	for _, revision := range revisions {
+		if sheet := sheetsByName[revision.SheetSafeName]; sheet != nil {
+			knownFiles[sheet.SafeComposer+"/"+sheet.SafeSheetName+"/revisions/"+strconv.Itoa(revision.Revision)] = true
+		}
+	}
+
+	// 1️⃣ Fichiers présents sur le disque sans ligne en base
+	orphans, err := listOrphanFiles(root, knownFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2️⃣ Lignes Sheet
+	for i := range sheets {
+		sheet := &sheets[i]
+		// Une partition uploadée en MusicXML ou MuseScore seulement n'a ni PDF ni thumbnail,
+		// une pièce de recueil n'a pas de PDF propre
+		if sheet.IsExcerpt() {
+			if !knownSheets[sheet.ParentSheet] {
+				report.add(IssueMissingParent, sheet.SafeSheetName, fmt.Sprintf("anthology %q does not exist", sheet.ParentSheet))
+			}
+		} else if sheet.HasPdf() {
+			checkSheetFile(root, sheet, orphans, report, fix)
+			checkPdfUrl(db, sheet, report, fix)
+			checkThumbnail(root, sheet, report, fix)
+			checkStructure(db, root, sheet, report, fix)
+		}
+		checkSource(root, sheet, report)
+
+		if !knownComposers[sheet.SafeComposer] {
+			issue := report.add(IssueUnknownComposer, sheet.SafeSheetName,
+				fmt.Sprintf("composer %q does not exist", sheet.SafeComposer))
+			if fix {
+				if err := createMissingComposer(db, root, sheet); err != nil {
+					issue.Action = err.Error()
+				} else {
+					issue.Fixed = true
+					issue.Action = "composer created"
+					knownComposers[sheet.SafeComposer] = true
+				}
+			}
+		}
+	}
+
+	// Parties dont le PDF est introuvable : signalées, pas de réparation automatique
+	for _, part := range parts {
+		sheet := sheetsByName[part.SheetSafeName]
+		if sheet == nil {
+			report.add(IssueMissingPart, part.SheetSafeName+"/"+part.SafeLabel, "part of an unknown sheet")
+			continue
+		}
+		partFile := path.Join(UploadDir(root), sheet.SafeComposer, sheet.SafeSheetName, part.SafeLabel+".pdf")
+		if _, err := os.Stat(partFile); err != nil {
+			report.add(IssueMissingPart, sheet.SafeSheetName+"/"+part.SafeLabel, "missing "+path.Join(sheet.SafeComposer, sheet.SafeSheetName, part.SafeLabel+".pdf"))
+		}
+	}
+
+	// Médias dont le fichier est introuvable : signalés également
+	for i := range mediaFiles {
+		item := &mediaFiles[i]
+		sheet := sheetsByName[item.SheetSafeName]
+		if sheet == nil {
+			report.add(IssueMissingMedia, item.SheetSafeName+"/"+item.SafeLabel, "media of an unknown sheet")
+			continue
+		}
+		rel := path.Join(sheet.SafeComposer, sheet.SafeSheetName, "media", item.SafeLabel+media.Extensions[item.Format])
+		if _, err := os.Stat(path.Join(UploadDir(root), rel)); err != nil {
+			report.add(IssueMissingMedia, sheet.SafeSheetName+"/"+item.SafeLabel, "missing "+rel)
+		}
+	}
+
+	for _, orphan := range orphans {
+		if orphan.claimed {
+			continue
+		}
+		issue := report.add(IssueOrphanFile, orphan.rel, "file has no matching sheet")
+		if fix {
+			quarantine(root, orphan.abs, orphan.rel, issue)
+		}
+	}
+
+	// 3️⃣ Thumbnails, miniatures redimensionnées et pages rendues sans Sheet
+	for _, orphan := range listOrphanCache(ThumbnailDir(root), knownSheets) {
+		issue := report.add(IssueOrphanThumbnail, orphan, "thumbnail has no matching sheet")
+		if fix {
+			quarantine(root, path.Join(ThumbnailDir(root), orphan), path.Join("thumbnails", orphan), issue)
+		}
+	}
+	pageDirs, _ := os.ReadDir(PageDir(root))
+	for _, entry := range pageDirs {
+		if !entry.IsDir() || knownSheets[entry.Name()] {
+			continue
+		}
+		issue := report.add(IssueOrphanPages, entry.Name(), "rendered pages have no matching sheet")
+		if fix {
+			quarantine(root, path.Join(PageDir(root), entry.Name()), path.Join("pages", entry.Name()), issue)
+		}
+	}
+
+	// 4️⃣ Composers sans Sheet ni Work, supprimés avec leurs alias comme par models.DeleteComposer
+	for _, comp := range composers {
+		if usedComposers[comp.SafeName] {
+			continue
+		}
+		issue := report.add(IssueEmptyComposer, comp.SafeName, "composer has no sheets or works")
+		if fix {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Where("composer_safe_name = ?", comp.SafeName).Delete(&models.ComposerAlias{}).Error; err != nil {
+					return err
+				}
+				return tx.Where("safe_name = ?", comp.SafeName).Delete(&models.Composer{}).Error
+			})
+			if err != nil {
+				issue.Action = err.Error()
+				continue
+			}
+			delete(knownComposers, comp.SafeName)
+			os.Remove(path.Join(UploadDir(root), comp.SafeName)) // uniquement si vide
+			issue.Fixed = true
+			issue.Action = "composer deleted"
+		}
+	}
+
+	// 5️⃣ Portraits sans Composer, tailles comprises (composer/<taille>/<safe_name>.<format>)
+	for _, orphan := range listOrphanCache(PortraitDir(root), knownComposers) {
+		issue := report.add(IssueOrphanPortrait, orphan, "portrait has no matching composer")
+		if fix {
+			quarantine(root, path.Join(PortraitDir(root), orphan), path.Join("composer", orphan), issue)
+		}
+	}
+
+	// 6️⃣ Portraits hébergés sur un site externe : téléchargés une fois puis servis localement
+	for _, comp := range composers {
+		if !knownComposers[comp.SafeName] || !utils.IsRemoteURL(comp.PortraitURL) {
+			continue
+		}
+		issue := report.add(IssueRemotePortrait, comp.SafeName, fmt.Sprintf("portrait is hosted at %s", comp.PortraitURL))
+		if fix {
+			if err := utils.DownloadPortrait(comp.PortraitURL, PortraitDir(root), comp.SafeName); err != nil {
+				issue.Action = err.Error()
+				continue
+			}
+			localURL := "/api/composer/portrait/" + comp.SafeName
+			if err := db.Model(&models.Composer{}).Where("safe_name = ?", comp.SafeName).Update("portrait_url", localURL).Error; err != nil {
+				issue.Action = err.Error()
+				continue
+			}
+			issue.Fixed = true
+			issue.Action = "portrait downloaded"
+		}
+	}
+
+	return report, nil
+}
+
+// listOrphanCache retourne les fichiers sous dir, sous-dossiers de taille compris, dont le nom
+// sans extension n'est pas dans known. Les chemins sont relatifs à dir.
+func listOrphanCache(dir string, known map[string]bool) []string {
+	var orphans []string
+	filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if known[strings.TrimSuffix(d.Name(), path.Ext(d.Name()))] {
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, p); err == nil {
+			orphans = append(orphans, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return orphans
+}
+
+type orphanFile struct {
+	abs     string
+	rel     string // "<safe_composer>/<file>.pdf"
+	name    string // "<file>" sans extension
+	claimed bool   // réutilisé pour réparer une Sheet
+}
+
+func listOrphanFiles(root string, knownFiles map[string]bool) ([]*orphanFile, error) {
+	var orphans []*orphanFile
+	uploadDir := UploadDir(root)
+
+	err := filepath.WalkDir(uploadDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".pdf") {
+			return nil
+		}
+		rel, err := filepath.Rel(uploadDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if knownFiles[strings.TrimSuffix(rel, ".pdf")] {
+			return nil
+		}
+		orphans = append(orphans, &orphanFile{
+			abs:  p,
+			rel:  rel,
+			name: strings.TrimSuffix(d.Name(), ".pdf"),
+		})
+		return nil
+	})
+
+	return orphans, err
+}
+
+// Vérifie que le PDF de la Sheet existe.
+// En mode fix, un fichier orphelin du même nom (par ex. resté dans l'ancien dossier d'un composer renommé) est remis en place.
+func checkSheetFile(root string, sheet *models.Sheet, orphans []*orphanFile, report *Report, fix bool) {
+	expected := path.Join(UploadDir(root), sheet.SafeComposer, sheet.SafeSheetName+".pdf")
+	if _, err := os.Stat(expected); err == nil {
+		return
+	}
+
+	issue := report.add(IssueMissingFile, sheet.SafeSheetName, "missing "+path.Join(sheet.SafeComposer, sheet.SafeSheetName+".pdf"))
+	if !fix {
+		return
+	}
+
+	for _, orphan := range orphans {
+		if orphan.claimed || orphan.name != sheet.SafeSheetName {
+			continue
+		}
+		if err := utils.CreateDir(path.Dir(expected)); err != nil {
+			issue.Action = err.Error()
+			return
+		}
+		if err := os.Rename(orphan.abs, expected); err != nil {
+			issue.Action = err.Error()
+			return
+		}
+		orphan.claimed = true
+		issue.Fixed = true
+		issue.Action = "relocated from " + orphan.rel
+		return
+	}
+	issue.Action = "no candidate file found"
+}
+
+func checkPdfUrl(db *gorm.DB, sheet *models.Sheet, report *Report, fix bool) {
+	expected := "sheet/pdf/" + sheet.SafeComposer + "/" + sheet.SafeSheetName
+	if sheet.PdfUrl == expected {
+		return
+	}
+
+	issue := report.add(IssueBadPdfUrl, sheet.SafeSheetName, fmt.Sprintf("pdf_url is %q, expected %q", sheet.PdfUrl, expected))
+	if !fix {
+		return
+	}
+	err := db.Model(&models.Sheet{}).Where("safe_sheet_name = ?", sheet.SafeSheetName).Update("pdf_url", expected).Error
+	if err != nil {
+		issue.Action = err.Error()
+		return
+	}
+	sheet.PdfUrl = expected
+	issue.Fixed = true
+	issue.Action = "pdf_url updated"
+}
+
+// Vérifie la thumbnail. En mode fix elle est régénérée par le service pdf2png.
+func checkThumbnail(root string, sheet *models.Sheet, report *Report, fix bool) {
+	thumbnail := path.Join(ThumbnailDir(root), sheet.SafeSheetName+".png")
+	if _, err := os.Stat(thumbnail); err == nil {
+		return
+	}
+
+	issue := report.add(IssueMissingThumbnail, sheet.SafeSheetName, "missing thumbnail")
+	if !fix {
+		return
+	}
+	pdfPath := path.Join(UploadDir(root), sheet.SafeComposer, sheet.SafeSheetName+".pdf")
+	if _, err := os.Stat(pdfPath); err != nil {
+		issue.Action = "no pdf to render"
+		return
+	}
+	utils.CreateDir(ThumbnailDir(root))
+	utils.RequestToPdfToImage(pdfPath, sheet.SafeSheetName)
+	if _, err := os.Stat(thumbnail); err != nil {
+		issue.Action = "thumbnail service failed"
+		return
+	}
+	issue.Fixed = true
+	issue.Action = "thumbnail regenerated"
+}
+
+func checkStructure(db *gorm.DB, root string, sheet *models.Sheet, report *Report, fix bool) {
+	if sheet.PageCount > 0 || sheet.Encrypted {
+		return
+	}
+	f, err := os.Open(path.Join(UploadDir(root), sheet.SafeComposer, sheet.SafeSheetName+".pdf"))
+	if err != nil {
+		return // déjà signalé comme missing_file
+	}
+	defer f.Close()
+
+	issue := report.add(IssueMissingStructure, sheet.SafeSheetName, "page count and PDF structure unknown")
+	if !fix {
+		return
+	}
+	structure, err := pdf.Analyze(f)
+	if err != nil {
+		issue.Action = err.Error()
+		return
+	}
+	sheet.SetStructure(structure)
+	err = db.Model(&models.Sheet{}).Where("safe_sheet_name = ?", sheet.SafeSheetName).Updates(map[string]interface{}{
+		"page_count":  sheet.PageCount,
+		"page_width":  sheet.PageWidth,
+		"page_height": sheet.PageHeight,
+		"orientation": sheet.Orientation,
+		"file_size":   sheet.FileSize,
+		"encrypted":   sheet.Encrypted,
+		"has_text":    sheet.HasText,
+	}).Error
+	if err != nil {
+		issue.Action = err.Error()
+		return
+	}
+	issue.Fixed = true
+	issue.Action = "structure analyzed"
+}
+
+// Vérifie que le fichier source déclaré existe. Signalé seulement : il ne peut pas être reconstruit.
+func checkSource(root string, sheet *models.Sheet, report *Report) {
+	if sheet.SourceFormat == "" {
+		return
+	}
+	rel := path.Join(sheet.SafeComposer, sheet.SafeSheetName, sheet.SafeSheetName+score.Extensions[sheet.SourceFormat])
+	if _, err := os.Stat(path.Join(UploadDir(root), rel)); err != nil {
+		report.add(IssueMissingSource, sheet.SafeSheetName, "missing "+rel)
+	}
+}
+
+func createMissingComposer(db *gorm.DB, root string, sheet *models.Sheet) error {
+	name := strings.TrimSpace(sheet.Composer)
+	if name == "" {
+		name = sheet.SafeComposer
+	}
+	comp := models.Composer{
+		Name:     name,
+		SafeName: sheet.SafeComposer,
+		Epoch:    "Unknown",
+	}
+	comp.Prepare()
+	if _, err := comp.SaveComposer(db); err != nil {
+		return err
+	}
+	return utils.CreateDir(path.Join(UploadDir(root), sheet.SafeComposer))
+}
+
+// Déplace un fichier dans <root>/sheets/quarantine/<rel>
+func quarantine(root string, abs string, rel string, issue *Issue) {
+	dest := path.Join(QuarantineDir(root), rel)
+	if err := os.MkdirAll(path.Dir(dest), os.ModePerm); err != nil {
+		issue.Action = err.Error()
+		return
+	}
+	if err := os.Rename(abs, dest); err != nil {
+		issue.Action = err.Error()
+		return
+	}
+	log.Printf("library: %s moved to quarantine\n", rel)
+	issue.Fixed = true
+	issue.Action = "moved to quarantine"
+}
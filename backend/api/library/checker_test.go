@@ -0,0 +1,196 @@
+package library
+
+import (
+	"backend/api/models"
+	"backend/api/utils"
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupLibrary(t *testing.T) (*gorm.DB, string) {
+	root := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(path.Join(root, "database.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Sheet{}, &models.Composer{}, &models.ComposerAlias{}, &models.Work{}, &models.SheetPart{}, &models.SheetMedia{}, &models.SheetRevision{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{UploadDir(root) + "/chopin", ThumbnailDir(root), PortraitDir(root)} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db, root
+}
+
+var (
+	testPDF     []byte
+	testPDFOnce sync.Once
+)
+
+// writeFile écrit un PDF valide d'une page
+func writeFile(t *testing.T, p string) {
+	testPDFOnce.Do(func() {
+		var img, out bytes.Buffer
+		png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 60, 80)))
+		if err := api.ImportImages(nil, &out, []io.Reader{&img}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		testPDF = out.Bytes()
+	})
+	if err := os.WriteFile(p, testPDF, 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckReportsAndFixes(t *testing.T) {
+	db, root := setupLibrary(t)
+
+	db.Create(&models.Composer{SafeName: "chopin", Name: "Chopin"})
+	db.Create(&models.Composer{SafeName: "liszt", Name: "Liszt"})
+	db.Create(&models.ComposerAlias{ComposerSafeName: "liszt", Alias: "Franz Liszt", SafeAlias: "franz-liszt"})
+	// Brahms n'a pas de partition mais une oeuvre : il est conservé
+	db.Create(&models.Composer{SafeName: "brahms", Name: "Brahms"})
+	db.Create(&models.Work{SafeName: "brahms-intermezzo", Title: "Intermezzo", SafeComposer: "brahms", Composer: "Brahms"})
+	db.Create(&models.Sheet{SafeSheetName: "etude", SafeComposer: "chopin", Composer: "Chopin", PdfUrl: "sheet/pdf/chopin/etude"})
+	db.Create(&models.Sheet{SafeSheetName: "ballade", SafeComposer: "chopin", Composer: "Chopin", PdfUrl: "sheet/pdf/chopin/ballade"})
+	db.Create(&models.Sheet{SafeSheetName: "sonata", SafeComposer: "mozart", Composer: "Mozart", PdfUrl: "sheet/pdf/mozart/sonata"})
+	// Pièces de recueil : sans PDF propre, seul le recueil manquant est signalé
+	db.Create(&models.Sheet{SafeSheetName: "etude-no-1", SafeComposer: "chopin", Composer: "Chopin", PdfUrl: "sheet/pdf/chopin/etude-no-1", ParentSheet: "etude", FirstPage: 1, LastPage: 1})
+	db.Create(&models.Sheet{SafeSheetName: "prelude", SafeComposer: "chopin", Composer: "Chopin", PdfUrl: "sheet/pdf/chopin/prelude", ParentSheet: "preludes", FirstPage: 1, LastPage: 2})
+
+	writeFile(t, path.Join(UploadDir(root), "chopin", "etude.pdf"))
+	writeFile(t, path.Join(ThumbnailDir(root), "etude.png"))
+	writeFile(t, path.Join(ThumbnailDir(root), "ballade.png"))
+	writeFile(t, path.Join(ThumbnailDir(root), "sonata.png"))
+	// ballade.pdf est resté dans un autre dossier, stray.pdf n'a pas de ligne
+	os.MkdirAll(path.Join(UploadDir(root), "old"), os.ModePerm)
+	writeFile(t, path.Join(UploadDir(root), "old", "ballade.pdf"))
+	writeFile(t, path.Join(UploadDir(root), "chopin", "stray.pdf"))
+
+	report, err := Check(db, root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := report.Counts()
+	assert.Equal(t, 2, counts[IssueMissingFile])
+	assert.Equal(t, 2, counts[IssueOrphanFile])
+	assert.Equal(t, 1, counts[IssueEmptyComposer])
+	assert.Equal(t, 1, counts[IssueUnknownComposer])
+	assert.Equal(t, 1, counts[IssueMissingStructure])
+	assert.Equal(t, 1, counts[IssueMissingParent])
+	assert.FileExists(t, path.Join(UploadDir(root), "chopin", "stray.pdf"))
+
+	report, err = Check(db, root, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.FileExists(t, path.Join(UploadDir(root), "chopin", "ballade.pdf"))
+	assert.FileExists(t, path.Join(QuarantineDir(root), "chopin", "stray.pdf"))
+	assert.NoFileExists(t, path.Join(UploadDir(root), "chopin", "stray.pdf"))
+
+	var composer models.Composer
+	assert.NoError(t, db.Where("safe_name = ?", "mozart").Take(&composer).Error)
+	assert.Error(t, db.Where("safe_name = ?", "liszt").Take(&composer).Error)
+	var brahms models.Composer
+	assert.NoError(t, db.Where("safe_name = ?", "brahms").Take(&brahms).Error)
+	var aliases int64
+	assert.NoError(t, db.Model(&models.ComposerAlias{}).Where("composer_safe_name = ?", "liszt").Count(&aliases).Error)
+	assert.Zero(t, aliases, "aliases are deleted with the composer")
+
+	// Seul le PDF de sonata reste introuvable
+	report, err = Check(db, root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]int{IssueMissingFile: 1, IssueMissingParent: 1}, report.Counts())
+}
+
+func TestCheckDownloadsRemotePortraits(t *testing.T) {
+	db, root := setupLibrary(t)
+	// Le serveur de test écoute sur 127.0.0.1, refusé en production
+	previous := utils.PortraitAddressAllowed
+	utils.PortraitAddressAllowed = func(net.IP) bool { return true }
+	t.Cleanup(func() { utils.PortraitAddressAllowed = previous })
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 80, 80)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	db.Create(&models.Composer{SafeName: "chopin", Name: "Chopin", PortraitURL: srv.URL + "/chopin.png"})
+	db.Create(&models.Sheet{SafeSheetName: "etude", SafeComposer: "chopin", Composer: "Chopin", PdfUrl: "sheet/pdf/chopin/etude"})
+	writeFile(t, path.Join(UploadDir(root), "chopin", "etude.pdf"))
+	writeFile(t, path.Join(ThumbnailDir(root), "etude.png"))
+
+	report, err := Check(db, root, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, report.Counts()[IssueRemotePortrait])
+	for _, issue := range report.Issues {
+		assert.True(t, issue.Fixed, issue.Kind)
+	}
+	assert.FileExists(t, path.Join(PortraitDir(root), "chopin.png"))
+
+	var comp models.Composer
+	db.Where("safe_name = ?", "chopin").Take(&comp)
+	assert.Equal(t, "/api/composer/portrait/chopin", comp.PortraitURL)
+}
+
+func TestCheckOrphanCaches(t *testing.T) {
+	db, root := setupLibrary(t)
+
+	db.Create(&models.Composer{SafeName: "chopin", Name: "Chopin"})
+	db.Create(&models.Sheet{SafeSheetName: "etude", SafeComposer: "chopin", Composer: "Chopin", PdfUrl: "sheet/pdf/chopin/etude", PageCount: 1})
+	writeFile(t, path.Join(UploadDir(root), "chopin", "etude.pdf"))
+	writeFile(t, path.Join(ThumbnailDir(root), "etude.png"))
+
+	// Miniatures, pages rendues et portraits de toutes tailles : seuls ceux de ballade et liszt sont orphelins
+	for _, p := range []string{
+		path.Join(ThumbnailDir(root), "240", "etude.webp"),
+		path.Join(ThumbnailDir(root), "240", "ballade.webp"),
+		path.Join(ThumbnailDir(root), "ballade.png"),
+		path.Join(PageDir(root), "etude", "1.png"),
+		path.Join(PageDir(root), "ballade", "1.png"),
+		path.Join(PortraitDir(root), "chopin.png"),
+		path.Join(PortraitDir(root), "small", "chopin.png"),
+		path.Join(PortraitDir(root), "small", "liszt.png"),
+	} {
+		if err := os.MkdirAll(path.Dir(p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, p)
+	}
+
+	report, err := Check(db, root, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]int{IssueOrphanThumbnail: 2, IssueOrphanPages: 1, IssueOrphanPortrait: 1}, report.Counts())
+	assert.FileExists(t, path.Join(QuarantineDir(root), "thumbnails", "240", "ballade.webp"))
+	assert.FileExists(t, path.Join(QuarantineDir(root), "thumbnails", "ballade.png"))
+	assert.FileExists(t, path.Join(QuarantineDir(root), "pages", "ballade", "1.png"))
+	assert.FileExists(t, path.Join(QuarantineDir(root), "composer", "small", "liszt.png"))
+	assert.FileExists(t, path.Join(ThumbnailDir(root), "240", "etude.webp"))
+	assert.FileExists(t, path.Join(PortraitDir(root), "small", "chopin.png"))
+	assert.NoDirExists(t, path.Join(PageDir(root), "ballade"))
+}
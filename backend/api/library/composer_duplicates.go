@@ -0,0 +1,60 @@
+package library
+
+import (
+	"backend/api/models"
+	"backend/api/utils"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Seuil à partir duquel deux compositeurs sont signalés comme doublons probables
+const ComposerSimilarityThreshold = 0.75
+
+// ComposerDuplicate : paire de compositeurs probablement identiques
+type ComposerDuplicate struct {
+	A      *models.Composer `json:"a"`
+	B      *models.Composer `json:"b"`
+	Score  float64          `json:"score"`
+	Reason string           `json:"reason"`
+}
+
+// ComposerDuplicates compare tous les compositeurs deux à deux (noms et alias translittérés)
+// et retourne les paires dont la similarité dépasse ComposerSimilarityThreshold, les plus probables en premier.
+// Ces paires sont des candidates pour POST /api/composer/:composerName/merge.
+func ComposerDuplicates(db *gorm.DB) ([]ComposerDuplicate, error) {
+	var composers []*models.Composer
+	if err := db.Preload("Aliases").Order("safe_name").Find(&composers).Error; err != nil {
+		return nil, err
+	}
+
+	names := make([][]string, len(composers))
+	for i, comp := range composers {
+		names[i] = []string{comp.Name}
+		for _, alias := range comp.Aliases {
+			names[i] = append(names[i], alias.Alias)
+		}
+	}
+
+	duplicates := []ComposerDuplicate{}
+	for i := 0; i < len(composers); i++ {
+		for j := i + 1; j < len(composers); j++ {
+			best, reason := 0.0, ""
+			for _, a := range names[i] {
+				for _, b := range names[j] {
+					if score, why := utils.NameSimilarity(a, b); score > best {
+						best, reason = score, why
+					}
+				}
+			}
+			if best >= ComposerSimilarityThreshold {
+				duplicates = append(duplicates, ComposerDuplicate{A: composers[i], B: composers[j], Score: best, Reason: reason})
+			}
+		}
+	}
+
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Score > duplicates[j].Score
+	})
+	return duplicates, nil
+}
@@ -0,0 +1,66 @@
+package library
+
+import (
+	"backend/api/models"
+	"backend/api/utils"
+	"log"
+	"path"
+
+	"gorm.io/gorm"
+)
+
+// DuplicateGroup regroupe les partitions dont le PDF a le même contenu
+type DuplicateGroup struct {
+	FileHash string          `json:"file_hash"`
+	Sheets   []*models.Sheet `json:"sheets"`
+}
+
+// Duplicates liste les partitions partageant le même hash SHA-256.
+// Les partitions uploadées avant l'ajout de FileHash n'ont de hash qu'une fois BackfillHashes passé au démarrage.
+func Duplicates(db *gorm.DB) ([]DuplicateGroup, error) {
+	var hashes []string
+	err := db.Model(&models.Sheet{}).
+		Select("file_hash").
+		Where("file_hash <> ''").
+		Group("file_hash").
+		Having("COUNT(*) > 1").
+		Pluck("file_hash", &hashes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []DuplicateGroup{}
+	for _, hash := range hashes {
+		var sheets []*models.Sheet
+		if err := db.Where("file_hash = ?", hash).Order("created_at").Find(&sheets).Error; err != nil {
+			return nil, err
+		}
+		groups = append(groups, DuplicateGroup{FileHash: hash, Sheets: sheets})
+	}
+	return groups, nil
+}
+
+// BackfillHashes calcule et enregistre le hash des PDF uploadés avant l'ajout de FileHash.
+// Les partitions sans PDF propre (source seule, pièce de recueil) sont ignorées.
+func BackfillHashes(db *gorm.DB, root string) (int, error) {
+	var sheets []models.Sheet
+	err := db.Where("(file_hash = '' OR file_hash IS NULL) AND pdf_url <> '' AND parent_sheet = ''").Find(&sheets).Error
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, sheet := range sheets {
+		hash, err := utils.HashFile(path.Join(UploadDir(root), sheet.SafeComposer, sheet.SafeSheetName+".pdf"))
+		if err != nil {
+			log.Printf("library: unable to hash %s: %v\n", sheet.SafeSheetName, err)
+			continue
+		}
+		err = db.Model(&models.Sheet{}).Where("safe_sheet_name = ?", sheet.SafeSheetName).Update("file_hash", hash).Error
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
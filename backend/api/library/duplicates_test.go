@@ -0,0 +1,39 @@
+package library
+
+import (
+	"backend/api/models"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicatesBackfillsHashes(t *testing.T) {
+	db, root := setupLibrary(t)
+
+	db.Create(&models.Sheet{SafeSheetName: "etude", SafeComposer: "chopin", PdfUrl: "sheet/pdf/chopin/etude"})
+	db.Create(&models.Sheet{SafeSheetName: "etude-copy", SafeComposer: "chopin", PdfUrl: "sheet/pdf/chopin/etude-copy"})
+	db.Create(&models.Sheet{SafeSheetName: "missing", SafeComposer: "chopin", PdfUrl: "sheet/pdf/chopin/missing"})
+	writeFile(t, path.Join(UploadDir(root), "chopin", "etude.pdf"))
+	writeFile(t, path.Join(UploadDir(root), "chopin", "etude-copy.pdf"))
+
+	groups, err := Duplicates(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, groups, "listing the duplicates does not hash the files")
+
+	updated, err := BackfillHashes(db, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, updated)
+	groups, err = Duplicates(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, groups, 1) {
+		assert.Len(t, groups[0].Sheets, 2)
+		assert.Len(t, groups[0].FileHash, 64)
+	}
+}
@@ -0,0 +1,186 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Débits MPEG en kbit/s, indexés par [version MPEG-1 / MPEG-2(.5)][couche I, II, III][index]
+var mp3Bitrates = [2][3][16]int{
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	},
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+}
+
+// Fréquences d'échantillonnage indexées par version (2.5, réservée, 2, 1)
+var mp3SampleRates = [4][3]int{
+	{11025, 12000, 8000},
+	{0, 0, 0},
+	{22050, 24000, 16000},
+	{44100, 48000, 32000},
+}
+
+type mp3Frame struct {
+	length     int
+	samples    int
+	sampleRate int
+}
+
+func parseMP3Header(h []byte) (mp3Frame, bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	version := int(h[1]>>3) & 3
+	layer := 4 - int(h[1]>>1)&3 // 1, 2 ou 3
+	bitrateIndex := int(h[2] >> 4)
+	rateIndex := int(h[2]>>2) & 3
+	padding := int(h[2]>>1) & 1
+	if version == 1 || layer == 4 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mp3Frame{}, false
+	}
+
+	table := 1
+	if version == 3 {
+		table = 0
+	}
+	bitrate := mp3Bitrates[table][layer-1][bitrateIndex] * 1000
+	sampleRate := mp3SampleRates[version][rateIndex]
+
+	frame := mp3Frame{sampleRate: sampleRate}
+	switch {
+	case layer == 1:
+		frame.samples = 384
+		frame.length = (12*bitrate/sampleRate + padding) * 4
+	case layer == 3 && version != 3:
+		frame.samples = 576
+		frame.length = 72*bitrate/sampleRate + padding
+	default:
+		frame.samples = 1152
+		frame.length = 144*bitrate/sampleRate + padding
+	}
+	return frame, frame.length > 4
+}
+
+// mp3Duration additionne la durée de chaque trame, ce qui reste exact avec un débit variable
+func mp3Duration(r io.Reader) (float64, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	// Balise ID3v2 en tête de fichier
+	if head, err := br.Peek(10); err == nil && bytes.Equal(head[:3], []byte("ID3")) {
+		size := int(head[6]&0x7F)<<21 | int(head[7]&0x7F)<<14 | int(head[8]&0x7F)<<7 | int(head[9]&0x7F)
+		if head[5]&0x10 != 0 {
+			size += 10 // pied de balise
+		}
+		if _, err := br.Discard(10 + size); err != nil {
+			return 0, ErrCorruptMedia
+		}
+	}
+
+	var seconds float64
+	frames := 0
+	for {
+		h, err := br.Peek(4)
+		if err != nil {
+			break
+		}
+		if bytes.Equal(h[:3], []byte("TAG")) {
+			break // ID3v1 en fin de fichier
+		}
+		frame, ok := parseMP3Header(h)
+		if !ok {
+			if frames == 0 {
+				return 0, ErrCorruptMedia
+			}
+			br.Discard(1) // resynchronisation
+			continue
+		}
+		seconds += float64(frame.samples) / float64(frame.sampleRate)
+		frames++
+		if _, err := br.Discard(frame.length); err != nil {
+			break
+		}
+	}
+	if frames == 0 {
+		return 0, ErrCorruptMedia
+	}
+	return seconds, nil
+}
+
+// oggDuration lit la fréquence dans l'en-tête d'identification, puis la position de la dernière page
+func oggDuration(rs io.ReadSeeker, size int64) (string, float64, error) {
+	first := make([]byte, 27+255+64)
+	n, _ := io.ReadFull(rs, first)
+	first = first[:n]
+	if len(first) < 28 {
+		return "", 0, ErrCorruptMedia
+	}
+	data := 27 + int(first[26])
+	if len(first) < data+19 {
+		return "", 0, ErrCorruptMedia
+	}
+	packet := first[data:]
+
+	var codec string
+	var rate float64
+	var preSkip int64
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x01vorbis")):
+		codec = "vorbis"
+		rate = float64(binary.LittleEndian.Uint32(packet[12:16]))
+	case bytes.HasPrefix(packet, []byte("OpusHead")):
+		codec = "opus"
+		rate = 48000 // la position Opus est toujours exprimée à 48 kHz
+		preSkip = int64(binary.LittleEndian.Uint16(packet[10:12]))
+	default:
+		return "", 0, ErrUnsupportedMedia
+	}
+	if rate == 0 {
+		return "", 0, ErrCorruptMedia
+	}
+
+	// Dernière page : recherche de "OggS" dans la fin du fichier
+	tail := min(size, 64*1024)
+	if _, err := rs.Seek(size-tail, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	buf := make([]byte, tail)
+	if _, err := io.ReadFull(rs, buf); err != nil {
+		return "", 0, err
+	}
+	last := bytes.LastIndex(buf, []byte("OggS"))
+	if last < 0 || len(buf) < last+14 {
+		return "", 0, ErrCorruptMedia
+	}
+	granule := int64(binary.LittleEndian.Uint64(buf[last+6 : last+14]))
+	if granule < preSkip {
+		return codec, 0, nil
+	}
+	return codec, float64(granule-preSkip) / rate, nil
+}
+
+// flacDuration lit le nombre d'échantillons et la fréquence du bloc STREAMINFO
+func flacDuration(r io.Reader) (float64, error) {
+	head := make([]byte, 4+4+34)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, ErrCorruptMedia
+	}
+	if head[4]&0x7F != 0 { // le premier bloc doit être STREAMINFO
+		return 0, ErrCorruptMedia
+	}
+	info := head[8:]
+	sampleRate := int64(info[10])<<12 | int64(info[11])<<4 | int64(info[12])>>4
+	total := int64(info[13]&0x0F)<<32 | int64(info[14])<<24 | int64(info[15])<<16 | int64(info[16])<<8 | int64(info[17])
+	if sampleRate == 0 {
+		return 0, ErrCorruptMedia
+	}
+	return float64(total) / float64(sampleRate), nil
+}
@@ -0,0 +1,152 @@
+package media
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+// Taille maximale d'un fichier MIDI lu en mémoire
+const maxMidiSize = 16 << 20
+
+type tempoChange struct {
+	tick  int64
+	tempo int64 // microsecondes par noire
+}
+
+// midiDuration parcourt toutes les pistes : la durée va jusqu'au dernier événement,
+// en tenant compte des changements de tempo (meta-événement FF 51)
+func midiDuration(r io.Reader) (float64, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxMidiSize+1))
+	if err != nil {
+		return 0, err
+	}
+	if len(data) > maxMidiSize || len(data) < 14 || binary.BigEndian.Uint32(data[4:8]) < 6 {
+		return 0, ErrCorruptMedia
+	}
+	division := binary.BigEndian.Uint16(data[12:14])
+	pos := 8 + int(binary.BigEndian.Uint32(data[4:8]))
+
+	var tempos []tempoChange
+	var end int64
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		start := pos + 8
+		if length < 0 || start+length > len(data) {
+			return 0, ErrCorruptMedia
+		}
+		if string(data[pos:pos+4]) == "MTrk" {
+			last, changes, err := parseMidiTrack(data[start : start+length])
+			if err != nil {
+				return 0, err
+			}
+			end = max(end, last)
+			tempos = append(tempos, changes...)
+		}
+		pos = start + length
+	}
+
+	// Division SMPTE : durée fixe par tick, le tempo est ignoré
+	if division&0x8000 != 0 {
+		fps := -int(int8(division >> 8))
+		ticksPerFrame := int(division & 0xFF)
+		if fps <= 0 || ticksPerFrame == 0 {
+			return 0, ErrCorruptMedia
+		}
+		return float64(end) / float64(fps*ticksPerFrame), nil
+	}
+	if division == 0 {
+		return 0, ErrCorruptMedia
+	}
+
+	sort.SliceStable(tempos, func(i, j int) bool { return tempos[i].tick < tempos[j].tick })
+	var micros float64
+	tick, tempo := int64(0), int64(500000) // 120 noires par minute par défaut
+	for _, change := range tempos {
+		if change.tick > end {
+			break
+		}
+		micros += float64(change.tick-tick) * float64(tempo) / float64(division)
+		tick, tempo = change.tick, change.tempo
+	}
+	micros += float64(end-tick) * float64(tempo) / float64(division)
+	return micros / 1e6, nil
+}
+
+// parseMidiTrack retourne le tick du dernier événement de la piste et ses changements de tempo
+func parseMidiTrack(track []byte) (int64, []tempoChange, error) {
+	var tick int64
+	var tempos []tempoChange
+	var running byte
+	pos := 0
+
+	readVarLen := func() (int64, bool) {
+		var v int64
+		for i := 0; i < 4; i++ {
+			if pos >= len(track) {
+				return 0, false
+			}
+			b := track[pos]
+			pos++
+			v = v<<7 | int64(b&0x7F)
+			if b&0x80 == 0 {
+				return v, true
+			}
+		}
+		return 0, false
+	}
+
+	for pos < len(track) {
+		delta, ok := readVarLen()
+		if !ok || pos >= len(track) {
+			return 0, nil, ErrCorruptMedia
+		}
+		tick += delta
+
+		status := track[pos]
+		if status&0x80 != 0 {
+			pos++
+		} else if running == 0 {
+			return 0, nil, ErrCorruptMedia
+		} else {
+			status = running // running status : l'octet lu est déjà une donnée
+		}
+
+		switch {
+		case status == 0xFF:
+			if pos >= len(track) {
+				return 0, nil, ErrCorruptMedia
+			}
+			kind := track[pos]
+			pos++
+			length, ok := readVarLen()
+			if !ok || pos+int(length) > len(track) {
+				return 0, nil, ErrCorruptMedia
+			}
+			if kind == 0x51 && length == 3 {
+				tempo := int64(track[pos])<<16 | int64(track[pos+1])<<8 | int64(track[pos+2])
+				tempos = append(tempos, tempoChange{tick: tick, tempo: tempo})
+			}
+			pos += int(length)
+			if kind == 0x2F {
+				return tick, tempos, nil // fin de piste
+			}
+		case status == 0xF0 || status == 0xF7:
+			length, ok := readVarLen()
+			if !ok || pos+int(length) > len(track) {
+				return 0, nil, ErrCorruptMedia
+			}
+			pos += int(length)
+		case status >= 0x80 && status < 0xF0:
+			running = status
+			size := 2
+			if status&0xF0 == 0xC0 || status&0xF0 == 0xD0 {
+				size = 1
+			}
+			pos += size
+		default:
+			return 0, nil, ErrCorruptMedia
+		}
+	}
+	return tick, tempos, nil
+}
@@ -0,0 +1,100 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+)
+
+// Lecture du format et de la durée des fichiers audio et MIDI joints aux partitions (pur Go).
+// Formats reconnus par leur contenu, et non par l'extension :
+//	- MP3  : parcours des trames MPEG (compatible débit variable)
+//	- OGG  : Vorbis ou Opus, position (granule) de la dernière page
+//	- FLAC : bloc STREAMINFO
+//	- MIDI : fichier SMF, durée calculée avec la carte des tempos
+
+// Formats des médias
+const (
+	FormatMP3  = "mp3"
+	FormatOGG  = "ogg"
+	FormatFLAC = "flac"
+	FormatMIDI = "midi"
+)
+
+// Types MIME et extensions des formats
+var (
+	MimeTypes = map[string]string{
+		FormatMP3:  "audio/mpeg",
+		FormatOGG:  "audio/ogg",
+		FormatFLAC: "audio/flac",
+		FormatMIDI: "audio/midi",
+	}
+	Extensions = map[string]string{
+		FormatMP3:  ".mp3",
+		FormatOGG:  ".ogg",
+		FormatFLAC: ".flac",
+		FormatMIDI: ".mid",
+	}
+)
+
+var (
+	ErrUnsupportedMedia = errors.New("unsupported media, expected MP3, OGG, FLAC or MIDI")
+	ErrCorruptMedia     = errors.New("corrupt media file")
+)
+
+// Info décrit un fichier média
+type Info struct {
+	Format   string  `json:"format"`
+	Codec    string  `json:"codec,omitempty"` // vorbis ou opus pour OGG
+	MimeType string  `json:"mime_type"`
+	Duration float64 `json:"duration"` // en secondes
+	Size     int64   `json:"size"`     // en octets
+}
+
+// Probe identifie le format du média et calcule sa durée. rs est rembobiné au début après lecture.
+func Probe(rs io.ReadSeeker) (*Info, error) {
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(rs, magic); err != nil {
+		return nil, ErrUnsupportedMedia
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	info := &Info{Size: size}
+	switch {
+	case bytes.Equal(magic, []byte("OggS")):
+		info.Format = FormatOGG
+		info.Codec, info.Duration, err = oggDuration(rs, size)
+	case bytes.Equal(magic, []byte("fLaC")):
+		info.Format = FormatFLAC
+		info.Duration, err = flacDuration(rs)
+	case bytes.Equal(magic, []byte("MThd")):
+		info.Format = FormatMIDI
+		info.Duration, err = midiDuration(rs)
+	case bytes.Equal(magic[:3], []byte("ID3")) || (magic[0] == 0xFF && magic[1]&0xE0 == 0xE0):
+		info.Format = FormatMP3
+		info.Duration, err = mp3Duration(rs)
+	default:
+		return nil, ErrUnsupportedMedia
+	}
+	if _, seekErr := rs.Seek(0, io.SeekStart); seekErr != nil && err == nil {
+		err = seekErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info.MimeType = MimeTypes[info.Format]
+	info.Duration = math.Round(info.Duration*1000) / 1000
+	return info, nil
+}
@@ -0,0 +1,111 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mp3Frames construit des trames MPEG-1 couche III à 128 kbit/s et 44,1 kHz (417 octets chacune)
+func mp3Frames(count int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("ID3\x04\x00\x00\x00\x00\x00\x05")
+	buf.Write(make([]byte, 5))
+	for i := 0; i < count; i++ {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+		buf.Write(frame)
+	}
+	return buf.Bytes()
+}
+
+// oggPage construit une page Ogg d'un seul segment
+func oggPage(granule int64, payload []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("OggS")
+	buf.Write([]byte{0, 0})
+	binary.Write(&buf, binary.LittleEndian, granule)
+	buf.Write(make([]byte, 12)) // numéro de flux, séquence, CRC
+	buf.WriteByte(1)
+	buf.WriteByte(byte(len(payload)))
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+func vorbisFile(rate uint32, granule int64) []byte {
+	id := make([]byte, 30)
+	copy(id, "\x01vorbis")
+	binary.LittleEndian.PutUint32(id[12:], rate)
+	return append(oggPage(0, id), oggPage(granule, []byte{0})...)
+}
+
+func flacFile(rate int64, samples int64) []byte {
+	info := make([]byte, 34)
+	info[10] = byte(rate >> 12)
+	info[11] = byte(rate >> 4)
+	info[12] = byte(rate<<4) | 0x02 // 2 canaux
+	info[13] = 0xF0 | byte(samples>>32)
+	binary.BigEndian.PutUint32(info[14:18], uint32(samples))
+	return append([]byte("fLaC\x80\x00\x00\x22"), info...)
+}
+
+// midiFile : 480 ticks par noire, une note d'une noire à 120, puis une noire à 60
+func midiFile() []byte {
+	track := []byte{
+		0x00, 0x90, 0x3C, 0x40, // note on
+		0x83, 0x60, 0x3C, 0x00, // 480 ticks plus tard, running status : note off
+		0x00, 0xFF, 0x51, 0x03, 0x0F, 0x42, 0x40, // tempo 1 000 000 µs par noire
+		0x83, 0x60, 0x80, 0x3C, 0x00,
+		0x00, 0xFF, 0x2F, 0x00,
+	}
+	var buf bytes.Buffer
+	buf.WriteString("MThd")
+	binary.Write(&buf, binary.BigEndian, []uint32{6})
+	binary.Write(&buf, binary.BigEndian, []uint16{0, 1, 480})
+	buf.WriteString("MTrk")
+	binary.Write(&buf, binary.BigEndian, uint32(len(track)))
+	buf.Write(track)
+	return buf.Bytes()
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		format   string
+		codec    string
+		duration float64
+	}{
+		{"mp3", mp3Frames(100), FormatMP3, "", 2.612},
+		{"vorbis", vorbisFile(44100, 88200), FormatOGG, "vorbis", 2},
+		{"flac", flacFile(44100, 441000), FormatFLAC, "", 10},
+		{"midi", midiFile(), FormatMIDI, "", 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := bytes.NewReader(tt.data)
+			info, err := Probe(rs)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.format, info.Format)
+			assert.Equal(t, tt.codec, info.Codec)
+			assert.Equal(t, MimeTypes[tt.format], info.MimeType)
+			assert.InDelta(t, tt.duration, info.Duration, 0.001)
+			assert.Equal(t, int64(len(tt.data)), info.Size)
+
+			pos, _ := rs.Seek(0, 1)
+			assert.Zero(t, pos)
+		})
+	}
+}
+
+func TestProbeRejectsUnknownContent(t *testing.T) {
+	_, err := Probe(bytes.NewReader([]byte("%PDF-1.4 not audio")))
+	assert.ErrorIs(t, err, ErrUnsupportedMedia)
+
+	_, err = Probe(bytes.NewReader([]byte("MThd\x00\x00")))
+	assert.ErrorIs(t, err, ErrCorruptMedia)
+}
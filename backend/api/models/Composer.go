@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,12 +14,67 @@ import (
 )
 
 type Composer struct {
-	SafeName    string    `gorm:"primary_key" json:"safe_name"`
-	Name        string    `json:"name"`
-	PortraitURL string    `json:"portrait_url"`
-	Epoch       string    `json:"epoch"`
-	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	SafeName    string `gorm:"primary_key" json:"safe_name"`
+	Name        string `json:"name"`
+	PortraitURL string `json:"portrait_url"`
+	Epoch       string `json:"epoch"`
+	Birth       string `json:"birth"` // "YYYY-MM-DD" ou "YYYY"
+	Death       string `json:"death"` // vide si le compositeur est vivant ou inconnu
+	Nationality string `json:"nationality"`
+	Biography   string `gorm:"type:TEXT" json:"biography"`
+
+	// Identifiants d'autorité
+	WikidataID    string `json:"wikidata_id"`    // ex: Q1268
+	ViafID        string `json:"viaf_id"`        // ex: 7390735
+	MusicBrainzID string `json:"musicbrainz_id"` // ex: 09ff1fe8-d61c-4b98-bb82-18487c74d7b7
+
+	Aliases   []ComposerAlias `gorm:"foreignKey:ComposerSafeName;references:SafeName" json:"aliases"`
+	CreatedAt time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// ComposerDetails regroupe les champs optionnels d'une mise à jour.
+// Une valeur vide laisse le champ inchangé, Aliases à nil laisse les alias inchangés.
+type ComposerDetails struct {
+	Birth         string
+	Death         string
+	Nationality   string
+	Biography     string
+	WikidataID    string
+	ViafID        string
+	MusicBrainzID string
+	Aliases       []string
+}
+
+// fillEmpty ne renseigne que les champs vides de c
+func (d ComposerDetails) fillEmpty(c *Composer) {
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = strings.TrimSpace(value)
+		}
+	}
+	fill(&c.Birth, d.Birth)
+	fill(&c.Death, d.Death)
+	fill(&c.Nationality, d.Nationality)
+	fill(&c.Biography, d.Biography)
+	fill(&c.WikidataID, d.WikidataID)
+	fill(&c.ViafID, d.ViafID)
+	fill(&c.MusicBrainzID, d.MusicBrainzID)
+}
+
+func (d ComposerDetails) apply(c *Composer) {
+	set := func(field *string, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			*field = value
+		}
+	}
+	set(&c.Birth, d.Birth)
+	set(&c.Death, d.Death)
+	set(&c.Nationality, d.Nationality)
+	set(&c.Biography, d.Biography)
+	set(&c.WikidataID, d.WikidataID)
+	set(&c.ViafID, d.ViafID)
+	set(&c.MusicBrainzID, d.MusicBrainzID)
 }
 
 func (c *Composer) Prepare() {
@@ -28,127 +82,292 @@ func (c *Composer) Prepare() {
 	c.SafeName = strings.TrimSpace(c.SafeName)
 	c.PortraitURL = strings.TrimSpace(c.PortraitURL)
 	c.Epoch = strings.TrimSpace(c.Epoch)
+	c.Birth = strings.TrimSpace(c.Birth)
+	c.Death = strings.TrimSpace(c.Death)
+	c.Nationality = strings.TrimSpace(c.Nationality)
+	c.Biography = strings.TrimSpace(c.Biography)
 	c.CreatedAt = time.Now()
 	c.UpdatedAt = time.Now()
 }
 
 func (c *Composer) SaveComposer(db *gorm.DB) (*Composer, error) {
-	err := db.Model(&Sheet{}).Create(&c).Error
+	err := db.Model(&Composer{}).Create(c).Error
 	if err != nil {
 		return &Composer{}, err
 	}
 	return c, nil
 }
 
-func (c *Composer) UpdateComposer(db *gorm.DB, originalName string, updatedName string, portraitUrl string, epoch string, uploadSuccess bool) (*Composer, error) {
+var (
+	ErrComposerNotFound      = errors.New("composer not found")
+	ErrComposerExists        = errors.New("a composer with this name already exists, merge them instead")
+	ErrDeleteUnknownComposer = errors.New("the unknown composer still has sheets or works")
+)
+
+// UpdateComposer met à jour un compositeur. En cas de changement de nom, la clé primaire change :
+// le dossier des partitions et le portrait sont déplacés, puis les partitions, alias et la ligne
+// du compositeur sont mis à jour dans une seule transaction. Si la transaction échoue,
+// les fichiers sont remis à leur place.
+func (c *Composer) UpdateComposer(db *gorm.DB, originalName string, updatedName string, portraitUrl string, epoch string, details ComposerDetails, uploadSuccess bool) (*Composer, error) {
 	composer, err := c.FindComposerBySafeName(db, originalName)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return &Composer{}, errors.New("Composer not found")
+			return &Composer{}, ErrComposerNotFound
 		}
 		return &Composer{}, err
 	}
+	oldName := composer.Name
 
-	db.Delete(&composer)
-
+	newSafeName := originalName
 	if updatedName != "" {
 		composer.Name = updatedName
-		composer.SafeName = utils.SanitizeName(updatedName)
+		newSafeName = utils.SanitizeName(updatedName)
+	}
+	renamed := newSafeName != originalName
+	if renamed {
+		var count int64
+		if err := db.Model(&Composer{}).Where("safe_name = ?", newSafeName).Count(&count).Error; err != nil {
+			return &Composer{}, err
+		}
+		if count > 0 {
+			return &Composer{}, fmt.Errorf("%w: %s", ErrComposerExists, newSafeName)
+		}
+		var alias ComposerAlias
+		err := db.Where("safe_alias = ? AND composer_safe_name <> ?", newSafeName, originalName).Take(&alias).Error
+		if err == nil {
+			return &Composer{}, fmt.Errorf("%w: %s (%s)", ErrAliasTaken, updatedName, alias.ComposerSafeName)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return &Composer{}, err
+		}
 	}
+	composer.SafeName = newSafeName
+
 	if portraitUrl != "" {
 		composer.PortraitURL = portraitUrl
 	}
 	if epoch != "" {
 		composer.Epoch = epoch
 	}
-	if uploadSuccess {
+	if uploadSuccess || (renamed && composer.PortraitURL == "/api/composer/portrait/"+originalName) {
 		composer.PortraitURL = "/api/composer/portrait/" + composer.SafeName
 	}
-
+	details.apply(composer)
 	composer.UpdatedAt = time.Now()
 
-	db.Save(&composer)
+	var sheets []Sheet
+	if err := db.Where("safe_composer = ?", originalName).Find(&sheets).Error; err != nil {
+		return &Composer{}, err
+	}
+
+	uploadDir, portraitDir := composerDirs()
+	journal := &fileJournal{}
+
+	// 1️⃣ Fichiers : PDF et portrait dans toutes ses tailles
+	if renamed {
+		if err := moveSheetFiles(journal, uploadDir, originalName, newSafeName, sheets); err != nil {
+			journal.rollback()
+			return &Composer{}, err
+		}
+		if _, err := movePortraitFiles(journal, portraitDir, originalName, newSafeName); err != nil {
+			journal.rollback()
+			return &Composer{}, err
+		}
+	}
+
+	// 2️⃣ Base de données
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if renamed {
+			if err := tx.Create(composer).Error; err != nil {
+				return err
+			}
+			if err := reassignSheets(tx, sheets, composer.SafeName, composer.Name); err != nil {
+				return err
+			}
+			if err := reassignWorks(tx, originalName, composer.SafeName, composer.Name); err != nil {
+				return err
+			}
+			// Les alias suivent le composer renommé, le nouveau nom n'est plus un alias
+			if err := tx.Model(&ComposerAlias{}).Where("composer_safe_name = ?", originalName).Update("composer_safe_name", composer.SafeName).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("safe_alias = ?", composer.SafeName).Delete(&ComposerAlias{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("safe_name = ?", originalName).Delete(&Composer{}).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Save(composer).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&Sheet{}).Where("safe_composer = ?", composer.SafeName).Update("composer", composer.Name).Error; err != nil {
+				return err
+			}
+			if err := reassignWorks(tx, composer.SafeName, composer.SafeName, composer.Name); err != nil {
+				return err
+			}
+		}
+
+		if details.Aliases != nil {
+			if err := SetAliases(tx, composer.SafeName, details.Aliases); err != nil {
+				return err
+			}
+		}
+		// L'ancien nom devient un alias
+		if renamed {
+			return AddAliases(tx, composer.SafeName, []string{oldName})
+		}
+		return nil
+	})
+	if err != nil {
+		journal.rollback()
+		return &Composer{}, err
+	}
 
-	// Update Sheets with that composer
-	db.Exec("UPDATE sheets SET pdf_url = REPLACE(pdf_url, ?, ?) WHERE safe_composer = ?;", originalName, utils.SanitizeName(updatedName), originalName)
-	db.Model(&Sheet{}).Where("safe_composer = ?", originalName).Update("safe_composer", utils.SanitizeName(updatedName))
-	db.Model(&Sheet{}).Where("safe_composer = ?", utils.SanitizeName(updatedName)).Update("composer", updatedName)
-	// Rename folder
-	p := path.Join(config.Config().ConfigPath, "sheets/uploaded-sheets/")
-	os.Rename(p+"/"+originalName, p+"/"+utils.SanitizeName(updatedName))
+	if renamed {
+		os.Remove(path.Join(uploadDir, originalName))
+	}
 
+	if err := db.Preload("Aliases").Where("safe_name = ?", composer.SafeName).Take(composer).Error; err != nil {
+		return &Composer{}, err
+	}
 	return composer, nil
 }
 
+// DeleteComposer supprime un compositeur et ses alias. Ses partitions sont rattachées au compositeur
+// "unknown" (créé si besoin) et leurs fichiers déplacés dans son dossier. Si la transaction échoue,
+// les fichiers sont remis à leur place.
 func (c *Composer) DeleteComposer(db *gorm.DB, composerName string) (int64, error) {
-	_, err := c.FindComposerBySafeName(db, composerName)
-	if err != nil {
+	if _, err := c.FindComposerBySafeName(db, composerName); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return 0, errors.New("composer not found")
+			return 0, ErrComposerNotFound
 		}
 		return 0, err
 	}
 
-	// Create Unknown Composer if doesn't exist
-	c.ProperComposerCheck(db, composerName)
-
-	// Delete Composer
-	db = db.Model(&Composer{}).Where("safe_name = ?", composerName).Take(&Composer{}).Delete(&Composer{})
-
-	if db.Error != nil {
-		if errors.Is(db.Error, gorm.ErrRecordNotFound) {
-			return 0, errors.New("composer not found")
-		}
-		return 0, db.Error
+	var sheets []Sheet
+	if err := db.Where("safe_composer = ?", composerName).Find(&sheets).Error; err != nil {
+		return 0, err
+	}
+	var works int64
+	if err := db.Model(&Work{}).Where("safe_composer = ?", composerName).Count(&works).Error; err != nil {
+		return 0, err
+	}
+	if composerName == "unknown" && (len(sheets) > 0 || works > 0) {
+		return 0, ErrDeleteUnknownComposer
 	}
 
-	// Swap sheets composer to Unknown
-	db.Exec("UPDATE 'sheets' SET 'composer' = 'Unknown' WHERE (safe_composer = ?);", composerName)
-	db.Exec("UPDATE 'sheets' SET 'safe_composer' = 'unknown' WHERE (composer = ?);", "Unknown")
-	db.Exec("UPDATE sheets s JOIN (SELECT id, pdf_url, LOCATE(?, pdf_url) AS pos FROM sheets WHERE safe_composer = ? LIMIT 1) t ON s.id = t.id SET s.pdf_url = CONCAT(SUBSTRING(s.pdf_url, 1, t.pos - 1), 'unknown', SUBSTRING(s.pdf_url, t.pos + CHAR_LENGTH(?)))", composerName, composerName, composerName)
+	uploadDir, portraitDir := composerDirs()
+	journal := &fileJournal{}
 
-	confPath := path.Join(config.Config().ConfigPath, "sheets/uploaded-sheets/")
+	// 1️⃣ Fichiers PDF vers le dossier unknown
+	if err := moveSheetFiles(journal, uploadDir, composerName, "unknown", sheets); err != nil {
+		journal.rollback()
+		return 0, err
+	}
 
-	// Move all files inside comp direcotry
-	filepath.Walk(confPath+"/"+composerName,
-		func(path string, info os.FileInfo, err error) error {
-			if err != nil {
+	// 2️⃣ Base de données
+	var rowsAffected int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(sheets) > 0 || works > 0 {
+			unknown := Composer{
+				SafeName:    "unknown",
+				Name:        "Unknown",
+				Epoch:       "Unknown",
+				PortraitURL: "/api/composer/portrait/unknown",
+			}
+			if err := tx.Where("safe_name = ?", unknown.SafeName).FirstOrCreate(&unknown).Error; err != nil {
 				return err
 			}
-			if !info.IsDir() {
-
-				pdfName := strings.Split(path, composerName)
-
-				os.Rename(path, confPath+"/"+"unknown"+pdfName[1])
+			if err := reassignSheets(tx, sheets, unknown.SafeName, unknown.Name); err != nil {
+				return err
 			}
-			return nil
-		})
+			if err := reassignWorks(tx, composerName, unknown.SafeName, unknown.Name); err != nil {
+				return err
+			}
+		}
 
-	// Remove folder
-	os.Remove(confPath + composerName)
+		if err := tx.Where("composer_safe_name = ?", composerName).Delete(&ComposerAlias{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("safe_name = ?", composerName).Delete(&Composer{})
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		journal.rollback()
+		return 0, err
+	}
 
-	// Add Check for Unknown composer
-	CheckAndDeleteUnknownComposer(db)
+	// Nettoyage après validation : dossier vide et portrait
+	os.Remove(path.Join(uploadDir, composerName))
+	for _, file := range utils.PortraitFiles(portraitDir, composerName) {
+		os.Remove(file)
+	}
 
-	return db.RowsAffected, nil
+	return rowsAffected, nil
 }
 
-func (c *Composer) ProperComposerCheck(db *gorm.DB, composerName string) {
-	/*
-		Check how many sheets the composer has before deletion and if none dont create an unknown composer
-		if yes create an unknown composer
-	*/
+// composerDirs retourne le dossier des partitions uploadées et celui des portraits
+func composerDirs() (string, string) {
+	root := config.Config().ConfigPath
+	return path.Join(root, "sheets/uploaded-sheets"), path.Join(root, "composer")
+}
 
-	var sheets []Sheet
+// moveSheetFiles déplace les PDF des partitions, et le dossier de leurs parties, du dossier from vers le dossier to.
+// Les fichiers absents sont ignorés, ils sont signalés par le vérificateur de bibliothèque.
+func moveSheetFiles(journal *fileJournal, uploadDir string, from string, to string, sheets []Sheet) error {
+	for _, sheet := range sheets {
+		for _, name := range []string{sheet.SafeSheetName + ".pdf", sheet.SafeSheetName} {
+			src := path.Join(uploadDir, from, name)
+			if _, err := os.Stat(src); err != nil {
+				continue
+			}
+			if err := journal.rename(src, path.Join(uploadDir, to, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-	result := db.Model(&Sheet{}).Where("safe_composer = ?", composerName).Find(&sheets)
-	fmt.Println("erennn")
-	fmt.Println("results etc etc")
-	fmt.Println(result.RowsAffected)
-	if result.RowsAffected >= 1 {
-		// Create Unknown Composer if doesn't exist
-		c.CreateUnknownComposer(db)
+// movePortraitFiles déplace le portrait du compositeur from vers to, dans toutes les tailles existantes.
+// Retourne faux si from n'a pas de portrait.
+func movePortraitFiles(journal *fileJournal, portraitDir string, from string, to string) (bool, error) {
+	moved := false
+	sources := utils.PortraitFiles(portraitDir, from)
+	targets := utils.PortraitFiles(portraitDir, to)
+	for i, src := range sources {
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := journal.rename(src, targets[i]); err != nil {
+			return moved, err
+		}
+		moved = true
 	}
+	return moved, nil
+}
+
+// reassignSheets rattache les partitions à un autre compositeur.
+// L'URL du PDF est recalculée en Go pour rester portable entre SQLite, MySQL et PostgreSQL.
+func reassignSheets(tx *gorm.DB, sheets []Sheet, safeComposer string, composer string) error {
+	for _, sheet := range sheets {
+		updates := map[string]interface{}{
+			"safe_composer": safeComposer,
+			"composer":      composer,
+		}
+		// Une partition sans PDF (MusicXML ou MuseScore seul) garde un pdf_url vide
+		if sheet.HasPdf() {
+			updates["pdf_url"] = "sheet/pdf/" + safeComposer + "/" + sheet.SafeSheetName
+		}
+		err := tx.Model(&Sheet{}).Where("safe_sheet_name = ?", sheet.SafeSheetName).Updates(updates).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (c *Composer) CreateUnknownComposer(db *gorm.DB) {
@@ -158,7 +377,7 @@ func (c *Composer) CreateUnknownComposer(db *gorm.DB) {
 		c.Name = "Unknown"
 		c.SafeName = "unknown"
 		c.Epoch = "Unknown"
-		c.PortraitURL = "https://icon-library.com/images/unknown-person-icon/unknown-person-icon-4.jpg"
+		c.PortraitURL = "/api/composer/portrait/unknown"
 		c.SaveComposer(db)
 
 		// Create a folder/directory at a full qualified path
@@ -195,17 +414,22 @@ func (c *Composer) GetAllComposer(db *gorm.DB) (*[]Composer, error) {
 }
 
 func SearchComposer(db *gorm.DB, searchValue string) []*Composer {
-	// Search for sheets with containing string
+	// Search for composers with containing string, in their name or one of their aliases
 	var composers []*Composer
+	safeValue := "%" + utils.SanitizeName(searchValue) + "%"
 	searchValue = "%" + searchValue + "%"
-	db.Where("safe_name LIKE ?", searchValue).Find(&composers)
+	db.Preload("Aliases").
+		Where("safe_name LIKE ?", safeValue).
+		Or("name LIKE ?", searchValue).
+		Or("safe_name IN (?)", db.Model(&ComposerAlias{}).Select("composer_safe_name").Where("safe_alias LIKE ?", safeValue)).
+		Find(&composers)
 	return composers
 }
 
 func (c *Composer) List(db *gorm.DB, pagination Pagination) (*Pagination, error) {
 	// For pagination
 	var composers []*Composer
-	db.Scopes(paginate(composers, &pagination, db)).Find(&composers)
+	db.Preload("Aliases").Scopes(paginate(composers, &pagination, db)).Find(&composers)
 	pagination.Rows = composers
 
 	return &pagination, nil
@@ -220,8 +444,118 @@ func CheckAndDeleteUnknownComposer(db *gorm.DB) {
 	var sheets []Sheet
 
 	result := db.Model(&Sheet{}).Where("safe_composer = ?", "unknown").Find(&sheets)
+	var works int64
+	db.Model(&Work{}).Where("safe_composer = ?", "unknown").Count(&works)
 
-	if result.RowsAffected <= 1 {
+	if result.RowsAffected <= 1 && works == 0 {
 		db = db.Model(&Composer{}).Where("safe_name = ?", "unknown").Take(&Composer{}).Delete(&Composer{})
 	}
 }
+
+var ErrMergeSameComposer = errors.New("cannot merge a composer into itself")
+
+// MergeComposer déplace les partitions, fichiers PDF, portrait et alias du composer source vers target,
+// puis supprime source. Le nom de source devient un alias de target.
+// Les fichiers sont déplacés avant la transaction et remis en place si celle-ci échoue.
+func MergeComposer(db *gorm.DB, source string, target string) (*Composer, error) {
+	if source == target {
+		return nil, ErrMergeSameComposer
+	}
+
+	var src, dst Composer
+	if err := db.Where("safe_name = ?", source).Take(&src).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrComposerNotFound
+		}
+		return nil, err
+	}
+	if err := db.Where("safe_name = ?", target).Take(&dst).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("target %w", ErrComposerNotFound)
+		}
+		return nil, err
+	}
+
+	var sheets []Sheet
+	if err := db.Where("safe_composer = ?", source).Find(&sheets).Error; err != nil {
+		return nil, err
+	}
+
+	uploadDir, portraitDir := composerDirs()
+	journal := &fileJournal{}
+
+	// 1️⃣ Fichiers PDF
+	if err := moveSheetFiles(journal, uploadDir, source, target, sheets); err != nil {
+		journal.rollback()
+		return nil, err
+	}
+
+	// 2️⃣ Portrait, uniquement si target n'en a pas
+	portraitMoved := false
+	if _, err := os.Stat(utils.PortraitPath(portraitDir, target, utils.DefaultPortraitSize)); os.IsNotExist(err) {
+		moved, err := movePortraitFiles(journal, portraitDir, source, target)
+		if err != nil {
+			journal.rollback()
+			return nil, err
+		}
+		portraitMoved = moved
+	}
+
+	// 3️⃣ Base de données
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := reassignSheets(tx, sheets, target, dst.Name); err != nil {
+			return err
+		}
+		if err := reassignWorks(tx, source, target, dst.Name); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&ComposerAlias{}).Where("composer_safe_name = ?", source).Update("composer_safe_name", target).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("safe_name = ?", source).Delete(&Composer{}).Error; err != nil {
+			return err
+		}
+		if err := AddAliases(tx, target, []string{src.Name}); err != nil {
+			return err
+		}
+
+		// target garde ses valeurs, source complète celles qui manquent
+		if portraitMoved {
+			dst.PortraitURL = "/api/composer/portrait/" + target
+		} else if dst.PortraitURL == "" {
+			dst.PortraitURL = src.PortraitURL
+		}
+		if dst.Epoch == "" || dst.Epoch == "Unknown" {
+			dst.Epoch = src.Epoch
+		}
+		ComposerDetails{
+			Birth:         src.Birth,
+			Death:         src.Death,
+			Nationality:   src.Nationality,
+			Biography:     src.Biography,
+			WikidataID:    src.WikidataID,
+			ViafID:        src.ViafID,
+			MusicBrainzID: src.MusicBrainzID,
+		}.fillEmpty(&dst)
+		dst.UpdatedAt = time.Now()
+		return tx.Save(&dst).Error
+	})
+	if err != nil {
+		journal.rollback()
+		return nil, err
+	}
+
+	// Nettoyage après validation : dossier vide et portrait non repris
+	os.Remove(path.Join(uploadDir, source))
+	if !portraitMoved {
+		for _, file := range utils.PortraitFiles(portraitDir, source) {
+			os.Remove(file)
+		}
+	}
+
+	if err := db.Preload("Aliases").Where("safe_name = ?", target).Take(&dst).Error; err != nil {
+		return nil, err
+	}
+	return &dst, nil
+}
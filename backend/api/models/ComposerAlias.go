@@ -0,0 +1,100 @@
+package models
+
+import (
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ComposerAlias : autre orthographe d'un compositeur
+// Exemple : Tchaikovsky / Tschaikowsky / Чайковский → pyotr-ilyich-tchaikovsky
+// SafeAlias est la forme "safe" de l'alias (utils.SanitizeName) et sert de clé de résolution :
+// un même alias ne peut désigner qu'un seul compositeur.
+type ComposerAlias struct {
+	ID               uint32 `gorm:"primary_key;auto_increment" json:"-"`
+	ComposerSafeName string `gorm:"index;not null" json:"-"`
+	Alias            string `json:"alias"`
+	SafeAlias        string `gorm:"uniqueIndex;not null" json:"safe_alias"`
+}
+
+var ErrAliasTaken = errors.New("alias already used by another composer")
+
+// ResolveComposer retrouve un compositeur par son nom ou l'un de ses alias
+func ResolveComposer(db *gorm.DB, name string) (*Composer, error) {
+	safeName := utils.SanitizeName(strings.TrimSpace(name))
+	if safeName == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var composer Composer
+	err := db.Preload("Aliases").Where("safe_name = ?", safeName).Take(&composer).Error
+	if err == nil {
+		return &composer, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var alias ComposerAlias
+	if err := db.Where("safe_alias = ?", safeName).Take(&alias).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Preload("Aliases").Where("safe_name = ?", alias.ComposerSafeName).Take(&composer).Error; err != nil {
+		return nil, err
+	}
+	return &composer, nil
+}
+
+// AddAliases ajoute des alias à un compositeur.
+// Les alias identiques au nom du compositeur ou déjà présents sont ignorés.
+func AddAliases(db *gorm.DB, composerSafeName string, aliases []string) error {
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		safeAlias := utils.SanitizeName(alias)
+		if safeAlias == "" || safeAlias == composerSafeName {
+			continue
+		}
+
+		var existing ComposerAlias
+		err := db.Where("safe_alias = ?", safeAlias).Take(&existing).Error
+		if err == nil {
+			if existing.ComposerSafeName != composerSafeName {
+				return fmt.Errorf("%w: %s (%s)", ErrAliasTaken, alias, existing.ComposerSafeName)
+			}
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// Un alias ne peut pas être le nom d'un autre compositeur
+		var count int64
+		db.Model(&Composer{}).Where("safe_name = ?", safeAlias).Count(&count)
+		if count > 0 {
+			return fmt.Errorf("%w: %s is a composer", ErrAliasTaken, alias)
+		}
+
+		err = db.Create(&ComposerAlias{
+			ComposerSafeName: composerSafeName,
+			Alias:            alias,
+			SafeAlias:        safeAlias,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetAliases remplace la liste des alias d'un compositeur
+func SetAliases(db *gorm.DB, composerSafeName string, aliases []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("composer_safe_name = ?", composerSafeName).Delete(&ComposerAlias{}).Error; err != nil {
+			return err
+		}
+		return AddAliases(tx, composerSafeName, aliases)
+	})
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"log"
+	"os"
+	"path"
+)
+
+// fileJournal garde la trace des déplacements de fichiers effectués pendant une opération
+// afin de pouvoir les annuler si la transaction en base de données échoue.
+// Usage :
+//
+//	journal := &fileJournal{}
+//	if err := journal.rename(from, to); err != nil { journal.rollback(); return err }
+//	err := db.Transaction(...)
+//	if err != nil { journal.rollback(); return err }
+type fileJournal struct {
+	renames [][2]string
+}
+
+// rename déplace from vers to en créant le répertoire de destination si besoin.
+// Le déplacement est refusé si la destination existe déjà.
+func (j *fileJournal) rename(from string, to string) error {
+	if _, err := os.Stat(to); err == nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrExist}
+	}
+	if err := os.MkdirAll(path.Dir(to), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	j.renames = append(j.renames, [2]string{from, to})
+	return nil
+}
+
+// rollback annule les déplacements dans l'ordre inverse
+func (j *fileJournal) rollback() {
+	for i := len(j.renames) - 1; i >= 0; i-- {
+		from, to := j.renames[i][0], j.renames[i][1]
+		if err := os.Rename(to, from); err != nil {
+			log.Printf("rollback: unable to move %s back to %s: %v\n", to, from, err)
+		}
+	}
+	j.renames = nil
+}
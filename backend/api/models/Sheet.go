@@ -2,11 +2,14 @@ package models
 
 import (
 	"backend/api/config"
+	"backend/api/pdf"
+	"backend/api/score"
 	"encoding/json"
 	"errors"
 	"log"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -28,11 +31,11 @@ import (
 // ["Classical", "Piano"]
 
 type Sheet struct {
-	SafeSheetName   string `gorm:"primary_key" json:"safe_sheet_name"`
-	SheetName       string `json:"sheet_name"`
-	SafeComposer    string `json:"safe_composer"`
-	Composer        string `json:"composer"`
-	ReleaseDate     time.Time
+	SafeSheetName   string    `gorm:"primary_key" json:"safe_sheet_name"`
+	SheetName       string    `json:"sheet_name"`
+	SafeComposer    string    `json:"safe_composer"`
+	Composer        string    `json:"composer"`
+	ReleaseDate     time.Time `json:"release_date"`
 	PdfUrl          string    `json:"pdf_url"`
 	UploaderID      uint32    `gorm:"not null" json:"uploader_id"`
 	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
@@ -40,6 +43,190 @@ type Sheet struct {
 	Tags            string    `gorm:"type:TEXT" json:"tags"`       // JSON-encoded array of strings
 	Categories      string    `gorm:"type:TEXT" json:"categories"` // JSON-encoded array of strings
 	InformationText string    `json:"information_text"`
+	FileHash        string    `gorm:"size:64;index" json:"file_hash"` // SHA-256 du PDF (hexadécimal)
+
+	// Structure du PDF, lue à l'upload
+	PageCount   int     `gorm:"index" json:"page_count"`
+	PageWidth   float64 `json:"page_width"`                       // en points, première page
+	PageHeight  float64 `json:"page_height"`                      // en points, première page
+	Orientation string  `gorm:"size:16;index" json:"orientation"` // portrait, landscape ou mixed
+	FileSize    int64   `json:"file_size"`                        // en octets
+	Encrypted   bool    `json:"encrypted"`
+	HasText     bool    `json:"has_text"` // faux pour un scan sans OCR
+	Revision    int     `json:"revision"` // nombre de modifications des pages, versions antérieures dans SheetRevision
+
+	// Fichier source MusicXML, MuseScore ou ABC, et informations musicales qui en sont extraites
+	SourceFormat  string `gorm:"size:16" json:"source_format"`          // musicxml, mxl, mscz ou abc, vide = pas de source
+	Key           string `gorm:"column:musical_key;size:32" json:"key"` // ex: Eb major ("key" est réservé en MySQL)
+	TimeSignature string `gorm:"size:16" json:"time_signature"`
+	Tempo         int    `json:"tempo"`                        // noires par minute
+	Instruments   string `gorm:"type:TEXT" json:"instruments"` // JSON-encoded array of strings, parties du fichier source
+
+	// Informations musicales saisies à l'upload ou par PUT /sheet/:sheetName/metadata (la tonalité peut aussi venir du fichier source)
+	CatalogueType   string `gorm:"size:16;index" json:"catalogue_type"`   // Op., BWV, K., D., Hob. ...
+	CatalogueNumber string `gorm:"size:32" json:"catalogue_number"`       // ex: 27 No. 2, 846
+	CatalogueSort   string `gorm:"size:64;index" json:"catalogue_sort"`   // clé de tri du numéro, ex: 000027.000002
+	Instrumentation string `gorm:"size:128;index" json:"instrumentation"` // ex: piano, string quartet, SATB choir
+	Difficulty      int    `gorm:"index" json:"difficulty"`               // grade de 1 à 8, 0 = inconnu
+	Duration        int    `gorm:"index" json:"duration"`                 // durée approximative en secondes, 0 = inconnue
+	Arranger        string `gorm:"size:128" json:"arranger"`
+	Lyricist        string `gorm:"size:128" json:"lyricist"`
+	Publisher       string `gorm:"size:128" json:"publisher"`    // éditeur ou édition, ex: Henle Urtext
+	Language        string `gorm:"size:8;index" json:"language"` // code ISO 639-1 du texte chanté, ex: de
+
+	// Edition possédée d'une oeuvre : l'éditeur est Publisher, plusieurs éditions d'une même oeuvre partagent WorkSafeName
+	Editor       string `gorm:"size:128" json:"editor"`               // responsable de l'édition, ex: Ewald Zimmermann
+	EditionYear  int    `gorm:"index" json:"edition_year"`            // 0 = inconnue
+	WorkSafeName string `gorm:"size:255;index" json:"work_safe_name"` // vide = partition sans oeuvre
+
+	// Pièce d'un recueil : pages FirstPage à LastPage du PDF de ParentSheet (voir SheetExcerpt.go)
+	ParentSheet string `gorm:"size:255;index" json:"parent_sheet"` // safe_sheet_name du recueil, vide = partition autonome
+	FirstPage   int    `json:"first_page"`
+	LastPage    int    `json:"last_page"`
+
+	// Filigrane des téléchargements : on, off ou vide = selon les catégories (voir Watermark.go)
+	Watermark string `gorm:"size:8" json:"watermark"`
+
+	// Licence : domaine public, Creative Commons, achat ou location (voir SheetLicense.go), vide = inconnue
+	License       string     `gorm:"size:32;index" json:"license"`
+	LicenseHolder string     `gorm:"size:128" json:"license_holder"` // éditeur ou loueur
+	LicenseSeats  int        `json:"license_seats"`                  // licence achetée : nombre de places, 0 = illimité
+	LicenseExpiry *time.Time `json:"license_expiry"`                 // location : dernier jour
+
+	// Parties séparées (oeuvres de chambre, orchestre), le PDF principal est le conducteur
+	Parts []SheetPart `gorm:"foreignKey:SheetSafeName;references:SafeSheetName" json:"parts"`
+	// Enregistrements, fichiers MIDI et pistes d'accompagnement
+	Media []SheetMedia `gorm:"foreignKey:SheetSafeName;references:SafeSheetName" json:"media"`
+}
+
+// SetStructure recopie la structure du PDF dans la partition
+func (s *Sheet) SetStructure(structure *pdf.Structure) {
+	s.PageCount = structure.PageCount
+	s.PageWidth = structure.PageWidth
+	s.PageHeight = structure.PageHeight
+	s.Orientation = structure.Orientation
+	s.FileSize = structure.FileSize
+	s.Encrypted = structure.Encrypted
+	s.HasText = structure.HasText
+}
+
+// SetScore recopie les informations musicales du fichier source dans la partition.
+// Les valeurs absentes du fichier ne remplacent pas celles déjà connues.
+func (s *Sheet) SetScore(meta *score.Metadata) {
+	s.SourceFormat = meta.Format
+	if meta.Key != "" {
+		s.Key = meta.Key
+	}
+	if meta.TimeSignature != "" {
+		s.TimeSignature = meta.TimeSignature
+	}
+	if meta.Tempo > 0 {
+		s.Tempo = meta.Tempo
+	}
+	if len(meta.Parts) > 0 {
+		instruments, _ := json.Marshal(meta.Parts)
+		s.Instruments = string(instruments)
+	}
+}
+
+// HasPdf indique si la partition a un PDF (faux pour une partition uploadée uniquement en MusicXML ou MuseScore)
+func (s *Sheet) HasPdf() bool {
+	return s.PdfUrl != ""
+}
+
+// SheetFilter regroupe les critères de filtrage de la liste des partitions.
+// Une valeur vide (ou nil) n'applique pas de filtre.
+// Exemple : pièces courtes de moins de 4 pages → SheetFilter{MaxPages: 3}
+type SheetFilter struct {
+	Composer    string
+	MinPages    int
+	MaxPages    int
+	Orientation string
+	Encrypted   *bool
+	HasText     *bool
+
+	// Informations musicales : égalité pour le catalogue, la tonalité et la langue,
+	// recherche partielle sans casse pour l'effectif, l'arrangeur, le parolier, l'éditeur et le responsable de l'édition
+	CatalogueType   string
+	Key             string
+	Instrumentation string
+	MinDifficulty   int
+	MaxDifficulty   int
+	MinDuration     int // en secondes
+	MaxDuration     int
+	Arranger        string
+	Lyricist        string
+	Publisher       string
+	Language        string
+	Editor          string
+	Work            string // safe_name de l'oeuvre
+	Parent          string // safe_sheet_name du recueil : ses pièces
+	License         string // licence déclarée, "unknown" = sans licence
+}
+
+func (f SheetFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Composer != "" {
+		db = db.Scopes(ComposerEqual(f.Composer))
+	}
+	if f.MinPages > 0 {
+		db = db.Where("page_count >= ?", f.MinPages)
+	}
+	if f.MaxPages > 0 {
+		db = db.Where("page_count <= ?", f.MaxPages)
+	}
+	if f.Orientation != "" {
+		db = db.Where("orientation = ?", f.Orientation)
+	}
+	if f.Encrypted != nil {
+		db = db.Where("encrypted = ?", *f.Encrypted)
+	}
+	if f.HasText != nil {
+		db = db.Where("has_text = ?", *f.HasText)
+	}
+	if f.CatalogueType != "" {
+		db = db.Where("catalogue_type = ?", f.CatalogueType)
+	}
+	if f.Key != "" {
+		db = db.Where("LOWER(musical_key) = ?", strings.ToLower(f.Key))
+	}
+	if f.MinDifficulty > 0 {
+		db = db.Where("difficulty >= ?", f.MinDifficulty)
+	}
+	if f.MaxDifficulty > 0 {
+		db = db.Where("difficulty BETWEEN 1 AND ?", f.MaxDifficulty)
+	}
+	if f.MinDuration > 0 {
+		db = db.Where("duration >= ?", f.MinDuration)
+	}
+	if f.MaxDuration > 0 {
+		db = db.Where("duration BETWEEN 1 AND ?", f.MaxDuration)
+	}
+	if f.Language != "" {
+		db = db.Where("language = ?", strings.ToLower(f.Language))
+	}
+	if f.Work != "" {
+		db = db.Where("work_safe_name = ?", f.Work)
+	}
+	if f.Parent != "" {
+		db = db.Where("parent_sheet = ?", f.Parent)
+	}
+	if f.License == "unknown" {
+		db = db.Where("license = ?", LicenseUnknown)
+	} else if f.License != "" {
+		db = db.Where("license = ?", strings.ToLower(f.License))
+	}
+	for _, contains := range []struct{ column, value string }{
+		{"instrumentation", f.Instrumentation},
+		{"arranger", f.Arranger},
+		{"lyricist", f.Lyricist},
+		{"publisher", f.Publisher},
+		{"editor", f.Editor},
+	} {
+		if contains.value != "" {
+			db = db.Where("LOWER("+contains.column+") LIKE ?", "%"+strings.ToLower(contains.value)+"%")
+		}
+	}
+	return db
 }
 
 var (
@@ -67,18 +254,49 @@ func (s *Sheet) DeleteSheet(db *gorm.DB, sheetName string) (int64, error) {
 		}
 		return 0, err
 	}
+	// Un recueil n'est supprimé qu'une fois ses pièces supprimées
+	var excerpts int64
+	if err := db.Model(&Sheet{}).Where("parent_sheet = ?", sheet.SafeSheetName).Count(&excerpts).Error; err != nil {
+		return 0, err
+	}
+	if excerpts > 0 {
+		return 0, ErrSheetHasExcerpts
+	}
+	// Ni tant qu'un exemplaire imprimé est prêté : le prêt en cours serait perdu
+	var loans int64
+	if err := db.Model(&SheetLoan{}).Where("sheet_safe_name = ? AND returned_at IS NULL", sheet.SafeSheetName).Count(&loans).Error; err != nil {
+		return 0, err
+	}
+	if loans > 0 {
+		return 0, ErrCopyOnLoan
+	}
 
 	paths := []string{
 		path.Join(config.Config().ConfigPath, "sheets/uploaded-sheets", sheet.SafeComposer, sheet.SafeSheetName+".pdf"),
 		path.Join(config.Config().ConfigPath, "sheets/thumbnails", sheet.SafeSheetName+".png"),
+		ExcerptPath(sheet),
 	}
 
+	// Miniatures redimensionnées (sheets/thumbnails/<taille>/<nom>.<format>)
+	sized, _ := filepath.Glob(path.Join(config.Config().ConfigPath, "sheets/thumbnails", "*", sheet.SafeSheetName+".*"))
+	paths = append(paths, sized...)
+
 	for _, filePath := range paths {
 		err := os.Remove(filePath)
 		if err != nil && !os.IsNotExist(err) {
 			log.Printf("Erreur lors de la suppression du fichier %s : %v\n", filePath, err)
 		}
 	}
+	// Rendus des pages, parties séparées, médias et fichier source
+	os.RemoveAll(path.Join(config.Config().ConfigPath, "sheets/pages", sheet.SafeSheetName))
+	os.RemoveAll(PartsDir(sheet))
+	os.RemoveAll(WatermarkDir(sheet))
+	db.Where("sheet_safe_name = ?", sheetName).Delete(&SheetPart{})
+	db.Where("sheet_safe_name = ?", sheetName).Delete(&SheetMedia{})
+	db.Where("sheet_safe_name = ?", sheetName).Delete(&SheetRevision{})
+	db.Where("sheet_safe_name = ?", sheetName).Delete(&SheetDistribution{})
+	db.Where("sheet_safe_name = ?", sheetName).Delete(&SheetLoan{})
+	db.Where("sheet_safe_name = ?", sheetName).Delete(&SheetCopy{})
 
 	if sheet.SafeComposer == "unknown" {
 		CheckAndDeleteUnknownComposer(db)
@@ -113,21 +331,19 @@ func (s *Sheet) GetAllSheets(db *gorm.DB) (*[]Sheet, error) {
 func (s *Sheet) FindSheetBySafeName(db *gorm.DB, sheetName string) (*Sheet, error) {
 	// Get information of one single sheet by the safe sheet name
 	var err error
-	err = db.Model(&Sheet{}).Where("safe_sheet_name = ?", sheetName).Take(&s).Error
+	err = db.Model(&Sheet{}).Preload("Parts", OrderedParts).Preload("Media", OrderedMedia).Where("safe_sheet_name = ?", sheetName).Take(&s).Error
 	if err != nil {
 		return &Sheet{}, err
 	}
 	return s, nil
 }
 
-func (s *Sheet) List(db *gorm.DB, pagination Pagination, composer string) (*Pagination, error) {
-	// For pagination
-
+func (s *Sheet) List(db *gorm.DB, pagination Pagination, filter SheetFilter) (*Pagination, error) {
+	// For pagination, the total is counted on the filtered sheets
 	var sheets []*Sheet
-	if composer != "" {
-		db.Scopes(ComposerEqual(composer), paginate(sheets, &pagination, db)).Find(&sheets)
-	} else {
-		db.Scopes(paginate(sheets, &pagination, db)).Find(&sheets)
+	filtered := filter.apply(db.Model(&Sheet{})).Session(&gorm.Session{})
+	if err := filtered.Scopes(paginate(sheets, &pagination, filtered)).Find(&sheets).Error; err != nil {
+		return nil, err
 	}
 
 	pagination.Rows = sheets
@@ -143,6 +359,18 @@ func SearchSheet(db *gorm.DB, searchValue string) []*Sheet {
 	return sheets
 }
 
+// FindSheetByHash retourne la partition dont le PDF a le hash SHA-256 donné
+func FindSheetByHash(db *gorm.DB, hash string) (*Sheet, error) {
+	var sheet Sheet
+	if hash == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := db.Model(&Sheet{}).Where("file_hash = ?", hash).Take(&sheet).Error; err != nil {
+		return nil, err
+	}
+	return &sheet, nil
+}
+
 func ComposerEqual(composer string) func(db *gorm.DB) *gorm.DB {
 	// Scope that composer is equal to composer (if you only want sheets from a certain composer)
 	return func(db *gorm.DB) *gorm.DB {
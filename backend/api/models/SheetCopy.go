@@ -0,0 +1,342 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SheetCopy : exemplaire imprimé d'une partition, identifié par le code-barres collé sur la couverture.
+// Un exemplaire est prêté à un utilisateur jusqu'à une date de retour (SheetLoan) ;
+// les prêts rendus restent en base comme historique.
+type SheetCopy struct {
+	ID            uint32     `gorm:"primary_key;auto_increment" json:"-"`
+	SheetSafeName string     `gorm:"size:255;index;not null" json:"sheet_safe_name"`
+	Barcode       string     `gorm:"size:64;uniqueIndex;not null" json:"barcode"`
+	Shelf         string     `gorm:"size:64;index" json:"shelf"`                     // emplacement, ex: B3-2
+	Condition     string     `gorm:"column:copy_condition;size:16" json:"condition"` // new, good, fair, poor ou damaged ("condition" est réservé en MySQL)
+	Notes         string     `gorm:"type:TEXT" json:"notes"`                         // annotations, pages manquantes ...
+	Loan          *SheetLoan `gorm:"-" json:"loan"`                                  // prêt en cours, nil = disponible
+	CreatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// SheetLoan : prêt d'un exemplaire. DueAt est le dernier jour du prêt, ReturnedAt nil = en cours.
+type SheetLoan struct {
+	ID            uint32     `gorm:"primary_key;auto_increment" json:"id"`
+	CopyID        uint32     `gorm:"index;not null" json:"-"`
+	SheetSafeName string     `gorm:"size:255;index;not null" json:"sheet_safe_name"`
+	Barcode       string     `gorm:"size:64;index" json:"barcode"`
+	UserID        uint32     `gorm:"index;not null" json:"user_id"`
+	CheckedOutAt  time.Time  `json:"checked_out_at"`
+	DueAt         time.Time  `gorm:"index" json:"due_at"`
+	ReturnedAt    *time.Time `gorm:"index" json:"returned_at"`
+	Reminders     int        `json:"reminders"` // nombre de rappels envoyés
+	RemindedAt    *time.Time `json:"reminded_at"`
+	Overdue       bool       `gorm:"-" json:"overdue"`
+}
+
+// Etats d'un exemplaire
+const (
+	ConditionNew     = "new"
+	ConditionGood    = "good"
+	ConditionFair    = "fair"
+	ConditionPoor    = "poor"
+	ConditionDamaged = "damaged"
+)
+
+var Conditions = []string{ConditionNew, ConditionGood, ConditionFair, ConditionPoor, ConditionDamaged}
+
+// DefaultLoanDays : durée d'un prêt sans date de retour précisée
+const DefaultLoanDays = 28
+
+var (
+	ErrInvalidBarcode   = errors.New("barcode must contain 1 to 64 letters, digits, '.', '_' or '-'")
+	ErrBarcodeExists    = errors.New("a copy with this barcode already exists")
+	ErrInvalidCondition = errors.New("condition must be one of " + strings.Join(Conditions, ", "))
+	ErrCopyNotFound     = errors.New("copy not found")
+	ErrCopyOnLoan       = errors.New("copy is on loan")
+	ErrCopyNotOnLoan    = errors.New("copy is not on loan")
+	ErrInvalidDueDate   = errors.New("due date must not be in the past")
+	ErrDueDateTooLate   = fmt.Errorf("due date must be within %d days", DefaultLoanDays)
+)
+
+var barcodePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// CopyUpdate : champs modifiables d'un exemplaire, nil = inchangé
+type CopyUpdate struct {
+	Barcode   *string
+	Shelf     *string
+	Condition *string
+	Notes     *string
+}
+
+func normalizeCondition(condition string) (string, error) {
+	condition = strings.ToLower(strings.TrimSpace(condition))
+	if condition == "" {
+		return ConditionGood, nil
+	}
+	if !slices.Contains(Conditions, condition) {
+		return "", ErrInvalidCondition
+	}
+	return condition, nil
+}
+
+func checkBarcode(db *gorm.DB, barcode string, id uint32) error {
+	if !barcodePattern.MatchString(barcode) {
+		return ErrInvalidBarcode
+	}
+	var count int64
+	if err := db.Model(&SheetCopy{}).Where("barcode = ? AND id <> ?", barcode, id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrBarcodeExists, barcode)
+	}
+	return nil
+}
+
+// AddCopy enregistre un exemplaire imprimé de la partition, en bon état si l'état n'est pas précisé
+func (s *Sheet) AddCopy(db *gorm.DB, item *SheetCopy) error {
+	item.Barcode = strings.TrimSpace(item.Barcode)
+	item.Shelf = strings.TrimSpace(item.Shelf)
+	condition, err := normalizeCondition(item.Condition)
+	if err != nil {
+		return err
+	}
+	item.Condition = condition
+	if err := checkBarcode(db, item.Barcode, 0); err != nil {
+		return err
+	}
+	item.SheetSafeName = s.SafeSheetName
+	return db.Create(item).Error
+}
+
+// Copies retourne les exemplaires de la partition avec leur prêt en cours
+func (s *Sheet) Copies(db *gorm.DB, now time.Time) ([]SheetCopy, error) {
+	copies := []SheetCopy{}
+	if err := db.Where("sheet_safe_name = ?", s.SafeSheetName).Order("barcode asc").Find(&copies).Error; err != nil {
+		return nil, err
+	}
+	for i := range copies {
+		if err := copies[i].loadLoan(db, now); err != nil {
+			return nil, err
+		}
+	}
+	return copies, nil
+}
+
+// FindCopy retourne un exemplaire par son code-barres, avec son prêt en cours
+func FindCopy(db *gorm.DB, barcode string, now time.Time) (*SheetCopy, error) {
+	var item SheetCopy
+	if err := db.Where("barcode = ?", strings.TrimSpace(barcode)).Take(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCopyNotFound
+		}
+		return nil, err
+	}
+	if err := item.loadLoan(db, now); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (c *SheetCopy) loadLoan(db *gorm.DB, now time.Time) error {
+	var loans []SheetLoan
+	if err := db.Where("copy_id = ? AND returned_at IS NULL", c.ID).Limit(1).Find(&loans).Error; err != nil {
+		return err
+	}
+	c.Loan = nil
+	if len(loans) > 0 {
+		loans[0].Overdue = loans[0].IsOverdue(now)
+		c.Loan = &loans[0]
+	}
+	return nil
+}
+
+// UpdateCopy modifie le code-barres, l'emplacement, l'état ou les notes d'un exemplaire
+func UpdateCopy(db *gorm.DB, barcode string, update CopyUpdate) (*SheetCopy, error) {
+	item, err := FindCopy(db, barcode, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if update.Barcode != nil {
+		item.Barcode = strings.TrimSpace(*update.Barcode)
+		if err := checkBarcode(db, item.Barcode, item.ID); err != nil {
+			return nil, err
+		}
+	}
+	if update.Shelf != nil {
+		item.Shelf = strings.TrimSpace(*update.Shelf)
+	}
+	if update.Condition != nil {
+		if item.Condition, err = normalizeCondition(*update.Condition); err != nil {
+			return nil, err
+		}
+	}
+	if update.Notes != nil {
+		item.Notes = strings.TrimSpace(*update.Notes)
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&SheetCopy{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+			"barcode":        item.Barcode,
+			"shelf":          item.Shelf,
+			"copy_condition": item.Condition,
+			"notes":          item.Notes,
+			"updated_at":     time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&SheetLoan{}).Where("copy_id = ?", item.ID).Update("barcode", item.Barcode).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// DeleteCopy supprime un exemplaire et l'historique de ses prêts, sauf s'il est prêté
+func DeleteCopy(db *gorm.DB, barcode string) error {
+	item, err := FindCopy(db, barcode, time.Now())
+	if err != nil {
+		return err
+	}
+	if item.Loan != nil {
+		return ErrCopyOnLoan
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("copy_id = ?", item.ID).Delete(&SheetLoan{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&SheetCopy{}, item.ID).Error
+	})
+}
+
+// IsOverdue indique si le prêt en cours a dépassé sa date de retour (dernier jour inclus)
+func (l *SheetLoan) IsOverdue(now time.Time) bool {
+	return l.ReturnedAt == nil && !now.Before(l.DueAt.AddDate(0, 0, 1))
+}
+
+// CheckoutCopy prête un exemplaire à un utilisateur jusqu'au jour due inclus,
+// ou pour DefaultLoanDays jours si due est zéro
+func CheckoutCopy(db *gorm.DB, barcode string, userID uint32, due time.Time, now time.Time) (*SheetLoan, error) {
+	if due.IsZero() {
+		due = now.AddDate(0, 0, DefaultLoanDays)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, due.Location())
+	if due.Before(today) {
+		return nil, ErrInvalidDueDate
+	}
+	item, err := FindCopy(db, barcode, now)
+	if err != nil {
+		return nil, err
+	}
+	if item.Loan != nil {
+		return nil, ErrCopyOnLoan
+	}
+	loan := SheetLoan{
+		CopyID:        item.ID,
+		SheetSafeName: item.SheetSafeName,
+		Barcode:       item.Barcode,
+		UserID:        userID,
+		CheckedOutAt:  now,
+		DueAt:         due,
+	}
+	if err := db.Create(&loan).Error; err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+// CheckLoanLength vérifie qu'un prêt demandé par un utilisateur ne dépasse pas DefaultLoanDays jours,
+// seul l'admin peut prêter plus longtemps
+func CheckLoanLength(due time.Time, now time.Time) error {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, due.Location())
+	if due.After(today.AddDate(0, 0, DefaultLoanDays)) {
+		return ErrDueDateTooLate
+	}
+	return nil
+}
+
+// ReturnCopy termine le prêt en cours d'un exemplaire et met à jour son état s'il est précisé
+func ReturnCopy(db *gorm.DB, barcode string, condition string, now time.Time) (*SheetLoan, error) {
+	item, err := FindCopy(db, barcode, now)
+	if err != nil {
+		return nil, err
+	}
+	if item.Loan == nil {
+		return nil, ErrCopyNotOnLoan
+	}
+	if condition = strings.TrimSpace(condition); condition != "" {
+		if condition, err = normalizeCondition(condition); err != nil {
+			return nil, err
+		}
+	}
+	loan := item.Loan
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&SheetLoan{}).Where("id = ?", loan.ID).Update("returned_at", now).Error; err != nil {
+			return err
+		}
+		if condition == "" {
+			return nil
+		}
+		return tx.Model(&SheetCopy{}).Where("id = ?", item.ID).Updates(map[string]interface{}{"copy_condition": condition, "updated_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	loan.ReturnedAt = &now
+	loan.Overdue = false
+	return loan, nil
+}
+
+// LoanFilter : filtres de la liste des prêts
+type LoanFilter struct {
+	UserID  uint32 // 0 = tous les utilisateurs
+	Sheet   string // safe_sheet_name, vide = toutes les partitions
+	Overdue bool   // uniquement les prêts en retard
+	History bool   // inclut les prêts rendus
+}
+
+// ListLoans retourne les prêts, les plus anciennes dates de retour en premier
+func ListLoans(db *gorm.DB, filter LoanFilter, now time.Time) ([]SheetLoan, error) {
+	query := db.Model(&SheetLoan{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Sheet != "" {
+		query = query.Where("sheet_safe_name = ?", filter.Sheet)
+	}
+	if filter.Overdue {
+		query = query.Where("returned_at IS NULL AND due_at <= ?", now.AddDate(0, 0, -1))
+	} else if !filter.History {
+		query = query.Where("returned_at IS NULL")
+	}
+	loans := []SheetLoan{}
+	if err := query.Order("due_at asc, id asc").Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	for i := range loans {
+		loans[i].Overdue = loans[i].IsOverdue(now)
+	}
+	return loans, nil
+}
+
+// MarkReminded enregistre l'envoi d'un rappel pour un prêt en retard
+func MarkReminded(db *gorm.DB, loan *SheetLoan, now time.Time) error {
+	err := db.Model(&SheetLoan{}).Where("id = ?", loan.ID).Updates(map[string]interface{}{
+		"reminders":   gorm.Expr("reminders + 1"),
+		"reminded_at": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	loan.Reminders++
+	loan.RemindedAt = &now
+	return nil
+}
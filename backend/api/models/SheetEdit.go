@@ -0,0 +1,180 @@
+package models
+
+import (
+	"backend/api/config"
+	"backend/api/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Modification des informations d'une partition par PUT /api/sheet/:sheetName :
+// titre, compositeur, date de publication, tags, catégories et texte d'information.
+// Un nouveau titre change le safe_sheet_name (clé primaire), un nouveau compositeur le dossier de la partition.
+// Les fichiers (PDF, dossier des parties, médias, source et révisions, thumbnails) sont déplacés avant la transaction
+// et remis en place par le fileJournal si elle échoue, comme pour le renommage d'un compositeur.
+
+var ErrSheetExists = errors.New("a sheet with this name already exists")
+
+// SheetEdit : champs modifiables, vide ou nil = inchangé
+type SheetEdit struct {
+	SheetName       string
+	Composer        *Composer // compositeur déjà enregistré
+	ReleaseDate     *time.Time
+	Tags            *[]string
+	Categories      *[]string
+	InformationText *string
+}
+
+// SafeSheetName retourne le nom de fichier (et clé) d'une partition à partir de son titre
+func SafeSheetName(title string) string {
+	return utils.SanitizeName(strings.TrimSpace(title))
+}
+
+// EditSheet applique edit à la partition. Les pièces, parties, médias, révisions, licence, exemplaires et prêts
+// suivent un renommage.
+func (s *Sheet) EditSheet(db *gorm.DB, edit SheetEdit) (*Sheet, error) {
+	old := *s
+	updated := *s
+	if title := strings.TrimSpace(edit.SheetName); title != "" {
+		updated.SheetName = title
+		updated.SafeSheetName = SafeSheetName(title)
+		if updated.SafeSheetName == "" {
+			return nil, fmt.Errorf("invalid sheet name %q", title)
+		}
+	}
+	if edit.Composer != nil {
+		updated.SafeComposer = edit.Composer.SafeName
+		updated.Composer = edit.Composer.Name
+	}
+	if edit.ReleaseDate != nil {
+		updated.ReleaseDate = *edit.ReleaseDate
+	}
+	if edit.Tags != nil {
+		tags, _ := json.Marshal(*edit.Tags)
+		updated.Tags = string(tags)
+	}
+	if edit.Categories != nil {
+		categories, _ := json.Marshal(*edit.Categories)
+		updated.Categories = string(categories)
+	}
+	if edit.InformationText != nil {
+		updated.InformationText = *edit.InformationText
+	}
+	updated.UpdatedAt = time.Now()
+
+	renamed := updated.SafeSheetName != old.SafeSheetName
+	moved := renamed || updated.SafeComposer != old.SafeComposer
+	if renamed {
+		var count int64
+		if err := db.Model(&Sheet{}).Where("safe_sheet_name = ?", updated.SafeSheetName).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrSheetExists, updated.SafeSheetName)
+		}
+	}
+	if moved && old.HasPdf() {
+		updated.PdfUrl = "sheet/pdf/" + updated.SafeComposer + "/" + updated.SafeSheetName
+	}
+
+	// 1️⃣ Fichiers
+	journal := &fileJournal{}
+	if moved {
+		if err := moveSheet(journal, &old, &updated, renamed); err != nil {
+			journal.rollback()
+			if errors.Is(err, os.ErrExist) {
+				return nil, fmt.Errorf("%w: %s", ErrSheetExists, updated.SafeSheetName)
+			}
+			return nil, err
+		}
+	}
+
+	// 2️⃣ Base de données
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !renamed {
+			return tx.Model(&Sheet{}).Where("safe_sheet_name = ?", old.SafeSheetName).Updates(map[string]interface{}{
+				"sheet_name":       updated.SheetName,
+				"safe_composer":    updated.SafeComposer,
+				"composer":         updated.Composer,
+				"pdf_url":          updated.PdfUrl,
+				"release_date":     updated.ReleaseDate,
+				"tags":             updated.Tags,
+				"categories":       updated.Categories,
+				"information_text": updated.InformationText,
+				"updated_at":       updated.UpdatedAt,
+			}).Error
+		}
+		// La clé primaire change : nouvelle ligne, rattachement des lignes liées, suppression de l'ancienne
+		row := updated
+		row.Parts, row.Media = nil, nil
+		if err := tx.Omit(clause.Associations).Create(&row).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&SheetPart{}, &SheetMedia{}, &SheetRevision{}, &SheetDistribution{}, &SheetCopy{}, &SheetLoan{}} {
+			if err := tx.Model(model).Where("sheet_safe_name = ?", old.SafeSheetName).Update("sheet_safe_name", updated.SafeSheetName).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&Sheet{}).Where("parent_sheet = ?", old.SafeSheetName).Update("parent_sheet", updated.SafeSheetName).Error; err != nil {
+			return err
+		}
+		return tx.Where("safe_sheet_name = ?", old.SafeSheetName).Delete(&Sheet{}).Error
+	})
+	if err != nil {
+		journal.rollback()
+		return nil, err
+	}
+
+	// Nettoyage après validation : caches recalculés à la demande, dossier du compositeur s'il est vide
+	if moved {
+		os.RemoveAll(path.Join(config.Config().ConfigPath, "sheets/pages", old.SafeSheetName))
+		os.RemoveAll(WatermarkDir(&old))
+		os.Remove(ExcerptPath(&old))
+		os.Remove(path.Dir(PdfPath(&old)))
+	}
+
+	result, err := (&Sheet{}).FindSheetBySafeName(db, updated.SafeSheetName)
+	if err != nil {
+		return nil, err
+	}
+	*s = *result
+	return s, nil
+}
+
+// moveSheet déplace le PDF, le dossier des parties et, si le nom change, le fichier source et les thumbnails
+func moveSheet(journal *fileJournal, from *Sheet, to *Sheet, renamed bool) error {
+	moves := [][2]string{
+		{PdfPath(from), PdfPath(to)},
+		{PartsDir(from), PartsDir(to)},
+	}
+	if renamed {
+		// Le fichier source porte le nom de la partition, dans le dossier déjà déplacé
+		if from.SourceFormat != "" {
+			moves = append(moves, [2]string{path.Join(PartsDir(to), path.Base(SourcePath(from))), SourcePath(to)})
+		}
+		thumbnails := path.Join(config.Config().ConfigPath, "sheets/thumbnails")
+		moves = append(moves, [2]string{path.Join(thumbnails, from.SafeSheetName+".png"), path.Join(thumbnails, to.SafeSheetName+".png")})
+		sized, _ := filepath.Glob(path.Join(thumbnails, "*", from.SafeSheetName+".*"))
+		for _, file := range sized {
+			moves = append(moves, [2]string{file, path.Join(path.Dir(file), to.SafeSheetName+path.Ext(file))})
+		}
+	}
+	for _, move := range moves {
+		if _, err := os.Stat(move[0]); err != nil {
+			continue
+		}
+		if err := journal.rename(move[0], move[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
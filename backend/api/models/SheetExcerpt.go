@@ -0,0 +1,91 @@
+package models
+
+import (
+	"backend/api/config"
+	"errors"
+	"fmt"
+	"path"
+
+	"gorm.io/gorm"
+)
+
+// Pièce d'un recueil (Das Wohltemperierte Klavier, fake book ...) : une Sheet à part entière (titre, compositeur,
+// tags, recherche) sans PDF propre, qui désigne une plage de pages du PDF de la Sheet parente.
+//
+//	sheets/uploaded-sheets/<safe_composer>/<parent>.pdf → recueil complet
+//	sheets/excerpts/<safe_sheet_name>.pdf               → pages de la pièce, extraites à la demande
+var (
+	ErrExcerptOfExcerpt = errors.New("an excerpt cannot contain other pieces")
+	ErrParentWithoutPdf = errors.New("the anthology has no PDF")
+	ErrInvalidPageRange = errors.New("invalid page range")
+	ErrSheetHasExcerpts = errors.New("the sheet is an anthology with excerpts, delete them first")
+)
+
+// IsExcerpt indique si la partition est une pièce d'un recueil
+func (s *Sheet) IsExcerpt() bool {
+	return s.ParentSheet != ""
+}
+
+// ExcerptPath retourne le chemin du PDF extrait d'une pièce de recueil
+func ExcerptPath(sheet *Sheet) string {
+	return path.Join(config.Config().ConfigPath, "sheets/excerpts", sheet.SafeSheetName+".pdf")
+}
+
+// SetExcerpt fait de la partition les pages first à last du recueil parent, sans l'enregistrer.
+// Dimensions et couche texte sont celles du recueil.
+func (s *Sheet) SetExcerpt(parent *Sheet, first int, last int) error {
+	if parent.IsExcerpt() {
+		return ErrExcerptOfExcerpt
+	}
+	if !parent.HasPdf() {
+		return ErrParentWithoutPdf
+	}
+	if first < 1 || last < first || (parent.PageCount > 0 && last > parent.PageCount) {
+		return fmt.Errorf("%w: %d-%d of %d pages", ErrInvalidPageRange, first, last, parent.PageCount)
+	}
+	s.ParentSheet = parent.SafeSheetName
+	s.FirstPage = first
+	s.LastPage = last
+	s.PageCount = last - first + 1
+	s.PageWidth = parent.PageWidth
+	s.PageHeight = parent.PageHeight
+	s.Orientation = parent.Orientation
+	s.Encrypted = parent.Encrypted
+	s.HasText = parent.HasText
+	s.FileSize = 0
+	s.PdfUrl = "sheet/pdf/" + s.SafeComposer + "/" + s.SafeSheetName
+	return nil
+}
+
+// SaveExcerpt enregistre la plage de pages de la pièce dans le recueil parent
+func (s *Sheet) SaveExcerpt(db *gorm.DB, parent *Sheet, first int, last int) error {
+	updated := *s
+	if err := updated.SetExcerpt(parent, first, last); err != nil {
+		return err
+	}
+	err := db.Model(&Sheet{}).Where("safe_sheet_name = ?", s.SafeSheetName).Updates(map[string]interface{}{
+		"parent_sheet": updated.ParentSheet,
+		"first_page":   updated.FirstPage,
+		"last_page":    updated.LastPage,
+		"page_count":   updated.PageCount,
+		"page_width":   updated.PageWidth,
+		"page_height":  updated.PageHeight,
+		"orientation":  updated.Orientation,
+		"encrypted":    updated.Encrypted,
+		"has_text":     updated.HasText,
+		"file_size":    updated.FileSize,
+		"pdf_url":      updated.PdfUrl,
+	}).Error
+	if err != nil {
+		return err
+	}
+	*s = updated
+	return nil
+}
+
+// Excerpts retourne les pièces d'un recueil dans l'ordre des pages
+func (s *Sheet) Excerpts(db *gorm.DB) ([]Sheet, error) {
+	excerpts := []Sheet{}
+	err := db.Model(&Sheet{}).Where("parent_sheet = ?", s.SafeSheetName).Order("first_page asc, last_page asc").Find(&excerpts).Error
+	return excerpts, err
+}
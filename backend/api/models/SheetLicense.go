@@ -0,0 +1,267 @@
+package models
+
+import (
+	"backend/api/config"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Licence d'une partition : domaine public, Creative Commons, licence achetée pour un nombre de places
+// ou location jusqu'à une date. Sans licence déclarée, le statut du domaine public est déduit
+// de la date de décès du compositeur et de la durée de protection de la juridiction (COPYRIGHT_TERM).
+//
+// Le téléchargement d'une location expirée est refusé. Une licence achetée dont le nombre de places
+// est dépassé (utilisateurs ayant téléchargé la partition, voir SheetDistribution) est signalée.
+
+// Licences
+const (
+	LicenseUnknown      = ""
+	LicensePublicDomain = "public-domain"
+	LicenseCC0          = "cc0"
+	LicenseCCBy         = "cc-by"
+	LicenseCCBySA       = "cc-by-sa"
+	LicenseCCByND       = "cc-by-nd"
+	LicenseCCByNC       = "cc-by-nc"
+	LicenseCCByNCSA     = "cc-by-nc-sa"
+	LicenseCCByNCND     = "cc-by-nc-nd"
+	LicensePurchased    = "purchased"
+	LicenseRental       = "rental"
+)
+
+var Licenses = []string{LicensePublicDomain, LicenseCC0, LicenseCCBy, LicenseCCBySA, LicenseCCByND, LicenseCCByNC,
+	LicenseCCByNCSA, LicenseCCByNCND, LicensePurchased, LicenseRental}
+
+// Statut du domaine public calculé à partir du compositeur
+const (
+	PublicDomainYes     = "public_domain"
+	PublicDomainNo      = "protected"
+	PublicDomainUnknown = "unknown" // date de décès inconnue ou compositeur vivant
+)
+
+var (
+	ErrUnknownLicense      = errors.New("unknown license, expected " + strings.Join(Licenses, ", "))
+	ErrRentalWithoutExpiry = errors.New("a rental needs an expiry date")
+	ErrInvalidSeats        = errors.New("seats must not be negative (0 = unlimited)")
+	ErrRentalExpired       = errors.New("the rental of this sheet has expired")
+)
+
+var deathYear = regexp.MustCompile(`^\d{4}`)
+
+// SheetLicense : licence déclarée pour une partition
+type SheetLicense struct {
+	License string
+	Holder  string     // éditeur ou loueur, ex: Bärenreiter
+	Seats   int        // licence achetée : nombre de places, 0 = illimité
+	Expiry  *time.Time // location : dernier jour
+}
+
+// SheetDistribution : utilisateur ayant téléchargé une partition, pour le décompte des places d'une licence
+type SheetDistribution struct {
+	ID            uint32    `gorm:"primary_key;auto_increment" json:"-"`
+	SheetSafeName string    `gorm:"uniqueIndex:idx_sheet_distribution;size:255;not null" json:"sheet_safe_name"`
+	UserID        uint32    `gorm:"uniqueIndex:idx_sheet_distribution;not null" json:"user_id"`
+	Downloads     int       `json:"downloads"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"` // premier téléchargement
+	UpdatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"` // dernier téléchargement
+}
+
+// PublicDomainStatus : statut du domaine public d'une oeuvre selon son compositeur
+type PublicDomainStatus struct {
+	Status    string `json:"status"`               // public_domain, protected ou unknown
+	DeathYear int    `json:"death_year,omitempty"` // année de décès du compositeur
+	Term      int    `json:"term"`                 // durée de protection après le décès, en années
+	Since     int    `json:"since,omitempty"`      // année d'entrée dans le domaine public
+}
+
+// LicenseStatus : licence déclarée, domaine public et usage des places d'une partition
+type LicenseStatus struct {
+	License       string             `json:"license"`
+	Effective     string             `json:"effective"` // licence déclarée, sinon public-domain si le compositeur y est tombé
+	Holder        string             `json:"holder"`
+	Seats         int                `json:"seats"`
+	Distributed   int64              `json:"distributed"` // utilisateurs ayant téléchargé la partition
+	SeatsExceeded bool               `json:"seats_exceeded"`
+	Expiry        *time.Time         `json:"expiry"`
+	Expired       bool               `json:"expired"`
+	PublicDomain  PublicDomainStatus `json:"public_domain"`
+}
+
+// PublicDomain calcule le statut du domaine public : l'oeuvre y entre le 1er janvier
+// qui suit la fin de l'année du décès du compositeur plus term années (règle de la convention de Berne)
+func PublicDomain(composer *Composer, term int, now time.Time) PublicDomainStatus {
+	status := PublicDomainStatus{Status: PublicDomainUnknown, Term: term}
+	if composer == nil {
+		return status
+	}
+	match := deathYear.FindString(strings.TrimSpace(composer.Death))
+	if match == "" {
+		return status
+	}
+	status.DeathYear, _ = strconv.Atoi(match)
+	status.Since = status.DeathYear + term + 1
+	if now.Year() >= status.Since {
+		status.Status = PublicDomainYes
+	} else {
+		status.Status = PublicDomainNo
+	}
+	return status
+}
+
+// RentalExpired indique si la location est terminée : la date d'expiration est le dernier jour inclus
+func (s *Sheet) RentalExpired(now time.Time) bool {
+	return s.License == LicenseRental && s.LicenseExpiry != nil && !now.Before(s.LicenseExpiry.AddDate(0, 0, 1))
+}
+
+// SetLicense enregistre la licence d'une partition. Le nombre de places ne concerne
+// qu'une licence achetée et la date d'expiration qu'une location.
+func SetLicense(db *gorm.DB, sheetName string, license SheetLicense) (*Sheet, error) {
+	license.License = strings.ToLower(strings.TrimSpace(license.License))
+	if license.License != LicenseUnknown && !slices.Contains(Licenses, license.License) {
+		return nil, ErrUnknownLicense
+	}
+	if license.Seats < 0 {
+		return nil, ErrInvalidSeats
+	}
+	if license.License != LicensePurchased {
+		license.Seats = 0
+	}
+	if license.License != LicenseRental {
+		license.Expiry = nil
+	} else if license.Expiry == nil {
+		return nil, ErrRentalWithoutExpiry
+	}
+
+	sheet, err := (&Sheet{}).FindSheetBySafeName(db, sheetName)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Model(&Sheet{}).Where("safe_sheet_name = ?", sheetName).Updates(map[string]interface{}{
+		"license":        license.License,
+		"license_holder": strings.TrimSpace(license.Holder),
+		"license_seats":  license.Seats,
+		"license_expiry": license.Expiry,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	sheet.License = license.License
+	sheet.LicenseHolder = strings.TrimSpace(license.Holder)
+	sheet.LicenseSeats = license.Seats
+	sheet.LicenseExpiry = license.Expiry
+	return sheet, nil
+}
+
+// licensed retourne la partition qui porte la licence : elle-même, ou le recueil d'une pièce sans licence propre
+func (s *Sheet) licensed(db *gorm.DB) (*Sheet, error) {
+	if s.License != LicenseUnknown || !s.IsExcerpt() {
+		return s, nil
+	}
+	return (&Sheet{}).FindSheetBySafeName(db, s.ParentSheet)
+}
+
+// CheckAccess refuse le téléchargement d'une location expirée (y compris les pièces d'un recueil loué)
+func (s *Sheet) CheckAccess(db *gorm.DB, now time.Time) error {
+	licensed, err := s.licensed(db)
+	if err != nil {
+		return err
+	}
+	if licensed.RentalExpired(now) {
+		return fmt.Errorf("%w on %s", ErrRentalExpired, licensed.LicenseExpiry.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// RecordDistribution enregistre le téléchargement de la partition par un utilisateur
+func RecordDistribution(db *gorm.DB, sheetName string, uid uint32) error {
+	distribution := SheetDistribution{SheetSafeName: sheetName, UserID: uid}
+	if err := db.Where(&distribution).FirstOrCreate(&distribution).Error; err != nil {
+		return err
+	}
+	return db.Model(&SheetDistribution{}).Where("id = ?", distribution.ID).Updates(map[string]interface{}{
+		"downloads":  gorm.Expr("downloads + 1"),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// distributed compte les utilisateurs ayant téléchargé la partition ou l'une de ses pièces
+func (s *Sheet) distributed(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Model(&SheetDistribution{}).
+		Where("sheet_safe_name = ? OR sheet_safe_name IN (?)", s.SafeSheetName,
+			db.Model(&Sheet{}).Select("safe_sheet_name").Where("parent_sheet = ?", s.SafeSheetName)).
+		Distinct("user_id").Count(&count).Error
+	return count, err
+}
+
+// LicenseStatus retourne la licence de la partition, son statut de domaine public et l'usage de ses places
+func (s *Sheet) LicenseStatus(db *gorm.DB, now time.Time) (*LicenseStatus, error) {
+	licensed, err := s.licensed(db)
+	if err != nil {
+		return nil, err
+	}
+	composer, err := (&Composer{}).FindComposerBySafeName(db, s.SafeComposer)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	status := &LicenseStatus{
+		License:      licensed.License,
+		Effective:    licensed.License,
+		Holder:       licensed.LicenseHolder,
+		Seats:        licensed.LicenseSeats,
+		Expiry:       licensed.LicenseExpiry,
+		Expired:      licensed.RentalExpired(now),
+		PublicDomain: PublicDomain(composer, config.Config().Copyright.Term, now),
+	}
+	if status.License == LicenseUnknown && status.PublicDomain.Status == PublicDomainYes {
+		status.Effective = LicensePublicDomain
+	}
+	if status.Distributed, err = licensed.distributed(db); err != nil {
+		return nil, err
+	}
+	status.SeatsExceeded = licensed.License == LicensePurchased && licensed.LicenseSeats > 0 && status.Distributed > int64(licensed.LicenseSeats)
+	return status, nil
+}
+
+// SeatUsage : licence achetée dont le nombre de places est dépassé
+type SeatUsage struct {
+	Sheet       Sheet `json:"sheet"`
+	Seats       int   `json:"seats"`
+	Distributed int64 `json:"distributed"`
+}
+
+// LicenseIssues retourne les locations expirées et les licences achetées dont le nombre de places est dépassé
+func LicenseIssues(db *gorm.DB, now time.Time) ([]Sheet, []SeatUsage, error) {
+	var rentals []Sheet
+	if err := db.Where("license = ?", LicenseRental).Order("license_expiry asc").Find(&rentals).Error; err != nil {
+		return nil, nil, err
+	}
+	expired := []Sheet{}
+	for _, sheet := range rentals {
+		if sheet.RentalExpired(now) {
+			expired = append(expired, sheet)
+		}
+	}
+
+	var purchased []Sheet
+	if err := db.Where("license = ? AND license_seats > 0", LicensePurchased).Order("safe_sheet_name asc").Find(&purchased).Error; err != nil {
+		return nil, nil, err
+	}
+	overused := []SeatUsage{}
+	for i := range purchased {
+		count, err := purchased[i].distributed(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		if count > int64(purchased[i].LicenseSeats) {
+			overused = append(overused, SeatUsage{Sheet: purchased[i], Seats: purchased[i].LicenseSeats, Distributed: count})
+		}
+	}
+	return expired, overused, nil
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"backend/api/media"
+	"backend/api/utils"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SheetMedia : enregistrement de référence, fichier MIDI ou piste d'accompagnement joint à une partition.
+// Les fichiers sont rangés à côté des parties :
+//
+//	sheets/uploaded-sheets/<safe_composer>/<safe_sheet_name>/media/<safe_label>.<mp3|ogg|flac|mid>
+type SheetMedia struct {
+	ID            uint32    `gorm:"primary_key;auto_increment" json:"-"`
+	SheetSafeName string    `gorm:"index;not null" json:"-"`
+	Label         string    `json:"label"`                      // ex: Horowitz 1966
+	SafeLabel     string    `gorm:"not null" json:"safe_label"` // unique pour une Sheet
+	Kind          string    `gorm:"size:16" json:"kind"`        // recording, midi ou practice
+	Format        string    `gorm:"size:16" json:"format"`      // mp3, ogg, flac ou midi
+	Codec         string    `gorm:"size:16" json:"codec,omitempty"`
+	MimeType      string    `gorm:"size:32" json:"mime_type"`
+	Duration      float64   `json:"duration"`  // en secondes
+	FileSize      int64     `json:"file_size"` // en octets
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// Types de médias
+const (
+	MediaRecording = "recording"
+	MediaMidi      = "midi"
+	MediaPractice  = "practice"
+)
+
+var MediaKinds = []string{MediaRecording, MediaMidi, MediaPractice}
+
+var (
+	ErrMediaExists   = errors.New("a media with this label already exists")
+	ErrMediaNotFound = errors.New("media not found")
+)
+
+// MediaDir retourne le dossier des médias d'une Sheet
+func MediaDir(sheet *Sheet) string {
+	return path.Join(PartsDir(sheet), "media")
+}
+
+// Path retourne le chemin du fichier média
+func (m *SheetMedia) Path(sheet *Sheet) string {
+	return path.Join(MediaDir(sheet), m.SafeLabel+media.Extensions[m.Format])
+}
+
+// OrderedMedia précharge les médias d'une Sheet dans l'ordre d'ajout
+func OrderedMedia(db *gorm.DB) *gorm.DB {
+	return db.Order("id asc")
+}
+
+// AddMedia enregistre un média décrit par info et écrit son fichier.
+// Sans type précisé, un fichier MIDI est de type midi et un fichier audio de type recording.
+// Le fichier est supprimé si l'enregistrement en base échoue.
+func (s *Sheet) AddMedia(db *gorm.DB, item *SheetMedia, info *media.Info, file io.Reader) error {
+	item.Label = strings.TrimSpace(item.Label)
+	item.SafeLabel = utils.SanitizeName(item.Label)
+	if item.SafeLabel == "" {
+		return ErrEmptyLabel
+	}
+	item.SheetSafeName = s.SafeSheetName
+	item.Format = info.Format
+	item.Codec = info.Codec
+	item.MimeType = info.MimeType
+	item.Duration = info.Duration
+	item.FileSize = info.Size
+	if item.Kind == "" {
+		item.Kind = MediaRecording
+		if info.Format == media.FormatMIDI {
+			item.Kind = MediaMidi
+		}
+	}
+
+	var count int64
+	if err := db.Model(&SheetMedia{}).Where("sheet_safe_name = ? AND safe_label = ?", s.SafeSheetName, item.SafeLabel).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrMediaExists
+	}
+
+	target := item.Path(s)
+	if err := os.MkdirAll(path.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, file); err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(target)
+		return err
+	}
+
+	if err := db.Create(item).Error; err != nil {
+		os.Remove(target)
+		return err
+	}
+	return nil
+}
+
+// FindMedia retourne le média d'une Sheet par son label "safe"
+func (s *Sheet) FindMedia(db *gorm.DB, safeLabel string) (*SheetMedia, error) {
+	var item SheetMedia
+	err := db.Where("sheet_safe_name = ? AND safe_label = ?", s.SafeSheetName, safeLabel).Take(&item).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMediaNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// DeleteMedia supprime un média et son fichier
+func (s *Sheet) DeleteMedia(db *gorm.DB, safeLabel string) error {
+	item, err := s.FindMedia(db, safeLabel)
+	if err != nil {
+		return err
+	}
+	if err := db.Delete(item).Error; err != nil {
+		return err
+	}
+	if err := os.Remove(item.Path(s)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// Dossiers supprimés uniquement s'ils sont vides
+	os.Remove(MediaDir(s))
+	os.Remove(PartsDir(s))
+	return nil
+}
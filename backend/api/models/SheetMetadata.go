@@ -0,0 +1,146 @@
+package models
+
+import (
+	"backend/api/catalogue"
+	"log"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SheetMetadata : informations musicales d'une partition modifiables à l'upload ou par PUT /sheet/:sheetName/metadata.
+// Un champ nil n'est pas modifié, une chaîne vide (ou 0) efface la valeur.
+// Les valeurs sont supposées déjà validées et normalisées (voir forms.SheetMetadataRequest).
+type SheetMetadata struct {
+	CatalogueType   *string
+	CatalogueNumber *string
+	Key             *string
+	Instrumentation *string
+	Difficulty      *int
+	Duration        *int
+	Arranger        *string
+	Lyricist        *string
+	Publisher       *string
+	Language        *string
+	Editor          *string
+	EditionYear     *int
+}
+
+// SetMetadata recopie les informations renseignées dans la partition, sans l'enregistrer,
+// et retourne les colonnes modifiées
+func (s *Sheet) SetMetadata(m SheetMetadata) map[string]interface{} {
+	columns := map[string]interface{}{}
+	for _, field := range []struct {
+		column string
+		target *string
+		value  *string
+	}{
+		{"catalogue_type", &s.CatalogueType, m.CatalogueType},
+		{"catalogue_number", &s.CatalogueNumber, m.CatalogueNumber},
+		{"musical_key", &s.Key, m.Key},
+		{"instrumentation", &s.Instrumentation, m.Instrumentation},
+		{"arranger", &s.Arranger, m.Arranger},
+		{"lyricist", &s.Lyricist, m.Lyricist},
+		{"publisher", &s.Publisher, m.Publisher},
+		{"language", &s.Language, m.Language},
+		{"editor", &s.Editor, m.Editor},
+	} {
+		if field.value != nil {
+			*field.target = strings.TrimSpace(*field.value)
+			columns[field.column] = *field.target
+		}
+	}
+	if m.CatalogueNumber != nil {
+		s.CatalogueSort = catalogue.SortKeyOf(s.CatalogueNumber)
+		columns["catalogue_sort"] = s.CatalogueSort
+	}
+	if m.Difficulty != nil {
+		s.Difficulty = *m.Difficulty
+		columns["difficulty"] = s.Difficulty
+	}
+	if m.Duration != nil {
+		s.Duration = *m.Duration
+		columns["duration"] = s.Duration
+	}
+	if m.EditionYear != nil {
+		s.EditionYear = *m.EditionYear
+		columns["edition_year"] = s.EditionYear
+	}
+	return columns
+}
+
+// UpdateMetadata enregistre les informations renseignées
+func (s *Sheet) UpdateMetadata(db *gorm.DB, m SheetMetadata) error {
+	updated := *s
+	columns := updated.SetMetadata(m)
+	if len(columns) == 0 {
+		return nil
+	}
+	if err := db.Model(&Sheet{}).Where("safe_sheet_name = ?", s.SafeSheetName).Updates(columns).Error; err != nil {
+		return err
+	}
+	*s = updated
+	return nil
+}
+
+// FillCatalogueNumbers complète le numéro de catalogue des partitions qui n'en ont pas à partir de leur titre
+// ("Prelude in C major, BWV 846"), et la clé de tri des numéros saisis. Retourne le nombre de partitions modifiées.
+func FillCatalogueNumbers(db *gorm.DB) (int, error) {
+	var sheets []Sheet
+	err := db.Model(&Sheet{}).Select("safe_sheet_name", "sheet_name", "catalogue_type", "catalogue_number", "catalogue_sort").
+		Where("catalogue_number = '' OR catalogue_number IS NULL OR catalogue_sort = '' OR catalogue_sort IS NULL").Find(&sheets).Error
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, sheet := range sheets {
+		var m SheetMetadata
+		if sheet.CatalogueNumber == "" {
+			number, ok := catalogue.Parse(sheet.SheetName)
+			if !ok || (sheet.CatalogueType != "" && sheet.CatalogueType != number.System) {
+				continue
+			}
+			m = SheetMetadata{CatalogueType: &number.System, CatalogueNumber: &number.Number}
+		} else {
+			if catalogue.SortKeyOf(sheet.CatalogueNumber) == "" {
+				continue // numéro libre, non triable
+			}
+			m = SheetMetadata{CatalogueNumber: &sheet.CatalogueNumber}
+		}
+		if err := sheet.UpdateMetadata(db, m); err != nil {
+			log.Printf("catalogue number of %s: %v\n", sheet.SafeSheetName, err)
+			continue
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// ComposerCatalogue retourne les partitions d'un compositeur triées par catalogue, dans l'ordre de catalogue.Systems,
+// puis par numéro, les partitions sans numéro en dernier. Avec system, seul ce catalogue est retenu.
+func ComposerCatalogue(db *gorm.DB, safeComposer string, system string) ([]Sheet, error) {
+	query := db.Model(&Sheet{}).Where("safe_composer = ?", safeComposer)
+	if system != "" {
+		query = query.Where("catalogue_type = ?", system)
+	}
+	var sheets []Sheet
+	if err := query.Order("catalogue_sort").Order("sheet_name").Find(&sheets).Error; err != nil {
+		return nil, err
+	}
+
+	rank := func(s Sheet) int {
+		if s.CatalogueNumber == "" {
+			return len(catalogue.Systems) + 1
+		}
+		for i, system := range catalogue.Systems {
+			if s.CatalogueType == system.Name {
+				return i
+			}
+		}
+		return len(catalogue.Systems)
+	}
+	sort.SliceStable(sheets, func(i, j int) bool { return rank(sheets[i]) < rank(sheets[j]) })
+	return sheets, nil
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"backend/api/config"
+	"backend/api/utils"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SheetPart : partie séparée d'une oeuvre (Violon I, Violon II, Alto, Violoncelle ...)
+// Le PDF principal de la Sheet reste le conducteur, les parties sont rangées à côté :
+//
+//	sheets/uploaded-sheets/<safe_composer>/<safe_sheet_name>.pdf              → conducteur
+//	sheets/uploaded-sheets/<safe_composer>/<safe_sheet_name>/<safe_label>.pdf → parties
+type SheetPart struct {
+	ID            uint32    `gorm:"primary_key;auto_increment" json:"-"`
+	SheetSafeName string    `gorm:"index;not null" json:"-"`
+	Label         string    `json:"label"`                      // ex: Violin I
+	SafeLabel     string    `gorm:"not null" json:"safe_label"` // ex: violin-i, unique pour une Sheet
+	Position      int       `json:"position"`                   // ordre d'affichage
+	FileHash      string    `gorm:"size:64" json:"file_hash"`   // SHA-256 du PDF
+	FileSize      int64     `json:"file_size"`                  // en octets
+	PageCount     int       `json:"page_count"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+var (
+	ErrPartExists   = errors.New("a part with this label already exists")
+	ErrPartNotFound = errors.New("part not found")
+	ErrEmptyLabel   = errors.New("empty part label")
+)
+
+// PartsDir retourne le dossier des parties d'une Sheet
+func PartsDir(sheet *Sheet) string {
+	return path.Join(config.Config().ConfigPath, "sheets/uploaded-sheets", sheet.SafeComposer, sheet.SafeSheetName)
+}
+
+// PartPath retourne le chemin du PDF d'une partie
+func PartPath(sheet *Sheet, safeLabel string) string {
+	return path.Join(PartsDir(sheet), safeLabel+".pdf")
+}
+
+// OrderedParts précharge les parties d'une Sheet dans l'ordre d'affichage
+func OrderedParts(db *gorm.DB) *gorm.DB {
+	return db.Order("position asc, id asc")
+}
+
+// AddPart enregistre une partie et écrit son PDF.
+// Une position à 0 place la partie après les autres.
+// Le fichier est supprimé si l'enregistrement en base échoue.
+func (s *Sheet) AddPart(db *gorm.DB, part *SheetPart, file io.Reader) error {
+	part.Label = strings.TrimSpace(part.Label)
+	part.SafeLabel = utils.SanitizeName(part.Label)
+	if part.SafeLabel == "" {
+		return ErrEmptyLabel
+	}
+	part.SheetSafeName = s.SafeSheetName
+
+	var count int64
+	if err := db.Model(&SheetPart{}).Where("sheet_safe_name = ? AND safe_label = ?", s.SafeSheetName, part.SafeLabel).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrPartExists
+	}
+	if part.Position == 0 {
+		var last int
+		db.Model(&SheetPart{}).Where("sheet_safe_name = ?", s.SafeSheetName).Select("COALESCE(MAX(position), 0)").Scan(&last)
+		part.Position = last + 1
+	}
+
+	target := PartPath(s, part.SafeLabel)
+	if err := os.MkdirAll(path.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, file); err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(target)
+		return err
+	}
+
+	if err := db.Create(part).Error; err != nil {
+		os.Remove(target)
+		return err
+	}
+	return nil
+}
+
+// FindPart retourne la partie d'une Sheet par son label "safe"
+func (s *Sheet) FindPart(db *gorm.DB, safeLabel string) (*SheetPart, error) {
+	var part SheetPart
+	err := db.Where("sheet_safe_name = ? AND safe_label = ?", s.SafeSheetName, safeLabel).Take(&part).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartNotFound
+		}
+		return nil, err
+	}
+	return &part, nil
+}
+
+// DeletePart supprime une partie et son PDF
+func (s *Sheet) DeletePart(db *gorm.DB, safeLabel string) error {
+	part, err := s.FindPart(db, safeLabel)
+	if err != nil {
+		return err
+	}
+	if err := db.Delete(part).Error; err != nil {
+		return err
+	}
+	if err := os.Remove(PartPath(s, part.SafeLabel)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	os.Remove(PartsDir(s)) // uniquement si vide
+	return nil
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"backend/api/config"
+	"backend/api/pdf"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SheetRevision : version antérieure du PDF d'une partition, conservée avant chaque modification des pages
+// (rotation, suppression, réordonnancement, recadrage, découpage) ou restauration.
+// Les fichiers sont rangés à côté des parties :
+//
+//	sheets/uploaded-sheets/<safe_composer>/<safe_sheet_name>/revisions/<revision>.pdf
+type SheetRevision struct {
+	ID            uint32    `gorm:"primary_key;auto_increment" json:"-"`
+	SheetSafeName string    `gorm:"index;not null" json:"-"`
+	Revision      int       `gorm:"not null" json:"revision"` // 1 = PDF uploadé, numéroté dans l'ordre des modifications
+	Operation     string    `gorm:"size:32" json:"operation"` // modification qui a remplacé cette version, ex: rotate
+	FileHash      string    `gorm:"size:64" json:"file_hash"` // SHA-256 de cette version
+	FileSize      int64     `json:"file_size"`                // en octets
+	PageCount     int       `json:"page_count"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"` // date du remplacement
+}
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// PdfPath retourne le chemin du PDF d'une partition
+func PdfPath(sheet *Sheet) string {
+	return path.Join(config.Config().ConfigPath, "sheets/uploaded-sheets", sheet.SafeComposer, sheet.SafeSheetName+".pdf")
+}
+
+// RevisionPath retourne le chemin d'une version antérieure du PDF
+func RevisionPath(sheet *Sheet, revision int) string {
+	return path.Join(PartsDir(sheet), "revisions", strconv.Itoa(revision)+".pdf")
+}
+
+// Revisions retourne les versions antérieures du PDF, la plus récente en premier
+func (s *Sheet) Revisions(db *gorm.DB) ([]SheetRevision, error) {
+	revisions := []SheetRevision{}
+	err := db.Where("sheet_safe_name = ?", s.SafeSheetName).Order("revision desc").Find(&revisions).Error
+	return revisions, err
+}
+
+// FindRevision retourne une version antérieure du PDF
+func (s *Sheet) FindRevision(db *gorm.DB, revision int) (*SheetRevision, error) {
+	var found SheetRevision
+	err := db.Where("sheet_safe_name = ? AND revision = ?", s.SafeSheetName, revision).Take(&found).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
+
+// ReplacePdf remplace le PDF de la partition par newFile : le PDF actuel devient la version antérieure
+// Revision+1 et la structure, l'empreinte et le numéro de révision sont mis à jour.
+// Les fichiers sont remis en place si l'enregistrement en base échoue.
+func (s *Sheet) ReplacePdf(db *gorm.DB, newFile string, operation string) error {
+	structure, hash, err := analyzeFile(newFile)
+	if err != nil {
+		return err
+	}
+	updated := *s
+	updated.SetStructure(structure)
+	updated.FileHash = hash
+	updated.Revision = s.Revision + 1
+	return s.replacePdf(db, newFile, operation, &updated)
+}
+
+// AttachPdf installe newFile comme PDF d'une partition qui n'en a pas encore (uploadée en MusicXML ou MuseScore).
+// Il n'y a pas de version antérieure à conserver.
+func (s *Sheet) AttachPdf(db *gorm.DB, newFile string) error {
+	structure, hash, err := analyzeFile(newFile)
+	if err != nil {
+		return err
+	}
+	updated := *s
+	updated.SetStructure(structure)
+	updated.FileHash = hash
+	updated.PdfUrl = "sheet/pdf/" + s.SafeComposer + "/" + s.SafeSheetName
+
+	current := PdfPath(s)
+	if err := os.Rename(newFile, current); err != nil {
+		return err
+	}
+	err = db.Model(&Sheet{}).Where("safe_sheet_name = ?", s.SafeSheetName).Updates(map[string]interface{}{
+		"pdf_url":     updated.PdfUrl,
+		"page_count":  updated.PageCount,
+		"page_width":  updated.PageWidth,
+		"page_height": updated.PageHeight,
+		"orientation": updated.Orientation,
+		"file_size":   updated.FileSize,
+		"encrypted":   updated.Encrypted,
+		"has_text":    updated.HasText,
+		"file_hash":   updated.FileHash,
+	}).Error
+	if err != nil {
+		os.Rename(current, newFile)
+		return err
+	}
+	*s = updated
+	return nil
+}
+
+// analyzeFile retourne la structure et l'empreinte SHA-256 d'un PDF
+func analyzeFile(fullpath string) (*pdf.Structure, string, error) {
+	f, err := os.Open(fullpath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	structure, err := pdf.Analyze(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path.Base(fullpath), err)
+	}
+	hash, err := utils.HashReader(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path.Base(fullpath), err)
+	}
+	return structure, hash, nil
+}
+
+func (s *Sheet) replacePdf(db *gorm.DB, newFile string, operation string, updated *Sheet) error {
+	current := PdfPath(s)
+	journal := &fileJournal{}
+	if err := journal.rename(current, RevisionPath(s, updated.Revision)); err != nil {
+		return err
+	}
+	if err := journal.rename(newFile, current); err != nil {
+		journal.rollback()
+		return err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		revision := SheetRevision{
+			SheetSafeName: s.SafeSheetName,
+			Revision:      updated.Revision,
+			Operation:     operation,
+			FileHash:      s.FileHash,
+			FileSize:      s.FileSize,
+			PageCount:     s.PageCount,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Sheet{}).Where("safe_sheet_name = ?", s.SafeSheetName).Updates(map[string]interface{}{
+			"page_count":  updated.PageCount,
+			"page_width":  updated.PageWidth,
+			"page_height": updated.PageHeight,
+			"orientation": updated.Orientation,
+			"file_size":   updated.FileSize,
+			"encrypted":   updated.Encrypted,
+			"has_text":    updated.HasText,
+			"file_hash":   updated.FileHash,
+			"revision":    updated.Revision,
+		}).Error
+	})
+	if err != nil {
+		journal.rollback()
+		return err
+	}
+	*s = *updated
+	return nil
+}
+
+// RestoreRevision remet en place une version antérieure du PDF.
+// Le PDF actuel est lui-même conservé comme version antérieure : l'historique n'est jamais réécrit.
+func (s *Sheet) RestoreRevision(db *gorm.DB, revision int) error {
+	if _, err := s.FindRevision(db, revision); err != nil {
+		return err
+	}
+	src, err := os.ReadFile(RevisionPath(s, revision))
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(path.Dir(PdfPath(s)), ".restore-*.pdf")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = s.ReplacePdf(db, tmp.Name(), "restore "+strconv.Itoa(revision))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"backend/api/score"
+	"io"
+	"os"
+	"path"
+
+	"gorm.io/gorm"
+)
+
+// Fichier source (MusicXML, MusicXML compressé, MuseScore ou ABC) d'une partition, rangé avec les parties :
+//
+//	sheets/uploaded-sheets/<safe_composer>/<safe_sheet_name>/<safe_sheet_name>.<musicxml|mxl|mscz|abc>
+
+// SourcePath retourne le chemin du fichier source, vide si la partition n'en a pas
+func SourcePath(sheet *Sheet) string {
+	if sheet.SourceFormat == "" {
+		return ""
+	}
+	return path.Join(PartsDir(sheet), sheet.SafeSheetName+score.Extensions[sheet.SourceFormat])
+}
+
+// WriteSource enregistre le fichier source décrit par meta, en remplaçant le précédent
+// (y compris s'il était dans un autre format), puis met à jour les informations musicales de la partition
+func (s *Sheet) WriteSource(db *gorm.DB, meta *score.Metadata, file io.Reader) error {
+	previous := SourcePath(s)
+	updated := *s
+	updated.SetScore(meta)
+	target := SourcePath(&updated)
+
+	if err := os.MkdirAll(path.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(path.Dir(target), ".source-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, file); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	err = db.Model(&Sheet{}).Where("safe_sheet_name = ?", s.SafeSheetName).Updates(map[string]interface{}{
+		"source_format":  updated.SourceFormat,
+		"musical_key":    updated.Key,
+		"time_signature": updated.TimeSignature,
+		"tempo":          updated.Tempo,
+		"instruments":    updated.Instruments,
+	}).Error
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+	if previous != "" && previous != target {
+		os.Remove(previous)
+	}
+	*s = updated
+	return nil
+}
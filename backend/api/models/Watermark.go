@@ -0,0 +1,143 @@
+package models
+
+import (
+	"backend/api/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Filigrane des PDF téléchargés : l'email de l'utilisateur, la date et un pied de page sont ajoutés à chaque page.
+// Une partition est filigranée si sa politique (Sheet.Watermark) est on, ou si elle hérite (vide)
+// et a une catégorie filigranée. Les PDF filigranés sont mis en cache par utilisateur, fichier, révision et jour :
+//
+//	sheets/watermarked/<safe_sheet_name>/<uid>_<label>_<revision>_<date>_<hash>.pdf
+//
+// label distingue les fichiers de la partition (sheet, part-violin-i, revision-2), hash est l'empreinte de l'email et du pied de page.
+// La date (AAAA-MM-JJ) fait expirer le cache chaque jour : la date imprimée est celle du téléchargement.
+type WatermarkCategory struct {
+	Category  string    `gorm:"primary_key;size:128" json:"category"` // en minuscules
+	Footer    string    `gorm:"size:255" json:"footer"`               // vide = pied de page de la configuration
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// Politique de filigrane d'une partition
+const (
+	WatermarkInherit = ""    // selon les catégories
+	WatermarkOn      = "on"  // toujours
+	WatermarkOff     = "off" // jamais, même pour une catégorie filigranée
+)
+
+var (
+	ErrInvalidWatermark          = errors.New("watermark must be on, off or inherit")
+	ErrEmptyWatermarkCategory    = errors.New("category is required")
+	ErrWatermarkCategoryNotFound = errors.New("category is not watermarked")
+)
+
+// WatermarkDir retourne le dossier des PDF filigranés d'une partition
+func WatermarkDir(sheet *Sheet) string {
+	return path.Join(config.Config().ConfigPath, "sheets/watermarked", sheet.SafeSheetName)
+}
+
+// normalizeCategory : les catégories sont comparées sans tenir compte de la casse
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// SetSheetWatermark change la politique de filigrane d'une partition : on, off ou inherit
+func SetSheetWatermark(db *gorm.DB, sheetName string, mode string) (*Sheet, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "inherit" {
+		mode = WatermarkInherit
+	}
+	if mode != WatermarkInherit && mode != WatermarkOn && mode != WatermarkOff {
+		return nil, ErrInvalidWatermark
+	}
+	sheet, err := (&Sheet{}).FindSheetBySafeName(db, sheetName)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Model(&Sheet{}).Where("safe_sheet_name = ?", sheetName).Update("watermark", mode).Error; err != nil {
+		return nil, err
+	}
+	sheet.Watermark = mode
+	return sheet, nil
+}
+
+// SetWatermarkCategory filigrane les partitions d'une catégorie, avec un pied de page propre ou celui de la configuration
+func SetWatermarkCategory(db *gorm.DB, category string, footer string) (*WatermarkCategory, error) {
+	policy := WatermarkCategory{Category: normalizeCategory(category), Footer: strings.TrimSpace(footer)}
+	if policy.Category == "" {
+		return nil, ErrEmptyWatermarkCategory
+	}
+	err := db.Where("category = ?", policy.Category).Assign(WatermarkCategory{Footer: policy.Footer}).FirstOrCreate(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// DeleteWatermarkCategory retire le filigrane d'une catégorie
+func DeleteWatermarkCategory(db *gorm.DB, category string) error {
+	result := db.Where("category = ?", normalizeCategory(category)).Delete(&WatermarkCategory{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrWatermarkCategoryNotFound, category)
+	}
+	return nil
+}
+
+// ListWatermarkCategories retourne les catégories filigranées par ordre alphabétique
+func ListWatermarkCategories(db *gorm.DB) ([]WatermarkCategory, error) {
+	categories := []WatermarkCategory{}
+	err := db.Order("category asc").Find(&categories).Error
+	return categories, err
+}
+
+// WatermarkFooter indique si les téléchargements de la partition sont filigranés et retourne le pied de page.
+// Le pied de page est celui de la première catégorie filigranée qui en définit un, sinon celui de la configuration.
+// Une pièce de recueil sans politique propre ni catégorie filigranée suit celle du recueil.
+func (s *Sheet) WatermarkFooter(db *gorm.DB) (bool, string, error) {
+	if s.Watermark == WatermarkOff {
+		return false, "", nil
+	}
+	var categories []string
+	if s.Categories != "" {
+		if err := json.Unmarshal([]byte(s.Categories), &categories); err != nil {
+			return false, "", err
+		}
+	}
+	for i := range categories {
+		categories[i] = normalizeCategory(categories[i])
+	}
+	var policies []WatermarkCategory
+	if len(categories) > 0 {
+		if err := db.Where("category IN ?", categories).Order("category asc").Find(&policies).Error; err != nil {
+			return false, "", err
+		}
+	}
+	if s.Watermark != WatermarkOn && len(policies) == 0 {
+		// Une pièce de recueil suit la politique du recueil
+		if s.IsExcerpt() {
+			parent, err := (&Sheet{}).FindSheetBySafeName(db, s.ParentSheet)
+			if err != nil {
+				return false, "", err
+			}
+			return parent.WatermarkFooter(db)
+		}
+		return false, "", nil
+	}
+	for _, policy := range policies {
+		if policy.Footer != "" {
+			return true, policy.Footer, nil
+		}
+	}
+	return true, config.Config().Watermark.Footer, nil
+}
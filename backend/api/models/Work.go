@@ -0,0 +1,213 @@
+package models
+
+import (
+	"backend/api/catalogue"
+	"backend/api/utils"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Work : oeuvre d'un compositeur (titre de référence et numéro de catalogue), indépendante de ses éditions.
+// Chaque édition possédée (Henle Urtext, Peters, arrangement ...) est une Sheet rattachée à l'oeuvre par WorkSafeName :
+//
+//	Work  bach-prelude-in-c-major-bwv-846
+//	  ├── Sheet prelude-in-c-major-bwv-846          (Henle, 2007)
+//	  └── Sheet prelude-in-c-major-bwv-846-peters   (Peters, 1950)
+type Work struct {
+	SafeName        string    `gorm:"primary_key" json:"safe_name"` // ex: bach-prelude-in-c-major-bwv-846
+	Title           string    `json:"title"`                        // titre de référence, ex: Prelude in C major
+	SafeComposer    string    `gorm:"index" json:"safe_composer"`
+	Composer        string    `json:"composer"`
+	CatalogueType   string    `gorm:"size:16;index" json:"catalogue_type"`
+	CatalogueNumber string    `gorm:"size:32" json:"catalogue_number"`
+	CatalogueSort   string    `gorm:"size:64;index" json:"catalogue_sort"`
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Editions chargées par FindWork et ListWorks, pas de contrainte en base :
+	// une Sheet sans oeuvre a un WorkSafeName vide
+	Editions []Sheet `gorm:"-" json:"editions"`
+}
+
+var (
+	ErrWorkNotFound      = errors.New("work not found")
+	ErrWorkExists        = errors.New("a work with this title already exists for this composer")
+	ErrEmptyWorkTitle    = errors.New("empty work title")
+	ErrEditionComposer   = errors.New("the edition has another composer than the work")
+	ErrEditionLinked     = errors.New("the edition is already linked to another work, unlink it first")
+	ErrEditionNotLinked  = errors.New("the edition is not linked to this work")
+	ErrEditionNotFound   = errors.New("edition not found")
+	ErrEmptyWorkComposer = errors.New("composer is required")
+)
+
+// WorkSafeName construit la clé d'une oeuvre : compositeur, titre, puis numéro de catalogue s'il n'est pas déjà dans le titre
+func WorkSafeName(safeComposer string, title string, number catalogue.Number) string {
+	name := safeComposer + " " + title
+	if number.Number != "" {
+		if found, ok := catalogue.Parse(title); !ok || found.String() != number.String() {
+			name += " " + number.String()
+		}
+	}
+	return utils.SanitizeName(name)
+}
+
+// OrderedEditions trie les éditions d'une oeuvre par année, puis par éditeur
+func OrderedEditions(db *gorm.DB) *gorm.DB {
+	return db.Order("edition_year asc, publisher asc, safe_sheet_name asc")
+}
+
+// CreateWork enregistre une oeuvre pour un compositeur existant.
+// Le numéro de catalogue est supposé déjà normalisé (voir forms.CreateWorkRequest).
+func CreateWork(db *gorm.DB, w *Work) error {
+	w.Title = strings.TrimSpace(w.Title)
+	if w.Title == "" {
+		return ErrEmptyWorkTitle
+	}
+	if w.SafeComposer == "" {
+		return ErrEmptyWorkComposer
+	}
+	composer, err := (&Composer{}).FindComposerBySafeName(db, w.SafeComposer)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrComposerNotFound
+		}
+		return err
+	}
+	w.Composer = composer.Name
+	w.CatalogueNumber = strings.TrimSpace(w.CatalogueNumber)
+	w.CatalogueSort = catalogue.SortKeyOf(w.CatalogueNumber)
+	if w.CatalogueNumber == "" {
+		w.CatalogueType = ""
+	}
+	w.SafeName = WorkSafeName(w.SafeComposer, w.Title, catalogue.Number{System: w.CatalogueType, Number: w.CatalogueNumber})
+
+	var count int64
+	if err := db.Model(&Work{}).Where("safe_name = ?", w.SafeName).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrWorkExists, w.SafeName)
+	}
+	w.CreatedAt = time.Now()
+	w.UpdatedAt = time.Now()
+	return db.Create(w).Error
+}
+
+// FindWork retourne une oeuvre et ses éditions
+func FindWork(db *gorm.DB, safeName string) (*Work, error) {
+	var work Work
+	if err := db.Where("safe_name = ?", safeName).Take(&work).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkNotFound
+		}
+		return nil, err
+	}
+	if err := OrderedEditions(db.Where("work_safe_name = ?", work.SafeName)).Find(&work.Editions).Error; err != nil {
+		return nil, err
+	}
+	return &work, nil
+}
+
+// ListWorks retourne une page d'oeuvres avec leurs éditions, éventuellement limitée à un compositeur
+func ListWorks(db *gorm.DB, pagination Pagination, safeComposer string) (*Pagination, error) {
+	var works []*Work
+	filtered := db.Model(&Work{})
+	if safeComposer != "" {
+		filtered = filtered.Where("safe_composer = ?", safeComposer)
+	}
+	filtered = filtered.Session(&gorm.Session{})
+	if err := filtered.Scopes(paginate(works, &pagination, filtered)).Find(&works).Error; err != nil {
+		return nil, err
+	}
+
+	if len(works) > 0 {
+		names := make([]string, len(works))
+		byName := make(map[string]*Work, len(works))
+		for i, work := range works {
+			names[i] = work.SafeName
+			byName[work.SafeName] = work
+			work.Editions = []Sheet{}
+		}
+		var editions []Sheet
+		if err := OrderedEditions(db.Where("work_safe_name IN ?", names)).Find(&editions).Error; err != nil {
+			return nil, err
+		}
+		for _, edition := range editions {
+			work := byName[edition.WorkSafeName]
+			work.Editions = append(work.Editions, edition)
+		}
+	}
+	pagination.Rows = works
+	return &pagination, nil
+}
+
+// LinkEdition rattache une partition à une oeuvre du même compositeur
+func LinkEdition(db *gorm.DB, workSafeName string, sheetSafeName string) (*Sheet, error) {
+	work, err := FindWork(db, workSafeName)
+	if err != nil {
+		return nil, err
+	}
+	var sheet Sheet
+	if err := db.Where("safe_sheet_name = ?", sheetSafeName).Take(&sheet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEditionNotFound
+		}
+		return nil, err
+	}
+	if sheet.SafeComposer != work.SafeComposer {
+		return nil, fmt.Errorf("%w: %s", ErrEditionComposer, sheet.SafeComposer)
+	}
+	if sheet.WorkSafeName == work.SafeName {
+		return &sheet, nil
+	}
+	if sheet.WorkSafeName != "" {
+		return nil, fmt.Errorf("%w: %s", ErrEditionLinked, sheet.WorkSafeName)
+	}
+	if err := db.Model(&Sheet{}).Where("safe_sheet_name = ?", sheet.SafeSheetName).Update("work_safe_name", work.SafeName).Error; err != nil {
+		return nil, err
+	}
+	sheet.WorkSafeName = work.SafeName
+	return &sheet, nil
+}
+
+// UnlinkEdition détache une partition de son oeuvre, la partition est conservée
+func UnlinkEdition(db *gorm.DB, workSafeName string, sheetSafeName string) error {
+	result := db.Model(&Sheet{}).Where("safe_sheet_name = ? AND work_safe_name = ?", sheetSafeName, workSafeName).Update("work_safe_name", "")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrEditionNotLinked
+	}
+	return nil
+}
+
+// DeleteWork supprime une oeuvre, ses éditions restent dans la bibliothèque sans oeuvre
+func DeleteWork(db *gorm.DB, safeName string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Sheet{}).Where("work_safe_name = ?", safeName).Update("work_safe_name", "").Error; err != nil {
+			return err
+		}
+		result := tx.Where("safe_name = ?", safeName).Delete(&Work{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWorkNotFound
+		}
+		return nil
+	})
+}
+
+// reassignWorks rattache les oeuvres d'un compositeur renommé, fusionné ou supprimé à un autre compositeur.
+// La clé des oeuvres ne change pas, comme celle des partitions.
+func reassignWorks(tx *gorm.DB, from string, safeComposer string, composer string) error {
+	return tx.Model(&Work{}).Where("safe_composer = ?", from).Updates(map[string]interface{}{
+		"safe_composer": safeComposer,
+		"composer":      composer,
+	}).Error
+}
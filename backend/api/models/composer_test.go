@@ -0,0 +1,235 @@
+package models
+
+import (
+	"backend/api/config"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Tests d'intégration sur SQLite : base et dossiers de la bibliothèque dans un répertoire temporaire.
+// La configuration étant un singleton, CONFIG_PATH est fixé une seule fois pour tout le package.
+func TestMain(m *testing.M) {
+	root, err := os.MkdirTemp("", "sheetflow-models")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("CONFIG_PATH", root)
+	config.Config()
+
+	code := m.Run()
+	os.RemoveAll(root)
+	os.Exit(code)
+}
+
+// setupLibrary repart d'une bibliothèque vide avec Chopin (2 partitions, un portrait) et Liszt
+func setupLibrary(t *testing.T) (*gorm.DB, string, string) {
+	root := config.Config().ConfigPath
+	for _, dir := range []string{"sheets", "composer"} {
+		require.NoError(t, os.RemoveAll(path.Join(root, dir)))
+	}
+	uploadDir, portraitDir := composerDirs()
+	require.NoError(t, os.MkdirAll(path.Join(uploadDir, "chopin"), os.ModePerm))
+	require.NoError(t, os.MkdirAll(portraitDir, os.ModePerm))
+
+	db, err := gorm.Open(sqlite.Open(path.Join(t.TempDir(), "database.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Sheet{}, &Composer{}, &ComposerAlias{}, &SheetPart{}, &SheetMedia{}, &Work{}, &SheetRevision{}, &WatermarkCategory{}, &SheetDistribution{}, &SheetCopy{}, &SheetLoan{}))
+
+	require.NoError(t, db.Create(&Composer{SafeName: "chopin", Name: "Chopin", Epoch: "Romantic", PortraitURL: "/api/composer/portrait/chopin"}).Error)
+	require.NoError(t, db.Create(&Composer{SafeName: "liszt", Name: "Liszt", Epoch: "Romantic"}).Error)
+	require.NoError(t, AddAliases(db, "chopin", []string{"Szopen"}))
+	for _, name := range []string{"etude", "ballade"} {
+		require.NoError(t, db.Create(&Sheet{SafeSheetName: name, SheetName: name, SafeComposer: "chopin", Composer: "Chopin", PdfUrl: "sheet/pdf/chopin/" + name}).Error)
+		writeFile(t, path.Join(uploadDir, "chopin", name+".pdf"))
+	}
+	writeFile(t, path.Join(portraitDir, "chopin.png"))
+	require.NoError(t, os.MkdirAll(path.Join(portraitDir, "small"), os.ModePerm))
+	writeFile(t, path.Join(portraitDir, "small", "chopin.png"))
+	return db, uploadDir, portraitDir
+}
+
+func writeFile(t *testing.T, p string) {
+	require.NoError(t, os.WriteFile(p, []byte("%PDF-1.4"), 0666))
+}
+
+func findSheet(t *testing.T, db *gorm.DB, name string) Sheet {
+	var sheet Sheet
+	require.NoError(t, db.Where("safe_sheet_name = ?", name).Take(&sheet).Error)
+	return sheet
+}
+
+func TestUpdateComposerRename(t *testing.T) {
+	db, uploadDir, portraitDir := setupLibrary(t)
+	// Partition uploadée en MusicXML seul : pas de PDF
+	require.NoError(t, db.Create(&Sheet{SafeSheetName: "mazurka", SheetName: "Mazurka", SafeComposer: "chopin", Composer: "Chopin", SourceFormat: "musicxml"}).Error)
+
+	composer, err := (&Composer{}).UpdateComposer(db, "chopin", "Frédéric Chopin", "", "", ComposerDetails{Nationality: "Polish"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "frederic-chopin", composer.SafeName)
+	assert.Equal(t, "Polish", composer.Nationality)
+	assert.Equal(t, "/api/composer/portrait/frederic-chopin", composer.PortraitURL)
+
+	sheet := findSheet(t, db, "etude")
+	assert.Equal(t, "frederic-chopin", sheet.SafeComposer)
+	assert.Equal(t, "Frédéric Chopin", sheet.Composer)
+	assert.Equal(t, "sheet/pdf/frederic-chopin/etude", sheet.PdfUrl)
+	mazurka := findSheet(t, db, "mazurka")
+	assert.Equal(t, "frederic-chopin", mazurka.SafeComposer)
+	assert.Empty(t, mazurka.PdfUrl, "a sheet without PDF gets no pdf_url")
+
+	assert.FileExists(t, path.Join(uploadDir, "frederic-chopin", "etude.pdf"))
+	assert.FileExists(t, path.Join(uploadDir, "frederic-chopin", "ballade.pdf"))
+	assert.NoDirExists(t, path.Join(uploadDir, "chopin"))
+	assert.FileExists(t, path.Join(portraitDir, "frederic-chopin.png"))
+	assert.FileExists(t, path.Join(portraitDir, "small", "frederic-chopin.png"))
+	assert.NoFileExists(t, path.Join(portraitDir, "chopin.png"))
+	assert.NoFileExists(t, path.Join(portraitDir, "small", "chopin.png"))
+
+	var count int64
+	db.Model(&Composer{}).Where("safe_name = ?", "chopin").Count(&count)
+	assert.Zero(t, count)
+
+	// Les alias ont suivi et l'ancien nom en est un
+	resolved, err := ResolveComposer(db, "Szopen")
+	require.NoError(t, err)
+	assert.Equal(t, "frederic-chopin", resolved.SafeName)
+	resolved, err = ResolveComposer(db, "Chopin")
+	require.NoError(t, err)
+	assert.Equal(t, "frederic-chopin", resolved.SafeName)
+}
+
+func TestUpdateComposerWithoutRename(t *testing.T) {
+	db, uploadDir, _ := setupLibrary(t)
+
+	composer, err := (&Composer{}).UpdateComposer(db, "chopin", "", "", "Classical", ComposerDetails{Aliases: []string{}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "chopin", composer.SafeName)
+	assert.Equal(t, "Classical", composer.Epoch)
+	assert.Empty(t, composer.Aliases)
+	assert.FileExists(t, path.Join(uploadDir, "chopin", "etude.pdf"))
+}
+
+func TestUpdateComposerRenameToExistingComposer(t *testing.T) {
+	db, uploadDir, _ := setupLibrary(t)
+
+	_, err := (&Composer{}).UpdateComposer(db, "chopin", "Liszt", "", "", ComposerDetails{}, false)
+	assert.ErrorIs(t, err, ErrComposerExists)
+	assert.Equal(t, "chopin", findSheet(t, db, "etude").SafeComposer)
+	assert.FileExists(t, path.Join(uploadDir, "chopin", "etude.pdf"))
+}
+
+func TestUpdateComposerRollsBackFilesOnDatabaseError(t *testing.T) {
+	db, uploadDir, portraitDir := setupLibrary(t)
+	require.NoError(t, AddAliases(db, "liszt", []string{"Ferenc"}))
+
+	// L'alias appartient déjà à Liszt : la transaction échoue après le déplacement des fichiers
+	_, err := (&Composer{}).UpdateComposer(db, "chopin", "Frederic Chopin", "", "", ComposerDetails{Aliases: []string{"Ferenc"}}, false)
+	assert.ErrorIs(t, err, ErrAliasTaken)
+
+	sheet := findSheet(t, db, "etude")
+	assert.Equal(t, "chopin", sheet.SafeComposer)
+	assert.Equal(t, "sheet/pdf/chopin/etude", sheet.PdfUrl)
+	assert.FileExists(t, path.Join(uploadDir, "chopin", "etude.pdf"))
+	assert.FileExists(t, path.Join(uploadDir, "chopin", "ballade.pdf"))
+	assert.NoFileExists(t, path.Join(uploadDir, "frederic-chopin", "etude.pdf"))
+	assert.FileExists(t, path.Join(portraitDir, "chopin.png"))
+
+	var count int64
+	db.Model(&Composer{}).Where("safe_name = ?", "frederic-chopin").Count(&count)
+	assert.Zero(t, count)
+	resolved, err := ResolveComposer(db, "Szopen")
+	require.NoError(t, err)
+	assert.Equal(t, "chopin", resolved.SafeName)
+}
+
+func TestDeleteComposerMovesSheetsToUnknown(t *testing.T) {
+	db, uploadDir, portraitDir := setupLibrary(t)
+
+	rows, err := (&Composer{}).DeleteComposer(db, "chopin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+
+	sheet := findSheet(t, db, "ballade")
+	assert.Equal(t, "unknown", sheet.SafeComposer)
+	assert.Equal(t, "Unknown", sheet.Composer)
+	assert.Equal(t, "sheet/pdf/unknown/ballade", sheet.PdfUrl)
+	assert.FileExists(t, path.Join(uploadDir, "unknown", "ballade.pdf"))
+	assert.NoDirExists(t, path.Join(uploadDir, "chopin"))
+	assert.NoFileExists(t, path.Join(portraitDir, "chopin.png"))
+
+	var unknown Composer
+	require.NoError(t, db.Where("safe_name = ?", "unknown").Take(&unknown).Error)
+	assert.Equal(t, "Unknown", unknown.Name)
+
+	var aliases int64
+	db.Model(&ComposerAlias{}).Where("composer_safe_name = ?", "chopin").Count(&aliases)
+	assert.Zero(t, aliases)
+}
+
+func TestDeleteComposerWithoutSheets(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+
+	_, err := (&Composer{}).DeleteComposer(db, "liszt")
+	require.NoError(t, err)
+
+	var count int64
+	db.Model(&Composer{}).Where("safe_name IN ?", []string{"liszt", "unknown"}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestDeleteComposerRollsBackOnFileConflict(t *testing.T) {
+	db, uploadDir, _ := setupLibrary(t)
+
+	// ballade.pdf existe déjà chez unknown : le second déplacement échoue, le premier est annulé
+	require.NoError(t, os.MkdirAll(path.Join(uploadDir, "unknown"), os.ModePerm))
+	writeFile(t, path.Join(uploadDir, "unknown", "ballade.pdf"))
+
+	_, err := (&Composer{}).DeleteComposer(db, "chopin")
+	assert.ErrorIs(t, err, os.ErrExist)
+
+	assert.FileExists(t, path.Join(uploadDir, "chopin", "etude.pdf"))
+	assert.FileExists(t, path.Join(uploadDir, "chopin", "ballade.pdf"))
+	assert.NoFileExists(t, path.Join(uploadDir, "unknown", "etude.pdf"))
+	assert.Equal(t, "chopin", findSheet(t, db, "etude").SafeComposer)
+
+	var count int64
+	db.Model(&Composer{}).Where("safe_name = ?", "chopin").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestDeleteUnknownComposerWithSheets(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+	_, err := (&Composer{}).DeleteComposer(db, "chopin")
+	require.NoError(t, err)
+
+	_, err = (&Composer{}).DeleteComposer(db, "unknown")
+	assert.ErrorIs(t, err, ErrDeleteUnknownComposer)
+}
+
+func TestDeleteComposerNotFound(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+
+	_, err := (&Composer{}).DeleteComposer(db, "bach")
+	assert.ErrorIs(t, err, ErrComposerNotFound)
+}
+
+func TestMergeComposer(t *testing.T) {
+	db, uploadDir, portraitDir := setupLibrary(t)
+
+	merged, err := MergeComposer(db, "chopin", "liszt")
+	require.NoError(t, err)
+	assert.Equal(t, "/api/composer/portrait/liszt", merged.PortraitURL)
+	assert.Equal(t, "sheet/pdf/liszt/etude", findSheet(t, db, "etude").PdfUrl)
+	assert.FileExists(t, path.Join(uploadDir, "liszt", "etude.pdf"))
+	assert.FileExists(t, path.Join(portraitDir, "liszt.png"))
+
+	resolved, err := ResolveComposer(db, "Chopin")
+	require.NoError(t, err)
+	assert.Equal(t, "liszt", resolved.SafeName)
+}
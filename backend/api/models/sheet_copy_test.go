@@ -0,0 +1,98 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSheetCopies(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+	etude := findSheet(t, db, "etude")
+	now := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	item := SheetCopy{Barcode: " SF-0001 ", Shelf: "B3-2"}
+	require.NoError(t, etude.AddCopy(db, &item))
+	assert.Equal(t, "SF-0001", item.Barcode)
+	assert.Equal(t, ConditionGood, item.Condition, "good by default")
+	require.NoError(t, etude.AddCopy(db, &SheetCopy{Barcode: "SF-0002", Condition: "Poor"}))
+
+	assert.ErrorIs(t, etude.AddCopy(db, &SheetCopy{Barcode: "SF-0001"}), ErrBarcodeExists)
+	assert.ErrorIs(t, etude.AddCopy(db, &SheetCopy{Barcode: "SF 3"}), ErrInvalidBarcode)
+	assert.ErrorIs(t, etude.AddCopy(db, &SheetCopy{Barcode: "SF-0003", Condition: "mint"}), ErrInvalidCondition)
+
+	shelf := "C1-4"
+	updated, err := UpdateCopy(db, "SF-0002", CopyUpdate{Shelf: &shelf})
+	require.NoError(t, err)
+	assert.Equal(t, "C1-4", updated.Shelf)
+	assert.Equal(t, ConditionPoor, updated.Condition)
+
+	// Prêt jusqu'au 10 octobre inclus
+	due := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	loan, err := CheckoutCopy(db, "SF-0001", 2, due, now)
+	require.NoError(t, err)
+	assert.False(t, loan.IsOverdue(due.Add(23*time.Hour)))
+	assert.True(t, loan.IsOverdue(due.AddDate(0, 0, 1)))
+	_, err = CheckoutCopy(db, "SF-0001", 3, due, now)
+	assert.ErrorIs(t, err, ErrCopyOnLoan)
+	_, err = CheckoutCopy(db, "SF-0002", 3, now.AddDate(0, 0, -2), now)
+	assert.ErrorIs(t, err, ErrInvalidDueDate)
+	assert.ErrorIs(t, DeleteCopy(db, "SF-0001"), ErrCopyOnLoan)
+	_, err = (&Sheet{}).DeleteSheet(db, "etude")
+	assert.ErrorIs(t, err, ErrCopyOnLoan, "the sheet of a lent copy is kept")
+
+	// Un utilisateur emprunte au plus DefaultLoanDays jours : jusqu'au 29 octobre inclus
+	assert.NoError(t, CheckLoanLength(time.Date(2026, 10, 29, 0, 0, 0, 0, time.UTC), now))
+	assert.NoError(t, CheckLoanLength(time.Time{}, now))
+	assert.ErrorIs(t, CheckLoanLength(time.Date(2026, 10, 30, 0, 0, 0, 0, time.UTC), now), ErrDueDateTooLate)
+
+	// Sans date de retour : DefaultLoanDays jours
+	loan, err = CheckoutCopy(db, "SF-0002", 3, time.Time{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, DefaultLoanDays), loan.DueAt)
+
+	copies, err := etude.Copies(db, now)
+	require.NoError(t, err)
+	require.Len(t, copies, 2)
+	require.NotNil(t, copies[0].Loan)
+	assert.EqualValues(t, 2, copies[0].Loan.UserID)
+
+	// Retards : seul le premier prêt a dépassé sa date de retour le 15 octobre
+	later := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	overdue, err := ListLoans(db, LoanFilter{Overdue: true}, later)
+	require.NoError(t, err)
+	require.Len(t, overdue, 1)
+	assert.Equal(t, "SF-0001", overdue[0].Barcode)
+	assert.True(t, overdue[0].Overdue)
+	require.NoError(t, MarkReminded(db, &overdue[0], later))
+	overdue, err = ListLoans(db, LoanFilter{Overdue: true}, later)
+	require.NoError(t, err)
+	assert.Equal(t, 1, overdue[0].Reminders)
+
+	// Retour avec l'état constaté
+	returned, err := ReturnCopy(db, "SF-0001", "fair", later)
+	require.NoError(t, err)
+	require.NotNil(t, returned.ReturnedAt)
+	_, err = ReturnCopy(db, "SF-0001", "", later)
+	assert.ErrorIs(t, err, ErrCopyNotOnLoan)
+	found, err := FindCopy(db, "SF-0001", later)
+	require.NoError(t, err)
+	assert.Nil(t, found.Loan)
+	assert.Equal(t, ConditionFair, found.Condition)
+
+	current, err := ListLoans(db, LoanFilter{UserID: 2}, later)
+	require.NoError(t, err)
+	assert.Empty(t, current)
+	history, err := ListLoans(db, LoanFilter{UserID: 2, History: true}, later)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	require.NoError(t, DeleteCopy(db, "SF-0001"))
+	_, err = FindCopy(db, "SF-0001", later)
+	assert.ErrorIs(t, err, ErrCopyNotFound)
+	var count int64
+	db.Model(&SheetLoan{}).Where("barcode = ?", "SF-0001").Count(&count)
+	assert.Zero(t, count, "loan history deleted with the copy")
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExcerpts(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+	require.NoError(t, db.Model(&Sheet{}).Where("safe_sheet_name = ?", "etude").Updates(map[string]interface{}{"page_count": 40, "page_width": 595.0, "has_text": true}).Error)
+	parent := findSheet(t, db, "etude")
+
+	for _, piece := range []struct {
+		name        string
+		first, last int
+	}{{"etude-no-2", 5, 9}, {"etude-no-1", 1, 4}} {
+		excerpt := Sheet{SafeSheetName: piece.name, SheetName: piece.name, SafeComposer: "chopin", Composer: "Chopin"}
+		require.NoError(t, db.Create(&excerpt).Error)
+		require.NoError(t, excerpt.SaveExcerpt(db, &parent, piece.first, piece.last))
+	}
+
+	excerpt := findSheet(t, db, "etude-no-2")
+	assert.True(t, excerpt.IsExcerpt())
+	assert.Equal(t, 5, excerpt.PageCount)
+	assert.Equal(t, 595.0, excerpt.PageWidth)
+	assert.True(t, excerpt.HasText)
+	assert.Equal(t, "sheet/pdf/chopin/etude-no-2", excerpt.PdfUrl)
+
+	// Plage hors du recueil, recueil dans un recueil
+	assert.ErrorIs(t, (&Sheet{}).SetExcerpt(&parent, 39, 41), ErrInvalidPageRange)
+	assert.ErrorIs(t, (&Sheet{}).SetExcerpt(&parent, 3, 2), ErrInvalidPageRange)
+	assert.ErrorIs(t, (&Sheet{}).SetExcerpt(&excerpt, 1, 1), ErrExcerptOfExcerpt)
+
+	excerpts, err := parent.Excerpts(db)
+	require.NoError(t, err)
+	require.Len(t, excerpts, 2)
+	assert.Equal(t, "etude-no-1", excerpts[0].SafeSheetName, "page order")
+
+	page, err := (&Sheet{}).List(db, Pagination{Sort: "first_page asc"}, SheetFilter{Parent: "etude"})
+	require.NoError(t, err)
+	assert.Len(t, page.Rows.([]*Sheet), 2)
+
+	// Le recueil n'est supprimé qu'après ses pièces
+	_, err = (&Sheet{}).DeleteSheet(db, "etude")
+	assert.ErrorIs(t, err, ErrSheetHasExcerpts)
+	for _, name := range []string{"etude-no-1", "etude-no-2"} {
+		_, err = (&Sheet{}).DeleteSheet(db, name)
+		require.NoError(t, err)
+	}
+	_, err = (&Sheet{}).DeleteSheet(db, "etude")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestPublicDomain(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	status := PublicDomain(&Composer{Death: "1849-10-17"}, 70, now)
+	assert.Equal(t, PublicDomainYes, status.Status)
+	assert.Equal(t, 1849, status.DeathYear)
+	assert.Equal(t, 1920, status.Since)
+
+	// Décès en 1960 : protégé jusqu'au 31 décembre 2030 en vie+70, libre depuis 2011 en vie+50
+	assert.Equal(t, PublicDomainNo, PublicDomain(&Composer{Death: "1960"}, 70, now).Status)
+	assert.Equal(t, PublicDomainYes, PublicDomain(&Composer{Death: "1960"}, 50, now).Status)
+	assert.Equal(t, 2031, PublicDomain(&Composer{Death: "1960"}, 70, now).Since)
+
+	assert.Equal(t, PublicDomainUnknown, PublicDomain(&Composer{}, 70, now).Status, "living or unknown death date")
+	assert.Equal(t, PublicDomainUnknown, PublicDomain(nil, 70, now).Status)
+}
+
+func TestSheetLicense(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+	require.NoError(t, db.Model(&Composer{}).Where("safe_name = ?", "chopin").Update("death", "1849-10-17").Error)
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	// Sans licence déclarée : domaine public d'après le compositeur
+	etude := findSheet(t, db, "etude")
+	status, err := etude.LicenseStatus(db, now)
+	require.NoError(t, err)
+	assert.Equal(t, LicenseUnknown, status.License)
+	assert.Equal(t, LicensePublicDomain, status.Effective)
+
+	// Location : la date d'expiration est le dernier jour inclus
+	expiry := time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC)
+	sheet, err := SetLicense(db, "ballade", SheetLicense{License: " Rental ", Holder: "Bärenreiter", Seats: 5, Expiry: &expiry})
+	require.NoError(t, err)
+	assert.Equal(t, LicenseRental, sheet.License)
+	assert.Zero(t, sheet.LicenseSeats, "seats only apply to a purchased license")
+	ballade := findSheet(t, db, "ballade")
+	assert.NoError(t, ballade.CheckAccess(db, expiry.Add(23*time.Hour)))
+	assert.ErrorIs(t, ballade.CheckAccess(db, now), ErrRentalExpired)
+
+	// Une pièce du recueil loué sans licence propre suit sa location
+	require.NoError(t, db.Create(&Sheet{SafeSheetName: "ballade-no-1", SheetName: "Ballade No. 1", SafeComposer: "chopin", ParentSheet: "ballade", FirstPage: 1, LastPage: 1}).Error)
+	excerpt := findSheet(t, db, "ballade-no-1")
+	assert.ErrorIs(t, excerpt.CheckAccess(db, now), ErrRentalExpired)
+
+	// Licence achetée : les utilisateurs au-delà du nombre de places sont signalés
+	_, err = SetLicense(db, "ballade", SheetLicense{License: LicensePurchased, Seats: 2, Expiry: &expiry})
+	require.NoError(t, err)
+	ballade = findSheet(t, db, "ballade")
+	assert.Nil(t, ballade.LicenseExpiry)
+	assert.NoError(t, ballade.CheckAccess(db, now))
+	for _, uid := range []uint32{2, 3, 2} {
+		require.NoError(t, RecordDistribution(db, "ballade", uid))
+	}
+	status, err = ballade.LicenseStatus(db, now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, status.Distributed)
+	assert.False(t, status.SeatsExceeded)
+
+	require.NoError(t, RecordDistribution(db, "ballade-no-1", 4))
+	status, err = ballade.LicenseStatus(db, now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, status.Distributed, "downloads of its pieces count")
+	assert.True(t, status.SeatsExceeded)
+
+	var distribution SheetDistribution
+	require.NoError(t, db.Where("sheet_safe_name = ? AND user_id = ?", "ballade", 2).Take(&distribution).Error)
+	assert.Equal(t, 2, distribution.Downloads)
+
+	_, err = SetLicense(db, "etude", SheetLicense{License: LicenseRental, Expiry: &expiry})
+	require.NoError(t, err)
+	expired, overused, err := LicenseIssues(db, now)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "etude", expired[0].SafeSheetName)
+	require.Len(t, overused, 1)
+	assert.Equal(t, "ballade", overused[0].Sheet.SafeSheetName)
+
+	// Filtre de la liste des partitions
+	var sheets []Sheet
+	require.NoError(t, SheetFilter{License: "unknown"}.apply(db.Model(&Sheet{})).Find(&sheets).Error)
+	require.Len(t, sheets, 1)
+	assert.Equal(t, "ballade-no-1", sheets[0].SafeSheetName)
+
+	_, err = SetLicense(db, "etude", SheetLicense{License: "all-rights"})
+	assert.ErrorIs(t, err, ErrUnknownLicense)
+	_, err = SetLicense(db, "etude", SheetLicense{License: LicenseRental})
+	assert.ErrorIs(t, err, ErrRentalWithoutExpiry)
+	_, err = SetLicense(db, "missing", SheetLicense{License: LicenseCCBy})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
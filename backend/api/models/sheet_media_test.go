@@ -0,0 +1,53 @@
+package models
+
+import (
+	"backend/api/media"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSheetMedia(t *testing.T) {
+	db, uploadDir, _ := setupLibrary(t)
+	sheet, err := (&Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+
+	mp3 := &media.Info{Format: media.FormatMP3, MimeType: "audio/mpeg", Duration: 182.5, Size: 4}
+	midi := &media.Info{Format: media.FormatMIDI, MimeType: "audio/midi", Duration: 180, Size: 4}
+
+	recording := &SheetMedia{Label: "Pollini 1972"}
+	require.NoError(t, sheet.AddMedia(db, recording, mp3, strings.NewReader("fake")))
+	assert.Equal(t, MediaRecording, recording.Kind)
+	assert.FileExists(t, path.Join(uploadDir, "chopin", "etude", "media", "pollini-1972.mp3"))
+
+	require.NoError(t, sheet.AddMedia(db, &SheetMedia{Label: "Score"}, midi, strings.NewReader("fake")))
+	assert.ErrorIs(t, sheet.AddMedia(db, &SheetMedia{Label: "score"}, midi, strings.NewReader("")), ErrMediaExists)
+
+	sheet, err = (&Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+	if assert.Len(t, sheet.Media, 2) {
+		assert.Equal(t, 182.5, sheet.Media[0].Duration)
+		assert.Equal(t, MediaMidi, sheet.Media[1].Kind)
+	}
+
+	// Les médias suivent le compositeur renommé
+	_, err = (&Composer{}).UpdateComposer(db, "chopin", "Frederic Chopin", "", "", ComposerDetails{}, false)
+	require.NoError(t, err)
+	sheet, err = (&Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+	assert.FileExists(t, path.Join(uploadDir, "frederic-chopin", "etude", "media", "score.mid"))
+
+	require.NoError(t, sheet.DeleteMedia(db, "score"))
+	assert.NoFileExists(t, path.Join(uploadDir, "frederic-chopin", "etude", "media", "score.mid"))
+	assert.ErrorIs(t, sheet.DeleteMedia(db, "score"), ErrMediaNotFound)
+
+	_, err = (&Sheet{}).DeleteSheet(db, "etude")
+	require.NoError(t, err)
+	assert.NoDirExists(t, path.Join(uploadDir, "frederic-chopin", "etude"))
+	var count int64
+	db.Model(&SheetMedia{}).Count(&count)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSheetParts(t *testing.T) {
+	db, uploadDir, _ := setupLibrary(t)
+	sheet, err := (&Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+
+	require.NoError(t, sheet.AddPart(db, &SheetPart{Label: "Violin II", Position: 2}, strings.NewReader("%PDF-1.4")))
+	require.NoError(t, sheet.AddPart(db, &SheetPart{Label: "Violin I", Position: 1}, strings.NewReader("%PDF-1.4")))
+	cello := &SheetPart{Label: "Cello"}
+	require.NoError(t, sheet.AddPart(db, cello, strings.NewReader("%PDF-1.4")))
+	assert.Equal(t, 3, cello.Position)
+	assert.FileExists(t, path.Join(uploadDir, "chopin", "etude", "violin-i.pdf"))
+
+	assert.ErrorIs(t, sheet.AddPart(db, &SheetPart{Label: "violin i"}, strings.NewReader("")), ErrPartExists)
+	assert.ErrorIs(t, sheet.AddPart(db, &SheetPart{Label: " "}, strings.NewReader("")), ErrEmptyLabel)
+
+	sheet, err = (&Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+	var labels []string
+	for _, part := range sheet.Parts {
+		labels = append(labels, part.SafeLabel)
+	}
+	assert.Equal(t, []string{"violin-i", "violin-ii", "cello"}, labels)
+
+	require.NoError(t, sheet.DeletePart(db, "cello"))
+	assert.NoFileExists(t, path.Join(uploadDir, "chopin", "etude", "cello.pdf"))
+	assert.ErrorIs(t, sheet.DeletePart(db, "cello"), ErrPartNotFound)
+
+	// Les parties suivent le compositeur renommé, puis disparaissent avec la partition
+	_, err = (&Composer{}).UpdateComposer(db, "chopin", "Frederic Chopin", "", "", ComposerDetails{}, false)
+	require.NoError(t, err)
+	assert.FileExists(t, path.Join(uploadDir, "frederic-chopin", "etude", "violin-i.pdf"))
+
+	_, err = (&Sheet{}).DeleteSheet(db, "etude")
+	require.NoError(t, err)
+	assert.NoDirExists(t, path.Join(uploadDir, "frederic-chopin", "etude"))
+	var count int64
+	db.Model(&SheetPart{}).Count(&count)
+	assert.Zero(t, count)
+	_, err = os.Stat(path.Join(uploadDir, "frederic-chopin", "ballade.pdf"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"backend/api/pdf"
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePDF écrit un PDF valide de pages pages
+func writePDF(t *testing.T, p string, pages int) {
+	var imgs []io.Reader
+	for i := 0; i < pages; i++ {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 60, 80))))
+		imgs = append(imgs, &buf)
+	}
+	var out bytes.Buffer
+	require.NoError(t, api.ImportImages(nil, &out, imgs, nil, nil))
+	require.NoError(t, os.WriteFile(p, out.Bytes(), 0666))
+}
+
+func TestSheetRevisions(t *testing.T) {
+	db, uploadDir, _ := setupLibrary(t)
+	current := path.Join(uploadDir, "chopin", "etude.pdf")
+	writePDF(t, current, 3)
+	require.NoError(t, db.Model(&Sheet{}).Where("safe_sheet_name = ?", "etude").Update("page_count", 3).Error)
+	sheet := findSheet(t, db, "etude")
+
+	edited := path.Join(uploadDir, "chopin", ".edit.pdf")
+	require.NoError(t, pdf.DeletePages(current, edited, []int{2}, 3))
+	require.NoError(t, sheet.ReplacePdf(db, edited, "delete"))
+	assert.Equal(t, 1, sheet.Revision)
+	assert.Equal(t, 2, sheet.PageCount)
+	assert.NotEmpty(t, sheet.FileHash)
+	assert.NoFileExists(t, edited)
+	count, err := pdf.PageCount(RevisionPath(&sheet, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count, "the uploaded PDF is kept as revision 1")
+	assert.Equal(t, 2, findSheet(t, db, "etude").PageCount)
+
+	// Restaurer conserve aussi la version remplacée
+	require.NoError(t, sheet.RestoreRevision(db, 1))
+	assert.Equal(t, 2, sheet.Revision)
+	assert.Equal(t, 3, sheet.PageCount)
+	revisions, err := sheet.Revisions(db)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "restore 1", revisions[0].Operation)
+	assert.Equal(t, 2, revisions[0].PageCount)
+	assert.Equal(t, "delete", revisions[1].Operation)
+	assert.Equal(t, 3, revisions[1].PageCount)
+	assert.ErrorIs(t, sheet.RestoreRevision(db, 5), ErrRevisionNotFound)
+
+	// Un fichier illisible ne remplace rien
+	assert.Error(t, sheet.ReplacePdf(db, path.Join(uploadDir, "missing.pdf"), "rotate"))
+	assert.Equal(t, 2, findSheet(t, db, "etude").Revision)
+
+	_, err = sheet.DeleteSheet(db, "etude")
+	require.NoError(t, err)
+	assert.NoFileExists(t, RevisionPath(&sheet, 1))
+	var rows int64
+	require.NoError(t, db.Model(&SheetRevision{}).Where("sheet_safe_name = ?", "etude").Count(&rows).Error)
+	assert.Zero(t, rows)
+}
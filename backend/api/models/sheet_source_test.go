@@ -0,0 +1,38 @@
+package models
+
+import (
+	"backend/api/score"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSource(t *testing.T) {
+	db, uploadDir, _ := setupLibrary(t)
+	sheet, err := (&Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+
+	meta := &score.Metadata{Format: score.FormatMusicXML, Key: "C minor", TimeSignature: "4/4", Tempo: 160, Parts: []string{"Piano"}}
+	require.NoError(t, sheet.WriteSource(db, meta, strings.NewReader("<score-partwise/>")))
+	assert.FileExists(t, path.Join(uploadDir, "chopin", "etude", "etude.musicxml"))
+
+	// Remplacement par un fichier MuseScore sans tempo : l'ancien fichier disparaît, le tempo est conservé
+	require.NoError(t, sheet.WriteSource(db, &score.Metadata{Format: score.FormatMSCZ, Key: "Ab major"}, strings.NewReader("zip")))
+	assert.NoFileExists(t, path.Join(uploadDir, "chopin", "etude", "etude.musicxml"))
+	assert.FileExists(t, path.Join(uploadDir, "chopin", "etude", "etude.mscz"))
+
+	sheet, err = (&Sheet{}).FindSheetBySafeName(db, "etude")
+	require.NoError(t, err)
+	assert.Equal(t, score.FormatMSCZ, sheet.SourceFormat)
+	assert.Equal(t, "Ab major", sheet.Key)
+	assert.Equal(t, 160, sheet.Tempo)
+	assert.Equal(t, `["Piano"]`, sheet.Instruments)
+
+	// Le fichier source suit le compositeur renommé
+	_, err = (&Composer{}).UpdateComposer(db, "chopin", "Frederic Chopin", "", "", ComposerDetails{}, false)
+	require.NoError(t, err)
+	assert.FileExists(t, path.Join(uploadDir, "frederic-chopin", "etude", "etude.mscz"))
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFiltersOnPdfStructure(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+	yes := true
+
+	require.NoError(t, db.Model(&Sheet{}).Where("safe_sheet_name = ?", "etude").Updates(map[string]interface{}{"page_count": 2, "orientation": "portrait", "has_text": true}).Error)
+	require.NoError(t, db.Model(&Sheet{}).Where("safe_sheet_name = ?", "ballade").Updates(map[string]interface{}{"page_count": 12, "orientation": "portrait"}).Error)
+	require.NoError(t, db.Create(&Sheet{SafeSheetName: "reverie", SafeComposer: "liszt", PageCount: 3, Orientation: "landscape"}).Error)
+
+	names := func(filter SheetFilter) []string {
+		page, err := (&Sheet{}).List(db, Pagination{Sort: "page_count asc"}, filter)
+		require.NoError(t, err)
+		var result []string
+		for _, sheet := range page.Rows.([]*Sheet) {
+			result = append(result, sheet.SafeSheetName)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"etude", "reverie"}, names(SheetFilter{MaxPages: 3}))
+	assert.Equal(t, []string{"etude"}, names(SheetFilter{MaxPages: 3, Composer: "chopin"}))
+	assert.Equal(t, []string{"reverie", "ballade"}, names(SheetFilter{MinPages: 3}))
+	assert.Equal(t, []string{"reverie"}, names(SheetFilter{Orientation: "landscape"}))
+	assert.Equal(t, []string{"etude"}, names(SheetFilter{HasText: &yes}))
+
+	page, err := (&Sheet{}).List(db, Pagination{Sort: "page_count asc"}, SheetFilter{MaxPages: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.TotalRows)
+}
+
+func TestUpdateMetadataAndFilter(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+	str := func(s string) *string { return &s }
+	num := func(n int) *int { return &n }
+
+	etude := findSheet(t, db, "etude")
+	require.NoError(t, etude.UpdateMetadata(db, SheetMetadata{
+		CatalogueType:   str("Op."),
+		CatalogueNumber: str(" 10 No. 3 "),
+		Key:             str("E major"),
+		Instrumentation: str("Piano"),
+		Difficulty:      num(6),
+		Duration:        num(240),
+		Publisher:       str("Henle Urtext"),
+	}))
+	assert.Equal(t, "10 No. 3", etude.CatalogueNumber)
+
+	ballade := findSheet(t, db, "ballade")
+	require.NoError(t, ballade.UpdateMetadata(db, SheetMetadata{
+		Instrumentation: str("piano"),
+		Difficulty:      num(8),
+		Duration:        num(600),
+		Language:        str("pl"),
+	}))
+
+	// Un champ absent n'est pas modifié, une valeur vide l'efface
+	require.NoError(t, etude.UpdateMetadata(db, SheetMetadata{Publisher: str("")}))
+	stored := findSheet(t, db, "etude")
+	assert.Equal(t, "", stored.Publisher)
+	assert.Equal(t, "Op.", stored.CatalogueType)
+	assert.Equal(t, "E major", stored.Key)
+	assert.Equal(t, 6, stored.Difficulty)
+
+	names := func(filter SheetFilter) []string {
+		page, err := (&Sheet{}).List(db, Pagination{Sort: "difficulty asc"}, filter)
+		require.NoError(t, err)
+		var result []string
+		for _, sheet := range page.Rows.([]*Sheet) {
+			result = append(result, sheet.SafeSheetName)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"etude", "ballade"}, names(SheetFilter{Instrumentation: "PIANO"}))
+	assert.Equal(t, []string{"etude"}, names(SheetFilter{CatalogueType: "Op."}))
+	assert.Equal(t, []string{"etude"}, names(SheetFilter{Key: "e major"}))
+	assert.Equal(t, []string{"etude"}, names(SheetFilter{MaxDifficulty: 7}))
+	assert.Equal(t, []string{"ballade"}, names(SheetFilter{MinDuration: 300}))
+	assert.Equal(t, []string{"etude"}, names(SheetFilter{MaxDuration: 300}))
+	assert.Equal(t, []string{"ballade"}, names(SheetFilter{Language: "PL"}))
+}
+
+func TestComposerCatalogueOrder(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+	for name, title := range map[string]string{
+		"wtc-1001": "Sonata for solo violin No. 1, BWV 1001",
+		"wtc-846":  "Prelude in C major, BWV 846",
+		"etude-op": "Etude Op. 10 No. 12",
+		"etude-3":  "Etude Op. 10 No. 3",
+		"fugue":    "fugue",
+	} {
+		require.NoError(t, db.Create(&Sheet{SafeSheetName: name, SheetName: title, SafeComposer: "chopin"}).Error)
+	}
+	// Numéro saisi sans clé de tri
+	require.NoError(t, db.Model(&Sheet{}).Where("safe_sheet_name = ?", "etude").Updates(map[string]interface{}{"catalogue_type": "Op.", "catalogue_number": "25 No. 1"}).Error)
+
+	updated, err := FillCatalogueNumbers(db)
+	require.NoError(t, err)
+	assert.Equal(t, 5, updated)
+	stored := findSheet(t, db, "wtc-846")
+	assert.Equal(t, "BWV", stored.CatalogueType)
+	assert.Equal(t, "846", stored.CatalogueNumber)
+	assert.Equal(t, "000846", stored.CatalogueSort)
+
+	sheets, err := ComposerCatalogue(db, "chopin", "")
+	require.NoError(t, err)
+	var names []string
+	for _, sheet := range sheets {
+		names = append(names, sheet.SafeSheetName)
+	}
+	assert.Equal(t, []string{"etude-3", "etude-op", "etude", "wtc-846", "wtc-1001", "ballade", "fugue"}, names)
+
+	sheets, err = ComposerCatalogue(db, "chopin", "BWV")
+	require.NoError(t, err)
+	assert.Len(t, sheets, 2)
+
+	// Rien à compléter au démarrage suivant
+	updated, err = FillCatalogueNumbers(db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, updated)
+}
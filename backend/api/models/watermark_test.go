@@ -0,0 +1,66 @@
+package models
+
+import (
+	"backend/api/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestWatermarkPolicy(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+	require.NoError(t, db.Model(&Sheet{}).Where("safe_sheet_name = ?", "etude").Update("categories", `["Piano","Licensed"]`).Error)
+
+	watermarked := func(name string) bool {
+		sheet := findSheet(t, db, name)
+		enabled, _, err := sheet.WatermarkFooter(db)
+		require.NoError(t, err)
+		return enabled
+	}
+	assert.False(t, watermarked("etude"), "no policy by default")
+
+	// Catégorie filigranée, sans tenir compte de la casse
+	policy, err := SetWatermarkCategory(db, " licensed ", "")
+	require.NoError(t, err)
+	assert.Equal(t, "licensed", policy.Category)
+	etude := findSheet(t, db, "etude")
+	enabled, footer, err := etude.WatermarkFooter(db)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+	assert.Equal(t, config.Config().Watermark.Footer, footer)
+
+	_, err = SetWatermarkCategory(db, "Licensed", "Rental material, do not copy")
+	require.NoError(t, err)
+	_, footer, err = etude.WatermarkFooter(db)
+	require.NoError(t, err)
+	assert.Equal(t, "Rental material, do not copy", footer, "the category footer replaces the default one")
+	categories, err := ListWatermarkCategories(db)
+	require.NoError(t, err)
+	assert.Len(t, categories, 1)
+
+	// La politique de la partition l'emporte sur ses catégories
+	_, err = SetSheetWatermark(db, "etude", "off")
+	require.NoError(t, err)
+	assert.False(t, watermarked("etude"))
+
+	sheet, err := SetSheetWatermark(db, "ballade", "on")
+	require.NoError(t, err)
+	assert.Equal(t, WatermarkOn, sheet.Watermark)
+	assert.True(t, watermarked("ballade"))
+
+	// Une pièce du recueil suit sa politique
+	require.NoError(t, db.Create(&Sheet{SafeSheetName: "ballade-no-1", SheetName: "Ballade No. 1", SafeComposer: "chopin", ParentSheet: "ballade", FirstPage: 1, LastPage: 1}).Error)
+	assert.True(t, watermarked("ballade-no-1"))
+
+	_, err = SetSheetWatermark(db, "ballade", "sometimes")
+	assert.ErrorIs(t, err, ErrInvalidWatermark)
+	_, err = SetSheetWatermark(db, "missing", "on")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = SetWatermarkCategory(db, " ", "")
+	assert.ErrorIs(t, err, ErrEmptyWatermarkCategory)
+
+	require.NoError(t, DeleteWatermarkCategory(db, "LICENSED"))
+	assert.ErrorIs(t, DeleteWatermarkCategory(db, "licensed"), ErrWatermarkCategoryNotFound)
+}
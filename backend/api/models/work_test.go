@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkEditions(t *testing.T) {
+	db, _, _ := setupLibrary(t)
+	require.NoError(t, db.Create(&Sheet{SafeSheetName: "etude-peters", SheetName: "etude", SafeComposer: "chopin", Composer: "Chopin", Publisher: "Peters", EditionYear: 1950}).Error)
+	require.NoError(t, db.Model(&Sheet{}).Where("safe_sheet_name = ?", "etude").Updates(map[string]interface{}{"publisher": "Henle", "edition_year": 2007}).Error)
+
+	work := Work{Title: "Etude", SafeComposer: "chopin", CatalogueType: "Op.", CatalogueNumber: "10 No. 3"}
+	require.NoError(t, CreateWork(db, &work))
+	assert.Equal(t, "chopin-etude-op.-10-no.-3", work.SafeName)
+	assert.Equal(t, "Chopin", work.Composer)
+	assert.Equal(t, "000010.000003", work.CatalogueSort)
+	assert.ErrorIs(t, CreateWork(db, &Work{Title: "Etude", SafeComposer: "chopin", CatalogueType: "Op.", CatalogueNumber: "10 No. 3"}), ErrWorkExists)
+	assert.ErrorIs(t, CreateWork(db, &Work{Title: "Etude", SafeComposer: "bach"}), ErrComposerNotFound)
+
+	for _, name := range []string{"etude", "etude-peters"} {
+		_, err := LinkEdition(db, work.SafeName, name)
+		require.NoError(t, err)
+	}
+	found, err := FindWork(db, work.SafeName)
+	require.NoError(t, err)
+	require.Len(t, found.Editions, 2)
+	assert.Equal(t, "etude-peters", found.Editions[0].SafeSheetName, "oldest edition first")
+	assert.Equal(t, "etude", found.Editions[1].SafeSheetName)
+
+	// Une partition d'un autre compositeur ou déjà rattachée n'est pas liée
+	require.NoError(t, db.Create(&Sheet{SafeSheetName: "liebestraum", SheetName: "Liebestraum", SafeComposer: "liszt", Composer: "Liszt"}).Error)
+	_, err = LinkEdition(db, work.SafeName, "liebestraum")
+	assert.ErrorIs(t, err, ErrEditionComposer)
+	other := Work{Title: "Ballade", SafeComposer: "chopin"}
+	require.NoError(t, CreateWork(db, &other))
+	_, err = LinkEdition(db, other.SafeName, "etude")
+	assert.ErrorIs(t, err, ErrEditionLinked)
+
+	page, err := ListWorks(db, Pagination{Sort: "title asc"}, "chopin")
+	require.NoError(t, err)
+	works := page.Rows.([]*Work)
+	require.Len(t, works, 2)
+	assert.Equal(t, "Ballade", works[0].Title)
+	assert.Empty(t, works[0].Editions)
+	assert.Len(t, works[1].Editions, 2)
+
+	require.NoError(t, UnlinkEdition(db, work.SafeName, "etude-peters"))
+	assert.ErrorIs(t, UnlinkEdition(db, work.SafeName, "etude-peters"), ErrEditionNotLinked)
+	assert.Empty(t, findSheet(t, db, "etude-peters").WorkSafeName)
+
+	// Les oeuvres suivent le compositeur renommé, la suppression de l'oeuvre garde ses éditions
+	_, err = (&Composer{}).UpdateComposer(db, "chopin", "Frédéric Chopin", "", "", ComposerDetails{}, false)
+	require.NoError(t, err)
+	found, err = FindWork(db, work.SafeName)
+	require.NoError(t, err)
+	assert.Equal(t, "frederic-chopin", found.SafeComposer)
+	assert.Equal(t, "Frédéric Chopin", found.Composer)
+
+	require.NoError(t, DeleteWork(db, work.SafeName))
+	assert.ErrorIs(t, DeleteWork(db, work.SafeName), ErrWorkNotFound)
+	assert.Empty(t, findSheet(t, db, "etude").WorkSafeName)
+}
@@ -0,0 +1,159 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Modification des pages d'un PDF : rotation, suppression, réordonnancement, recadrage et sélection.
+// Chaque opération lit src et écrit le résultat dans out, src n'est jamais modifié.
+
+var (
+	ErrInvalidPages   = errors.New("invalid page selection")
+	ErrInvalidMargins = errors.New("invalid margins, expected 1 to 4 values in points or percent, e.g. 20 or 10 5 or 5%")
+	ErrInvalidAngle   = errors.New("rotation must be a multiple of 90 degrees")
+)
+
+var marginsPattern = regexp.MustCompile(`^\d+(\.\d+)?%?(\s+\d+(\.\d+)?%?){0,3}$`)
+
+// ParsePages lit une sélection de pages ("1,3-5,8") d'un PDF de count pages, dans l'ordre donné.
+// Une sélection vide désigne toutes les pages.
+func ParsePages(value string, count int) ([]int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		pages := make([]int, count)
+		for i := range pages {
+			pages[i] = i + 1
+		}
+		return pages, nil
+	}
+	var pages []int
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		from, to, isRange := strings.Cut(item, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("%w %q", ErrInvalidPages, item)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+				return nil, fmt.Errorf("%w %q", ErrInvalidPages, item)
+			}
+		}
+		if first < 1 || last < first || last > count {
+			return nil, fmt.Errorf("%w %q, the document has %d pages", ErrInvalidPages, item, count)
+		}
+		for p := first; p <= last; p++ {
+			pages = append(pages, p)
+		}
+	}
+	return pages, nil
+}
+
+// ParseOrder lit le nouvel ordre des pages ("3,1,2" ou "2-5,1") : chaque page doit y figurer une seule fois
+func ParseOrder(value string, count int) ([]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, fmt.Errorf("%w: empty order", ErrInvalidPages)
+	}
+	order, err := ParsePages(value, count)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool, len(order))
+	for _, p := range order {
+		if seen[p] {
+			return nil, fmt.Errorf("%w: page %d is listed twice", ErrInvalidPages, p)
+		}
+		seen[p] = true
+	}
+	if len(order) != count {
+		return nil, fmt.Errorf("%w: the order must list all %d pages", ErrInvalidPages, count)
+	}
+	return order, nil
+}
+
+// selection convertit des numéros de page en sélection pdfcpu
+func selection(pages []int) []string {
+	selected := make([]string, len(pages))
+	for i, p := range pages {
+		selected[i] = strconv.Itoa(p)
+	}
+	return selected
+}
+
+// edit applique fn au PDF src et écrit le résultat dans out
+func edit(src string, out string, fn func(rs io.ReadSeeker, w io.Writer) error) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	w, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := fn(in, w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// RotatePages tourne les pages de degrees (multiple de 90, sens horaire, négatif = anti-horaire)
+func RotatePages(src string, out string, pages []int, degrees int) error {
+	if degrees == 0 || degrees%90 != 0 {
+		return ErrInvalidAngle
+	}
+	return edit(src, out, func(rs io.ReadSeeker, w io.Writer) error {
+		return api.Rotate(rs, w, degrees, selection(pages), relaxedConfig())
+	})
+}
+
+// DeletePages supprime les pages, il doit en rester au moins une
+func DeletePages(src string, out string, pages []int, count int) error {
+	remaining := map[int]bool{}
+	for p := 1; p <= count; p++ {
+		remaining[p] = true
+	}
+	for _, p := range pages {
+		delete(remaining, p)
+	}
+	if len(remaining) == 0 {
+		return fmt.Errorf("%w: at least one page must remain", ErrInvalidPages)
+	}
+	return edit(src, out, func(rs io.ReadSeeker, w io.Writer) error {
+		return api.RemovePages(rs, w, selection(pages), relaxedConfig())
+	})
+}
+
+// SelectPages écrit les pages dans l'ordre donné : réordonnancement ou extraction d'une partie du PDF
+func SelectPages(src string, out string, pages []int) error {
+	return edit(src, out, func(rs io.ReadSeeker, w io.Writer) error {
+		return api.Collect(rs, w, selection(pages), relaxedConfig())
+	})
+}
+
+// CropPages réduit la zone visible des pages de marges en points ou en pourcentage,
+// comme en CSS : "20" (partout), "10 5" (haut et bas, côtés), "10 5 15" ou "10 5 15 5" (haut, droite, bas, gauche)
+func CropPages(src string, out string, pages []int, margins string) error {
+	margins = strings.TrimSpace(margins)
+	if !marginsPattern.MatchString(margins) {
+		return ErrInvalidMargins
+	}
+	box, err := api.Box(margins, types.POINTS)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMargins, err)
+	}
+	return edit(src, out, func(rs io.ReadSeeker, w io.Writer) error {
+		return api.Crop(rs, w, selection(pages), box, relaxedConfig())
+	})
+}
@@ -0,0 +1,82 @@
+package pdf
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func analyzeFile(t *testing.T, p string) *Structure {
+	f, err := os.Open(p)
+	require.NoError(t, err)
+	defer f.Close()
+	structure, err := Analyze(f)
+	require.NoError(t, err)
+	return structure
+}
+
+func TestParsePages(t *testing.T) {
+	pages, err := ParsePages("", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, pages)
+
+	pages, err = ParsePages(" 4, 1-2 ", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 1, 2}, pages)
+
+	for _, value := range []string{"0", "6", "3-2", "a", "1-", "2,4-6"} {
+		_, err := ParsePages(value, 5)
+		assert.ErrorIs(t, err, ErrInvalidPages, value)
+	}
+
+	order, err := ParseOrder("3,1-2", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 1, 2}, order)
+	for _, value := range []string{"", "1,2", "1,1,2,3"} {
+		_, err := ParseOrder(value, 3)
+		assert.ErrorIs(t, err, ErrInvalidPages, value)
+	}
+}
+
+func TestEditPages(t *testing.T) {
+	dir := t.TempDir()
+	src := path.Join(dir, "sonata.pdf")
+	writeTestPDF(t, src, 4)
+	before, err := os.ReadFile(src)
+	require.NoError(t, err)
+
+	out := path.Join(dir, "rotated.pdf")
+	require.NoError(t, RotatePages(src, out, []int{1, 2, 3, 4}, 90))
+	assert.Equal(t, OrientationLandscape, analyzeFile(t, out).Orientation)
+	assert.ErrorIs(t, RotatePages(src, out, []int{1}, 45), ErrInvalidAngle)
+
+	out = path.Join(dir, "deleted.pdf")
+	require.NoError(t, DeletePages(src, out, []int{2, 3}, 4))
+	assert.Equal(t, 2, analyzeFile(t, out).PageCount)
+	assert.ErrorIs(t, DeletePages(src, out, []int{1, 2, 3, 4}, 4), ErrInvalidPages, "at least one page must remain")
+
+	out = path.Join(dir, "extracted.pdf")
+	require.NoError(t, SelectPages(src, out, []int{4, 1}))
+	assert.Equal(t, 2, analyzeFile(t, out).PageCount)
+
+	out = path.Join(dir, "cropped.pdf")
+	require.NoError(t, CropPages(src, out, []int{1}, "10 5"))
+	ctx, err := api.ReadContextFile(out)
+	require.NoError(t, err)
+	boundaries, err := ctx.PageBoundaries(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, boundaries[0].CropBox().Height(), "10 points cropped top and bottom")
+	assert.Equal(t, 80.0, boundaries[1].CropBox().Height(), "other pages unchanged")
+	for _, margins := range []string{"", "-5", "1 2 3 4 5", "10mm"} {
+		assert.ErrorIs(t, CropPages(src, out, []int{1}, margins), ErrInvalidMargins, margins)
+	}
+
+	// Le PDF d'origine n'est jamais modifié
+	after, err := os.ReadFile(src)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
@@ -0,0 +1,49 @@
+package pdf
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// ExtractPages écrit dans out les pages first à last (numérotées à partir de 1) du PDF,
+// sauf si out est déjà à jour. Sert les pièces d'un recueil sans dupliquer le PDF complet.
+func ExtractPages(pdfPath string, first int, last int, out string) error {
+	if Fresh(out, pdfPath) {
+		return nil
+	}
+	count, err := PageCount(pdfPath)
+	if err != nil {
+		return err
+	}
+	if first < 1 || last < first || last > count {
+		return fmt.Errorf("%w: %d-%d of %d", ErrPageOutOfRange, first, last, count)
+	}
+	if err := os.MkdirAll(path.Dir(out), os.ModePerm); err != nil {
+		return err
+	}
+
+	src, err := os.Open(pdfPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Fichier temporaire : deux requêtes simultanées ne voient jamais un PDF tronqué
+	tmp, err := os.CreateTemp(path.Dir(out), ".excerpt-*.pdf")
+	if err != nil {
+		return err
+	}
+	if err := api.Trim(src, tmp, []string{fmt.Sprintf("%d-%d", first, last)}, relaxedConfig()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), out)
+}
@@ -0,0 +1,270 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	_ "golang.org/x/image/webp"
+)
+
+// Assemblage de photos de partitions (téléphone, scanner) en un PDF, une image par page.
+// Chaque image est redressée selon son orientation EXIF puis centrée sur une page A4,
+// en portrait ou en paysage selon son format.
+
+var ErrUnsupportedImage = errors.New("unsupported image, expected JPEG, PNG or WebP")
+
+// ImageExtensions : extensions des images acceptées à l'upload
+var ImageExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true}
+
+const (
+	// Qualité JPEG d'une photo ré-encodée après redressement
+	orientedJPEGQuality = 90
+	// Taille maximale d'une image, vérifiée sur l'en-tête avant décodage (50 Mpx, 200 Mo en RGBA)
+	maxImagePixels = 50_000_000
+)
+
+// ErrImageTooLarge : image dont le décodage dépasserait maxImagePixels, refusée comme une image non supportée
+var ErrImageTooLarge = fmt.Errorf("%w: image larger than %d megapixels", ErrUnsupportedImage, maxImagePixels/1_000_000)
+
+// ImagesToPDF écrit dans w un PDF d'une page par image, dans l'ordre donné.
+// Le document est construit en une passe, chaque page ayant son propre format A4 portrait ou paysage.
+func ImagesToPDF(images [][]byte, w io.Writer) error {
+	if len(images) == 0 {
+		return fmt.Errorf("%w: no image", ErrUnsupportedImage)
+	}
+	portrait, err := api.Import("formsize:A4P, position:c, scalefactor:1.0", types.POINTS)
+	if err != nil {
+		return err
+	}
+	landscape, err := api.Import("formsize:A4L, position:c, scalefactor:1.0", types.POINTS)
+	if err != nil {
+		return err
+	}
+
+	conf := relaxedConfig()
+	conf.Cmd = model.IMPORTIMAGES
+	ctx, err := pdfcpu.CreateContextWithXRefTable(conf, portrait.PageDim)
+	if err != nil {
+		return err
+	}
+	pagesIndRef, err := ctx.Pages()
+	if err != nil {
+		return err
+	}
+	pagesDict, err := ctx.DereferenceDict(*pagesIndRef)
+	if err != nil {
+		return err
+	}
+
+	for i, data := range images {
+		oriented, isLandscape, err := orientImage(data)
+		if err != nil {
+			return fmt.Errorf("image %d: %w", i+1, err)
+		}
+		imp := portrait
+		if isLandscape {
+			imp = landscape
+		}
+		indRefs, err := pdfcpu.NewPagesForImage(ctx.XRefTable, bytes.NewReader(oriented), pagesIndRef, imp)
+		if err != nil {
+			return fmt.Errorf("image %d: %w", i+1, err)
+		}
+		for _, indRef := range indRefs {
+			if err := ctx.SetValid(*indRef); err != nil {
+				return err
+			}
+			if err := model.AppendPageTree(indRef, 1, pagesDict); err != nil {
+				return err
+			}
+			ctx.PageCount++
+		}
+	}
+	return api.Write(ctx, w, conf)
+}
+
+// orientImage redresse l'image selon son orientation EXIF et indique si elle est en paysage une fois redressée.
+// Une image sans rotation est gardée telle quelle, sans perte.
+func orientImage(data []byte) ([]byte, bool, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, ErrUnsupportedImage
+	}
+	switch format {
+	case "jpeg", "png", "webp":
+	default:
+		return nil, false, ErrUnsupportedImage
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return nil, false, ErrImageTooLarge
+	}
+
+	orientation := exifOrientation(data, format)
+	if orientation <= 1 || orientation > 8 {
+		return data, config.Width > config.Height, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	oriented := applyOrientation(img, orientation)
+
+	var buf bytes.Buffer
+	if format == "png" {
+		err = png.Encode(&buf, oriented)
+	} else {
+		err = jpeg.Encode(&buf, oriented, &jpeg.Options{Quality: orientedJPEGQuality})
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	bounds := oriented.Bounds()
+	return buf.Bytes(), bounds.Dx() > bounds.Dy(), nil
+}
+
+// applyOrientation applique la transformation EXIF (2 à 8) : miroirs et rotations de 90, 180 ou 270 degrés.
+// Les pixels sont copiés directement entre les tampons RGBA, sans passer par At/Set.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+w*4]
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // miroir horizontal
+				dx, dy = w-1-x, y
+			case 3: // 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // miroir vertical
+				dx, dy = x, h-1-y
+			case 5: // transposition
+				dx, dy = y, x
+			case 6: // 90° sens horaire
+				dx, dy = h-1-y, x
+			case 7: // transposition inverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // 90° sens anti-horaire
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], row[x*4:x*4+4])
+		}
+	}
+	return dst
+}
+
+// exifOrientation retourne l'orientation EXIF (1 à 8) de l'image, 0 si elle n'est pas renseignée.
+// L'EXIF est lu dans le segment APP1 d'un JPEG, le chunk eXIf d'un PNG ou le chunk EXIF d'un WebP.
+func exifOrientation(data []byte, format string) int {
+	var tiff []byte
+	switch format {
+	case "jpeg":
+		tiff = jpegExif(data)
+	case "png":
+		tiff = pngExif(data)
+	case "webp":
+		tiff = webpExif(data)
+	}
+	return tiffOrientation(bytes.TrimPrefix(tiff, []byte("Exif\x00\x00")))
+}
+
+func jpegExif(data []byte) []byte {
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		if marker == 0xD9 || marker == 0xDA { // fin d'image, début des données
+			return nil
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return nil
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment
+		}
+		i += 2 + size
+	}
+	return nil
+}
+
+func pngExif(data []byte) []byte {
+	for i := 8; i+8 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[i:]))
+		kind := string(data[i+4 : i+8])
+		if size < 0 || i+12+size > len(data) {
+			return nil
+		}
+		if kind == "eXIf" {
+			return data[i+8 : i+8+size]
+		}
+		if kind == "IDAT" || kind == "IEND" {
+			return nil
+		}
+		i += 12 + size
+	}
+	return nil
+}
+
+func webpExif(data []byte) []byte {
+	for i := 12; i+8 <= len(data); {
+		kind := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		if size < 0 || i+8+size > len(data) {
+			return nil
+		}
+		if kind == "EXIF" {
+			return data[i+8 : i+8+size]
+		}
+		i += 8 + size + size%2
+	}
+	return nil
+}
+
+// tiffOrientation lit le tag Orientation (0x0112) du premier IFD d'un bloc EXIF au format TIFF
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for e := 0; e < entries; e++ {
+		entry := offset + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jpegWithOrientation encode une photo w x h et ajoute un segment EXIF avec l'orientation donnée
+func jpegWithOrientation(t *testing.T, w int, h int, orientation uint16) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil))
+	data := buf.Bytes()
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3) // SHORT
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	payload := append([]byte("Exif\x00\x00"), append(append(tiff, entry...), 0, 0, 0, 0)...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(append(append([]byte{}, data[:2]...), append(segment, payload...)...), data[2:]...)
+}
+
+func TestOrientImage(t *testing.T) {
+	photo := jpegWithOrientation(t, 80, 60, 6)
+	assert.Equal(t, 6, exifOrientation(photo, "jpeg"))
+	oriented, landscape, err := orientImage(photo)
+	require.NoError(t, err)
+	assert.False(t, landscape, "a landscape sensor image rotated by 90° is portrait")
+	config, _, err := image.DecodeConfig(bytes.NewReader(oriented))
+	require.NoError(t, err)
+	assert.Equal(t, 60, config.Width)
+	assert.Equal(t, 80, config.Height)
+
+	// Sans EXIF, l'image est gardée telle quelle
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 80, 60))))
+	oriented, landscape, err = orientImage(buf.Bytes())
+	require.NoError(t, err)
+	assert.True(t, landscape)
+	assert.Equal(t, buf.Bytes(), oriented)
+
+	_, _, err = orientImage([]byte("GIF89a"))
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+
+	// Le pixel en haut à gauche passe en haut à droite (90° horaire) ou en bas à gauche (90° anti-horaire)
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	src.Set(0, 0, red)
+	rotated := applyOrientation(src, 6)
+	assert.Equal(t, image.Rect(0, 0, 2, 4), rotated.Bounds())
+	assert.Equal(t, red, rotated.At(1, 0))
+	assert.Equal(t, red, applyOrientation(src, 8).At(0, 3))
+	assert.Equal(t, red, applyOrientation(src, 3).At(3, 1))
+}
+
+func TestImagesToPDF(t *testing.T) {
+	var landscape bytes.Buffer
+	require.NoError(t, png.Encode(&landscape, image.NewRGBA(image.Rect(0, 0, 80, 60))))
+	var webp bytes.Buffer
+	require.NoError(t, nativewebp.Encode(&webp, image.NewRGBA(image.Rect(0, 0, 60, 80)), nil))
+	images := [][]byte{jpegWithOrientation(t, 80, 60, 6), landscape.Bytes(), webp.Bytes()}
+
+	var out bytes.Buffer
+	require.NoError(t, ImagesToPDF(images, &out))
+	structure, err := Analyze(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 3, structure.PageCount)
+	assert.Equal(t, OrientationMixed, structure.Orientation, "portrait, landscape and portrait photos")
+	assert.InDelta(t, 595, structure.PageWidth, 1, "A4 page")
+
+	assert.ErrorIs(t, ImagesToPDF(nil, &out), ErrUnsupportedImage)
+
+	// En-tête PNG annonçant 10000 x 10000 pixels : refusé avant décodage
+	huge := append([]byte{}, landscape.Bytes()...)
+	binary.BigEndian.PutUint32(huge[16:], 10000)
+	binary.BigEndian.PutUint32(huge[20:], 10000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	err = ImagesToPDF([][]byte{landscape.Bytes(), huge}, &out)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+}
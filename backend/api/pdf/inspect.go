@@ -0,0 +1,103 @@
+package pdf
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Lecture des métadonnées d'un PDF à l'aide de pdfcpu (pur Go, pas de dépendance externe)
+// Sources lues :
+//	- dictionnaire Info du document (Title, Author, Subject, Keywords, CreationDate)
+//	- flux XMP du catalogue (dc:title, dc:creator, dc:description, pdf:Keywords, xmp:CreateDate)
+// Les valeurs XMP, si présentes, sont prioritaires sur le dictionnaire Info.
+
+func init() {
+	// Par défaut pdfcpu crée un répertoire de configuration dans le $HOME de l'utilisateur
+	api.DisableConfigDir()
+}
+
+// Metadata regroupe les informations lues dans un PDF
+type Metadata struct {
+	Title        string    `json:"title"`
+	Author       string    `json:"author"`
+	Subject      string    `json:"subject"`
+	Keywords     []string  `json:"keywords"`
+	CreationDate time.Time `json:"creation_date"`
+	PageCount    int       `json:"page_count"`
+}
+
+// Configuration pdfcpu tolérante : les PDF scannés sont rarement parfaitement conformes
+func relaxedConfig() *model.Configuration {
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+	return conf
+}
+
+// Inspect lit les métadonnées du PDF. rs est rembobiné au début après lecture.
+func Inspect(rs io.ReadSeeker) (*Metadata, error) {
+	ctx, err := api.ReadAndValidate(rs, relaxedConfig())
+	if _, seekErr := rs.Seek(0, io.SeekStart); seekErr != nil && err == nil {
+		err = seekErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{
+		Title:     strings.TrimSpace(ctx.Title),
+		Author:    strings.TrimSpace(ctx.Author),
+		Subject:   strings.TrimSpace(ctx.Subject),
+		PageCount: ctx.PageCount,
+		Keywords:  []string{},
+	}
+	if t, ok := types.DateTime(ctx.XRefTable.CreationDate, true); ok {
+		meta.CreationDate = t
+	}
+	for keyword := range ctx.KeywordList {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			meta.Keywords = append(meta.Keywords, keyword)
+		}
+	}
+	sort.Strings(meta.Keywords)
+	if ctx.Keywords != "" && len(meta.Keywords) == 0 {
+		meta.Keywords = splitKeywords(ctx.Keywords)
+	}
+
+	// XMP prioritaire
+	if x := ctx.CatalogXMPMeta; x != nil {
+		d := x.RDF.Description
+		if title := strings.TrimSpace(strings.Join(d.Title.Alt.Entries, ", ")); title != "" {
+			meta.Title = title
+		}
+		if author := strings.TrimSpace(strings.Join(d.Author.Seq.Entries, ", ")); author != "" {
+			meta.Author = author
+		}
+		if subject := strings.TrimSpace(strings.Join(d.Subject.Alt.Entries, ", ")); subject != "" {
+			meta.Subject = subject
+		}
+		if created := time.Time(d.CreationDate); !created.IsZero() {
+			meta.CreationDate = created
+		}
+		if d.Keywords != "" {
+			meta.Keywords = splitKeywords(d.Keywords)
+		}
+	}
+
+	return meta, nil
+}
+
+func splitKeywords(s string) []string {
+	keywords := []string{}
+	for _, k := range strings.FieldsFunc(s, func(c rune) bool { return c == ',' || c == ';' || c == '\r' || c == '\n' }) {
+		if k = strings.TrimSpace(k); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords
+}
@@ -0,0 +1,96 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pdfWithInfo écrit un PDF d'une page, avec le dictionnaire Info info s'il n'est pas vide
+func pdfWithInfo(info string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>",
+	}
+	if info != "" {
+		objects = append(objects, info)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	trailer := fmt.Sprintf("/Size %d /Root 1 0 R", len(objects)+1)
+	if info != "" {
+		trailer += " /Info 4 0 R"
+	}
+	fmt.Fprintf(&buf, "trailer\n<< %s >>\nstartxref\n%d\n%%%%EOF\n", trailer, xref)
+	return buf.Bytes()
+}
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		name string
+		info string
+		want Metadata
+	}{
+		{
+			name: "without metadata",
+			want: Metadata{Keywords: []string{}, PageCount: 1},
+		},
+		{
+			name: "info dictionary",
+			info: "<< /Title ( Nocturne Op. 9 No. 2 ) /Author (Frederic Chopin) /Subject (Piano) /Keywords (romantic; nocturne) /CreationDate (D:20240315120000Z) >>",
+			want: Metadata{
+				Title:        "Nocturne Op. 9 No. 2",
+				Author:       "Frederic Chopin",
+				Subject:      "Piano",
+				Keywords:     []string{"nocturne", "romantic"},
+				CreationDate: time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC),
+				PageCount:    1,
+			},
+		},
+		{
+			name: "title only",
+			info: "<< /Title (Fur Elise) >>",
+			want: Metadata{Title: "Fur Elise", Keywords: []string{}, PageCount: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := bytes.NewReader(pdfWithInfo(tt.info))
+			meta, err := Inspect(rs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.Title, meta.Title)
+			assert.Equal(t, tt.want.Author, meta.Author)
+			assert.Equal(t, tt.want.Subject, meta.Subject)
+			assert.Equal(t, tt.want.Keywords, meta.Keywords)
+			assert.True(t, tt.want.CreationDate.Equal(meta.CreationDate), "creation date %v", meta.CreationDate)
+			assert.Equal(t, tt.want.PageCount, meta.PageCount)
+
+			offset, _ := rs.Seek(0, io.SeekCurrent)
+			assert.Zero(t, offset, "reader rewound")
+		})
+	}
+
+	_, err := Inspect(bytes.NewReader([]byte("not a pdf")))
+	assert.Error(t, err)
+}
+
+func TestSplitKeywords(t *testing.T) {
+	assert.Equal(t, []string{"a", "b c", "d"}, splitKeywords(" a, b c ;\nd ,"))
+	assert.Equal(t, []string{}, splitKeywords(""))
+}
@@ -0,0 +1,156 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Etiquettes à coller sur les classeurs des exemplaires imprimés : titre, compositeur,
+// numéro de catalogue et QR code du lien vers la partition numérique.
+// Planche A4 de 2 x 7 étiquettes de 99,1 x 38,1 mm (format Avery L7163), en points.
+const (
+	labelColumns = 2
+	labelRows    = 7
+	labelHeight  = 108.0
+	labelLeft    = 13.2  // marge gauche de la planche
+	labelTop     = 42.8  // marge haute de la planche
+	labelPitchX  = 288.0 // distance entre les bords gauches de deux colonnes
+	pageHeight   = 841.89
+	qrSize       = 90.0
+
+	// Largeur du texte à droite du QR code, en caractères Helvetica
+	titleLineLength = 30
+	textLineLength  = 38
+)
+
+// LabelsPerPage : nombre d'étiquettes d'une planche
+const LabelsPerPage = labelColumns * labelRows
+
+var ErrNoLabels = errors.New("no labels to print")
+
+// Label : contenu d'une étiquette
+type Label struct {
+	Title     string
+	Composer  string
+	Catalogue string // ex: Op. 27 No. 2, vide = pas de numéro
+	URL       string // lien encodé dans le QR code
+}
+
+// QRCode retourne l'image PNG de size x size pixels du QR code de content
+func QRCode(content string, size int) ([]byte, error) {
+	return qrcode.Encode(content, qrcode.Medium, size)
+}
+
+// Labels écrit dans w les planches A4 des étiquettes, dans l'ordre donné
+func Labels(labels []Label, w io.Writer) error {
+	if len(labels) == 0 {
+		return ErrNoLabels
+	}
+	// Les images du document sont lues depuis des fichiers
+	dir, err := os.MkdirTemp("", "sheetflow-labels")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	pages := map[string]interface{}{}
+	for i, label := range labels {
+		png, err := QRCode(label.URL, 256)
+		if err != nil {
+			return fmt.Errorf("unable to encode %q: %w", label.URL, err)
+		}
+		src := filepath.Join(dir, fmt.Sprintf("qr-%d.png", i))
+		if err := os.WriteFile(src, png, 0666); err != nil {
+			return err
+		}
+
+		page := strconv.Itoa(i/LabelsPerPage + 1)
+		if pages[page] == nil {
+			pages[page] = map[string]interface{}{"content": map[string]interface{}{"text": []interface{}{}, "image": []interface{}{}}}
+		}
+		content := pages[page].(map[string]interface{})["content"].(map[string]interface{})
+
+		// Coin inférieur gauche de l'étiquette, origine en bas à gauche de la page
+		slot := i % LabelsPerPage
+		x := labelLeft + float64(slot%labelColumns)*labelPitchX
+		y := pageHeight - labelTop - float64(slot/labelColumns+1)*labelHeight
+
+		content["image"] = append(content["image"].([]interface{}), map[string]interface{}{
+			"src":    src,
+			"pos":    []float64{x + 9, y + (labelHeight-qrSize)/2},
+			"width":  qrSize,
+			"height": qrSize,
+		})
+		textX := x + qrSize + 18
+		lines := []struct {
+			value string
+			font  string
+			size  int
+			y     float64
+		}{
+			{wrapLine(label.Title, titleLineLength, 2), "Helvetica-Bold", 11, y + 66},
+			{truncate(label.Composer, textLineLength), "Helvetica", 9, y + 36},
+			{truncate(label.Catalogue, textLineLength), "Helvetica", 9, y + 22},
+		}
+		for _, line := range lines {
+			if line.value == "" {
+				continue
+			}
+			content["text"] = append(content["text"].([]interface{}), map[string]interface{}{
+				"value": line.value,
+				"pos":   []float64{textX, line.y},
+				"font":  map[string]interface{}{"name": line.font, "size": line.size},
+			})
+		}
+	}
+
+	document, err := json.Marshal(map[string]interface{}{
+		"paper":  "A4P",
+		"origin": "LowerLeft",
+		"pages":  pages,
+	})
+	if err != nil {
+		return err
+	}
+	return api.Create(nil, bytes.NewReader(document), w, nil)
+}
+
+// truncate coupe value à max caractères, avec des points de suspension
+func truncate(value string, max int) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	runes := []rune(value)
+	return strings.TrimSpace(string(runes[:max-3])) + "..."
+}
+
+// wrapLine répartit value sur au plus lines lignes de max caractères, la dernière est tronquée
+func wrapLine(value string, max int, lines int) string {
+	words := strings.Fields(value)
+	result := []string{}
+	for len(words) > 0 && len(result) < lines-1 {
+		n, length := 1, utf8.RuneCountInString(words[0])
+		for n < len(words) && length+1+utf8.RuneCountInString(words[n]) <= max {
+			length += 1 + utf8.RuneCountInString(words[n])
+			n++
+		}
+		result = append(result, truncate(strings.Join(words[:n], " "), max))
+		words = words[n:]
+	}
+	if len(words) > 0 {
+		result = append(result, truncate(strings.Join(words, " "), max))
+	}
+	return strings.Join(result, "\n")
+}
@@ -0,0 +1,50 @@
+package pdf
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQRCode(t *testing.T) {
+	data, err := QRCode("http://localhost:8080/api/sheet/fuer-elise", 200)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 200, img.Bounds().Dx())
+	assert.Equal(t, 200, img.Bounds().Dy())
+}
+
+func TestLabels(t *testing.T) {
+	labels := make([]Label, LabelsPerPage+1)
+	for i := range labels {
+		labels[i] = Label{Title: "Nocturne in E-flat major", Composer: "Frédéric Chopin", Catalogue: "Op. 9 No. 2", URL: "http://localhost:3000/sheet/nocturne"}
+	}
+	out := path.Join(t.TempDir(), "labels.pdf")
+	f, err := os.Create(out)
+	require.NoError(t, err)
+	require.NoError(t, Labels(labels, f))
+	require.NoError(t, f.Close())
+
+	count, err := PageCount(out)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "a second sheet for the 15th label")
+
+	assert.ErrorIs(t, Labels(nil, &bytes.Buffer{}), ErrNoLabels)
+}
+
+func TestWrapLine(t *testing.T) {
+	assert.Equal(t, "Nocturne", wrapLine("  Nocturne ", 30, 2))
+	assert.Equal(t, "Piano Sonata No. 14 in C-sharp\nminor Moonlight", wrapLine("Piano Sonata No. 14 in C-sharp minor Moonlight", 30, 2))
+	wrapped := wrapLine("Das wohltemperierte Klavier Teil I Praeludium und Fuge in C-Dur und andere Stücke", 30, 2)
+	lines := strings.Split(wrapped, "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[1], "..."))
+	assert.LessOrEqual(t, len([]rune(lines[1])), 30)
+}
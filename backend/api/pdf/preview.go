@@ -0,0 +1,162 @@
+package pdf
+
+import (
+	"backend/api/utils"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"golang.org/x/image/draw"
+)
+
+// Aperçus des partitions : miniatures en plusieurs tailles et rendu d'une page quelconque.
+// Le rendu PDF → PNG est délégué au service pdf2png, qui ne sait convertir que la première page :
+// pour une autre page, elle est d'abord extraite dans un PDF d'une seule page.
+// Les images sont générées à la demande puis mises en cache sur le disque ;
+// une image plus ancienne que sa source est régénérée.
+
+// Tailles des aperçus : largeur en pixels, la hauteur suit les proportions de la page
+var PreviewSizes = map[string]int{
+	"list":   120,
+	"grid":   300,
+	"detail": 800,
+}
+
+// Formats des aperçus et leur Content-Type
+var PreviewFormats = map[string]string{
+	"png":  "image/png",
+	"webp": "image/webp",
+}
+
+var ErrPageOutOfRange = errors.New("page out of range")
+
+// Renderer convertit la première page d'un PDF en PNG. Remplaçable dans les tests.
+var Renderer = utils.RenderPdfToPng
+
+// PageCount retourne le nombre de pages du PDF
+func PageCount(pdfPath string) (int, error) {
+	f, err := os.Open(pdfPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return api.PageCount(f, relaxedConfig())
+}
+
+// RenderPage écrit dans out le rendu PNG de la page (numérotée à partir de 1), sauf s'il est déjà à jour
+func RenderPage(pdfPath string, page int, out string) error {
+	if Fresh(out, pdfPath) {
+		return nil
+	}
+	count, err := PageCount(pdfPath)
+	if err != nil {
+		return err
+	}
+	if page < 1 || page > count {
+		return ErrPageOutOfRange
+	}
+	if err := os.MkdirAll(path.Dir(out), os.ModePerm); err != nil {
+		return err
+	}
+	if page == 1 {
+		return Renderer(pdfPath, out)
+	}
+
+	single, err := os.CreateTemp(path.Dir(out), ".page-*.pdf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(single.Name())
+
+	src, err := os.Open(pdfPath)
+	if err != nil {
+		single.Close()
+		return err
+	}
+	defer src.Close()
+	if err := api.Trim(src, single, []string{strconv.Itoa(page)}, relaxedConfig()); err != nil {
+		single.Close()
+		return err
+	}
+	if err := single.Close(); err != nil {
+		return err
+	}
+	return Renderer(single.Name(), out)
+}
+
+// Resize écrit dans out l'image src redimensionnée à la taille et au format demandés,
+// sauf si out est déjà à jour
+func Resize(src string, out string, size string, format string) error {
+	if Fresh(out, src) {
+		return nil
+	}
+	width, ok := PreviewSizes[size]
+	if !ok {
+		return errors.New("unknown preview size " + strconv.Quote(size))
+	}
+	if _, ok := PreviewFormats[format]; !ok {
+		return errors.New("unknown preview format " + strconv.Quote(format))
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	b := img.Bounds()
+	height := max(1, b.Dy()*width/max(1, b.Dx()))
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, b, draw.Src, nil)
+
+	return writeImage(out, func(w io.Writer) error {
+		if format == "webp" {
+			return nativewebp.Encode(w, resized, nil)
+		}
+		return png.Encode(w, resized)
+	})
+}
+
+// Fresh indique si out existe et n'est pas plus ancien que source
+func Fresh(out string, source string) bool {
+	outInfo, err := os.Stat(out)
+	if err != nil {
+		return false
+	}
+	srcInfo, err := os.Stat(source)
+	if err != nil {
+		return true
+	}
+	return !outInfo.ModTime().Before(srcInfo.ModTime())
+}
+
+// writeImage écrit d'abord un fichier temporaire : deux requêtes simultanées ne voient jamais une image tronquée
+func writeImage(out string, encode func(w io.Writer) error) error {
+	if err := os.MkdirAll(path.Dir(out), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(path.Dir(out), ".preview-*")
+	if err != nil {
+		return err
+	}
+	if err := encode(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), out)
+}
@@ -0,0 +1,119 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/webp"
+)
+
+// writeTestPDF crée un PDF de pages pages, une image par page
+func writeTestPDF(t *testing.T, p string, pages int) {
+	var imgs []io.Reader
+	for i := 0; i < pages; i++ {
+		var buf bytes.Buffer
+		img := image.NewRGBA(image.Rect(0, 0, 60, 80))
+		img.Set(0, 0, color.RGBA{uint8(i), 0, 0, 0xff})
+		png.Encode(&buf, img)
+		imgs = append(imgs, &buf)
+	}
+	var out bytes.Buffer
+	if err := api.ImportImages(nil, &out, imgs, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, out.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeRenderer remplace le service pdf2png : une image 400x600 et le nombre de pages du PDF reçu
+func fakeRenderer(t *testing.T) *[]int {
+	var received []int
+	previous := Renderer
+	Renderer = func(pdfPath string, out string) error {
+		count, err := PageCount(pdfPath)
+		if err != nil {
+			return err
+		}
+		received = append(received, count)
+		var buf bytes.Buffer
+		png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 600)))
+		return os.WriteFile(out, buf.Bytes(), 0666)
+	}
+	t.Cleanup(func() { Renderer = previous })
+	return &received
+}
+
+func TestRenderPageExtractsSinglePage(t *testing.T) {
+	dir := t.TempDir()
+	src := path.Join(dir, "sheet.pdf")
+	writeTestPDF(t, src, 3)
+	received := fakeRenderer(t)
+
+	out := path.Join(dir, "pages", "2.png")
+	assert.NoError(t, RenderPage(src, 2, out))
+	assert.FileExists(t, out)
+	assert.Equal(t, []int{1}, *received)
+
+	// Deuxième appel : servi depuis le cache
+	assert.NoError(t, RenderPage(src, 2, out))
+	assert.Len(t, *received, 1)
+
+	assert.ErrorIs(t, RenderPage(src, 4, path.Join(dir, "pages", "4.png")), ErrPageOutOfRange)
+	assert.ErrorIs(t, RenderPage(src, 0, path.Join(dir, "pages", "0.png")), ErrPageOutOfRange)
+}
+
+func TestResizeKeepsAspectRatio(t *testing.T) {
+	dir := t.TempDir()
+	src := path.Join(dir, "thumb.png")
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 600)))
+	if err := os.WriteFile(src, buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	pngOut := path.Join(dir, "list", "thumb.png")
+	assert.NoError(t, Resize(src, pngOut, "list", "png"))
+	f, _ := os.Open(pngOut)
+	cfg, err := png.DecodeConfig(f)
+	f.Close()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 120, cfg.Width)
+		assert.Equal(t, 180, cfg.Height)
+	}
+
+	webpOut := path.Join(dir, "grid", "thumb.webp")
+	assert.NoError(t, Resize(src, webpOut, "grid", "webp"))
+	f, _ = os.Open(webpOut)
+	cfg, err = webp.DecodeConfig(f)
+	f.Close()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 300, cfg.Width)
+		assert.Equal(t, 450, cfg.Height)
+	}
+
+	assert.Error(t, Resize(src, path.Join(dir, "huge.png"), "huge", "png"))
+}
+
+func TestExtractPages(t *testing.T) {
+	dir := t.TempDir()
+	src := path.Join(dir, "anthology.pdf")
+	writeTestPDF(t, src, 5)
+
+	out := path.Join(dir, "excerpts", "prelude.pdf")
+	assert.NoError(t, ExtractPages(src, 2, 4, out))
+	count, err := PageCount(out)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	assert.ErrorIs(t, ExtractPages(src, 4, 6, path.Join(dir, "excerpts", "out.pdf")), ErrPageOutOfRange)
+	assert.ErrorIs(t, ExtractPages(src, 3, 2, path.Join(dir, "excerpts", "out.pdf")), ErrPageOutOfRange)
+}
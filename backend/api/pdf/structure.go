@@ -0,0 +1,136 @@
+package pdf
+
+import (
+	"errors"
+	"io"
+	"math"
+	"regexp"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Orientations des pages
+const (
+	OrientationPortrait  = "portrait"
+	OrientationLandscape = "landscape"
+	OrientationMixed     = "mixed" // pages de formats différents
+)
+
+// Nombre maximal de pages parcourues pour détecter une couche texte
+const textScanPages = 10
+
+// Opérateurs d'affichage de texte d'un flux de contenu PDF
+var textOperator = regexp.MustCompile(`(^|[\s\])>])(Tj|TJ)(\s|$)`)
+
+// Structure décrit le document PDF lui-même, indépendamment de ses métadonnées
+type Structure struct {
+	PageCount   int     `json:"page_count"`
+	PageWidth   float64 `json:"page_width"`  // en points (1/72 pouce), première page
+	PageHeight  float64 `json:"page_height"` // en points, première page
+	Orientation string  `json:"orientation"` // portrait, landscape ou mixed
+	FileSize    int64   `json:"file_size"`   // en octets
+	Encrypted   bool    `json:"encrypted"`
+	HasText     bool    `json:"has_text"` // faux pour un scan sans OCR
+}
+
+// Analyze lit la structure du PDF. rs est rembobiné au début après lecture.
+// Un PDF protégé par un mot de passe utilisateur ne peut pas être lu :
+// seuls FileSize et Encrypted sont alors renseignés.
+func Analyze(rs io.ReadSeeker) (*Structure, error) {
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	structure := &Structure{FileSize: size}
+
+	ctx, err := api.ReadAndValidate(rs, relaxedConfig())
+	if _, seekErr := rs.Seek(0, io.SeekStart); seekErr != nil && err == nil {
+		err = seekErr
+	}
+	if errors.Is(err, pdfcpu.ErrWrongPassword) {
+		structure.Encrypted = true
+		return structure, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	structure.PageCount = ctx.PageCount
+	structure.Encrypted = ctx.Encrypt != nil
+
+	dims, err := ctx.PageDims()
+	if err != nil {
+		return nil, err
+	}
+	if len(dims) > 0 {
+		structure.PageWidth = round(dims[0].Width)
+		structure.PageHeight = round(dims[0].Height)
+		structure.Orientation = orientation(dims[0])
+		for _, d := range dims[1:] {
+			if orientation(d) != structure.Orientation {
+				structure.Orientation = OrientationMixed
+				break
+			}
+		}
+	}
+
+	for page := 1; page <= min(ctx.PageCount, textScanPages) && !structure.HasText; page++ {
+		d, _, _, err := ctx.PageDict(page, true)
+		if err != nil || d == nil {
+			continue
+		}
+		if content, err := ctx.PageContent(d, page); err == nil && textOperator.Match(content) {
+			structure.HasText = true
+			continue
+		}
+		resources, _ := ctx.DereferenceDict(d["Resources"])
+		structure.HasText = xObjectsHaveText(ctx, resources, 0)
+	}
+
+	return structure, nil
+}
+
+// xObjectsHaveText cherche du texte dans les XObjects de formulaire (filigranes, tampons, contenus imbriqués)
+func xObjectsHaveText(ctx *model.Context, resources types.Dict, depth int) bool {
+	if resources == nil || depth > 3 {
+		return false
+	}
+	xObjects, err := ctx.DereferenceDict(resources["XObject"])
+	if err != nil || xObjects == nil {
+		return false
+	}
+	for _, o := range xObjects {
+		sd, _, err := ctx.DereferenceStreamDict(o)
+		if err != nil || sd == nil {
+			continue
+		}
+		if subtype := sd.Dict.NameEntry("Subtype"); subtype == nil || *subtype != "Form" {
+			continue
+		}
+		if err := sd.Decode(); err == nil && textOperator.Match(sd.Content) {
+			return true
+		}
+		inner, _ := ctx.DereferenceDict(sd.Dict["Resources"])
+		if xObjectsHaveText(ctx, inner, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+func orientation(d types.Dim) string {
+	if d.Width > d.Height {
+		return OrientationLandscape
+	}
+	return OrientationPortrait
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,67 @@
+package pdf
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestPDF(t *testing.T, pages int) []byte {
+	p := path.Join(t.TempDir(), "sheet.pdf")
+	writeTestPDF(t, p, pages)
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestAnalyzeScannedPDF(t *testing.T) {
+	data := readTestPDF(t, 3)
+
+	s, err := Analyze(bytes.NewReader(data))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, s.PageCount)
+		assert.Equal(t, int64(len(data)), s.FileSize)
+		assert.Equal(t, OrientationPortrait, s.Orientation)
+		assert.Greater(t, s.PageHeight, s.PageWidth)
+		assert.False(t, s.Encrypted)
+		assert.False(t, s.HasText)
+	}
+}
+
+func TestAnalyzeDetectsTextLayer(t *testing.T) {
+	wm, err := api.TextWatermark("Etude", "rot:0", true, false, types.POINTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := api.AddWatermarks(bytes.NewReader(readTestPDF(t, 1)), &out, nil, wm, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Analyze(bytes.NewReader(out.Bytes()))
+	if assert.NoError(t, err) {
+		assert.True(t, s.HasText)
+	}
+}
+
+func TestAnalyzeEncryptedPDF(t *testing.T) {
+	conf := model.NewAESConfiguration("user", "owner", 256)
+	var out bytes.Buffer
+	if err := api.Encrypt(bytes.NewReader(readTestPDF(t, 2)), &out, conf); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Analyze(bytes.NewReader(out.Bytes()))
+	if assert.NoError(t, err) {
+		assert.True(t, s.Encrypted)
+		assert.Equal(t, int64(out.Len()), s.FileSize)
+	}
+}
@@ -0,0 +1,44 @@
+package pdf
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Filigrane personnalisé d'un PDF téléchargé : quelques lignes en pied de chaque page, au-dessus du contenu
+const watermarkStyle = "font:Helvetica, points:7, position:bc, offset:0 6, rotation:0, scalefactor:1 abs, opacity:0.75, fillcolor:#555555"
+
+// Watermark écrit dans out le PDF src avec lines en pied de chaque page, sauf si out est déjà à jour.
+// Le PDF est écrit dans un fichier temporaire : deux téléchargements simultanés ne voient jamais un PDF tronqué.
+func Watermark(src string, out string, lines []string) error {
+	if Fresh(out, src) {
+		return nil
+	}
+	// % introduit les numéros de page dans un texte pdfcpu
+	text := strings.ReplaceAll(strings.Join(lines, "\n"), "%", "")
+	wm, err := api.TextWatermark(text, watermarkStyle, true, false, types.POINTS)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(out), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(path.Dir(out), ".watermark-*.pdf")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	err = edit(src, tmp.Name(), func(rs io.ReadSeeker, w io.Writer) error {
+		return api.AddWatermarks(rs, w, nil, wm, relaxedConfig())
+	})
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), out)
+}
@@ -0,0 +1,47 @@
+package pdf
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatermark(t *testing.T) {
+	dir := t.TempDir()
+	src := path.Join(dir, "sonata.pdf")
+	writeTestPDF(t, src, 2)
+
+	out := path.Join(dir, "watermarked", "1-sheet.pdf")
+	lines := []string{"Licensed to anna@example.com on 2026-10-19", "Do not distribute"}
+	require.NoError(t, Watermark(src, out, lines))
+	count, err := PageCount(out)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	f, err := os.Open(out)
+	require.NoError(t, err)
+	defer f.Close()
+	stamped, err := api.HasWatermarks(f, nil)
+	require.NoError(t, err)
+	assert.True(t, stamped)
+
+	// Déjà à jour : le PDF n'est pas refait
+	info, err := os.Stat(out)
+	require.NoError(t, err)
+	require.NoError(t, Watermark(src, out, lines))
+	again, err := os.Stat(out)
+	require.NoError(t, err)
+	assert.Equal(t, info.ModTime(), again.ModTime())
+
+	// Source plus récente : le PDF est refait
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(src, later, later))
+	require.NoError(t, Watermark(src, out, lines))
+	again, err = os.Stat(out)
+	require.NoError(t, err)
+	assert.NotEqual(t, info.ModTime(), again.ModTime())
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/mozillazg/go-unidecode"
+)
+
+//go:embed data/composers.json
+var offlineDataset []byte
+
+// Offline recherche dans le jeu de données embarqué (data/composers.json)
+type Offline struct {
+	byName map[string]*ComposerInfo
+}
+
+func NewOffline() *Offline {
+	var composers []*ComposerInfo
+	if err := json.Unmarshal(offlineDataset, &composers); err != nil {
+		log.Printf("offline composer dataset: %v\n", err)
+	}
+
+	o := &Offline{byName: map[string]*ComposerInfo{}}
+	for _, comp := range composers {
+		o.byName[normalize(comp.CompleteName)] = comp
+	}
+	// Les noms courts et alias ne sont indexés que s'ils ne sont pas ambigus (Strauss, Schumann ...)
+	for _, comp := range composers {
+		for _, name := range append([]string{comp.Name}, comp.Aliases...) {
+			key := normalize(name)
+			if existing, ok := o.byName[key]; ok && existing != nil && existing != comp {
+				if normalize(existing.CompleteName) != key {
+					o.byName[key] = nil
+				}
+			} else if !ok {
+				o.byName[key] = comp
+			}
+		}
+	}
+	return o
+}
+
+func (o *Offline) Name() string { return "offline" }
+
+func (o *Offline) Lookup(name string) (*ComposerInfo, error) {
+	comp := o.byName[normalize(name)]
+	if comp == nil {
+		return nil, ErrNotFound
+	}
+	info := *comp
+	info.Aliases = append([]string{}, comp.Aliases...)
+	return &info, nil
+}
+
+// Forme de comparaison : ASCII, minuscules, espaces simples
+// "Frédéric  Chopin" → "frederic chopin"
+func normalize(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(unidecode.Unidecode(name))), " ")
+}
@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"backend/api/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// OpenOpus interroge https://api.openopus.org
+// Les réponses (trouvé ou non trouvé) sont gardées en mémoire et sur le disque dans cacheDir
+// afin de ne pas appeler l'API à chaque upload. Les erreurs réseau ne sont pas mises en cache.
+type OpenOpus struct {
+	BaseURL  string
+	Client   *http.Client
+	CacheDir string
+	TTL      time.Duration // durée de validité d'un résultat trouvé
+	MissTTL  time.Duration // durée de validité d'un résultat non trouvé
+
+	mu     sync.Mutex
+	memory map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	Info      *ComposerInfo `json:"info"`
+	FetchedAt time.Time     `json:"fetched_at"`
+}
+
+// Réponse de l'API Open Opus
+type openOpusResponse struct {
+	Composers []ComposerInfo `json:"composers"`
+}
+
+func NewOpenOpus(cacheDir string) *OpenOpus {
+	return &OpenOpus{
+		BaseURL:  "https://api.openopus.org",
+		Client:   &http.Client{Timeout: 5 * time.Second},
+		CacheDir: cacheDir,
+		TTL:      30 * 24 * time.Hour,
+		MissTTL:  24 * time.Hour,
+		memory:   map[string]cacheEntry{},
+	}
+}
+
+func (o *OpenOpus) Name() string { return "openopus" }
+
+func (o *OpenOpus) Lookup(name string) (*ComposerInfo, error) {
+	key := utils.SanitizeName(name)
+	if key == "" {
+		return nil, ErrNotFound
+	}
+
+	if entry, ok := o.cached(key); ok {
+		if entry.Info == nil {
+			return nil, ErrNotFound
+		}
+		info := *entry.Info
+		return &info, nil
+	}
+
+	info, err := o.fetch(name)
+	if err != nil {
+		return nil, err
+	}
+	o.store(key, cacheEntry{Info: info, FetchedAt: time.Now()})
+
+	if info == nil {
+		return nil, ErrNotFound
+	}
+	result := *info
+	return &result, nil
+}
+
+// fetch retourne (nil, nil) si l'API ne connaît pas le compositeur
+func (o *OpenOpus) fetch(name string) (*ComposerInfo, error) {
+	resp, err := o.Client.Get(o.BaseURL + "/composer/list/search/" + url.PathEscape(name) + ".json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openopus returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var response openOpusResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	// Le nom saisi et le nom retourné par l'API doivent correspondre
+	for i := range response.Composers {
+		if matches(name, &response.Composers[i]) {
+			return &response.Composers[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (o *OpenOpus) cached(key string) (cacheEntry, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entry, ok := o.memory[key]
+	if !ok && o.CacheDir != "" {
+		data, err := os.ReadFile(path.Join(o.CacheDir, key+".json"))
+		if err == nil && json.Unmarshal(data, &entry) == nil {
+			ok = true
+			o.memory[key] = entry
+		}
+	}
+	if !ok {
+		return entry, false
+	}
+
+	ttl := o.TTL
+	if entry.Info == nil {
+		ttl = o.MissTTL
+	}
+	if time.Since(entry.FetchedAt) > ttl {
+		return entry, false
+	}
+	return entry, true
+}
+
+func (o *OpenOpus) store(key string, entry cacheEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.memory[key] = entry
+	if o.CacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(o.CacheDir, os.ModePerm); err != nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	os.WriteFile(path.Join(o.CacheDir, key+".json"), data, 0666)
+}
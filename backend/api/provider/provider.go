@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"errors"
+	"log"
+	"strings"
+)
+
+// Fournisseurs de métadonnées des compositeurs
+// Lors d'un upload, le nom saisi par l'utilisateur est enrichi (nom complet, dates, époque, portrait)
+// par un ComposerProvider. Les implémentations disponibles :
+//	- "openopus" : API https://api.openopus.org avec cache disque
+//	- "offline"  : jeu de données embarqué dans le binaire (serveurs sans accès Internet)
+//	- "none"     : aucun enrichissement
+// Plusieurs fournisseurs peuvent être chaînés (COMPOSER_PROVIDER=openopus,offline) :
+// le premier qui trouve le compositeur gagne.
+
+var ErrNotFound = errors.New("composer not found")
+
+// ComposerInfo est le résultat d'une recherche auprès d'un fournisseur.
+// Les dates sont au format "YYYY-MM-DD" ou "YYYY" si seule l'année est connue.
+type ComposerInfo struct {
+	Name         string   `json:"name"`
+	CompleteName string   `json:"complete_name"`
+	Birth        string   `json:"birth"`
+	Death        string   `json:"death"`
+	Epoch        string   `json:"epoch"`
+	Portrait     string   `json:"portrait"`
+	Nationality  string   `json:"nationality"`
+	Aliases      []string `json:"aliases"` // autres orthographes connues
+}
+
+// ComposerProvider recherche les informations d'un compositeur à partir du nom saisi.
+// Lookup retourne ErrNotFound si le compositeur est inconnu du fournisseur.
+type ComposerProvider interface {
+	Name() string
+	Lookup(name string) (*ComposerInfo, error)
+}
+
+// New construit le fournisseur décrit par spec, liste séparée par des virgules
+// Exemple : "openopus,offline". cacheDir est utilisé par OpenOpus.
+func New(spec string, cacheDir string) ComposerProvider {
+	var providers []ComposerProvider
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "openopus":
+			providers = append(providers, NewOpenOpus(cacheDir))
+		case "offline":
+			providers = append(providers, NewOffline())
+		case "none", "":
+			// rien
+		default:
+			log.Printf("unknown composer provider %q ignored\n", name)
+		}
+	}
+
+	switch len(providers) {
+	case 0:
+		return Noop{}
+	case 1:
+		return providers[0]
+	default:
+		return Chain(providers)
+	}
+}
+
+// Chain interroge les fournisseurs dans l'ordre
+type Chain []ComposerProvider
+
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, p := range c {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func (c Chain) Lookup(name string) (*ComposerInfo, error) {
+	for _, p := range c {
+		info, err := p.Lookup(name)
+		if err == nil {
+			return info, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("composer provider %s: %v\n", p.Name(), err)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Noop ne trouve jamais rien
+type Noop struct{}
+
+func (Noop) Name() string { return "none" }
+
+func (Noop) Lookup(name string) (*ComposerInfo, error) {
+	return nil, ErrNotFound
+}
+
+// Le nom saisi correspond-il au nom court ou complet retourné par le fournisseur ?
+func matches(input string, info *ComposerInfo) bool {
+	input = normalize(input)
+	return input != "" && (input == normalize(info.Name) || input == normalize(info.CompleteName))
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfflineLookup(t *testing.T) {
+	offline := NewOffline()
+
+	info, err := offline.Lookup("frederic  chopin")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Frédéric Chopin", info.CompleteName)
+		assert.Equal(t, "1849-10-17", info.Death)
+	}
+
+	info, err = offline.Lookup("Chopin")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Frédéric Chopin", info.CompleteName)
+	}
+
+	info, err = offline.Lookup("Чайковский")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Pyotr Ilyich Tchaikovsky", info.CompleteName)
+		assert.Equal(t, "Russian", info.Nationality)
+	}
+
+	// Nom court ambigu : Johann Strauss II / Richard Strauss
+	_, err = offline.Lookup("Strauss")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestOpenOpusCachesResponses(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"composers":[{"name":"Chopin","complete_name":"Frédéric Chopin","birth":"1810-03-01","death":"1849-10-17","epoch":"Romantic","portrait":"https://example.org/chopin.jpg"}]}`)
+	}))
+	defer srv.Close()
+
+	cacheDir := t.TempDir()
+	openOpus := NewOpenOpus(cacheDir)
+	openOpus.BaseURL = srv.URL
+
+	for i := 0; i < 2; i++ {
+		info, err := openOpus.Lookup("Chopin")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Romantic", info.Epoch)
+		}
+	}
+	_, err := openOpus.Lookup("Liszt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = openOpus.Lookup("Liszt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 2, calls)
+
+	// Une nouvelle instance relit le cache disque
+	reloaded := NewOpenOpus(cacheDir)
+	reloaded.BaseURL = srv.URL
+	_, err = reloaded.Lookup("Chopin")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestChainFallsBack(t *testing.T) {
+	chain := New("none,offline", "")
+	info, err := chain.Lookup("Brahms")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Johannes Brahms", info.CompleteName)
+	}
+	_, err = New("none", "").Lookup("Brahms")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
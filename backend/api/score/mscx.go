@@ -0,0 +1,125 @@
+package score
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+)
+
+// parseMSCX lit le document MuseScore (.mscx) contenu dans une archive .mscz (MuseScore 3 et 4)
+func parseMSCX(r io.Reader) (*Metadata, error) {
+	d := newDecoder(r)
+	meta := &Metadata{}
+	var workTitle, movementTitle, textTitle, textComposer string
+	root := false
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidScore
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !root {
+			if start.Name.Local != "museScore" {
+				return nil, ErrInvalidScore
+			}
+			root = true
+			continue
+		}
+
+		switch start.Name.Local {
+		case "metaTag":
+			var tag struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:",chardata"`
+			}
+			if d.DecodeElement(&tag, &start) != nil {
+				continue
+			}
+			switch tag.Name {
+			case "workTitle":
+				workTitle = tag.Value
+			case "movementTitle":
+				movementTitle = tag.Value
+			case "composer":
+				meta.Composer = strings.TrimSpace(tag.Value)
+			}
+		case "Part":
+			var part struct {
+				TrackName  string `xml:"trackName"`
+				Instrument struct {
+					LongName string `xml:"longName"`
+				} `xml:"Instrument"`
+			}
+			if d.DecodeElement(&part, &start) == nil {
+				addPart(meta, firstNonEmpty(part.TrackName, part.Instrument.LongName))
+			}
+		case "KeySig":
+			// MuseScore 4 : concertKey, MuseScore 3 : accidental
+			var key struct {
+				ConcertKey *int   `xml:"concertKey"`
+				Accidental *int   `xml:"accidental"`
+				Mode       string `xml:"mode"`
+			}
+			if d.DecodeElement(&key, &start) != nil || meta.Key != "" {
+				continue
+			}
+			if key.ConcertKey == nil {
+				key.ConcertKey = key.Accidental
+			}
+			if key.ConcertKey != nil {
+				meta.Key = KeyName(*key.ConcertKey, key.Mode == "minor")
+			}
+		case "TimeSig":
+			var sig struct {
+				SigN string `xml:"sigN"`
+				SigD string `xml:"sigD"`
+			}
+			if d.DecodeElement(&sig, &start) == nil && meta.TimeSignature == "" && sig.SigN != "" && sig.SigD != "" {
+				meta.TimeSignature = strings.TrimSpace(sig.SigN) + "/" + strings.TrimSpace(sig.SigD)
+			}
+		case "Tempo":
+			// Tempo exprimé en noires par seconde
+			var tempo struct {
+				Tempo float64 `xml:"tempo"`
+			}
+			if d.DecodeElement(&tempo, &start) == nil && meta.Tempo == 0 {
+				meta.Tempo = int(math.Round(tempo.Tempo * 60))
+			}
+		case "Text":
+			var text struct {
+				Style string `xml:"style"`
+				Text  string `xml:"text"`
+			}
+			if d.DecodeElement(&text, &start) != nil {
+				continue
+			}
+			switch strings.ToLower(text.Style) {
+			case "title":
+				if textTitle == "" {
+					textTitle = text.Text
+				}
+			case "composer":
+				if textComposer == "" {
+					textComposer = text.Text
+				}
+			}
+		}
+	}
+	if !root {
+		return nil, ErrInvalidScore
+	}
+
+	meta.Title = firstNonEmpty(workTitle, movementTitle, textTitle)
+	if meta.Composer == "" {
+		meta.Composer = strings.TrimSpace(textComposer)
+	}
+	return meta, nil
+}
@@ -0,0 +1,159 @@
+package score
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Durée des unités de battue du métronome, en noires
+var beatUnits = map[string]float64{
+	"whole":   4,
+	"half":    2,
+	"quarter": 1,
+	"eighth":  0.5,
+	"16th":    0.25,
+}
+
+// parseMusicXML parcourt le document (partwise ou timewise) sans le charger entièrement en mémoire
+func parseMusicXML(r io.Reader) (*Metadata, error) {
+	d := newDecoder(r)
+	meta := &Metadata{}
+	var workTitle, movementTitle, creditTitle, creditComposer string
+	var soundTempo, metronomeTempo float64
+	root := false
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidScore
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !root {
+			if start.Name.Local != "score-partwise" && start.Name.Local != "score-timewise" {
+				return nil, ErrInvalidScore
+			}
+			root = true
+			continue
+		}
+
+		switch start.Name.Local {
+		case "work-title":
+			d.DecodeElement(&workTitle, &start)
+		case "movement-title":
+			d.DecodeElement(&movementTitle, &start)
+		case "creator":
+			var creator struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			}
+			if d.DecodeElement(&creator, &start) == nil && creator.Type == "composer" && meta.Composer == "" {
+				meta.Composer = strings.TrimSpace(creator.Value)
+			}
+		case "credit":
+			var credit struct {
+				Types []string `xml:"credit-type"`
+				Words []string `xml:"credit-words"`
+			}
+			if d.DecodeElement(&credit, &start) != nil {
+				continue
+			}
+			text := strings.TrimSpace(strings.Join(credit.Words, " "))
+			for _, t := range credit.Types {
+				switch {
+				case t == "title" && creditTitle == "":
+					creditTitle = text
+				case t == "composer" && creditComposer == "":
+					creditComposer = text
+				}
+			}
+		case "score-part":
+			var part struct {
+				Name string `xml:"part-name"`
+			}
+			if d.DecodeElement(&part, &start) == nil {
+				addPart(meta, part.Name)
+			}
+		case "key":
+			var key struct {
+				Fifths *int   `xml:"fifths"`
+				Mode   string `xml:"mode"`
+			}
+			if d.DecodeElement(&key, &start) == nil && meta.Key == "" && key.Fifths != nil {
+				meta.Key = KeyName(*key.Fifths, key.Mode == "minor")
+			}
+		case "time":
+			var time struct {
+				Beats    []string `xml:"beats"`
+				BeatType []string `xml:"beat-type"`
+			}
+			if d.DecodeElement(&time, &start) == nil && meta.TimeSignature == "" && len(time.Beats) > 0 && len(time.BeatType) > 0 {
+				meta.TimeSignature = strings.TrimSpace(time.Beats[0]) + "/" + strings.TrimSpace(time.BeatType[0])
+			}
+		case "sound":
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "tempo" && soundTempo == 0 {
+					soundTempo, _ = strconv.ParseFloat(strings.TrimSpace(attr.Value), 64)
+				}
+			}
+		case "metronome":
+			var metronome struct {
+				BeatUnit  string     `xml:"beat-unit"`
+				Dots      []struct{} `xml:"beat-unit-dot"`
+				PerMinute string     `xml:"per-minute"`
+			}
+			if d.DecodeElement(&metronome, &start) == nil && metronomeTempo == 0 {
+				metronomeTempo = metronomeQuarters(metronome.BeatUnit, len(metronome.Dots), metronome.PerMinute)
+			}
+		}
+	}
+	if !root {
+		return nil, ErrInvalidScore
+	}
+
+	meta.Title = firstNonEmpty(workTitle, movementTitle, creditTitle)
+	if meta.Composer == "" {
+		meta.Composer = creditComposer
+	}
+	if soundTempo == 0 {
+		soundTempo = metronomeTempo
+	}
+	meta.Tempo = int(math.Round(soundTempo))
+	return meta, nil
+}
+
+var numberPattern = regexp.MustCompile(`[0-9]+(\.[0-9]+)?`)
+
+// metronomeQuarters convertit une indication de métronome en noires par minute ("c. 120" est accepté)
+func metronomeQuarters(unit string, dots int, perMinute string) float64 {
+	value, err := strconv.ParseFloat(numberPattern.FindString(perMinute), 64)
+	if err != nil {
+		return 0
+	}
+	quarters, ok := beatUnits[unit]
+	if !ok {
+		return 0
+	}
+	for i, add := 0, quarters/2; i < dots; i, add = i+1, add/2 {
+		quarters += add
+	}
+	return value * quarters
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,233 @@
+package score
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"backend/api/abc"
+)
+
+// Lecture des partitions au format source : MusicXML (.musicxml, .xml), MusicXML compressé (.mxl),
+// MuseScore (.mscz) et notation ABC (.abc). Seules les informations utiles aux métadonnées de la Sheet sont extraites :
+// titre, compositeur, parties, tonalité, chiffrage de la mesure et tempo (les premiers rencontrés).
+
+// Formats des fichiers source
+const (
+	FormatMusicXML = "musicxml"
+	FormatMXL      = "mxl"
+	FormatMSCZ     = "mscz"
+	FormatABC      = "abc"
+)
+
+// Extensions utilisées pour stocker le fichier source et leur Content-Type
+var (
+	Extensions = map[string]string{
+		FormatMusicXML: ".musicxml",
+		FormatMXL:      ".mxl",
+		FormatMSCZ:     ".mscz",
+		FormatABC:      ".abc",
+	}
+	MimeTypes = map[string]string{
+		FormatMusicXML: "application/vnd.recordare.musicxml+xml",
+		FormatMXL:      "application/vnd.recordare.musicxml",
+		FormatMSCZ:     "application/x-musescore",
+		FormatABC:      "text/vnd.abc",
+	}
+)
+
+var (
+	ErrUnsupportedScore = errors.New("unsupported score, expected .musicxml, .xml, .mxl, .mscz or .abc")
+	ErrInvalidScore     = errors.New("invalid score file")
+	ErrNotMusicXML      = errors.New("a MusicXML source (.musicxml, .xml or .mxl) is required")
+)
+
+// Taille maximale d'un document décompressé
+const maxDocumentSize = 64 << 20
+
+// Metadata : informations extraites du fichier source
+type Metadata struct {
+	Format        string   `json:"format"`
+	Title         string   `json:"title"`
+	Composer      string   `json:"composer"`
+	Parts         []string `json:"parts"`
+	Key           string   `json:"key"`            // ex: Eb major
+	TimeSignature string   `json:"time_signature"` // ex: 3/4
+	Tempo         int      `json:"tempo"`          // noires par minute, 0 = inconnu
+}
+
+// FormatFromName retourne le format correspondant à l'extension du fichier
+func FormatFromName(filename string) (string, bool) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".musicxml", ".xml":
+		return FormatMusicXML, true
+	case ".mxl":
+		return FormatMXL, true
+	case ".mscz":
+		return FormatMSCZ, true
+	case ".abc":
+		return FormatABC, true
+	}
+	return "", false
+}
+
+// Parse lit le fichier source du format donné
+func Parse(data []byte, format string) (*Metadata, error) {
+	var (
+		meta *Metadata
+		err  error
+	)
+	switch format {
+	case FormatMusicXML:
+		meta, err = parseMusicXML(bytes.NewReader(data))
+	case FormatMXL:
+		var doc []byte
+		if doc, err = Document(data, format); err == nil {
+			meta, err = parseMusicXML(bytes.NewReader(doc))
+		}
+	case FormatMSCZ:
+		var doc []byte
+		if doc, err = rootDocument(data, ".mscx"); err == nil {
+			meta, err = parseMSCX(bytes.NewReader(doc))
+		}
+	case FormatABC:
+		meta, err = parseABC(data)
+	default:
+		return nil, ErrUnsupportedScore
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta.Format = format
+	return meta, nil
+}
+
+// Document retourne le document MusicXML d'un fichier source, décompressé pour un .mxl
+func Document(data []byte, format string) ([]byte, error) {
+	switch format {
+	case FormatMusicXML:
+		return data, nil
+	case FormatMXL:
+		return rootDocument(data, ".musicxml", ".xml")
+	}
+	return nil, ErrNotMusicXML
+}
+
+// rootDocument extrait le document principal d'une archive .mxl ou .mscz :
+// celui déclaré dans META-INF/container.xml, sinon le premier fichier portant l'une des extensions
+func rootDocument(data []byte, extensions ...string) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrInvalidScore
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	var root string
+	if container := files["META-INF/container.xml"]; container != nil {
+		if content, err := readZipFile(container); err == nil {
+			var c struct {
+				Rootfiles []struct {
+					FullPath string `xml:"full-path,attr"`
+				} `xml:"rootfiles>rootfile"`
+			}
+			if xml.Unmarshal(content, &c) == nil && len(c.Rootfiles) > 0 {
+				root = c.Rootfiles[0].FullPath
+			}
+		}
+	}
+	if files[root] == nil {
+		root = ""
+		for _, f := range archive.File {
+			if strings.HasPrefix(f.Name, "META-INF/") {
+				continue
+			}
+			for _, ext := range extensions {
+				if strings.EqualFold(path.Ext(f.Name), ext) {
+					root = f.Name
+					break
+				}
+			}
+			if root != "" {
+				break
+			}
+		}
+	}
+	if root == "" {
+		return nil, ErrInvalidScore
+	}
+	return readZipFile(files[root])
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, ErrInvalidScore
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxDocumentSize+1))
+	if err != nil || len(data) > maxDocumentSize {
+		return nil, ErrInvalidScore
+	}
+	return data, nil
+}
+
+// Tonalités majeures et mineures indexées par le nombre d'altérations + 7 (-7 = 7 bémols)
+var (
+	majorKeys = []string{"Cb", "Gb", "Db", "Ab", "Eb", "Bb", "F", "C", "G", "D", "A", "E", "B", "F#", "C#"}
+	minorKeys = []string{"Ab", "Eb", "Bb", "F", "C", "G", "D", "A", "E", "B", "F#", "C#", "G#", "D#", "A#"}
+)
+
+// KeyName retourne le nom de la tonalité, ex: KeyName(-3, false) = "Eb major"
+func KeyName(fifths int, minor bool) string {
+	if fifths < -7 || fifths > 7 {
+		return ""
+	}
+	if minor {
+		return minorKeys[fifths+7] + " minor"
+	}
+	return majorKeys[fifths+7] + " major"
+}
+
+// parseABC lit l'en-tête du premier air ABC
+func parseABC(data []byte) (*Metadata, error) {
+	tune, err := abc.Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	meta := &Metadata{
+		Title:    tune.Title(),
+		Composer: tune.Composer,
+		Key:      tune.Key.Name(),
+		Tempo:    tune.Tempo,
+	}
+	switch tune.Meter {
+	case "C":
+		meta.TimeSignature = "4/4"
+	case "C|":
+		meta.TimeSignature = "2/2"
+	case "none":
+	default:
+		meta.TimeSignature = tune.Meter
+	}
+	return meta, nil
+}
+
+func newDecoder(r io.Reader) *xml.Decoder {
+	d := xml.NewDecoder(r)
+	d.Strict = false // entités HTML dans certains exports
+	return d
+}
+
+func addPart(meta *Metadata, name string) {
+	if name = strings.TrimSpace(name); name != "" {
+		meta.Parts = append(meta.Parts, name)
+	}
+}
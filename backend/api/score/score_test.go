@@ -0,0 +1,145 @@
+package score
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMusicXML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 4.0 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">
+<score-partwise version="4.0">
+  <work><work-title>String Quartet Op. 18 No. 1</work-title></work>
+  <movement-title>Allegro con brio</movement-title>
+  <identification><creator type="composer">Ludwig van Beethoven</creator></identification>
+  <part-list>
+    <score-part id="P1"><part-name>Violin I</part-name></score-part>
+    <score-part id="P2"><part-name>Violin II</part-name></score-part>
+    <score-part id="P3"><part-name>Viola</part-name></score-part>
+    <score-part id="P4"><part-name>Violoncello</part-name></score-part>
+  </part-list>
+  <part id="P1">
+    <measure number="1">
+      <attributes>
+        <divisions>1</divisions>
+        <key><fifths>-1</fifths><mode>major</mode></key>
+        <time><beats>3</beats><beat-type>4</beat-type></time>
+      </attributes>
+      <direction><direction-type><metronome><beat-unit>half</beat-unit><beat-unit-dot/><per-minute>c. 60</per-minute></metronome></direction-type></direction>
+      <note><rest/><duration>3</duration></note>
+    </measure>
+  </part>
+</score-partwise>`
+
+const testMSCX = `<?xml version="1.0" encoding="UTF-8"?>
+<museScore version="4.20">
+  <Score>
+    <metaTag name="composer">Frédéric Chopin</metaTag>
+    <metaTag name="workTitle">Nocturne Op. 9 No. 2</metaTag>
+    <Part id="1"><trackName>Piano</trackName><Instrument id="piano"><longName>Piano</longName></Instrument></Part>
+    <Staff id="1">
+      <Measure>
+        <voice>
+          <KeySig><concertKey>-3</concertKey></KeySig>
+          <TimeSig><sigN>12</sigN><sigD>8</sigD></TimeSig>
+          <Tempo><tempo>1.5</tempo><text>Andante</text></Tempo>
+        </voice>
+      </Measure>
+    </Staff>
+  </Score>
+</museScore>`
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		f.Write([]byte(content))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestParseMusicXML(t *testing.T) {
+	meta, err := Parse([]byte(testMusicXML), FormatMusicXML)
+	require.NoError(t, err)
+	assert.Equal(t, "String Quartet Op. 18 No. 1", meta.Title)
+	assert.Equal(t, "Ludwig van Beethoven", meta.Composer)
+	assert.Equal(t, []string{"Violin I", "Violin II", "Viola", "Violoncello"}, meta.Parts)
+	assert.Equal(t, "F major", meta.Key)
+	assert.Equal(t, "3/4", meta.TimeSignature)
+	assert.Equal(t, 180, meta.Tempo) // blanche pointée à 60
+}
+
+func TestParseCompressedMusicXML(t *testing.T) {
+	data := zipArchive(t, map[string]string{
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="score/quartet.xml"/></rootfiles></container>`,
+		"score/quartet.xml":      testMusicXML,
+		"other.xml":              "<not-a-score/>",
+	})
+	meta, err := Parse(data, FormatMXL)
+	require.NoError(t, err)
+	assert.Equal(t, FormatMXL, meta.Format)
+	assert.Equal(t, "String Quartet Op. 18 No. 1", meta.Title)
+}
+
+func TestParseMuseScore(t *testing.T) {
+	data := zipArchive(t, map[string]string{"nocturne.mscx": testMSCX})
+	meta, err := Parse(data, FormatMSCZ)
+	require.NoError(t, err)
+	assert.Equal(t, "Nocturne Op. 9 No. 2", meta.Title)
+	assert.Equal(t, "Frédéric Chopin", meta.Composer)
+	assert.Equal(t, []string{"Piano"}, meta.Parts)
+	assert.Equal(t, "Eb major", meta.Key)
+	assert.Equal(t, "12/8", meta.TimeSignature)
+	assert.Equal(t, 90, meta.Tempo)
+}
+
+func TestParseABC(t *testing.T) {
+	data := []byte("X:1\nT:Drowsy Maggie\nC:Trad.\nR:reel\nM:C|\nL:1/8\nQ:1/2=100\nK:E dor\n|:E2BE dEBE|E2BE AFDF:|\n")
+	meta, err := Parse(data, FormatABC)
+	require.NoError(t, err)
+	assert.Equal(t, FormatABC, meta.Format)
+	assert.Equal(t, "Drowsy Maggie", meta.Title)
+	assert.Equal(t, "Trad.", meta.Composer)
+	assert.Equal(t, "E dorian", meta.Key)
+	assert.Equal(t, "2/2", meta.TimeSignature)
+	assert.Equal(t, 200, meta.Tempo)
+	assert.Empty(t, meta.Parts)
+
+	_, err = Parse([]byte("X:1\nT:No key\nCDE"), FormatABC)
+	assert.Error(t, err)
+
+	// Pas de transposition : l'ABC n'est pas un document MusicXML
+	_, err = Document(data, FormatABC)
+	assert.ErrorIs(t, err, ErrNotMusicXML)
+}
+
+func TestParseRejectsInvalidFiles(t *testing.T) {
+	_, err := Parse([]byte("<html></html>"), FormatMusicXML)
+	assert.ErrorIs(t, err, ErrInvalidScore)
+	_, err = Parse([]byte("not a zip"), FormatMSCZ)
+	assert.ErrorIs(t, err, ErrInvalidScore)
+	_, err = Parse(nil, "pdf")
+	assert.ErrorIs(t, err, ErrUnsupportedScore)
+
+	format, ok := FormatFromName("Quartet.MXL")
+	assert.True(t, ok)
+	assert.Equal(t, FormatMXL, format)
+	format, ok = FormatFromName("the-kesh.abc")
+	assert.True(t, ok)
+	assert.Equal(t, FormatABC, format)
+	_, ok = FormatFromName("quartet.pdf")
+	assert.False(t, ok)
+}
+
+func TestKeyName(t *testing.T) {
+	assert.Equal(t, "C major", KeyName(0, false))
+	assert.Equal(t, "C# minor", KeyName(4, true))
+	assert.Equal(t, "Cb major", KeyName(-7, false))
+	assert.Equal(t, "", KeyName(8, false))
+}
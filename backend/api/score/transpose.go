@@ -0,0 +1,570 @@
+package score
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Transposition d'un document MusicXML.
+// Le document est réécrit sur place : seuls les éléments pitch, root, bass (accords), fifths (armures)
+// et le texte des accidental changent (ainsi que transpose pour un instrument transpositeur), le reste du fichier est conservé octet pour octet.
+// Les hauteurs sont transposées par intervalle (degrés et demi-tons), ce qui conserve une orthographe correcte :
+// Fa# monté d'une tierce majeure donne La#, et non Sib.
+
+var (
+	ErrInvalidInterval = errors.New("invalid interval, expected e.g. M2, -m3, P5 or a number of semitones")
+	ErrInvalidKey      = errors.New("invalid key, expected e.g. Bb, F# or Eb")
+	ErrNoKeySignature  = errors.New("the score has no key signature")
+)
+
+// Interval : transposition en degrés diatoniques et en demi-tons, comme l'élément <transpose> de MusicXML.
+// Une tierce majeure ascendante vaut {Diatonic: 2, Chromatic: 4}.
+type Interval struct {
+	Diatonic  int `json:"diatonic"`
+	Chromatic int `json:"chromatic"`
+}
+
+// Add retourne la somme des deux intervalles
+func (i Interval) Add(o Interval) Interval {
+	return Interval{Diatonic: i.Diatonic + o.Diatonic, Chromatic: i.Chromatic + o.Chromatic}
+}
+
+// fifths : déplacement de l'intervalle sur le cycle des quintes (P5 = 1, M2 = 2, m3 = -3)
+func (i Interval) fifths() int {
+	step, alter, _ := transposePitch(0, 0, 0, i)
+	return letterFifths[step] + 7*alter
+}
+
+var (
+	letters       = "CDEFGAB"
+	naturalPC     = []int{0, 2, 4, 5, 7, 9, 11}
+	letterFifths  = []int{0, 2, 4, -1, 1, 3, 5}
+	perfectNumber = []bool{true, false, false, true, true, false, false}
+)
+
+// Intervalle par défaut pour un nombre de demi-tons (0 à 11)
+var semitoneIntervals = []string{"P1", "m2", "M2", "m3", "M3", "P4", "A4", "P5", "m6", "M6", "m7", "M7"}
+
+var intervalPattern = regexp.MustCompile(`^([+-]?)([PMmAd])([0-9]+)$`)
+
+// Transposition limitée à 4 octaves dans chaque sens : 48 demi-tons, intervalle de 29e
+const maxTransposeOctaves = 4
+
+// ParseInterval lit un intervalle : qualité et numéro ("M2", "-m3", "P5", "A4", "M9")
+// ou nombre de demi-tons ("-3", "+7"), orthographié avec l'intervalle le plus courant
+func ParseInterval(s string) (Interval, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < -12*maxTransposeOctaves || n > 12*maxTransposeOctaves {
+			return Interval{}, ErrInvalidInterval
+		}
+		sign, n := 1, n
+		if n < 0 {
+			sign, n = -1, -n
+		}
+		i, _ := ParseInterval(semitoneIntervals[n%12])
+		i.Diatonic += 7 * (n / 12)
+		i.Chromatic += 12 * (n / 12)
+		return Interval{Diatonic: sign * i.Diatonic, Chromatic: sign * i.Chromatic}, nil
+	}
+
+	m := intervalPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Interval{}, ErrInvalidInterval
+	}
+	number, err := strconv.Atoi(m[3])
+	if err != nil || number < 1 || number > 7*maxTransposeOctaves+1 {
+		return Interval{}, ErrInvalidInterval
+	}
+	simple := (number - 1) % 7
+	octaves := (number - 1) / 7
+	chromatic := naturalPC[simple]
+
+	switch quality := m[2]; {
+	case perfectNumber[simple] && quality == "P":
+	case !perfectNumber[simple] && quality == "M":
+	case !perfectNumber[simple] && quality == "m":
+		chromatic--
+	case quality == "A":
+		chromatic++
+	case quality == "d" && perfectNumber[simple]:
+		chromatic--
+	case quality == "d":
+		chromatic -= 2
+	default:
+		return Interval{}, ErrInvalidInterval
+	}
+
+	i := Interval{Diatonic: simple + 7*octaves, Chromatic: chromatic + 12*octaves}
+	if m[1] == "-" {
+		i = Interval{Diatonic: -i.Diatonic, Chromatic: -i.Chromatic}
+	}
+	return i, nil
+}
+
+// Instruments transpositeurs : intervalle entre le son réel et la note écrite
+var TransposingInstruments = map[string]string{
+	"clarinet-bb":   "M2",
+	"trumpet-bb":    "M2",
+	"soprano-sax":   "M2",
+	"clarinet-a":    "m3",
+	"horn-f":        "P5",
+	"english-horn":  "P5",
+	"alto-sax":      "M6",
+	"clarinet-eb":   "-m3",
+	"tenor-sax":     "M9",
+	"bass-clarinet": "M9",
+	"baritone-sax":  "M13",
+}
+
+// InstrumentInterval retourne l'intervalle de la partie écrite pour un instrument transpositeur
+func InstrumentInterval(instrument string) (Interval, error) {
+	spec, ok := TransposingInstruments[strings.ToLower(strings.TrimSpace(instrument))]
+	if !ok {
+		return Interval{}, fmt.Errorf("unknown transposing instrument %q", instrument)
+	}
+	return ParseInterval(spec)
+}
+
+var notePattern = regexp.MustCompile(`^([A-Ga-g])(#{1,2}|x|b{1,2}|♯|♭)?$`)
+
+// parseNote lit un nom de note ("Bb", "F#", "C") et retourne son degré (C = 0) et son altération
+func parseNote(s string) (int, int, error) {
+	m := notePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, 0, ErrInvalidKey
+	}
+	step := strings.IndexByte(letters, strings.ToUpper(m[1])[0])
+	alter := 0
+	switch m[2] {
+	case "#", "♯":
+		alter = 1
+	case "##", "x":
+		alter = 2
+	case "b", "♭":
+		alter = -1
+	case "bb":
+		alter = -2
+	}
+	return step, alter, nil
+}
+
+// IntervalToKey retourne l'intervalle le plus court (au plus un triton) entre la tonique de la tonalité
+// donnée par son armure et le mode, et la tonique cible ("Bb")
+func IntervalToKey(fifths int, minor bool, target string) (Interval, error) {
+	toStep, toAlter, err := parseNote(target)
+	if err != nil {
+		return Interval{}, err
+	}
+	// Tonique sur le cycle des quintes : Do majeur = 0, La mineur = 3
+	tonic := fifths
+	if minor {
+		tonic += 3
+	}
+	fromStep, fromAlter := 0, 0
+	for step, f := range letterFifths {
+		if (tonic-f)%7 == 0 {
+			fromStep, fromAlter = step, (tonic-f)/7
+		}
+	}
+
+	i := Interval{
+		Diatonic:  toStep - fromStep,
+		Chromatic: naturalPC[toStep] + toAlter - naturalPC[fromStep] - fromAlter,
+	}
+	// Ramené entre une quarte diminuée descendante et une quinte ascendante
+	for i.Diatonic < 0 {
+		i = i.Add(Interval{7, 12})
+	}
+	for i.Diatonic >= 7 {
+		i = i.Add(Interval{-7, -12})
+	}
+	if i.Chromatic > 6 {
+		i = i.Add(Interval{-7, -12})
+	}
+	return i, nil
+}
+
+// transposePitch transpose une hauteur (degré C = 0, altération, octave)
+func transposePitch(step int, alter int, octave int, i Interval) (int, int, int) {
+	index := step + i.Diatonic
+	newStep := ((index % 7) + 7) % 7
+	newOctave := octave + (index-newStep)/7
+	target := octave*12 + naturalPC[step] + alter + i.Chromatic
+	return newStep, target - (newOctave*12 + naturalPC[newStep]), newOctave
+}
+
+// KeySignature : première armure du document
+type KeySignature struct {
+	Fifths int
+	Minor  bool
+}
+
+// FirstKey retourne la première armure du document MusicXML
+func FirstKey(doc []byte) (*KeySignature, error) {
+	keys, err := keySignatures(doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKeySignature
+	}
+	return &keys[0], nil
+}
+
+func keySignatures(doc []byte) ([]KeySignature, error) {
+	d := newDecoder(bytes.NewReader(doc))
+	var keys []KeySignature
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return keys, nil
+		}
+		if err != nil {
+			return nil, ErrInvalidScore
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "key" {
+			var key struct {
+				Fifths *int   `xml:"fifths"`
+				Mode   string `xml:"mode"`
+			}
+			if d.DecodeElement(&key, &start) == nil && key.Fifths != nil {
+				keys = append(keys, KeySignature{Fifths: *key.Fifths, Minor: key.Mode == "minor"})
+			}
+		}
+	}
+}
+
+// Noms MusicXML des altérations
+var accidentalNames = map[int]string{-2: "flat-flat", -1: "flat", 0: "natural", 1: "sharp", 2: "double-sharp"}
+
+// Transpose retourne le document MusicXML transposé de l'intervalle.
+// Si une armure dépasse 7 altérations, l'intervalle est orthographié enharmoniquement (quarte augmentée → quinte diminuée).
+func Transpose(doc []byte, i Interval) ([]byte, error) {
+	keys, err := keySignatures(doc)
+	if err != nil {
+		return nil, err
+	}
+	i = fitKeySignatures(keys, i)
+	shift := i.fifths()
+
+	type replacement struct {
+		start, end int64
+		text       string
+	}
+	var replacements []replacement
+	var noteAlter *int // altération de la hauteur de la note en cours, pour ses accidental
+
+	d := newDecoder(bytes.NewReader(doc))
+	for {
+		start := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidScore
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch el.Name.Local {
+		case "note":
+			noteAlter = nil
+		case "pitch":
+			var p struct {
+				Step   string  `xml:"step"`
+				Alter  float64 `xml:"alter"`
+				Octave int     `xml:"octave"`
+			}
+			if err := d.DecodeElement(&p, &el); err != nil {
+				return nil, ErrInvalidScore
+			}
+			step, alter, octave, micro := transposeSpelled(p.Step, p.Alter, p.Octave, i)
+			if step == "" {
+				continue
+			}
+			text := "<pitch><step>" + step + "</step>"
+			if alter != 0 || micro != 0 {
+				text += "<alter>" + formatAlter(float64(alter)+micro) + "</alter>"
+			}
+			text += "<octave>" + strconv.Itoa(octave) + "</octave></pitch>"
+			replacements = append(replacements, replacement{start, d.InputOffset(), text})
+			if micro == 0 {
+				noteAlter = &alter
+			}
+		case "root", "bass":
+			prefix := el.Name.Local
+			var raw struct {
+				Inner []byte `xml:",innerxml"`
+			}
+			if err := d.DecodeElement(&raw, &el); err != nil {
+				return nil, ErrInvalidScore
+			}
+			stepText, alterText := childText(raw.Inner, prefix+"-step"), childText(raw.Inner, prefix+"-alter")
+			alterValue, _ := strconv.ParseFloat(strings.TrimSpace(alterText), 64)
+			step, alter, _, micro := transposeSpelled(stepText, alterValue, 0, i)
+			if step == "" {
+				continue
+			}
+			text := "<" + prefix + "><" + prefix + "-step>" + step + "</" + prefix + "-step>"
+			if alter != 0 || micro != 0 {
+				text += "<" + prefix + "-alter>" + formatAlter(float64(alter)+micro) + "</" + prefix + "-alter>"
+			}
+			text += "</" + prefix + ">"
+			replacements = append(replacements, replacement{start, d.InputOffset(), text})
+		case "fifths":
+			var fifths int
+			if err := d.DecodeElement(&fifths, &el); err != nil {
+				return nil, ErrInvalidScore
+			}
+			replacements = append(replacements, replacement{start, d.InputOffset(), "<fifths>" + strconv.Itoa(fifths+shift) + "</fifths>"})
+		case "accidental":
+			contentStart := d.InputOffset()
+			var value string
+			if err := d.DecodeElement(&value, &el); err != nil {
+				return nil, ErrInvalidScore
+			}
+			if noteAlter == nil {
+				continue
+			}
+			name, ok := accidentalNames[*noteAlter]
+			if !ok {
+				continue
+			}
+			// Seul le texte change : les attributs (cautionary, parentheses ...) sont conservés
+			end := d.InputOffset() - int64(len("</accidental>"))
+			if end < contentStart {
+				continue // élément vide <accidental/>
+			}
+			replacements = append(replacements, replacement{contentStart, end, name})
+		}
+	}
+
+	var out bytes.Buffer
+	var pos int64
+	for _, r := range replacements {
+		out.Write(doc[pos:r.start])
+		out.WriteString(r.text)
+		pos = r.end
+	}
+	out.Write(doc[pos:])
+	return out.Bytes(), nil
+}
+
+// Enfants de <attributes> qui précèdent <transpose> dans le schéma MusicXML
+var beforeTranspose = map[string]bool{
+	"divisions": true, "key": true, "time": true, "staves": true, "part-symbol": true,
+	"instruments": true, "clef": true, "staff-details": true,
+}
+
+// SetTransposition inscrit dans chaque partie d'un document transposé pour un instrument transpositeur
+// l'élément <transpose> (intervalle de la note écrite au son réel) : written est l'intervalle appliqué aux notes.
+// Un élément <transpose> existant est mis à jour, sinon il est ajouté aux premiers attributs de la partie.
+func SetTransposition(doc []byte, written Interval) ([]byte, error) {
+	type replacement struct {
+		start, end int64
+		text       string
+	}
+	var replacements []replacement
+	transposed := map[string]bool{}    // parties qui ont déjà un <transpose>
+	first := map[string]*replacement{} // ajout dans les premiers attributs de chaque partie
+	var parts []string
+	part := ""
+
+	d := newDecoder(bytes.NewReader(doc))
+	for {
+		start := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidScore
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch el.Name.Local {
+		case "part":
+			part = attr(el, "id")
+			if _, seen := first[part]; !seen {
+				first[part] = nil
+				parts = append(parts, part)
+			}
+		case "attributes":
+			innerStart := d.InputOffset()
+			selfClosing := bytes.HasSuffix(doc[start:innerStart], []byte("/>"))
+			var raw struct {
+				Inner []byte `xml:",innerxml"`
+			}
+			if err := d.DecodeElement(&raw, &el); err != nil {
+				return nil, ErrInvalidScore
+			}
+			if selfClosing {
+				if first[part] == nil {
+					first[part] = &replacement{start, d.InputOffset(), "<attributes>" + transposeElement(written.negate(), "") + "</attributes>"}
+				}
+				continue
+			}
+
+			insertAt := int64(0)
+			inner := newDecoder(bytes.NewReader(raw.Inner))
+			for {
+				childStart := inner.InputOffset()
+				tok, err := inner.Token()
+				if err != nil {
+					break
+				}
+				child, ok := tok.(xml.StartElement)
+				if !ok {
+					continue
+				}
+				if child.Name.Local != "transpose" {
+					inner.Skip()
+					if beforeTranspose[child.Name.Local] {
+						insertAt = inner.InputOffset()
+					}
+					continue
+				}
+				var t struct {
+					Diatonic     int       `xml:"diatonic"`
+					Chromatic    int       `xml:"chromatic"`
+					OctaveChange int       `xml:"octave-change"`
+					Double       *struct{} `xml:"double"`
+				}
+				if err := inner.DecodeElement(&t, &child); err != nil {
+					return nil, ErrInvalidScore
+				}
+				current := Interval{t.Diatonic + 7*t.OctaveChange, t.Chromatic + 12*t.OctaveChange}
+				text := transposeElement(current.Add(written.negate()), attr(child, "number"))
+				if text != "" && t.Double != nil {
+					text = strings.Replace(text, "</transpose>", "<double/></transpose>", 1)
+				}
+				replacements = append(replacements, replacement{innerStart + childStart, innerStart + inner.InputOffset(), text})
+				transposed[part] = true
+			}
+			if first[part] == nil {
+				first[part] = &replacement{innerStart + insertAt, innerStart + insertAt, transposeElement(written.negate(), "")}
+			}
+		}
+	}
+
+	for _, p := range parts {
+		if r := first[p]; r != nil && !transposed[p] {
+			replacements = append(replacements, *r)
+		}
+	}
+	sort.Slice(replacements, func(a, b int) bool { return replacements[a].start < replacements[b].start })
+
+	var out bytes.Buffer
+	var pos int64
+	for _, r := range replacements {
+		out.Write(doc[pos:r.start])
+		out.WriteString(r.text)
+		pos = r.end
+	}
+	out.Write(doc[pos:])
+	return out.Bytes(), nil
+}
+
+func (i Interval) negate() Interval {
+	return Interval{-i.Diatonic, -i.Chromatic}
+}
+
+// transposeElement écrit l'élément <transpose> de l'intervalle, les octaves entières dans octave-change.
+// Un intervalle nul (son réel) n'a pas d'élément.
+func transposeElement(i Interval, number string) string {
+	if i == (Interval{}) {
+		return ""
+	}
+	octaves := i.Chromatic / 12
+	i = i.Add(Interval{-7 * octaves, -12 * octaves})
+	text := "<transpose"
+	if number != "" {
+		text += ` number="` + number + `"`
+	}
+	text += "><diatonic>" + strconv.Itoa(i.Diatonic) + "</diatonic><chromatic>" + strconv.Itoa(i.Chromatic) + "</chromatic>"
+	if octaves != 0 {
+		text += "<octave-change>" + strconv.Itoa(octaves) + "</octave-change>"
+	}
+	return text + "</transpose>"
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// fitKeySignatures choisit l'orthographe de l'intervalle qui garde toutes les armures entre -7 et 7
+func fitKeySignatures(keys []KeySignature, i Interval) Interval {
+	worst := func(i Interval) int {
+		w := 0
+		for _, k := range keys {
+			w = max(w, abs(k.Fifths+i.fifths()))
+		}
+		return w
+	}
+	// Seconde diminuée : même hauteur, 12 quintes d'écart (Fa# → Solb)
+	for _, alt := range []Interval{i.Add(Interval{1, 0}), i.Add(Interval{-1, 0})} {
+		if worst(i) > 7 && worst(alt) < worst(i) {
+			i = alt
+		}
+	}
+	return i
+}
+
+// transposeSpelled transpose une hauteur écrite ("F", 1, 4 → "A", 1, 4 pour une tierce majeure).
+// Une altération microtonale (0.5) est conservée à part dans micro.
+func transposeSpelled(stepText string, alter float64, octave int, i Interval) (string, int, int, float64) {
+	stepText = strings.TrimSpace(stepText)
+	if len(stepText) != 1 || !strings.Contains(letters, stepText) {
+		return "", 0, 0, 0
+	}
+	whole := math.Round(alter)
+	micro := alter - whole
+	step, newAlter, newOctave := transposePitch(strings.Index(letters, stepText), int(whole), octave, i)
+	return string(letters[step]), newAlter, newOctave, micro
+}
+
+func formatAlter(alter float64) string {
+	return strconv.FormatFloat(alter, 'f', -1, 64)
+}
+
+// childText retourne le texte du premier élément enfant nommé name
+func childText(inner []byte, name string) string {
+	d := newDecoder(bytes.NewReader(inner))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return ""
+		}
+		if el, ok := tok.(xml.StartElement); ok && el.Name.Local == name {
+			var value string
+			d.DecodeElement(&value, &el)
+			return value
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,140 @@
+package score
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const transposeXML = `<?xml version="1.0" encoding="UTF-8"?>
+<score-partwise version="4.0">
+  <part id="P1">
+    <measure number="1">
+      <attributes><key><fifths>-1</fifths><mode>major</mode></key></attributes>
+      <harmony><root><root-step>F</root-step></root><kind>major</kind><bass><bass-step>C</bass-step></bass></harmony>
+      <note><pitch><step>F</step><octave>4</octave></pitch><duration>1</duration></note>
+      <note><pitch><step>B</step><octave>4</octave></pitch><duration>1</duration><accidental cautionary="yes">natural</accidental></note>
+      <note>
+        <pitch>
+          <step>F</step>
+          <alter>1</alter>
+          <octave>5</octave>
+        </pitch>
+        <duration>1</duration>
+        <accidental>sharp</accidental>
+      </note>
+    </measure>
+  </part>
+</score-partwise>`
+
+func TestParseInterval(t *testing.T) {
+	tests := map[string]Interval{
+		"M2":  {1, 2},
+		"-m3": {-2, -3},
+		"P5":  {4, 7},
+		"A4":  {3, 6},
+		"d5":  {4, 6},
+		"M9":  {8, 14},
+		"-3":  {-2, -3},
+		"+14": {8, 14},
+		"-48": {-28, -48},
+		"P29": {28, 48},
+	}
+	for spec, expected := range tests {
+		i, err := ParseInterval(spec)
+		if assert.NoError(t, err, spec) {
+			assert.Equal(t, expected, i, spec)
+		}
+	}
+	for _, spec := range []string{"M5", "P3", "X2", "", "-9223372036854775808", "49", "P99999999999999999999", "M30"} {
+		_, err := ParseInterval(spec)
+		assert.ErrorIs(t, err, ErrInvalidInterval, spec)
+	}
+}
+
+func TestIntervalToKey(t *testing.T) {
+	i, err := IntervalToKey(-3, false, "Bb") // Mib majeur → Sib : quarte descendante
+	require.NoError(t, err)
+	assert.Equal(t, Interval{-3, -5}, i)
+
+	i, err = IntervalToKey(0, true, "C") // La mineur → Do : tierce mineure ascendante
+	require.NoError(t, err)
+	assert.Equal(t, Interval{2, 3}, i)
+
+	_, err = IntervalToKey(0, false, "H")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestTransposeRespellsPitches(t *testing.T) {
+	out, err := Transpose([]byte(transposeXML), Interval{1, 2}) // Fa majeur → Sol majeur
+	require.NoError(t, err)
+	doc := string(out)
+
+	assert.Contains(t, doc, "<fifths>1</fifths>")
+	assert.Contains(t, doc, "<root><root-step>G</root-step></root>")
+	assert.Contains(t, doc, "<bass><bass-step>D</bass-step></bass>")
+	assert.Contains(t, doc, "<pitch><step>G</step><octave>4</octave></pitch>")
+	assert.Contains(t, doc, `<pitch><step>C</step><alter>1</alter><octave>5</octave></pitch><duration>1</duration><accidental cautionary="yes">sharp</accidental>`)
+	assert.Contains(t, doc, "<pitch><step>G</step><alter>1</alter><octave>5</octave></pitch>")
+	assert.Contains(t, doc, "<accidental>sharp</accidental>")
+	// Le reste du document est conservé
+	assert.True(t, strings.HasPrefix(doc, `<?xml version="1.0" encoding="UTF-8"?>`))
+	assert.Contains(t, doc, "<kind>major</kind>")
+
+	meta, err := Parse(out, FormatMusicXML)
+	require.NoError(t, err)
+	assert.Equal(t, "G major", meta.Key)
+}
+
+func TestTransposeKeepsKeySignaturesInRange(t *testing.T) {
+	doc := strings.Replace(transposeXML, "<fifths>-1</fifths>", "<fifths>6</fifths>", 1)
+	out, err := Transpose([]byte(doc), Interval{0, 1}) // Fa# majeur + unisson augmenté → Sol majeur
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "<fifths>1</fifths>")
+	assert.Contains(t, string(out), "<pitch><step>G</step><octave>5</octave></pitch>")
+}
+
+func TestInstrumentInterval(t *testing.T) {
+	i, err := InstrumentInterval("Clarinet-Bb")
+	require.NoError(t, err)
+	assert.Equal(t, Interval{1, 2}, i)
+	_, err = InstrumentInterval("kazoo")
+	assert.Error(t, err)
+}
+
+func TestSetTransposition(t *testing.T) {
+	doc := `<score-partwise version="4.0">
+  <part id="P1">
+    <measure number="1">
+      <attributes><divisions>1</divisions><key><fifths>0</fifths></key><clef><sign>G</sign><line>2</line></clef><measure-style><slash/></measure-style></attributes>
+      <note><pitch><step>C</step><octave>4</octave></pitch><duration>1</duration></note>
+    </measure>
+    <measure number="2"><attributes><key><fifths>1</fifths></key></attributes></measure>
+  </part>
+  <part id="P2">
+    <measure number="1">
+      <attributes><clef><sign>G</sign></clef><transpose number="1"><diatonic>-1</diatonic><chromatic>-2</chromatic><double/></transpose></attributes>
+    </measure>
+  </part>
+  <part id="P3"><measure number="1"><attributes/></measure></part>
+</score-partwise>`
+
+	// Partie de saxophone ténor : écrite une neuvième majeure au-dessus du son réel
+	out, err := SetTransposition([]byte(doc), Interval{8, 14})
+	require.NoError(t, err)
+	result := string(out)
+	assert.Contains(t, result, "</clef><transpose><diatonic>-1</diatonic><chromatic>-2</chromatic><octave-change>-1</octave-change></transpose><measure-style>",
+		"added after the clef, before measure-style")
+	assert.Equal(t, 3, strings.Count(result, "<transpose"), "one element per part, in its first attributes")
+	assert.Contains(t, result, `<transpose number="1"><diatonic>-2</diatonic><chromatic>-4</chromatic><octave-change>-1</octave-change><double/></transpose>`,
+		"the existing element of P2 is updated")
+	assert.Contains(t, result, "<attributes><transpose><diatonic>-1</diatonic><chromatic>-2</chromatic><octave-change>-1</octave-change></transpose></attributes>")
+
+	// Retour au son réel : l'élément disparaît
+	out, err = SetTransposition(out, Interval{-8, -14})
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "<transpose><")
+	assert.Contains(t, string(out), `<transpose number="1"><diatonic>-1</diatonic><chromatic>-2</chromatic><double/></transpose>`)
+}
@@ -1,6 +1,8 @@
 package seed
 
 import (
+	"backend/api/config"
+	"backend/api/library"
 	"backend/api/models"
 	"errors"
 	"fmt"
@@ -17,10 +19,33 @@ func Load(db *gorm.DB, email string, password string) {
 		&models.User{},
 		&models.Sheet{},
 		&models.Composer{},
+		&models.ComposerAlias{},
+		&models.SheetPart{},
+		&models.SheetMedia{},
+		&models.Work{},
+		&models.SheetRevision{},
+		&models.WatermarkCategory{},
+		&models.SheetDistribution{},
+		&models.SheetCopy{},
+		&models.SheetLoan{},
 	); err != nil {
 		log.Fatalf("cannot migrate table: %v", err)
 	}
 
+	// Numéros de catalogue des partitions existantes, déduits de leur titre
+	if updated, err := models.FillCatalogueNumbers(db); err != nil {
+		log.Printf("catalogue numbers: %v\n", err)
+	} else if updated > 0 {
+		fmt.Printf("Catalogue numbers filled for %d sheets\n", updated)
+	}
+
+	// Hash des PDF uploadés avant la détection des doublons
+	if updated, err := library.BackfillHashes(db, config.Config().ConfigPath); err != nil {
+		log.Printf("file hashes: %v\n", err)
+	} else if updated > 0 {
+		fmt.Printf("File hashes filled for %d sheets\n", updated)
+	}
+
 	var existing models.User
 
 	// Vérification de l'existence de l'utilisateur administrateur
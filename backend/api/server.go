@@ -3,8 +3,12 @@ package api
 import (
 	"backend/api/config"
 	"backend/api/controllers"
+	"backend/api/library"
 	"backend/api/seed"
+	"encoding/json"
 	"fmt"
+	"log"
+	"os"
 )
 
 var server = controllers.Server{}
@@ -41,3 +45,31 @@ func RunWithPort(port int, version string) {
 
 	server.Run(fmt.Sprintf("0.0.0.0:%d", port), config.Config().Dev)
 }
+
+// CheckLibrary() est appelée depuis la ligne de commande (sf-backend check [--fix]).
+// Elle vérifie la cohérence entre la base de données et les répertoires de la bibliothèque,
+// affiche le rapport en JSON et retourne un code de sortie 1 s'il reste des incohérences non réparées.
+func CheckLibrary(fix bool, version string) {
+	server.Initialize(version)
+
+	seed.Load(server.DB, config.Config().AdminEmail, config.Config().AdminPassword)
+
+	report, err := library.Check(server.DB, config.Config().ConfigPath, fix)
+	if err != nil {
+		log.Fatalf("library check failed: %v", err)
+	}
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+
+	unresolved := 0
+	for _, issue := range report.Issues {
+		if !issue.Fixed {
+			unresolved++
+		}
+	}
+	fmt.Printf("%d issue(s) found, %d unresolved\n", len(report.Issues), unresolved)
+	if unresolved > 0 {
+		os.Exit(1)
+	}
+}
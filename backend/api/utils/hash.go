@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// HashReader calcule le SHA-256 (hexadécimal) du contenu lu
+// Si r implémente io.Seeker, il est rembobiné au début après lecture pour pouvoir être relu.
+func HashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	if s, ok := r.(io.Seeker); ok {
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashFile calcule le SHA-256 (hexadécimal) d'un fichier sur le disque
+func HashFile(fullpath string) (string, error) {
+	f, err := os.Open(fullpath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return HashReader(f)
+}
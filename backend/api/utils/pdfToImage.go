@@ -11,24 +11,42 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 // POST request onto pdf creation Locat !
 // api/utils/pdfToImage.go
+// Retourne false si le service pdf2png n'a pas produit le thumbnail : il sera rendu à la demande par GET /sheet/thumbnail/:name
 func RequestToPdfToImage(path string, name string) bool {
 	fmt.Println("📄 PDF source:", path)
 	fmt.Println("🖼 Thumbnail name:", name)
 	//	sendRequest(path, name, "https://pdf2png.sheetable.net/createthumbnail")
-	sendRequest(path, name, "http://localhost:5000/createthumbnail")
-	return true
+	return sendRequest(path, name, "http://localhost:5000/createthumbnail")
 }
 
 func sendRequest(pdfPath string, name string, remoteURL string) bool {
+	thumbnailPath := path.Join(
+		config.Config().ConfigPath,
+		"sheets/thumbnails",
+		name+".png",
+	)
+	if err := renderRequest(pdfPath, thumbnailPath, remoteURL); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// RenderPdfToPng convertit la première page du PDF en PNG via le service pdf2png et l'écrit dans outPath
+func RenderPdfToPng(pdfPath string, outPath string) error {
+	return renderRequest(pdfPath, outPath, "http://localhost:5000/createthumbnail")
+}
+
+func renderRequest(pdfPath string, outPath string, remoteURL string) error {
 	file, err := os.Open(pdfPath)
 	if err != nil {
-		log.Println("open pdf:", err)
-		return false
+		return fmt.Errorf("open pdf: %w", err)
 	}
 	defer file.Close()
 
@@ -37,18 +55,16 @@ func sendRequest(pdfPath string, name string, remoteURL string) bool {
 
 	part, err := writer.CreateFormFile("file", filepath.Base(pdfPath))
 	if err != nil {
-		log.Println(err)
-		return false
+		return err
 	}
 	io.Copy(part, file)
 
-	writer.WriteField("name", name)
+	writer.WriteField("name", strings.TrimSuffix(filepath.Base(outPath), filepath.Ext(outPath)))
 	writer.Close()
 
 	req, err := http.NewRequest("POST", remoteURL, body)
 	if err != nil {
-		log.Println(err)
-		return false
+		return err
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
@@ -58,31 +74,29 @@ func sendRequest(pdfPath string, name string, remoteURL string) bool {
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Println("request failed:", err)
-		return false
+		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Println("pdf2png returned:", resp.Status)
-		return false
+		return fmt.Errorf("pdf2png returned: %s", resp.Status)
 	}
 
-	thumbnailPath := path.Join(
-		config.Config().ConfigPath,
-		"sheets/thumbnails",
-		name+".png",
-	)
-
-	out, err := os.Create(thumbnailPath)
+	// Écriture dans un fichier temporaire pour ne jamais laisser une image tronquée
+	tmp, err := os.CreateTemp(path.Dir(outPath), ".render-*")
 	if err != nil {
-		log.Println(err)
-		return false
+		return err
 	}
-	defer out.Close()
-
-	io.Copy(out, resp.Body)
-	return true
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), outPath)
 }
 
 func Upload(client *http.Client, url string, values map[string]io.Reader, name string) (err error) {
@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	defaultPortrait     []byte
+	defaultPortraitOnce sync.Once
+)
+
+// Tailles standard des portraits (carrés, en pixels).
+// La grande taille est stockée dans composer/<safe_name>.png, les autres dans composer/<taille>/<safe_name>.png
+var PortraitSizes = map[string]int{
+	"small":  64,
+	"medium": 256,
+	"large":  512,
+}
+
+const DefaultPortraitSize = "large"
+
+// Taille maximale d'un portrait uploadé ou téléchargé
+const maxPortraitBytes = 10 << 20
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image, expected JPEG, PNG, WebP or GIF")
+	ErrPortraitTooLarge = errors.New("portrait exceeds 10 MB")
+	ErrPortraitAddress  = errors.New("portrait url must be a public http or https address")
+)
+
+// PortraitAddressAllowed indique si le serveur peut télécharger un portrait depuis l'adresse ip.
+// Les adresses privées, locales et de lien local sont refusées. Remplaçable dans les tests.
+var PortraitAddressAllowed = isPublicAddress
+
+// Le portrait est téléchargé depuis une URL saisie par l'utilisateur : l'adresse est vérifiée
+// à la connexion (après résolution DNS et à chaque redirection), sans proxy.
+var portraitClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network string, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !PortraitAddressAllowed(ip) {
+					return ErrPortraitAddress
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		if !IsRemoteURL(req.URL.String()) {
+			return ErrPortraitAddress
+		}
+		return nil
+	},
+}
+
+// isPublicAddress refuse les adresses de boucle locale, privées (RFC 1918, fc00::/7, 100.64.0.0/10),
+// de lien local (dont 169.254.169.254 des métadonnées cloud), multicast et non spécifiées
+func isPublicAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+		return false
+	}
+	return true
+}
+
+// DefaultPortraitPNG retourne une silhouette neutre (PNG 256x256) servie quand un compositeur n'a pas de portrait.
+// Elle est générée une seule fois en mémoire, plutôt que de pointer vers une image hébergée sur un site externe.
+func DefaultPortraitPNG() []byte {
+	defaultPortraitOnce.Do(func() {
+		const size = 256
+		background := color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+		figure := color.RGBA{0x9e, 0x9e, 0x9e, 0xff}
+
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				dx, dy := x-size/2, y-size*3/8
+				head := dx*dx+dy*dy <= (size/6)*(size/6)
+				sx, sy := x-size/2, y-size
+				shoulders := sx*sx+sy*sy <= (size*3/8)*(size*3/8)
+				if head || shoulders {
+					img.Set(x, y, figure)
+				} else {
+					img.Set(x, y, background)
+				}
+			}
+		}
+
+		var buf bytes.Buffer
+		png.Encode(&buf, img)
+		defaultPortrait = buf.Bytes()
+	})
+	return defaultPortrait
+}
+
+// DecodePortrait lit une image JPEG, PNG, WebP ou GIF.
+// Le format est déterminé par le contenu et non par l'extension ou le Content-Type.
+func DecodePortrait(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxPortraitBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPortraitBytes {
+		return nil, ErrPortraitTooLarge
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	switch format {
+	case "jpeg", "png", "webp", "gif":
+		return img, nil
+	}
+	return nil, ErrUnsupportedImage
+}
+
+// FetchPortrait télécharge et décode un portrait distant, en http ou https uniquement
+func FetchPortrait(url string) (image.Image, error) {
+	if !IsRemoteURL(url) {
+		return nil, ErrPortraitAddress
+	}
+	resp, err := portraitClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download portrait %s: %s", url, resp.Status)
+	}
+	return DecodePortrait(resp.Body)
+}
+
+// IsRemoteURL indique si une URL de portrait pointe vers un site externe
+func IsRemoteURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// PortraitPath retourne le chemin du portrait d'un compositeur pour une taille donnée
+func PortraitPath(dir string, safeName string, size string) string {
+	if size == DefaultPortraitSize {
+		return path.Join(dir, safeName+".png")
+	}
+	return path.Join(dir, size, safeName+".png")
+}
+
+// PortraitFiles retourne les chemins du portrait d'un compositeur dans toutes les tailles,
+// grande taille en premier puis par ordre alphabétique (l'ordre est le même pour tous les compositeurs)
+func PortraitFiles(dir string, safeName string) []string {
+	var sizes []string
+	for size := range PortraitSizes {
+		if size != DefaultPortraitSize {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Strings(sizes)
+
+	files := []string{PortraitPath(dir, safeName, DefaultPortraitSize)}
+	for _, size := range sizes {
+		files = append(files, PortraitPath(dir, safeName, size))
+	}
+	return files
+}
+
+// SavePortrait recadre l'image en carré centré puis l'enregistre en PNG dans toutes les tailles standard
+func SavePortrait(img image.Image, dir string, safeName string) error {
+	square := cropSquare(img)
+	for size := range PortraitSizes {
+		if err := savePortraitSize(square, dir, safeName, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DownloadPortrait télécharge un portrait distant et l'enregistre localement
+func DownloadPortrait(url string, dir string, safeName string) error {
+	img, err := FetchPortrait(url)
+	if err != nil {
+		return err
+	}
+	return SavePortrait(img, dir, safeName)
+}
+
+// EnsurePortraitSize génère une taille manquante à partir de la grande taille.
+// Utile pour les portraits enregistrés avant l'introduction des tailles standard.
+func EnsurePortraitSize(dir string, safeName string, size string) (string, error) {
+	target := PortraitPath(dir, safeName, size)
+	if _, err := os.Stat(target); err == nil {
+		return target, nil
+	}
+	f, err := os.Open(PortraitPath(dir, safeName, DefaultPortraitSize))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	img, err := DecodePortrait(f)
+	if err != nil {
+		return "", err
+	}
+	if err := savePortraitSize(cropSquare(img), dir, safeName, size); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+func cropSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, image.Point{X: x0, Y: y0}, draw.Src)
+	return square
+}
+
+// savePortraitSize écrit d'abord un fichier temporaire pour ne jamais laisser un portrait tronqué
+func savePortraitSize(square image.Image, dir string, safeName string, size string) error {
+	side := PortraitSizes[size]
+	resized := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), square, square.Bounds(), draw.Src, nil)
+
+	target := PortraitPath(dir, safeName, size)
+	if err := os.MkdirAll(path.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := target + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, resized); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, target)
+}
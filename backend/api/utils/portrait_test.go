@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testImage : image 300x200 rouge à gauche, bleue à droite
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			if x < 150 {
+				img.Set(x, y, color.RGBA{0xff, 0, 0, 0xff})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 0xff, 0xff})
+			}
+		}
+	}
+	return img
+}
+
+func encodeImage(t *testing.T, format string) []byte {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, testImage())
+	case "jpeg":
+		err = jpeg.Encode(&buf, testImage(), nil)
+	case "gif":
+		err = gif.Encode(&buf, testImage(), nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodePortraitFormats(t *testing.T) {
+	for _, format := range []string{"png", "jpeg", "gif"} {
+		img, err := DecodePortrait(bytes.NewReader(encodeImage(t, format)))
+		if assert.NoError(t, err, format) {
+			assert.Equal(t, 300, img.Bounds().Dx(), format)
+		}
+	}
+}
+
+func TestDecodePortraitRejectsNonImage(t *testing.T) {
+	_, err := DecodePortrait(bytes.NewReader([]byte("%PDF-1.4 not an image")))
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+}
+
+func TestSavePortraitCropsAndResizes(t *testing.T) {
+	dir := t.TempDir()
+	if err := SavePortrait(testImage(), dir, "chopin"); err != nil {
+		t.Fatal(err)
+	}
+
+	for size, side := range PortraitSizes {
+		f, err := os.Open(PortraitPath(dir, "chopin", size))
+		if !assert.NoError(t, err, size) {
+			continue
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if assert.NoError(t, err, size) {
+			assert.Equal(t, image.Rect(0, 0, side, side), img.Bounds(), size)
+			// Recadrage centré : moitié gauche rouge, moitié droite bleue
+			r, _, b, _ := img.At(side/4, side/2).RGBA()
+			assert.True(t, r > b, size)
+			r, _, b, _ = img.At(side*3/4, side/2).RGBA()
+			assert.True(t, b > r, size)
+		}
+	}
+	assert.FileExists(t, dir+"/chopin.png")
+}
+
+func TestDownloadPortrait(t *testing.T) {
+	data := encodeImage(t, "jpeg")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chopin.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	// Le serveur de test écoute sur 127.0.0.1 : refusé tant que les adresses locales ne sont pas permises
+	dir := t.TempDir()
+	assert.ErrorIs(t, DownloadPortrait(srv.URL+"/chopin.jpg", dir, "chopin"), ErrPortraitAddress)
+	allowLocalPortraits(t)
+
+	assert.Error(t, DownloadPortrait(srv.URL+"/missing.jpg", dir, "chopin"))
+	assert.NoFileExists(t, PortraitPath(dir, "chopin", DefaultPortraitSize))
+
+	assert.NoError(t, DownloadPortrait(srv.URL+"/chopin.jpg", dir, "chopin"))
+	assert.FileExists(t, PortraitPath(dir, "chopin", "small"))
+}
+
+// allowLocalPortraits permet de télécharger depuis un serveur httptest le temps du test
+func allowLocalPortraits(t *testing.T) {
+	previous := PortraitAddressAllowed
+	PortraitAddressAllowed = func(net.IP) bool { return true }
+	t.Cleanup(func() { PortraitAddressAllowed = previous })
+}
+
+func TestFetchPortraitRefusesPrivateAddresses(t *testing.T) {
+	for _, url := range []string{
+		"file:///etc/passwd",
+		"ftp://example.com/chopin.jpg",
+		"http://127.0.0.1/chopin.jpg",
+		"http://localhost:8080/chopin.jpg",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/chopin.jpg",
+		"http://[::1]/chopin.jpg",
+	} {
+		_, err := FetchPortrait(url)
+		assert.ErrorIs(t, err, ErrPortraitAddress, url)
+	}
+
+	for ip, public := range map[string]bool{
+		"93.184.216.34": true,
+		"2606:2800::1":  true,
+		"192.168.1.10":  false,
+		"172.16.0.1":    false,
+		"100.64.0.1":    false,
+		"fe80::1":       false,
+		"fd00::1":       false,
+		"0.0.0.0":       false,
+	} {
+		assert.Equal(t, public, isPublicAddress(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestFetchPortraitLimitsSize(t *testing.T) {
+	allowLocalPortraits(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, maxPortraitBytes+1))
+	}))
+	defer srv.Close()
+
+	_, err := FetchPortrait(srv.URL + "/huge.png")
+	assert.ErrorIs(t, err, ErrPortraitTooLarge)
+}
+
+func TestEnsurePortraitSizeFromLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(PortraitPath(dir, "chopin", DefaultPortraitSize), encodeImage(t, "png"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := EnsurePortraitSize(dir, "chopin", "medium")
+	assert.NoError(t, err)
+	assert.Equal(t, PortraitPath(dir, "chopin", "medium"), p)
+	assert.FileExists(t, p)
+
+	_, err = EnsurePortraitSize(dir, "liszt", "medium")
+	assert.Error(t, err)
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-unidecode"
+)
+
+// NameTokens découpe un nom de personne en mots ASCII minuscules.
+// La ponctuation est ignorée ainsi que les suffixes (II, Jr ...).
+// Exemple : "Frédéric-François Chopin" → [frederic francois chopin]
+func NameTokens(name string) []string {
+	ascii := strings.ToLower(unidecode.Unidecode(name))
+	fields := strings.FieldsFunc(ascii, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := fields[:0]
+	for _, f := range fields {
+		switch f {
+		case "i", "ii", "iii", "jr", "sr", "the", "elder", "younger":
+			continue
+		}
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
+
+// Levenshtein retourne la distance d'édition entre a et b
+func Levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// StringSimilarity retourne une similarité entre 0 (différent) et 1 (identique)
+func StringSimilarity(a string, b string) float64 {
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(Levenshtein(a, b))/float64(longest)
+}
+
+// NameSimilarity compare deux noms de personnes après translittération.
+// Elle retourne un score entre 0 et 1 et la raison principale :
+//   - "same name"       : "Frédéric Chopin" / "Frederic Chopin"
+//   - "name contained"  : "Chopin" / "Frédéric Chopin", "J. S. Bach" / "Johann Sebastian Bach"
+//   - "similar spelling": "Tchaikovsky" / "Tschaikowsky"
+func NameSimilarity(a string, b string) (float64, string) {
+	ta, tb := NameTokens(a), NameTokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0, ""
+	}
+	ja, jb := strings.Join(ta, " "), strings.Join(tb, " ")
+	if ja == jb {
+		return 1, "same name"
+	}
+
+	// Même nom de famille (dernier mot) et prénoms compatibles
+	if len(ta) > len(tb) {
+		ta, tb = tb, ta
+	}
+	surname := StringSimilarity(ta[len(ta)-1], tb[len(tb)-1])
+	if surname >= 0.75 && givenNamesCompatible(ta[:len(ta)-1], tb[:len(tb)-1]) {
+		if surname == 1 {
+			return 0.9, "name contained"
+		}
+		return 0.9 * surname, "similar spelling"
+	}
+
+	score := StringSimilarity(ja, jb)
+	if score >= 0.8 {
+		return score, "similar spelling"
+	}
+	return score, ""
+}
+
+// Chaque prénom du nom le plus court doit se retrouver, ou son initiale, dans le nom le plus long
+func givenNamesCompatible(short []string, long []string) bool {
+	for _, s := range short {
+		found := false
+		for _, l := range long {
+			if s == l || (len(s) == 1 && strings.HasPrefix(l, s)) || (len(l) == 1 && strings.HasPrefix(s, l)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b      string
+		duplicate bool
+	}{
+		{"Frédéric Chopin", "Frederic Chopin", true},
+		{"Chopin", "Frédéric Chopin", true},
+		{"J. S. Bach", "Johann Sebastian Bach", true},
+		{"Tchaikovsky", "Tschaikowsky", true},
+		{"Clara Schumann", "Robert Schumann", false},
+		{"Johann Strauss II", "Richard Strauss", false},
+		{"Ravel", "Debussy", false},
+	}
+	for _, c := range cases {
+		score, _ := NameSimilarity(c.a, c.b)
+		assert.Equal(t, c.duplicate, score >= 0.75, "%s / %s: %.2f", c.a, c.b, score)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, Levenshtein("bach", "bach"))
+	assert.Equal(t, 3, Levenshtein("kitten", "sitting"))
+	assert.Equal(t, 5, Levenshtein("", "liszt"))
+}
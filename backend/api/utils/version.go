@@ -36,5 +36,5 @@ func PrintAsciiVersion(version string) {
 | |_) | (_| | (__|   <  __/ | | | (_| | 	 ___) |  __/ |   \ V /  __/ |
 |____/ \__,_|\___|_|\_\___|_| |_|\__,_| 	|____/ \___|_|    \_/ \___|_|   `
 
-	fmt.Printf(asciiArt + version + "\n \n")
+	fmt.Print(asciiArt + version + "\n \n")
 }
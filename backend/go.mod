@@ -9,6 +9,7 @@ module backend
 
 // | Package                                  |   Rôle / Usage principal                                                                                                                                 |
 // | ---------------------------------------- | ------------------------------------------------------------------------------------------------------------------------------------------------------ |
+// | `github.com/HugoSmits86/nativewebp`      |  Encodeur **WebP** en pur Go (sans cgo), pour les aperçus de pages et les miniatures servis en `format=webp`.                                           |
 // | `github.com/gin-contrib/cors`            |  Middleware pour **Golang Gin** permettant de gérer les **CORS** (Cross-Origin Resource Sharing) pour ton API.                                          |
 // | `github.com/gin-gonic/gin`               |  Framework web léger et performant pour Go, utilisé pour créer des **routes, handlers, middlewares**, etc.                                              |
 // | `github.com/glebarez/sqlite`             |  Driver **SQLite** compatible avec **GORM**, permettant d’utiliser SQLite comme base de données locale pour tests ou production légère.                 |
@@ -17,8 +18,11 @@ module backend
 // | `github.com/golobby/config/v3`           |  Librairie pour **charger la configuration** depuis fichiers `.env` ou variables d’environnement avec mapping direct sur des structs Go.                |
 // | `github.com/google/uuid`                 |  Génération d’**UUIDs** pour identifiants uniques, tokens, clés, etc.                                                                                   |
 // | `github.com/mozillazg/go-unidecode`      |  **Translittération Unicode → ASCII**. Par exemple `Éléphant.pdf` devient `Elephant.pdf`. Utile pour noms de fichiers ou URLs “sûres”.                  |
+// | `github.com/pdfcpu/pdfcpu`               |  Lecture et manipulation de **PDF en pur Go** : métadonnées (Info/XMP), nombre de pages, extraction, rotation, filigrane, etc.                          |
+// | `github.com/skip2/go-qrcode`             |  Génération des **QR codes** (PNG) du lien d'une partition, pour `qr.png` et les planches d'étiquettes des classeurs.                                   |
 // | `github.com/stretchr/testify`            |  Framework de **tests unitaires** Go, avec assertions (`assert`) et mocks pour simplifier l’écriture de tests.                                          |
 // | `golang.org/x/crypto`                    |  Fournit des fonctions **cryptographiques avancées**, comme bcrypt, PBKDF2, AES, etc., pour le hachage des mots de passe et la sécurité.                |
+// | `golang.org/x/image`                     |  Décodage **WebP** (`x/image/webp`) et redimensionnement de qualité (`x/image/draw`) des portraits, miniatures et aperçus.                              |
 // | `gorm.io/driver/mysql`                   |  Driver **MySQL/MariaDB** pour GORM. Permet de se connecter et interagir avec une base MySQL via GORM.                                                  |
 // | `gorm.io/driver/postgres`                |  Driver **PostgreSQL** pour GORM. Permet de se connecter et interagir avec une base PostgreSQL via GORM.                                                |
 // | `gorm.io/gorm`                           |  ORM (**Object-Relational Mapping**) pour Go. Facilite les interactions avec différentes bases SQL (MySQL, PostgreSQL, SQLite) avec des structs Go.     |
@@ -26,6 +30,7 @@ module backend
 go 1.24.0
 
 require (
+	github.com/HugoSmits86/nativewebp v1.2.0
 	github.com/gin-contrib/cors v1.7.6
 	github.com/gin-gonic/gin v1.11.0
 	github.com/glebarez/sqlite v1.11.0
@@ -34,8 +39,11 @@ require (
 	github.com/golobby/config/v3 v3.2.2
 	github.com/google/uuid v1.6.0
 	github.com/mozillazg/go-unidecode v0.2.0
+	github.com/pdfcpu/pdfcpu v0.11.1
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/stretchr/testify v1.11.1
 	golang.org/x/crypto v0.48.0
+	golang.org/x/image v0.32.0
 	gorm.io/driver/mysql v1.6.0
 	gorm.io/driver/postgres v1.6.0
 	gorm.io/gorm v1.31.1
@@ -46,6 +54,7 @@ require (
 	github.com/BurntSushi/toml v0.4.1 // indirect
 	github.com/bytedance/sonic v1.14.0 // indirect
 	github.com/bytedance/sonic/loader v0.3.0 // indirect
+	github.com/clipperhouse/uax29/v2 v2.2.0 // indirect
 	github.com/cloudwego/base64x v0.1.6 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
@@ -60,6 +69,9 @@ require (
 	github.com/golobby/cast v1.1.4 // indirect
 	github.com/golobby/dotenv v1.2.0 // indirect
 	github.com/golobby/env/v2 v2.1.0 // indirect
+	github.com/hhrutter/lzw v1.0.0 // indirect
+	github.com/hhrutter/pkcs7 v0.2.0 // indirect
+	github.com/hhrutter/tiff v1.0.2 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
 	github.com/jackc/pgx/v5 v5.6.0 // indirect
@@ -71,10 +83,12 @@ require (
 	github.com/kr/text v0.2.0 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/mattn/go-runewidth v0.0.19 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/ncruces/go-strftime v1.0.0 // indirect
 	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/quic-go/qpack v0.5.1 // indirect
 	github.com/quic-go/quic-go v0.54.0 // indirect
@@ -92,6 +106,7 @@ require (
 	golang.org/x/text v0.34.0 // indirect
 	golang.org/x/tools v0.41.0 // indirect
 	google.golang.org/protobuf v1.36.9 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	modernc.org/libc v1.67.6 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
@@ -3,16 +3,30 @@ package main
 import (
 	"backend/api"
 	"backend/api/utils"
+	"flag"
+	"os"
 )
 
 // main.go appelle dans la package api, api.Run() (fichier server.go)
 // api.Run() appelle server.Initialize() (fichier base.go)
 // server.Initialize() appelle server.SetupRouter() (fichier routes.go)
 // SetupRouter() (dans routes.go) définit toutes les routes Gin
+//
+// Sous-commande disponible :
+//	sf-backend check [--fix]  → vérifie la cohérence base de données / fichiers (api.CheckLibrary() dans server.go)
 
 var Version string = "DEV"
 
 func main() {
 	utils.PrintAsciiVersion(Version) // affiche une bannière ASCII avec la version du serveur fichier version.go
-	api.Run(Version)                 // appelle api.Run() dans server.go pour démarrer le serveur Gin
+
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		checkCmd := flag.NewFlagSet("check", flag.ExitOnError)
+		fix := checkCmd.Bool("fix", false, "repair or quarantine the inconsistencies found")
+		checkCmd.Parse(os.Args[2:])
+		api.CheckLibrary(*fix, Version)
+		return
+	}
+
+	api.Run(Version) // appelle api.Run() dans server.go pour démarrer le serveur Gin
 }